@@ -7,9 +7,7 @@ import Script from 'next/script';
 import { useRouter, useSearchParams } from 'next/navigation';
 import Navbar from '@/components/Navbar';
 import OptimizedModelViewer from '@/components/OptimizedModelViewer';
-
-const BASE_URL = process.env.NEXT_PUBLIC_API_URL?.replace('/api/v1', '') || '';
-const CDN_DOMAIN = process.env.NEXT_PUBLIC_CDN_DOMAIN || '';
+import { storageUrl } from '@/utils/storage-url';
 
 function ModelsContent() {
     const router = useRouter();
@@ -96,7 +94,7 @@ function ModelsContent() {
         e.preventDefault();
         setError('');
 
-        if (!formData.name || !formData.glbFile || !formData.usdzFile) {
+        if (!formData.name || !formData.glbFile) {
             setError('Please fill in all required fields');
             return;
         }
@@ -228,9 +226,7 @@ function ModelsContent() {
                                     <div className="aspect-square relative group">
                                         <img
                                             src={model.thumbnail
-                                                ? (CDN_DOMAIN
-                                                    ? `${CDN_DOMAIN}/${model.thumbnail}`
-                                                    : `${BASE_URL}${model.thumbnail}.png`)
+                                                ? storageUrl(model.thumbnail, '.png')
                                                 : '/placeholder-model.png'}
                                             alt={model.name}
                                             className="w-full h-full object-cover"
@@ -312,14 +308,14 @@ function ModelsContent() {
                                             />
                                         </div>
                                         <div>
-                                            <label className="block text-sm font-medium text-gray-700">USDZ File</label>
+                                            <label className="block text-sm font-medium text-gray-700">USDZ File (optional)</label>
                                             <input
                                                 type="file"
                                                 accept=".usdz"
                                                 onChange={(e) => handleFileChange(e, 'usdzFile')}
                                                 className="mt-1 block w-full text-sm text-gray-500 file:mr-4 file:py-2 file:px-4 file:rounded-md file:border-0 file:text-sm file:font-semibold file:bg-indigo-50 file:text-indigo-700 hover:file:bg-indigo-100"
-                                                required
                                             />
+                                            <p className="mt-1 text-xs text-gray-500">Generated from the GLB file when left empty</p>
                                         </div>
                                     </div>
 
@@ -409,9 +405,9 @@ function ModelsContent() {
                             </div>
                             <div className="relative bg-gray-100" style={{ height: '70vh' }}>
                                 <OptimizedModelViewer
-                                    src={CDN_DOMAIN ? `${CDN_DOMAIN}/${selectedModel.glbFile}` : `${BASE_URL}${selectedModel.glbFile}.glb`}
-                                    ios-src={CDN_DOMAIN ? `${CDN_DOMAIN}/${selectedModel.usdzFile}` : `${BASE_URL}${selectedModel.usdzFile}.usdz`}
-                                    poster={selectedModel.thumbnail ? (CDN_DOMAIN ? `${CDN_DOMAIN}/${selectedModel.thumbnail}` : `${BASE_URL}${selectedModel.thumbnail}.png`) : ''}
+                                    src={storageUrl(selectedModel.glbFile, '.glb')}
+                                    ios-src={storageUrl(selectedModel.usdzFile, '.usdz')}
+                                    poster={storageUrl(selectedModel.thumbnail, '.png')}
                                     alt={`3D model of ${selectedModel.name}`}
                                     style={{ width: '100%', height: '100%' }}
                                 />
@@ -429,14 +425,14 @@ function ModelsContent() {
                                     </div>
                                     <div className="flex gap-2">
                                         <a
-                                            href={CDN_DOMAIN ? `${CDN_DOMAIN}/${selectedModel.glbFile}` : `${BASE_URL}${selectedModel.glbFile}.glb`}
+                                            href={storageUrl(selectedModel.glbFile, '.glb')}
                                             download
                                             className="px-3 py-1.5 text-sm font-medium text-gray-700 bg-gray-100 rounded-md hover:bg-gray-200"
                                         >
                                             Download GLB
                                         </a>
                                         <a
-                                            href={CDN_DOMAIN ? `${CDN_DOMAIN}/${selectedModel.usdzFile}` : `${BASE_URL}${selectedModel.usdzFile}.usdz`}
+                                            href={storageUrl(selectedModel.usdzFile, '.usdz')}
                                             download
                                             className="px-3 py-1.5 text-sm font-medium text-gray-700 bg-gray-100 rounded-md hover:bg-gray-200"
                                         >
@@ -31,7 +31,11 @@ export default function DashboardPage() {
             try {
                 setLoading(true);
                 const data = await DashboardService.getDashboardData();
-                setStats(data.stats);
+                setStats({
+                    totalMenus: data.totalMenus,
+                    activeMenus: data.activeMenus,
+                    totalViews: data.current.totals.views
+                });
                 setRecentMenus(data.recentMenus);
                 setError(null);
             } catch (error) {
@@ -106,7 +110,7 @@ export default function DashboardPage() {
                     </div>
                     <div className="bg-white p-6 rounded-lg shadow-sm border border-gray-100">
                         <div className="flex items-center justify-between">
-                            <h3 className="text-gray-500 text-sm font-medium">Total Views</h3>
+                            <h3 className="text-gray-500 text-sm font-medium">Views (last 30 days)</h3>
                             <svg className="h-5 w-5 text-purple-500" fill="none" viewBox="0 0 24 24" stroke="currentColor">
                                 <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M15 12a3 3 0 11-6 0 3 3 0 016 0z" />
                                 <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M2.458 12C3.732 7.943 7.523 5 12 5c4.478 0 8.268 2.943 9.542 7-1.274 4.057-5.064 7-9.542 7-4.477 0-8.268-2.943-9.542-7z" />
@@ -2,9 +2,8 @@
 
 import React, { useEffect, useRef, useState } from 'react';
 import { menuService } from '@/services/menu-service';
-
-const API_URL = process.env.NEXT_PUBLIC_API_URL?.replace('/api/v1', '') || '';
-const CDN_DOMAIN = process.env.NEXT_PUBLIC_CDN_DOMAIN || '';
+import { analyticsService } from '@/services/analytics-service';
+import { storageUrl } from '@/utils/storage-url';
 
 // Cache for loaded models
 const modelCache = new Map();
@@ -21,9 +20,9 @@ const ModelViewer = ({ item, menuId, onClose }) => {
     if (!item?.modelInfo) return null;
 
     // Use CDN URLs if available, fallback to API URLs
-    const glbUrl = CDN_DOMAIN ? `${CDN_DOMAIN}/${item.modelInfo.glbFile}` : `${API_URL}${item.modelInfo.glbFile}.glb`;
-    const usdzUrl = CDN_DOMAIN ? `${CDN_DOMAIN}/${item.modelInfo.usdzFile}` : `${API_URL}${item.modelInfo.usdzFile}.usdz`;
-    const thumbnailUrl = CDN_DOMAIN ? `${CDN_DOMAIN}/${item.modelInfo.thumbnail}` : `${API_URL}${item.modelInfo.thumbnail}.png`;
+    const glbUrl = storageUrl(item.modelInfo.glbFile, '.glb');
+    const usdzUrl = storageUrl(item.modelInfo.usdzFile, '.usdz');
+    const thumbnailUrl = storageUrl(item.modelInfo.thumbnail, '.png');
 
     useEffect(() => {
         let mounted = true;
@@ -179,7 +178,7 @@ const MenuItemCard = ({ item, isSelected, onClick }) => {
             {item.modelInfo?.thumbnail ? (
                 <div className="relative h-24 sm:h-32 bg-gray-50 rounded-t-lg overflow-hidden">
                     <img
-                        src={`${CDN_DOMAIN}/${item.modelInfo.thumbnail}`}
+                        src={storageUrl(item.modelInfo.thumbnail, '.png')}
                         alt={item.name}
                         className="w-full h-full object-cover"
                     />
@@ -246,8 +245,10 @@ const ARMenuView = ({ menuId }) => {
     useEffect(() => {
         const fetchMenu = async () => {
             try {
-                const menuData = await menuService.getMenu(menuId);
+                const lang = new URLSearchParams(window.location.search).get('lang');
+                const menuData = await menuService.getMenu(menuId, lang);
                 setMenu(menuData);
+                analyticsService.trackMenuView(menuId);
 
                 // Flatten menu items and extract categories
                 const items = menuData.categories?.flatMap(category =>
@@ -388,6 +389,7 @@ const ARMenuView = ({ menuId }) => {
     const handleARButtonClick = async () => {
         const modelViewer = document.querySelector('model-viewer');
         if (modelViewer) {
+            analyticsService.trackARLaunch(menuId, selectedItem?.id, selectedItem?.modelId);
             try {
                 // Force stop all camera streams
                 stopCamera();
@@ -504,8 +506,20 @@ const ARMenuView = ({ menuId }) => {
         );
     }
 
+    // the theme and text direction of the locale the menu was served in
+    const theme = menu?.customization || {};
+    const themeStyle = {
+        '--menu-primary': theme.colors?.primary,
+        '--menu-background': theme.colors?.background,
+        '--menu-text': theme.colors?.text,
+        '--menu-price': theme.colors?.price,
+        '--menu-font-heading': theme.font?.heading,
+        '--menu-font-body': theme.font?.body,
+        fontFamily: theme.font?.body,
+    };
+
     return (
-        <main className="relative min-h-screen">
+        <main className="relative min-h-screen" dir={menu?.direction || 'ltr'} lang={menu?.locale} style={themeStyle}>
             <div className="fixed inset-0 bg-black z-50">
                 {/* Camera Background */}
                 {!isARMode && (
@@ -620,7 +634,10 @@ const ARMenuView = ({ menuId }) => {
                                             key={item.id}
                                             item={item}
                                             isSelected={selectedItem?.id === item.id}
-                                            onClick={() => setSelectedItem(item)}
+                                            onClick={() => {
+                                                setSelectedItem(item);
+                                                analyticsService.trackItemOpen(menuId, item.id);
+                                            }}
                                         />
                                     ))}
                                 </div>
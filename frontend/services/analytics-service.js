@@ -0,0 +1,86 @@
+const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || 'https://192.168.1.37:8000/api';
+
+// Events are sent in small batches, they are flushed when the page is hidden
+// so the last ones are not lost when the guest leaves
+const FLUSH_DELAY = 2000;
+const MAX_BATCH = 50;
+
+let pending = null;
+let timer = null;
+
+const readContext = () => {
+    const urlParams = new URLSearchParams(window.location.search);
+    return {
+        source: urlParams.get('src') || undefined,
+        tableToken: urlParams.get('table') || undefined,
+    };
+};
+
+const send = (batch) => {
+    const body = JSON.stringify(batch);
+    const url = `${API_BASE_URL}/events`;
+
+    if (navigator.sendBeacon && navigator.sendBeacon(url, new Blob([body], { type: 'application/json' }))) {
+        return;
+    }
+
+    fetch(url, {
+        method: 'POST',
+        body,
+        keepalive: true,
+        headers: { 'Content-Type': 'application/json' },
+    }).catch(() => { });
+};
+
+const flush = () => {
+    clearTimeout(timer);
+    timer = null;
+    if (!pending || pending.events.length === 0) {
+        return;
+    }
+
+    const batch = pending;
+    pending = null;
+    send(batch);
+};
+
+if (typeof window !== 'undefined') {
+    document.addEventListener('visibilitychange', () => {
+        if (document.visibilityState === 'hidden') {
+            flush();
+        }
+    });
+    window.addEventListener('pagehide', flush);
+}
+
+export const analyticsService = {
+    track: (menuId, event) => {
+        if (typeof window === 'undefined' || !menuId) {
+            return;
+        }
+
+        if (pending && pending.menuId !== menuId) {
+            flush();
+        }
+        if (!pending) {
+            pending = { menuId, ...readContext(), events: [] };
+        }
+
+        pending.events.push(event);
+        if (pending.events.length >= MAX_BATCH) {
+            flush();
+        } else if (!timer) {
+            timer = setTimeout(flush, FLUSH_DELAY);
+        }
+    },
+
+    trackMenuView: (menuId) => analyticsService.track(menuId, { type: 'menu_view' }),
+
+    trackItemOpen: (menuId, itemId) => analyticsService.track(menuId, { type: 'item_open', itemId }),
+
+    trackARLaunch: (menuId, itemId, modelId) => {
+        if (modelId) {
+            analyticsService.track(menuId, { type: 'ar_launch', itemId, modelId });
+        }
+    },
+};
@@ -4,9 +4,11 @@ const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || 'https://192.168.1.37:80
 import base from './base';
 
 class DashboardService {
-    static async getDashboardData() {
+    // params may hold from, to (YYYY-MM-DD) and interval (hour, day or week)
+    static async getDashboardData(params = {}) {
         try {
-            const response = await fetch(`${API_BASE_URL}/dashboard`, {
+            const query = new URLSearchParams(Object.entries(params).filter(([, value]) => value)).toString();
+            const response = await fetch(`${API_BASE_URL}/dashboard${query ? `?${query}` : ''}`, {
                 method: 'GET',
                 headers: {
                     'Content-Type': 'application/json',
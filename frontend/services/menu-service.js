@@ -20,8 +20,10 @@ export const menuService = {
         return response.json();
     },
 
-    getMenu: async (id) => {
-        const response = await fetch(`${API_BASE_URL}/menu/${id}`, {
+    // lang picks the language of the menu, otherwise the browser's languages are used
+    getMenu: async (id, lang) => {
+        const query = lang ? `?lang=${encodeURIComponent(lang)}` : '';
+        const response = await fetch(`${API_BASE_URL}/menu/${id}${query}`, {
             method: 'GET',
             headers: {
                 'Content-Type': 'application/json',
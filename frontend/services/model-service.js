@@ -45,7 +45,9 @@ class ModelService {
             const formData = new FormData();
             formData.append('name', data.name);
             formData.append('glb', data.glbFile);
-            formData.append('usdz', data.usdzFile);
+            if (data.usdzFile) {
+                formData.append('usdz', data.usdzFile);
+            }
             formData.append('thumbnail', data.thumbnail);
             formData.append('clientId', clientId);
 
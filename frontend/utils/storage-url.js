@@ -0,0 +1,14 @@
+const API_URL = process.env.NEXT_PUBLIC_API_URL?.replace('/api/v1', '') || '';
+const CDN_DOMAIN = process.env.NEXT_PUBLIC_CDN_DOMAIN || '';
+const FILE_EXTENSION = /\.(glb|gltf|usdz|png|jpe?g|webp)$/i;
+
+// storageUrl resolves a stored file path. S3 storage returns keys served from
+// the CDN, local storage returns /static paths served by the API. Paths stored
+// before local storage kept the extension get the fallback one.
+export function storageUrl(path, fallbackExt = '') {
+    if (!path) return '';
+    if (/^https?:\/\//.test(path)) return path;
+    if (CDN_DOMAIN && !path.startsWith('/')) return `${CDN_DOMAIN}/${path}`;
+
+    return FILE_EXTENSION.test(path) ? `${API_URL}${path}` : `${API_URL}${path}${fallbackExt}`;
+}
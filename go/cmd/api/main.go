@@ -10,9 +10,14 @@ import (
 	"time"
 
 	"github.com/ahmetkoprulu/bidi-menu/common/data"
+	"github.com/ahmetkoprulu/bidi-menu/common/mq"
+	"github.com/ahmetkoprulu/bidi-menu/common/ocr"
+	"github.com/ahmetkoprulu/bidi-menu/common/preprocess"
+	"github.com/ahmetkoprulu/bidi-menu/common/storage"
 	"github.com/ahmetkoprulu/bidi-menu/common/utils"
 	"github.com/ahmetkoprulu/bidi-menu/internal/api"
 	"github.com/ahmetkoprulu/bidi-menu/internal/config"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
 	repoImpl "github.com/ahmetkoprulu/bidi-menu/internal/repository/impl"
 	serviceImpl "github.com/ahmetkoprulu/bidi-menu/internal/services/impl"
 )
@@ -66,28 +71,79 @@ func main() {
 	menuRepo := repoImpl.NewMenuRepository(db)
 	magicLinkRepo := repoImpl.NewMagicLinkRepository(db)
 	modelRepo := repoImpl.NewModelRepository(db)
+	scanJobRepo := repoImpl.NewScanJobRepository(db)
+	tableRepo := repoImpl.NewTableRepository(db)
+	shortLinkRepo := repoImpl.NewShortLinkRepository(db)
+	analyticsRepo := repoImpl.NewAnalyticsRepository(db)
+	dashboardRepo := repoImpl.NewDashboardRepository(db)
+
+	mqProvider, err := newMqProvider(config)
+	if err != nil {
+		utils.Logger.Fatal("Failed to connect to message queue", utils.Logger.String("error", err.Error()))
+	}
+	defer mqProvider.Disconnect()
 
 	// Initialize services
 	emailService := serviceImpl.NewEmailService(config)
 	magicLinkService := serviceImpl.NewMagicLinkService(magicLinkRepo)
 	authService := serviceImpl.NewAuthService(authRepo)
 	clientService := serviceImpl.NewClientService(clientRepo, emailService, magicLinkService)
-	menuService := serviceImpl.NewMenuService(menuRepo)
-	modelService := serviceImpl.NewModelService(modelRepo)
+	preprocessor, err := newPreprocessor(config)
+	if err != nil {
+		utils.Logger.Fatal("Invalid image preprocessing settings", utils.Logger.String("error", err.Error()))
+	}
+	ocrService := serviceImpl.NewOCRService(newOcrEngine(config), ocr.NewPopplerPdfReader(ocr.PopplerConfig{BinDir: config.PopplerPath}), preprocessor, config.OcrReviewThreshold)
+	storageService, err := newStorageService(config)
+	if err != nil {
+		utils.Logger.Fatal("Failed to create storage service", utils.Logger.String("error", err.Error()))
+	}
+	qrCodeService := serviceImpl.NewQRCodeService(clientRepo, shortLinkRepo, storageService, config.BaseUrl, config.ShortLinkBaseUrl)
+	shortLinkService := serviceImpl.NewShortLinkService(shortLinkRepo, menuRepo, clientRepo, config.BaseUrl, config.ShortLinkBaseUrl)
+	menuService := serviceImpl.NewMenuService(menuRepo, shortLinkService, qrCodeService)
+	tableService := serviceImpl.NewTableService(tableRepo, menuRepo, qrCodeService)
+	scanService := serviceImpl.NewScanService(scanJobRepo, ocrService, mqProvider, config.ScanUploadDir)
+	modelService := serviceImpl.NewModelService(modelRepo, storageService, mqProvider, config.ThumbnailConfig)
 	adminService := serviceImpl.NewAdminService()
+	analyticsService := serviceImpl.NewAnalyticsService(analyticsRepo)
+	dashboardService := serviceImpl.NewDashboardService(dashboardRepo, clientRepo)
 
 	// Create and configure server
 	server := api.NewServer(
 		authService,
 		clientService,
 		menuService,
+		scanService,
 		modelService,
 		adminService,
 		magicLinkService,
+		tableService,
+		shortLinkService,
+		analyticsService,
+		dashboardService,
+		storageService,
 		db,
 		config,
 	)
 
+	// Start scan and model optimization workers, they stop when the server
+	// shuts down
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	if err := scanService.StartWorkers(workerCtx, config.ScanWorkers); err != nil {
+		utils.Logger.Fatal("Failed to start scan workers", utils.Logger.String("error", err.Error()))
+	}
+	if err := modelService.StartWorkers(workerCtx, config.ModelWorkers); err != nil {
+		utils.Logger.Fatal("Failed to start model optimization workers", utils.Logger.String("error", err.Error()))
+	}
+
+	// Analytics events are flushed in batches, the last batch once the server
+	// stopped taking requests
+	analyticsDone := make(chan struct{})
+	go func() {
+		analyticsService.Run(workerCtx)
+		close(analyticsDone)
+	}()
+
 	// Start server in a goroutine
 	go func() {
 		addr := fmt.Sprintf(":%s", os.Getenv("PORT"))
@@ -114,5 +170,64 @@ func main() {
 		utils.Logger.Fatal("Server forced to shutdown", utils.Logger.String("error", err.Error()))
 	}
 
+	stopWorkers()
+	<-analyticsDone
+
 	utils.Logger.Info("Server exited gracefully")
 }
+
+// newOcrEngine selects the OCR engine from the OCR_ENGINE setting, defaulting to tesseract
+func newOcrEngine(config *models.Config) ocr.IOcrEngine {
+	switch config.OcrEngine {
+	case "fake":
+		return ocr.NewFakeOcrEngine(nil)
+	default:
+		return ocr.NewTesseractOcrEngine(ocr.TesseractConfig{
+			Path:      config.TesseractPath,
+			Languages: config.OcrLanguages,
+		})
+	}
+}
+
+// newPreprocessor builds the image preprocessing steps from PREPROCESS_STEPS and
+// PREPROCESS_MAX_SIZE
+func newPreprocessor(config *models.Config) (*preprocess.Preprocessor, error) {
+	options, err := preprocess.ParseSteps(config.PreprocessSteps)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.MaxDimension > 0 && config.PreprocessMaxSize > 0 {
+		options.MaxDimension = config.PreprocessMaxSize
+	}
+
+	return preprocess.NewPreprocessor(options), nil
+}
+
+// newStorageService selects the storage backend from STORAGE_BACKEND, only S3
+// storage needs credentials
+func newStorageService(config *models.Config) (storage.StorageService, error) {
+	switch config.StorageBackend {
+	case models.StorageBackendLocal:
+		return storage.NewStorageService(), nil
+	case models.StorageBackendS3:
+		return storage.NewSpacesService(config.SpacesConfig)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", config.StorageBackend)
+	}
+}
+
+// newMqProvider connects to RabbitMQ when MQ_URL is set, otherwise scan and model
+// jobs run on in-process queues. Every queue is bound to the exchange by its name.
+func newMqProvider(config *models.Config) (mq.IMqProvider, error) {
+	if config.MqURL == "" {
+		return mq.NewMemoryMqProvider(0), nil
+	}
+
+	return mq.NewRabbitmqMqProvider(mq.RabbitMqConfig{
+		URL:          config.MqURL,
+		Exchange:     "bidi",
+		ExchangeType: "direct",
+		Durable:      true,
+	})
+}
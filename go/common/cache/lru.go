@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRUCache implements Cache interface with in-memory storage bounded to a number
+// of items, the least recently used item is evicted when a new one does not fit
+type LRUCache[T any] struct {
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+	mu       sync.Mutex
+}
+
+type lruEntry[T any] struct {
+	key  string
+	item Item[T]
+}
+
+// NewLRUCache creates a new in-memory cache holding at most capacity items
+func NewLRUCache[T any](capacity int) *LRUCache[T] {
+	if capacity <= 0 {
+		capacity = 1
+	}
+
+	return &LRUCache[T]{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *LRUCache[T]) Set(key string, value T, ttl time.Duration) error {
+	if key == "" {
+		return ErrInvalidKey
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(key, value, expiration(ttl))
+	return nil
+}
+
+func (c *LRUCache[T]) Get(key string) (T, error) {
+	value, _, err := c.GetWithExpiration(key)
+	return value, err
+}
+
+func (c *LRUCache[T]) GetWithExpiration(key string) (T, *time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero T
+	element, exists := c.items[key]
+	if !exists {
+		return zero, nil, ErrKeyNotFound
+	}
+
+	entry := element.Value.(*lruEntry[T])
+	if entry.item.expired() {
+		c.remove(element)
+		return zero, nil, ErrKeyExpired
+	}
+
+	c.order.MoveToFront(element)
+	return entry.item.Value, entry.item.Expiration, nil
+}
+
+func (c *LRUCache[T]) Has(key string) bool {
+	_, _, err := c.GetWithExpiration(key)
+	return err == nil
+}
+
+func (c *LRUCache[T]) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, exists := c.items[key]; exists {
+		c.remove(element)
+	}
+	return nil
+}
+
+func (c *LRUCache[T]) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+	return nil
+}
+
+func (c *LRUCache[T]) GetMultiple(keys []string) (map[string]T, error) {
+	result := make(map[string]T)
+	for _, key := range keys {
+		if value, err := c.Get(key); err == nil {
+			result[key] = value
+		}
+	}
+
+	return result, nil
+}
+
+func (c *LRUCache[T]) SetMultiple(items map[string]T, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	exp := expiration(ttl)
+	for key, value := range items {
+		if key == "" {
+			return ErrInvalidKey
+		}
+		c.set(key, value, exp)
+	}
+
+	return nil
+}
+
+func (c *LRUCache[T]) set(key string, value T, exp *time.Time) {
+	item := Item[T]{Value: value, Expiration: exp}
+	if element, exists := c.items[key]; exists {
+		element.Value.(*lruEntry[T]).item = item
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry[T]{key: key, item: item})
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *LRUCache[T]) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.items, element.Value.(*lruEntry[T]).key)
+}
+
+func expiration(ttl time.Duration) *time.Time {
+	if ttl <= 0 {
+		return nil
+	}
+
+	exp := time.Now().Add(ttl)
+	return &exp
+}
+
+func (i Item[T]) expired() bool {
+	return i.Expiration != nil && time.Now().After(*i.Expiration)
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRUCache[int](2)
+	c.Set("a", 1, 0)
+	c.Set("b", 2, 0)
+
+	// reading a makes b the least recently used
+	if _, err := c.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+	c.Set("c", 3, 0)
+
+	if _, err := c.Get("b"); err != ErrKeyNotFound {
+		t.Errorf("expected b to be evicted, got %v", err)
+	}
+	for key, want := range map[string]int{"a": 1, "c": 3} {
+		if got, err := c.Get(key); err != nil || got != want {
+			t.Errorf("%s: expected %d, got %d (%v)", key, want, got, err)
+		}
+	}
+}
+
+func TestLRUCacheExpiresItems(t *testing.T) {
+	c := NewLRUCache[int](2)
+	c.Set("a", 1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := c.Get("a"); err != ErrKeyExpired {
+		t.Errorf("expected ErrKeyExpired, got %v", err)
+	}
+	if c.Has("a") {
+		t.Error("expected the expired item to be removed")
+	}
+}
+
+func TestLRUCacheConcurrentAccess(t *testing.T) {
+	c := NewLRUCache[int](8)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprintf("%d", (i+j)%16)
+				c.Set(key, j, time.Microsecond)
+				c.Get(key)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if c.order.Len() > 8 || len(c.items) > 8 {
+		t.Errorf("expected at most 8 items, got %d", len(c.items))
+	}
+}
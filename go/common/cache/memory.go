@@ -40,8 +40,8 @@ func (c *MemoryCache[T]) Set(key string, value T, ttl time.Duration) error {
 }
 
 func (c *MemoryCache[T]) Get(key string) (T, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
 	item, exists := c.items[key]
 	if !exists {
@@ -59,8 +59,8 @@ func (c *MemoryCache[T]) Get(key string) (T, error) {
 }
 
 func (c *MemoryCache[T]) GetWithExpiration(key string) (T, *time.Time, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
 	item, exists := c.items[key]
 	if !exists {
@@ -78,8 +78,8 @@ func (c *MemoryCache[T]) GetWithExpiration(key string) (T, *time.Time, error) {
 }
 
 func (c *MemoryCache[T]) Has(key string) bool {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
 	item, exists := c.items[key]
 	if !exists {
@@ -0,0 +1,131 @@
+package gltf
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+)
+
+// sparse is the part of an accessor that overrides some of its elements
+type sparse struct {
+	Count   int `json:"count"`
+	Indices struct {
+		BufferView    int `json:"bufferView"`
+		ByteOffset    int `json:"byteOffset"`
+		ComponentType int `json:"componentType"`
+	} `json:"indices"`
+	Values struct {
+		BufferView int `json:"bufferView"`
+		ByteOffset int `json:"byteOffset"`
+	} `json:"values"`
+}
+
+// ReadFloats returns the components of every element of an accessor one after
+// the other. Integer components are mapped to -1 to 1 or 0 to 1 when the
+// accessor is normalized.
+func (d *Document) ReadFloats(index int) ([]float64, error) {
+	accessor := d.Accessors[index]
+	components := accessor.Components()
+	values := make([]float64, accessor.Count*components)
+
+	if accessor.BufferView != nil {
+		view := d.BufferViews[*accessor.BufferView]
+		data := d.BufferViewData(*accessor.BufferView)
+		stride := max(view.ByteStride, accessor.ElementSize())
+		for i := 0; i < accessor.Count; i++ {
+			offset := accessor.ByteOffset + i*stride
+			readElement(values[i*components:(i+1)*components], data[offset:], accessor.ComponentType, accessor.Normalized)
+		}
+	}
+
+	if accessor.Sparse != nil {
+		if err := d.applySparse(index, values); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// ReadIndices returns the elements of a scalar integer accessor
+func (d *Document) ReadIndices(index int) ([]uint32, error) {
+	accessor := d.Accessors[index]
+	floats, err := d.ReadFloats(index)
+	if err != nil {
+		return nil, err
+	}
+
+	indices := make([]uint32, accessor.Count)
+	for i := range indices {
+		indices[i] = uint32(floats[i*accessor.Components()])
+	}
+
+	return indices, nil
+}
+
+func (d *Document) applySparse(index int, values []float64) error {
+	accessor := d.Accessors[index]
+	var s sparse
+	if err := json.Unmarshal(accessor.Sparse, &s); err != nil {
+		return invalid("the sparse part of accessor %d is malformed", index)
+	}
+	if s.Count < 1 || s.Count > accessor.Count || !inRange(s.Indices.BufferView, len(d.BufferViews)) || !inRange(s.Values.BufferView, len(d.BufferViews)) {
+		return invalid("the sparse part of accessor %d refers to missing buffer views", index)
+	}
+
+	indexSize := componentSizes[s.Indices.ComponentType]
+	indexData := d.BufferViewData(s.Indices.BufferView)
+	valueData := d.BufferViewData(s.Values.BufferView)
+	size, components := accessor.ElementSize(), accessor.Components()
+	if indexSize == 0 || !fits(s.Indices.ByteOffset, indexSize, s.Count-1, indexSize, len(indexData)) || !fits(s.Values.ByteOffset, size, s.Count-1, size, len(valueData)) {
+		return invalid("the sparse part of accessor %d reads past its buffer views", index)
+	}
+
+	target := make([]float64, 1)
+	for i := 0; i < s.Count; i++ {
+		readElement(target, indexData[s.Indices.ByteOffset+i*indexSize:], s.Indices.ComponentType, false)
+		element := int(target[0])
+		if element >= accessor.Count {
+			return invalid("the sparse part of accessor %d overrides the missing element %d", index, element)
+		}
+		readElement(values[element*components:(element+1)*components], valueData[s.Values.ByteOffset+i*size:], accessor.ComponentType, accessor.Normalized)
+	}
+
+	return nil
+}
+
+// readElement decodes the components of one element into values
+func readElement(values []float64, data []byte, componentType int, normalized bool) {
+	size := componentSizes[componentType]
+	for i := range values {
+		b := data[i*size:]
+		var value float64
+		switch componentType {
+		case ComponentByte:
+			value = float64(int8(b[0]))
+			if normalized {
+				value = math.Max(value/127, -1)
+			}
+		case ComponentUnsignedByte:
+			value = float64(b[0])
+			if normalized {
+				value /= 255
+			}
+		case ComponentShort:
+			value = float64(int16(binary.LittleEndian.Uint16(b)))
+			if normalized {
+				value = math.Max(value/32767, -1)
+			}
+		case ComponentUnsignedShort:
+			value = float64(binary.LittleEndian.Uint16(b))
+			if normalized {
+				value /= 65535
+			}
+		case ComponentUnsignedInt:
+			value = float64(binary.LittleEndian.Uint32(b))
+		case ComponentFloat:
+			value = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		}
+		values[i] = value
+	}
+}
@@ -0,0 +1,240 @@
+// Package gltf reads glTF 2.0 models, either binary .glb files or .gltf files
+// with their buffers and images embedded as data URIs. Files are checked
+// thoroughly on the way in, so the rest of the app can rely on every index
+// and byte range of a parsed document.
+package gltf
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalid is wrapped by every error about the contents of a file, the
+// message names the reason
+var ErrInvalid = errors.New("invalid glTF model")
+
+const (
+	ComponentByte          = 5120
+	ComponentUnsignedByte  = 5121
+	ComponentShort         = 5122
+	ComponentUnsignedShort = 5123
+	ComponentUnsignedInt   = 5125
+	ComponentFloat         = 5126
+)
+
+const (
+	ModePoints        = 0
+	ModeLines         = 1
+	ModeLineLoop      = 2
+	ModeLineStrip     = 3
+	ModeTriangles     = 4
+	ModeTriangleStrip = 5
+	ModeTriangleFan   = 6
+)
+
+// Document is a parsed model. Parts the app does not look into, such as
+// animations and skins, are kept as they were.
+type Document struct {
+	Asset              Asset                      `json:"asset"`
+	Scene              *int                       `json:"scene,omitempty"`
+	Scenes             []*Scene                   `json:"scenes,omitempty"`
+	Nodes              []*Node                    `json:"nodes,omitempty"`
+	Meshes             []*Mesh                    `json:"meshes,omitempty"`
+	Accessors          []*Accessor                `json:"accessors,omitempty"`
+	BufferViews        []*BufferView              `json:"bufferViews,omitempty"`
+	Buffers            []*Buffer                  `json:"buffers,omitempty"`
+	Materials          []*Material                `json:"materials,omitempty"`
+	Textures           []*Texture                 `json:"textures,omitempty"`
+	Images             []*Image                   `json:"images,omitempty"`
+	Samplers           []json.RawMessage          `json:"samplers,omitempty"`
+	Animations         []json.RawMessage          `json:"animations,omitempty"`
+	Skins              []json.RawMessage          `json:"skins,omitempty"`
+	Cameras            []json.RawMessage          `json:"cameras,omitempty"`
+	ExtensionsUsed     []string                   `json:"extensionsUsed,omitempty"`
+	ExtensionsRequired []string                   `json:"extensionsRequired,omitempty"`
+	Extensions         map[string]json.RawMessage `json:"extensions,omitempty"`
+	Extras             json.RawMessage            `json:"extras,omitempty"`
+
+	// data holds the bytes of each buffer
+	data [][]byte
+}
+
+type Asset struct {
+	Version    string          `json:"version"`
+	MinVersion string          `json:"minVersion,omitempty"`
+	Generator  string          `json:"generator,omitempty"`
+	Copyright  string          `json:"copyright,omitempty"`
+	Extras     json.RawMessage `json:"extras,omitempty"`
+}
+
+type Scene struct {
+	Name   string          `json:"name,omitempty"`
+	Nodes  []int           `json:"nodes,omitempty"`
+	Extras json.RawMessage `json:"extras,omitempty"`
+}
+
+// Node places a mesh in the scene, either with a matrix or with a
+// translation, rotation and scale
+type Node struct {
+	Name        string                     `json:"name,omitempty"`
+	Children    []int                      `json:"children,omitempty"`
+	Mesh        *int                       `json:"mesh,omitempty"`
+	Skin        *int                       `json:"skin,omitempty"`
+	Camera      *int                       `json:"camera,omitempty"`
+	Matrix      []float64                  `json:"matrix,omitempty"`
+	Translation []float64                  `json:"translation,omitempty"`
+	Rotation    []float64                  `json:"rotation,omitempty"`
+	Scale       []float64                  `json:"scale,omitempty"`
+	Weights     []float64                  `json:"weights,omitempty"`
+	Extensions  map[string]json.RawMessage `json:"extensions,omitempty"`
+	Extras      json.RawMessage            `json:"extras,omitempty"`
+}
+
+type Mesh struct {
+	Name       string          `json:"name,omitempty"`
+	Primitives []*Primitive    `json:"primitives"`
+	Weights    []float64       `json:"weights,omitempty"`
+	Extras     json.RawMessage `json:"extras,omitempty"`
+}
+
+type Primitive struct {
+	Attributes map[string]int             `json:"attributes"`
+	Indices    *int                       `json:"indices,omitempty"`
+	Material   *int                       `json:"material,omitempty"`
+	Mode       *int                       `json:"mode,omitempty"`
+	Targets    []map[string]int           `json:"targets,omitempty"`
+	Extensions map[string]json.RawMessage `json:"extensions,omitempty"`
+	Extras     json.RawMessage            `json:"extras,omitempty"`
+}
+
+type Accessor struct {
+	Name          string          `json:"name,omitempty"`
+	BufferView    *int            `json:"bufferView,omitempty"`
+	ByteOffset    int             `json:"byteOffset,omitempty"`
+	ComponentType int             `json:"componentType"`
+	Normalized    bool            `json:"normalized,omitempty"`
+	Count         int             `json:"count"`
+	Type          string          `json:"type"`
+	Min           []float64       `json:"min,omitempty"`
+	Max           []float64       `json:"max,omitempty"`
+	Sparse        json.RawMessage `json:"sparse,omitempty"`
+	Extras        json.RawMessage `json:"extras,omitempty"`
+}
+
+type BufferView struct {
+	Name       string          `json:"name,omitempty"`
+	Buffer     int             `json:"buffer"`
+	ByteOffset int             `json:"byteOffset,omitempty"`
+	ByteLength int             `json:"byteLength"`
+	ByteStride int             `json:"byteStride,omitempty"`
+	Target     int             `json:"target,omitempty"`
+	Extras     json.RawMessage `json:"extras,omitempty"`
+}
+
+// Buffer is the binary chunk of a glb file when it has no URI
+type Buffer struct {
+	Name       string          `json:"name,omitempty"`
+	URI        string          `json:"uri,omitempty"`
+	ByteLength int             `json:"byteLength"`
+	Extras     json.RawMessage `json:"extras,omitempty"`
+}
+
+type Material struct {
+	Name                 string                     `json:"name,omitempty"`
+	PBRMetallicRoughness *PBRMetallicRoughness      `json:"pbrMetallicRoughness,omitempty"`
+	NormalTexture        *TextureInfo               `json:"normalTexture,omitempty"`
+	OcclusionTexture     *TextureInfo               `json:"occlusionTexture,omitempty"`
+	EmissiveTexture      *TextureInfo               `json:"emissiveTexture,omitempty"`
+	EmissiveFactor       []float64                  `json:"emissiveFactor,omitempty"`
+	AlphaMode            string                     `json:"alphaMode,omitempty"`
+	AlphaCutoff          *float64                   `json:"alphaCutoff,omitempty"`
+	DoubleSided          bool                       `json:"doubleSided,omitempty"`
+	Extensions           map[string]json.RawMessage `json:"extensions,omitempty"`
+	Extras               json.RawMessage            `json:"extras,omitempty"`
+}
+
+type PBRMetallicRoughness struct {
+	BaseColorFactor          []float64                  `json:"baseColorFactor,omitempty"`
+	BaseColorTexture         *TextureInfo               `json:"baseColorTexture,omitempty"`
+	MetallicFactor           *float64                   `json:"metallicFactor,omitempty"`
+	RoughnessFactor          *float64                   `json:"roughnessFactor,omitempty"`
+	MetallicRoughnessTexture *TextureInfo               `json:"metallicRoughnessTexture,omitempty"`
+	Extensions               map[string]json.RawMessage `json:"extensions,omitempty"`
+}
+
+// TextureInfo points a material at a texture, scale is used by normal
+// textures and strength by occlusion textures
+type TextureInfo struct {
+	Index      int                        `json:"index"`
+	TexCoord   int                        `json:"texCoord,omitempty"`
+	Scale      *float64                   `json:"scale,omitempty"`
+	Strength   *float64                   `json:"strength,omitempty"`
+	Extensions map[string]json.RawMessage `json:"extensions,omitempty"`
+}
+
+type Texture struct {
+	Name       string                     `json:"name,omitempty"`
+	Sampler    *int                       `json:"sampler,omitempty"`
+	Source     *int                       `json:"source,omitempty"`
+	Extensions map[string]json.RawMessage `json:"extensions,omitempty"`
+	Extras     json.RawMessage            `json:"extras,omitempty"`
+}
+
+// Image is either embedded in a buffer view or in a data URI
+type Image struct {
+	Name       string          `json:"name,omitempty"`
+	URI        string          `json:"uri,omitempty"`
+	MimeType   string          `json:"mimeType,omitempty"`
+	BufferView *int            `json:"bufferView,omitempty"`
+	Extras     json.RawMessage `json:"extras,omitempty"`
+}
+
+// componentSizes are the sizes in bytes of the component types
+var componentSizes = map[int]int{
+	ComponentByte:          1,
+	ComponentUnsignedByte:  1,
+	ComponentShort:         2,
+	ComponentUnsignedShort: 2,
+	ComponentUnsignedInt:   4,
+	ComponentFloat:         4,
+}
+
+// typeComponents are the number of components of the accessor types
+var typeComponents = map[string]int{
+	"SCALAR": 1,
+	"VEC2":   2,
+	"VEC3":   3,
+	"VEC4":   4,
+	"MAT2":   4,
+	"MAT3":   9,
+	"MAT4":   16,
+}
+
+// BufferData returns the bytes of a buffer
+func (d *Document) BufferData(buffer int) []byte {
+	return d.data[buffer]
+}
+
+// BufferViewData returns the bytes of a buffer view
+func (d *Document) BufferViewData(view int) []byte {
+	v := d.BufferViews[view]
+	return d.data[v.Buffer][v.ByteOffset : v.ByteOffset+v.ByteLength]
+}
+
+// ElementSize is the size in bytes of one element of the accessor
+func (a *Accessor) ElementSize() int {
+	return componentSizes[a.ComponentType] * typeComponents[a.Type]
+}
+
+// Components is the number of components of one element of the accessor
+func (a *Accessor) Components() int {
+	return typeComponents[a.Type]
+}
+
+// PrimitiveMode is the topology of the primitive, triangles by default
+func (p *Primitive) PrimitiveMode() int {
+	if p.Mode == nil {
+		return ModeTriangles
+	}
+	return *p.Mode
+}
@@ -0,0 +1,258 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// triangle builds a glb with one indexed triangle modeled in centimeters,
+// scaled down to meters by its root node, and a 4x2 png base color texture
+func triangle(t *testing.T, edit func(doc map[string]any)) []byte {
+	t.Helper()
+
+	bin := &bytes.Buffer{}
+	for _, v := range []float32{0, 0, 0, 100, 0, 0, 0, 200, 0} {
+		binary.Write(bin, binary.LittleEndian, v)
+	}
+	binary.Write(bin, binary.LittleEndian, []uint16{0, 1, 2, 0})
+	imageOffset := bin.Len()
+	if err := png.Encode(bin, image.NewRGBA(image.Rect(0, 0, 4, 2))); err != nil {
+		t.Fatal(err)
+	}
+	imageLength := bin.Len() - imageOffset
+	for bin.Len()%4 != 0 {
+		bin.WriteByte(0)
+	}
+
+	doc := map[string]any{
+		"asset":  map[string]any{"version": "2.0"},
+		"scene":  0,
+		"scenes": []any{map[string]any{"nodes": []int{0}}},
+		"nodes": []any{
+			map[string]any{"scale": []float64{0.01, 0.01, 0.01}, "children": []int{1}},
+			map[string]any{"mesh": 0, "translation": []float64{0, 0, 5}},
+		},
+		"meshes": []any{map[string]any{"primitives": []any{
+			map[string]any{"attributes": map[string]int{"POSITION": 0}, "indices": 1, "material": 0},
+		}}},
+		"accessors": []any{
+			map[string]any{"bufferView": 0, "componentType": ComponentFloat, "count": 3, "type": "VEC3", "min": []float64{0, 0, 0}, "max": []float64{100, 200, 0}},
+			map[string]any{"bufferView": 1, "componentType": ComponentUnsignedShort, "count": 3, "type": "SCALAR"},
+		},
+		"bufferViews": []any{
+			map[string]any{"buffer": 0, "byteOffset": 0, "byteLength": 36},
+			map[string]any{"buffer": 0, "byteOffset": 36, "byteLength": 6},
+			map[string]any{"buffer": 0, "byteOffset": imageOffset, "byteLength": imageLength},
+		},
+		"buffers":   []any{map[string]any{"byteLength": bin.Len()}},
+		"materials": []any{map[string]any{"pbrMetallicRoughness": map[string]any{"baseColorTexture": map[string]int{"index": 0}}}},
+		"textures":  []any{map[string]int{"source": 0}},
+		"images":    []any{map[string]any{"bufferView": 2, "mimeType": "image/png"}},
+	}
+	if edit != nil {
+		edit(doc)
+	}
+
+	return glb(t, doc, bin.Bytes())
+}
+
+func glb(t *testing.T, doc map[string]any, bin []byte) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for len(data)%4 != 0 {
+		data = append(data, ' ')
+	}
+
+	out := &bytes.Buffer{}
+	length := glbHeaderSize + 8 + len(data)
+	if bin != nil {
+		length += 8 + len(bin)
+	}
+	binary.Write(out, binary.LittleEndian, []uint32{glbMagic, 2, uint32(length), uint32(len(data)), chunkJSON})
+	out.Write(data)
+	if bin != nil {
+		binary.Write(out, binary.LittleEndian, []uint32{uint32(len(bin)), chunkBIN})
+		out.Write(bin)
+	}
+
+	return out.Bytes()
+}
+
+func TestParseStats(t *testing.T) {
+	doc, err := Parse(triangle(t, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := doc.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Triangles != 1 || stats.Vertices != 3 || stats.Materials != 1 || stats.Textures != 1 {
+		t.Errorf("unexpected counts %+v", stats)
+	}
+	if len(stats.TextureSizes) != 1 || stats.TextureSizes[0] != (ImageSize{Width: 4, Height: 2}) {
+		t.Errorf("expected one 4x2 texture, got %v", stats.TextureSizes)
+	}
+	if stats.Scale != [3]float64{0.01, 0.01, 0.01} {
+		t.Errorf("expected the root scale, got %v", stats.Scale)
+	}
+
+	dimensions := stats.Dimensions()
+	for i, want := range []float64{1, 2, 0} {
+		if math.Abs(dimensions[i]-want) > 1e-9 {
+			t.Fatalf("expected dimensions of 1x2x0 meters, got %v", dimensions)
+		}
+	}
+	if math.Abs(stats.Min[2]-0.05) > 1e-9 {
+		t.Errorf("expected the child translation to be scaled by its parent, got %v", stats.Min)
+	}
+
+	indices, err := doc.ReadIndices(1)
+	if err != nil || len(indices) != 3 || indices[2] != 2 {
+		t.Errorf("unexpected indices %v, %v", indices, err)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	photo := &bytes.Buffer{}
+	if err := jpeg.Encode(photo, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	valid := triangle(t, nil)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		reason string
+	}{
+		{"renamed jpeg", photo.Bytes(), "neither a binary glb"},
+		{"truncated file", valid[:len(valid)-10], "truncated"},
+		{"version 1", triangle(t, func(doc map[string]any) {
+			doc["asset"] = map[string]any{"version": "1.0"}
+		}), "version"},
+		{"accessor past its buffer view", triangle(t, func(doc map[string]any) {
+			doc["accessors"].([]any)[0].(map[string]any)["count"] = 30
+		}), "reads past the end"},
+		{"external buffer", triangle(t, func(doc map[string]any) {
+			doc["buffers"] = []any{map[string]any{"byteLength": 64, "uri": "model.bin"}}
+		}), "external file"},
+		{"missing image", triangle(t, func(doc map[string]any) {
+			doc["textures"] = []any{map[string]int{"source": 3}}
+		}), "missing image"},
+		{"image that is not an image", triangle(t, func(doc map[string]any) {
+			doc["images"] = []any{map[string]any{"bufferView": 0, "mimeType": "image/png"}}
+		}), "not a valid png"},
+		{"node cycle", triangle(t, func(doc map[string]any) {
+			doc["nodes"].([]any)[1].(map[string]any)["children"] = []int{0}
+		}), "own ancestor"},
+		{"positions that are not 3d vectors", triangle(t, func(doc map[string]any) {
+			doc["accessors"].([]any)[0].(map[string]any)["type"] = "VEC2"
+		}), "not float vectors"},
+		{"huge texture", triangle(t, func(doc map[string]any) {
+			doc["images"] = []any{map[string]any{"uri": "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngHeader(30000, 30000))}}
+		}), "larger than"},
+		{"huge accessor without a buffer view", triangle(t, withNormals(map[string]any{"count": 4000000000000})), "more than"},
+		{"accessor count that overflows", triangle(t, withNormals(map[string]any{"count": 1<<61 + 1})), "more than"},
+		{"accessor offset that overflows", triangle(t, func(doc map[string]any) {
+			doc["accessors"].([]any)[0].(map[string]any)["byteOffset"] = math.MaxInt64 - 8
+		}), "reads past the end"},
+		{"negative sparse index offset", triangle(t, withNormals(map[string]any{"sparse": sparseNormals(-4, 2, ComponentUnsignedShort)})), "reads past its buffer views"},
+		{"sparse indices past their buffer view", triangle(t, withNormals(map[string]any{"sparse": sparseNormals(6, 2, ComponentUnsignedShort)})), "reads past its buffer views"},
+		{"more sparse elements than the accessor", triangle(t, withNormals(map[string]any{"sparse": sparseNormals(0, 4, ComponentUnsignedShort)})), "overrides 4 of its 3"},
+		{"float sparse indices", triangle(t, withNormals(map[string]any{"sparse": sparseNormals(0, 2, ComponentFloat)})), "not unsigned integers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.data)
+			if !errors.Is(err, ErrInvalid) {
+				t.Fatalf("expected an invalid model error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.reason) {
+				t.Errorf("expected the reason %q, got %q", tt.reason, err)
+			}
+		})
+	}
+}
+
+// withNormals adds a NORMAL attribute read from a sparse accessor without a
+// buffer view, edited by the given fields
+func withNormals(fields map[string]any) func(doc map[string]any) {
+	return func(doc map[string]any) {
+		accessor := map[string]any{"componentType": ComponentFloat, "count": 3, "type": "VEC3", "sparse": sparseNormals(0, 2, ComponentUnsignedShort)}
+		for key, value := range fields {
+			accessor[key] = value
+		}
+		doc["accessors"] = append(doc["accessors"].([]any), accessor)
+		primitive := doc["meshes"].([]any)[0].(map[string]any)["primitives"].([]any)[0].(map[string]any)
+		primitive["attributes"].(map[string]int)["NORMAL"] = 2
+	}
+}
+
+// sparseNormals overrides the first count normals with the triangle's
+// positions, the indices come from the triangle's index buffer
+func sparseNormals(indexOffset, count, indexType int) map[string]any {
+	return map[string]any{
+		"count":   count,
+		"indices": map[string]any{"bufferView": 1, "byteOffset": indexOffset, "componentType": indexType},
+		"values":  map[string]any{"bufferView": 0},
+	}
+}
+
+func TestParseSparseAccessor(t *testing.T) {
+	doc, err := Parse(triangle(t, withNormals(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	normals, err := doc.ReadFloats(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// indices 0 and 1 take the first two positions, the third stays zero
+	want := []float64{0, 0, 0, 100, 0, 0, 0, 0, 0}
+	if !slices.Equal(normals, want) {
+		t.Errorf("expected %v, got %v", want, normals)
+	}
+}
+
+// pngHeader is the signature and header chunk of a png of the given size,
+// enough for the size to be read but not for the pixels to be decoded
+func pngHeader(width, height int) []byte {
+	chunk := &bytes.Buffer{}
+	chunk.WriteString("IHDR")
+	binary.Write(chunk, binary.BigEndian, []uint32{uint32(width), uint32(height)})
+	chunk.Write([]byte{8, 6, 0, 0, 0})
+
+	out := &bytes.Buffer{}
+	out.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(out, binary.BigEndian, uint32(chunk.Len()-4))
+	out.Write(chunk.Bytes())
+	binary.Write(out, binary.BigEndian, crc32.ChecksumIEEE(chunk.Bytes()))
+	return out.Bytes()
+}
+
+func TestParseAcceptsLargestTexture(t *testing.T) {
+	_, err := Parse(triangle(t, func(doc map[string]any) {
+		doc["images"] = []any{map[string]any{"uri": "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngHeader(8192, 8192))}}
+	}))
+	if err != nil {
+		t.Errorf("expected an 8192x8192 texture to be accepted, got %v", err)
+	}
+}
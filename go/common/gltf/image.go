@@ -0,0 +1,81 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+var ktx2Identifier = []byte{0xab, 'K', 'T', 'X', ' ', '2', '0', 0xbb, '\r', '\n', 0x1a, '\n'}
+
+// ImageSize reads the resolution of an image from its header. Png and jpeg
+// are the formats of the core spec, webp and ktx2 come with extensions.
+func (d *Document) ImageSize(index int) (int, int, error) {
+	data, err := d.ImageData(index)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var width, height int
+	var ok bool
+	switch {
+	case bytes.HasPrefix(data, []byte("RIFF")) && len(data) >= 12 && string(data[8:12]) == "WEBP":
+		width, height, ok = webpSize(data)
+	case bytes.HasPrefix(data, ktx2Identifier):
+		width, height, ok = ktx2Size(data)
+	default:
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		width, height, ok = config.Width, config.Height, err == nil
+	}
+
+	if !ok || width < 1 || height < 1 {
+		return 0, 0, invalid("image %d is not a valid png, jpeg, webp or ktx2 image", index)
+	}
+
+	return width, height, nil
+}
+
+// webpSize reads the size from the first chunk of a webp file, which is
+// VP8 for lossy, VP8L for lossless and VP8X for extended files
+func webpSize(data []byte) (int, int, bool) {
+	if len(data) < 30 {
+		return 0, 0, false
+	}
+
+	chunk := data[12:]
+	switch string(chunk[:4]) {
+	case "VP8 ":
+		// a key frame starts with the start code 9d 01 2a after three bytes
+		if !bytes.Equal(chunk[11:14], []byte{0x9d, 0x01, 0x2a}) {
+			return 0, 0, false
+		}
+		width := int(binary.LittleEndian.Uint16(chunk[14:])) & 0x3fff
+		height := int(binary.LittleEndian.Uint16(chunk[16:])) & 0x3fff
+		return width, height, true
+	case "VP8L":
+		if chunk[8] != 0x2f {
+			return 0, 0, false
+		}
+		bits := binary.LittleEndian.Uint32(chunk[9:])
+		return int(bits&0x3fff) + 1, int(bits>>14&0x3fff) + 1, true
+	case "VP8X":
+		width := int(chunk[12]) | int(chunk[13])<<8 | int(chunk[14])<<16
+		height := int(chunk[15]) | int(chunk[16])<<8 | int(chunk[17])<<16
+		return width + 1, height + 1, true
+	}
+
+	return 0, 0, false
+}
+
+// ktx2Size reads the size of the base level from a ktx2 header
+func ktx2Size(data []byte) (int, int, bool) {
+	if len(data) < 28 {
+		return 0, 0, false
+	}
+
+	width := int(binary.LittleEndian.Uint32(data[20:]))
+	height := max(int(binary.LittleEndian.Uint32(data[24:])), 1)
+	return width, height, true
+}
@@ -0,0 +1,817 @@
+package gltf
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// ErrUnsupported is wrapped when a model uses something the optimizer can not
+// carry over, such models are served as they were uploaded
+var ErrUnsupported = errors.New("the model can not be optimized")
+
+const (
+	targetArrayBuffer        = 34962
+	targetElementArrayBuffer = 34963
+	quantizationExtension    = "KHR_mesh_quantization"
+)
+
+// optimizableExtensions are the extensions the optimizer knows to carry over.
+// Others may point at parts of the model by index, and those indices change.
+var optimizableExtensions = map[string]bool{
+	quantizationExtension:             true,
+	"KHR_materials_unlit":             true,
+	"KHR_materials_emissive_strength": true,
+	"KHR_materials_ior":               true,
+	"KHR_texture_transform":           true,
+	"KHR_lights_punctual":             true,
+	"EXT_texture_webp":                true,
+}
+
+type OptimizeOptions struct {
+	// MaxTextureSize is the longest side png and jpeg textures are scaled down
+	// to, zero keeps their size
+	MaxTextureSize int
+	// Quantize stores positions as shorts, normals and tangents as bytes and
+	// texture coordinates as unsigned shorts
+	Quantize bool
+}
+
+// encoding is how an accessor is written to the optimized buffer
+type encoding int
+
+const (
+	encodeAsIs encoding = iota
+	encodeIndices
+	encodeVertex
+	encodePosition
+	encodeNormal
+	encodeTexCoord
+)
+
+type accessorUse struct {
+	index    int
+	encoding encoding
+	// mesh is the mesh whose quantization applies to encodePosition
+	mesh int
+}
+
+// quantization maps the positions of a mesh to shorts, the node that places
+// the mesh scales them back
+type quantization struct {
+	offset [3]float64
+	scale  float64
+}
+
+type usage struct {
+	nodes, meshes, materials, textures, images []bool
+}
+
+type optimizer struct {
+	src     *Document
+	out     *Document
+	options OptimizeOptions
+	bin     bytes.Buffer
+
+	nodes, meshes, materials, textures, images map[int]int
+	quantizations                              map[int]*quantization
+	accessors                                  map[accessorUse]int
+	accessorKeys                               map[string]int
+	quantized                                  bool
+}
+
+// animationRefs and skinRefs are the references of the parts the document
+// keeps raw
+type animationRefs struct {
+	Channels []struct {
+		Target struct {
+			Node *int `json:"node"`
+		} `json:"target"`
+	} `json:"channels"`
+	Samplers []struct {
+		Input  int `json:"input"`
+		Output int `json:"output"`
+	} `json:"samplers"`
+}
+
+type skinRefs struct {
+	InverseBindMatrices *int  `json:"inverseBindMatrices"`
+	Skeleton            *int  `json:"skeleton"`
+	Joints              []int `json:"joints"`
+}
+
+// Optimize returns a smaller copy of the document. Parts the scenes do not
+// use are dropped, identical images, textures, materials, meshes and
+// accessors are stored once, and all data is packed into one buffer.
+func (d *Document) Optimize(options OptimizeOptions) (*Document, error) {
+	for _, name := range d.ExtensionsUsed {
+		if !optimizableExtensions[name] {
+			return nil, fmt.Errorf("%w: it uses the extension %s", ErrUnsupported, name)
+		}
+	}
+
+	animations, skins, err := d.rawRefs()
+	if err != nil {
+		return nil, err
+	}
+	used := d.usage(animations, skins)
+
+	o := &optimizer{
+		src: d,
+		out: &Document{
+			Asset:              d.Asset,
+			Scene:              d.Scene,
+			Samplers:           d.Samplers,
+			Cameras:            d.Cameras,
+			ExtensionsUsed:     slices.Clone(d.ExtensionsUsed),
+			ExtensionsRequired: slices.Clone(d.ExtensionsRequired),
+			Extensions:         d.Extensions,
+			Extras:             d.Extras,
+		},
+		options:       options,
+		nodes:         map[int]int{},
+		meshes:        map[int]int{},
+		materials:     map[int]int{},
+		textures:      map[int]int{},
+		images:        map[int]int{},
+		quantizations: map[int]*quantization{},
+		accessors:     map[accessorUse]int{},
+		accessorKeys:  map[string]int{},
+	}
+
+	steps := []func(*usage) error{o.addImages, o.addTextures, o.addMaterials, o.addMeshes, o.addNodes}
+	for _, step := range steps {
+		if err := step(used); err != nil {
+			return nil, err
+		}
+	}
+	if err := o.addAnimations(animations); err != nil {
+		return nil, err
+	}
+	if err := o.addSkins(skins); err != nil {
+		return nil, err
+	}
+
+	if o.quantized {
+		if !slices.Contains(o.out.ExtensionsUsed, quantizationExtension) {
+			o.out.ExtensionsUsed = append(o.out.ExtensionsUsed, quantizationExtension)
+		}
+		if !slices.Contains(o.out.ExtensionsRequired, quantizationExtension) {
+			o.out.ExtensionsRequired = append(o.out.ExtensionsRequired, quantizationExtension)
+		}
+	}
+
+	if o.bin.Len() > 0 {
+		o.out.Buffers = []*Buffer{{ByteLength: o.bin.Len()}}
+		o.out.data = [][]byte{o.bin.Bytes()}
+	}
+	if err := o.out.validate(); err != nil {
+		return nil, fmt.Errorf("the optimized model is broken: %w", err)
+	}
+
+	return o.out, nil
+}
+
+func (d *Document) rawRefs() ([]animationRefs, []skinRefs, error) {
+	animations := make([]animationRefs, len(d.Animations))
+	for i, raw := range d.Animations {
+		if err := json.Unmarshal(raw, &animations[i]); err != nil {
+			return nil, nil, invalid("animation %d is malformed", i)
+		}
+		for _, channel := range animations[i].Channels {
+			if channel.Target.Node != nil && !inRange(*channel.Target.Node, len(d.Nodes)) {
+				return nil, nil, invalid("animation %d targets the missing node %d", i, *channel.Target.Node)
+			}
+		}
+		for _, sampler := range animations[i].Samplers {
+			if !inRange(sampler.Input, len(d.Accessors)) || !inRange(sampler.Output, len(d.Accessors)) {
+				return nil, nil, invalid("animation %d refers to a missing accessor", i)
+			}
+		}
+	}
+
+	skins := make([]skinRefs, len(d.Skins))
+	for i, raw := range d.Skins {
+		if err := json.Unmarshal(raw, &skins[i]); err != nil {
+			return nil, nil, invalid("skin %d is malformed", i)
+		}
+		skin := skins[i]
+		if skin.InverseBindMatrices != nil && !inRange(*skin.InverseBindMatrices, len(d.Accessors)) {
+			return nil, nil, invalid("skin %d refers to a missing accessor", i)
+		}
+		for _, joint := range append(slices.Clone(skin.Joints), ptrValues(skin.Skeleton)...) {
+			if !inRange(joint, len(d.Nodes)) {
+				return nil, nil, invalid("skin %d refers to the missing node %d", i, joint)
+			}
+		}
+	}
+
+	return animations, skins, nil
+}
+
+// usage marks what the scenes show, with the nodes animations and skins need.
+// Files without scenes keep everything.
+func (d *Document) usage(animations []animationRefs, skins []skinRefs) *usage {
+	used := &usage{
+		nodes:     make([]bool, len(d.Nodes)),
+		meshes:    make([]bool, len(d.Meshes)),
+		materials: make([]bool, len(d.Materials)),
+		textures:  make([]bool, len(d.Textures)),
+		images:    make([]bool, len(d.Images)),
+	}
+
+	var visit func(node int)
+	visit = func(node int) {
+		if used.nodes[node] {
+			return
+		}
+		used.nodes[node] = true
+		for _, child := range d.Nodes[node].Children {
+			visit(child)
+		}
+	}
+	for _, scene := range d.Scenes {
+		for _, node := range scene.Nodes {
+			visit(node)
+		}
+	}
+	for _, animation := range animations {
+		for _, channel := range animation.Channels {
+			if channel.Target.Node != nil {
+				visit(*channel.Target.Node)
+			}
+		}
+	}
+	for _, skin := range skins {
+		for _, joint := range append(slices.Clone(skin.Joints), ptrValues(skin.Skeleton)...) {
+			visit(joint)
+		}
+	}
+
+	if len(d.Scenes) == 0 {
+		for i := range used.nodes {
+			used.nodes[i] = true
+		}
+		for i := range used.meshes {
+			used.meshes[i] = true
+		}
+	}
+	for i, node := range d.Nodes {
+		if used.nodes[i] && node.Mesh != nil {
+			used.meshes[*node.Mesh] = true
+		}
+	}
+
+	for i, mesh := range d.Meshes {
+		if !used.meshes[i] {
+			continue
+		}
+		for _, primitive := range mesh.Primitives {
+			if primitive.Material != nil {
+				used.materials[*primitive.Material] = true
+			}
+		}
+	}
+	for i, material := range d.Materials {
+		if !used.materials[i] {
+			continue
+		}
+		for _, info := range material.TextureInfos() {
+			used.textures[info.Index] = true
+		}
+	}
+	for i, texture := range d.Textures {
+		if !used.textures[i] {
+			continue
+		}
+		for _, source := range []*int{texture.Source, texture.ImageSource()} {
+			if source != nil {
+				used.images[*source] = true
+			}
+		}
+	}
+
+	return used
+}
+
+// addImages moves the used images into the buffer, scaling down the ones that
+// are too large
+func (o *optimizer) addImages(used *usage) error {
+	keys := map[string]int{}
+	for i, img := range o.src.Images {
+		if !used.images[i] {
+			continue
+		}
+
+		data, err := o.src.ImageData(i)
+		if err != nil {
+			return err
+		}
+		mimeType := imageMimeType(data, img.MimeType)
+		if data, err = downsize(data, mimeType, o.options.MaxTextureSize); err != nil {
+			return fmt.Errorf("failed to scale down image %d: %w", i, err)
+		}
+
+		key := fmt.Sprintf("%s %x", mimeType, sha256.Sum256(data))
+		if index, ok := keys[key]; ok {
+			o.images[i] = index
+			continue
+		}
+
+		view := o.addView(data, 0, 0)
+		o.images[i] = len(o.out.Images)
+		keys[key] = o.images[i]
+		o.out.Images = append(o.out.Images, &Image{Name: img.Name, MimeType: mimeType, BufferView: &view, Extras: img.Extras})
+	}
+
+	return nil
+}
+
+func (o *optimizer) addTextures(used *usage) error {
+	keys := map[string]int{}
+	for i, texture := range o.src.Textures {
+		if !used.textures[i] {
+			continue
+		}
+
+		copied := *texture
+		if texture.Source != nil {
+			copied.Source = remap(o.images, *texture.Source)
+		}
+		if raw, ok := texture.Extensions["EXT_texture_webp"]; ok {
+			extensions := make(map[string]json.RawMessage, len(texture.Extensions))
+			for name, value := range texture.Extensions {
+				extensions[name] = value
+			}
+			rewritten, err := rewriteRaw(raw, func(fields map[string]any) {
+				if source, ok := fields["source"].(float64); ok {
+					fields["source"] = o.images[int(source)]
+				}
+			})
+			if err != nil {
+				return invalid("texture %d has a malformed extension", i)
+			}
+			extensions["EXT_texture_webp"] = rewritten
+			copied.Extensions = extensions
+		}
+
+		key := partKey(&copied, func(t *Texture) { t.Name = "" })
+		if index, ok := keys[key]; ok {
+			o.textures[i] = index
+			continue
+		}
+		o.textures[i] = len(o.out.Textures)
+		keys[key] = o.textures[i]
+		o.out.Textures = append(o.out.Textures, &copied)
+	}
+
+	return nil
+}
+
+func (o *optimizer) addMaterials(used *usage) error {
+	keys := map[string]int{}
+	for i, material := range o.src.Materials {
+		if !used.materials[i] {
+			continue
+		}
+
+		var copied Material
+		if err := deepCopy(material, &copied); err != nil {
+			return fmt.Errorf("failed to copy material %d: %w", i, err)
+		}
+		for _, info := range copied.TextureInfos() {
+			info.Index = o.textures[info.Index]
+		}
+
+		// exporters name copies of a material Material.001 and so on, those
+		// are stored once
+		key := partKey(&copied, func(m *Material) { m.Name = "" })
+		if index, ok := keys[key]; ok {
+			o.materials[i] = index
+			continue
+		}
+		o.materials[i] = len(o.out.Materials)
+		keys[key] = o.materials[i]
+		o.out.Materials = append(o.out.Materials, &copied)
+	}
+
+	return nil
+}
+
+func (o *optimizer) addMeshes(used *usage) error {
+	skinned := map[int]bool{}
+	for _, node := range o.src.Nodes {
+		if node.Mesh != nil && node.Skin != nil {
+			skinned[*node.Mesh] = true
+		}
+	}
+
+	keys := map[string]int{}
+	for i, mesh := range o.src.Meshes {
+		if !used.meshes[i] {
+			continue
+		}
+
+		quantize := o.options.Quantize && !slices.ContainsFunc(mesh.Primitives, func(p *Primitive) bool { return len(p.Targets) > 0 })
+		if quantize && !skinned[i] && len(o.src.Scenes) > 0 {
+			q, err := o.planQuantization(mesh)
+			if err != nil {
+				return err
+			}
+			o.quantizations[i] = q
+		}
+
+		copied := &Mesh{Name: mesh.Name, Weights: mesh.Weights, Extras: mesh.Extras}
+		for _, primitive := range mesh.Primitives {
+			p, err := o.addPrimitive(i, primitive, quantize)
+			if err != nil {
+				return err
+			}
+			copied.Primitives = append(copied.Primitives, p)
+		}
+
+		// the key includes the quantization, since the nodes apply it
+		key := partKey(copied, func(m *Mesh) { m.Name = "" }) + fmt.Sprint(o.quantizations[i])
+		if index, ok := keys[key]; ok {
+			o.meshes[i] = index
+			continue
+		}
+		o.meshes[i] = len(o.out.Meshes)
+		keys[key] = o.meshes[i]
+		o.out.Meshes = append(o.out.Meshes, copied)
+	}
+
+	return nil
+}
+
+// planQuantization centers the positions of a mesh and scales them by the
+// same factor on all axes, so normals stay valid
+func (o *optimizer) planQuantization(mesh *Mesh) (*quantization, error) {
+	b := newBounds()
+	for _, primitive := range mesh.Primitives {
+		positions, err := o.src.ReadFloats(primitive.Attributes["POSITION"])
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i+2 < len(positions); i += 3 {
+			b.add([3]float64{positions[i], positions[i+1], positions[i+2]})
+		}
+	}
+
+	q := &quantization{}
+	var extent float64
+	for axis := range q.offset {
+		q.offset[axis] = (b.min[axis] + b.max[axis]) / 2
+		extent = max(extent, (b.max[axis]-b.min[axis])/2)
+	}
+	if b.empty() || extent == 0 || math.IsInf(extent, 0) || math.IsNaN(extent) {
+		return nil, nil
+	}
+	q.scale = extent / math.MaxInt16
+
+	return q, nil
+}
+
+func (o *optimizer) addPrimitive(mesh int, primitive *Primitive, quantize bool) (*Primitive, error) {
+	copied := &Primitive{
+		Attributes: make(map[string]int, len(primitive.Attributes)),
+		Mode:       primitive.Mode,
+		Extensions: primitive.Extensions,
+		Extras:     primitive.Extras,
+	}
+
+	// map iteration order is random, the attributes are written sorted so the
+	// output is the same for the same model
+	names := make([]string, 0, len(primitive.Attributes))
+	for name := range primitive.Attributes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		use := accessorUse{index: primitive.Attributes[name], encoding: encodeVertex}
+		float := o.src.Accessors[use.index].ComponentType == ComponentFloat
+		switch {
+		case name == "POSITION" && o.quantizations[mesh] != nil:
+			use.encoding, use.mesh = encodePosition, mesh
+		case quantize && float && (name == "NORMAL" || name == "TANGENT"):
+			use.encoding = encodeNormal
+		case quantize && float && strings.HasPrefix(name, "TEXCOORD_"):
+			use.encoding = encodeTexCoord
+		}
+
+		index, err := o.addAccessor(use)
+		if err != nil {
+			return nil, err
+		}
+		copied.Attributes[name] = index
+	}
+
+	if primitive.Indices != nil {
+		index, err := o.addAccessor(accessorUse{index: *primitive.Indices, encoding: encodeIndices})
+		if err != nil {
+			return nil, err
+		}
+		copied.Indices = &index
+	}
+	if primitive.Material != nil {
+		copied.Material = remap(o.materials, *primitive.Material)
+	}
+
+	for _, target := range primitive.Targets {
+		copiedTarget := make(map[string]int, len(target))
+		for name, accessor := range target {
+			index, err := o.addAccessor(accessorUse{index: accessor, encoding: encodeVertex})
+			if err != nil {
+				return nil, err
+			}
+			copiedTarget[name] = index
+		}
+		copied.Targets = append(copied.Targets, copiedTarget)
+	}
+
+	return copied, nil
+}
+
+// addAccessor writes an accessor with the given encoding, accessors with the
+// same data are written once
+func (o *optimizer) addAccessor(use accessorUse) (int, error) {
+	if index, ok := o.accessors[use]; ok {
+		return index, nil
+	}
+
+	src := o.src.Accessors[use.index]
+	values, err := o.src.ReadFloats(use.index)
+	if err != nil {
+		return 0, err
+	}
+	accessor := &Accessor{
+		Name:          src.Name,
+		ComponentType: src.ComponentType,
+		Normalized:    src.Normalized,
+		Count:         src.Count,
+		Type:          src.Type,
+		Min:           src.Min,
+		Max:           src.Max,
+		Extras:        src.Extras,
+	}
+
+	if use.encoding == encodeTexCoord && slices.ContainsFunc(values, func(v float64) bool { return v < 0 || v > 1 }) {
+		// repeating textures use coordinates outside of 0 to 1
+		use.encoding = encodeVertex
+	}
+
+	vertex := use.encoding != encodeAsIs && use.encoding != encodeIndices
+	switch use.encoding {
+	case encodeIndices:
+		// 65535 is left out, some renderers read it as a strip restart
+		if accessor.ComponentType == ComponentUnsignedInt && !slices.ContainsFunc(values, func(v float64) bool { return v >= math.MaxUint16 }) {
+			accessor.ComponentType = ComponentUnsignedShort
+		}
+	case encodePosition:
+		q := o.quantizations[use.mesh]
+		for i := range values {
+			values[i] = math.Round((values[i] - q.offset[i%3]) / q.scale)
+		}
+		accessor.ComponentType, accessor.Normalized = ComponentShort, false
+		accessor.Min, accessor.Max = componentBounds(values, 3)
+		o.quantized = true
+	case encodeNormal:
+		accessor.ComponentType, accessor.Normalized, accessor.Min, accessor.Max = ComponentByte, true, nil, nil
+		o.quantized = true
+	case encodeTexCoord:
+		accessor.ComponentType, accessor.Normalized, accessor.Min, accessor.Max = ComponentUnsignedShort, true, nil, nil
+		o.quantized = true
+	}
+
+	size := accessor.ElementSize()
+	stride := size
+	if vertex {
+		// vertex attributes start at multiples of four bytes
+		stride = (size + 3) &^ 3
+	}
+	data := make([]byte, stride*accessor.Count)
+	components := accessor.Components()
+	for i := 0; i < accessor.Count; i++ {
+		writeElement(data[i*stride:], values[i*components:(i+1)*components], accessor.ComponentType, accessor.Normalized)
+	}
+
+	header, _ := json.Marshal(accessor)
+	key := fmt.Sprintf("%s %d %t %x", header, stride, vertex, sha256.Sum256(data))
+	if index, ok := o.accessorKeys[key]; ok {
+		o.accessors[use] = index
+		return index, nil
+	}
+
+	target, viewStride := 0, 0
+	switch {
+	case use.encoding == encodeIndices:
+		target = targetElementArrayBuffer
+	case vertex:
+		target = targetArrayBuffer
+		if stride != size {
+			viewStride = stride
+		}
+	}
+	view := o.addView(data, viewStride, target)
+	accessor.BufferView = &view
+
+	index := len(o.out.Accessors)
+	o.out.Accessors = append(o.out.Accessors, accessor)
+	o.accessors[use] = index
+	o.accessorKeys[key] = index
+
+	return index, nil
+}
+
+// addNodes copies the used nodes. Meshes with quantized positions move to a
+// new child node that scales them back, so animations of the node still
+// apply to the whole node.
+func (o *optimizer) addNodes(used *usage) error {
+	for i := range o.src.Nodes {
+		if used.nodes[i] {
+			o.nodes[i] = len(o.nodes)
+		}
+	}
+
+	var quantized []*Node
+	for i, node := range o.src.Nodes {
+		if !used.nodes[i] {
+			continue
+		}
+
+		copied := *node
+		copied.Children = nil
+		for _, child := range node.Children {
+			copied.Children = append(copied.Children, o.nodes[child])
+		}
+		if node.Mesh != nil {
+			copied.Mesh = remap(o.meshes, *node.Mesh)
+			if q := o.quantizations[*node.Mesh]; q != nil {
+				// the children added for quantization follow the copied nodes
+				copied.Children = append(copied.Children, len(o.nodes)+len(quantized))
+				quantized = append(quantized, &Node{
+					Mesh:        copied.Mesh,
+					Translation: slices.Clone(q.offset[:]),
+					Scale:       []float64{q.scale, q.scale, q.scale},
+				})
+				copied.Mesh = nil
+			}
+		}
+		o.out.Nodes = append(o.out.Nodes, &copied)
+	}
+	o.out.Nodes = append(o.out.Nodes, quantized...)
+
+	for _, scene := range o.src.Scenes {
+		copied := *scene
+		copied.Nodes = nil
+		for _, node := range scene.Nodes {
+			copied.Nodes = append(copied.Nodes, o.nodes[node])
+		}
+		o.out.Scenes = append(o.out.Scenes, &copied)
+	}
+
+	return nil
+}
+
+// addAnimations copies the animations with their node and accessor
+// references rewritten
+func (o *optimizer) addAnimations(animations []animationRefs) error {
+	for i, raw := range o.src.Animations {
+		refs := animations[i]
+		inputs := make([]int, len(refs.Samplers))
+		outputs := make([]int, len(refs.Samplers))
+		for j, sampler := range refs.Samplers {
+			var err error
+			if inputs[j], err = o.addAccessor(accessorUse{index: sampler.Input}); err != nil {
+				return err
+			}
+			if outputs[j], err = o.addAccessor(accessorUse{index: sampler.Output}); err != nil {
+				return err
+			}
+		}
+
+		rewritten, err := rewriteRaw(raw, func(fields map[string]any) {
+			channels, _ := fields["channels"].([]any)
+			for _, channel := range channels {
+				target, _ := channel.(map[string]any)["target"].(map[string]any)
+				if node, ok := target["node"].(float64); ok {
+					target["node"] = o.nodes[int(node)]
+				}
+			}
+			samplers, _ := fields["samplers"].([]any)
+			for j, sampler := range samplers {
+				sampler.(map[string]any)["input"] = inputs[j]
+				sampler.(map[string]any)["output"] = outputs[j]
+			}
+		})
+		if err != nil {
+			return invalid("animation %d is malformed", i)
+		}
+		o.out.Animations = append(o.out.Animations, rewritten)
+	}
+
+	return nil
+}
+
+// addSkins copies the skins with their node and accessor references
+// rewritten
+func (o *optimizer) addSkins(skins []skinRefs) error {
+	for i, raw := range o.src.Skins {
+		inverseBindMatrices := -1
+		if index := skins[i].InverseBindMatrices; index != nil {
+			var err error
+			if inverseBindMatrices, err = o.addAccessor(accessorUse{index: *index}); err != nil {
+				return err
+			}
+		}
+
+		rewritten, err := rewriteRaw(raw, func(fields map[string]any) {
+			if inverseBindMatrices >= 0 {
+				fields["inverseBindMatrices"] = inverseBindMatrices
+			}
+			if skeleton, ok := fields["skeleton"].(float64); ok {
+				fields["skeleton"] = o.nodes[int(skeleton)]
+			}
+			joints, _ := fields["joints"].([]any)
+			for j, joint := range joints {
+				joints[j] = o.nodes[int(joint.(float64))]
+			}
+		})
+		if err != nil {
+			return invalid("skin %d is malformed", i)
+		}
+		o.out.Skins = append(o.out.Skins, rewritten)
+	}
+
+	return nil
+}
+
+// addView appends data to the buffer at the next multiple of four bytes
+func (o *optimizer) addView(data []byte, stride, target int) int {
+	for o.bin.Len()%4 != 0 {
+		o.bin.WriteByte(0)
+	}
+
+	view := &BufferView{ByteOffset: o.bin.Len(), ByteLength: len(data), ByteStride: stride, Target: target}
+	o.bin.Write(data)
+	o.out.BufferViews = append(o.out.BufferViews, view)
+
+	return len(o.out.BufferViews) - 1
+}
+
+func remap(indices map[int]int, index int) *int {
+	mapped := indices[index]
+	return &mapped
+}
+
+func ptrValues(value *int) []int {
+	if value == nil {
+		return nil
+	}
+	return []int{*value}
+}
+
+// partKey identifies a part by its json, without the fields clear empties
+func partKey[T any](value *T, clear func(*T)) string {
+	copied := *value
+	clear(&copied)
+	data, _ := json.Marshal(&copied)
+	return string(data)
+}
+
+func deepCopy(src, dst any) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// rewriteRaw edits the fields of a json object kept raw
+func rewriteRaw(raw json.RawMessage, edit func(fields map[string]any)) (json.RawMessage, error) {
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	edit(fields)
+	return json.Marshal(fields)
+}
+
+// componentBounds are the minimum and maximum of each component
+func componentBounds(values []float64, components int) ([]float64, []float64) {
+	low, high := make([]float64, components), make([]float64, components)
+	for i := range low {
+		low[i], high[i] = math.Inf(1), math.Inf(-1)
+	}
+	for i, value := range values {
+		low[i%components] = math.Min(low[i%components], value)
+		high[i%components] = math.Max(high[i%components], value)
+	}
+	return low, high
+}
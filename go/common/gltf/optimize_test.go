@@ -0,0 +1,118 @@
+package gltf
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOptimize(t *testing.T) {
+	data := triangle(t, func(doc map[string]any) {
+		// a copy of the mesh with a copy of the material, and a node outside of
+		// the scene with a material nothing else uses
+		doc["materials"] = append(doc["materials"].([]any),
+			map[string]any{"name": "Copy", "pbrMetallicRoughness": map[string]any{"baseColorTexture": map[string]int{"index": 0}}},
+			map[string]any{"name": "Unused"},
+		)
+		doc["meshes"] = append(doc["meshes"].([]any), map[string]any{"primitives": []any{
+			map[string]any{"attributes": map[string]int{"POSITION": 0}, "indices": 1, "material": 1},
+		}})
+		doc["nodes"] = append(doc["nodes"].([]any), map[string]any{"mesh": 2})
+		doc["meshes"] = append(doc["meshes"].([]any), map[string]any{"primitives": []any{
+			map[string]any{"attributes": map[string]int{"POSITION": 0}, "material": 2},
+		}})
+		doc["nodes"].([]any)[0].(map[string]any)["children"] = []int{1, 3}
+		doc["nodes"] = append(doc["nodes"].([]any), map[string]any{"mesh": 1})
+
+		// a translation of the mesh node, keyed by the first float of the
+		// positions
+		doc["accessors"] = append(doc["accessors"].([]any), map[string]any{"bufferView": 0, "componentType": ComponentFloat, "count": 1, "type": "SCALAR"})
+		doc["animations"] = []any{map[string]any{
+			"channels": []any{map[string]any{"sampler": 0, "target": map[string]any{"node": 1, "path": "translation"}}},
+			"samplers": []any{map[string]any{"input": 2, "output": 0}},
+		}}
+	})
+
+	doc, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := doc.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	optimized, err := doc.Optimize(OptimizeOptions{MaxTextureSize: 2, Quantize: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := optimized.EncodeGLB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reparsed, err := Parse(encoded)
+	if err != nil {
+		t.Fatalf("the optimized model does not parse: %v", err)
+	}
+	after, err := reparsed.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reparsed.Materials) != 1 || len(reparsed.Meshes) != 1 {
+		t.Errorf("expected the materials and meshes to be merged and pruned, got %d materials and %d meshes", len(reparsed.Materials), len(reparsed.Meshes))
+	}
+	// the root, the two mesh nodes and the nodes that scale their quantized
+	// positions back
+	if len(reparsed.Nodes) != 5 || after.Triangles != before.Triangles {
+		t.Errorf("expected the node outside of the scene to be dropped, got %d nodes and %d triangles", len(reparsed.Nodes), after.Triangles)
+	}
+	if !slices.Contains(reparsed.ExtensionsRequired, quantizationExtension) {
+		t.Errorf("expected quantized positions to require %s", quantizationExtension)
+	}
+	if position := reparsed.Accessors[reparsed.Meshes[0].Primitives[0].Attributes["POSITION"]]; position.ComponentType != ComponentShort {
+		t.Errorf("expected positions stored as shorts, got %d", position.ComponentType)
+	}
+	if len(after.TextureSizes) != 1 || after.TextureSizes[0] != (ImageSize{Width: 2, Height: 1}) {
+		t.Errorf("expected the texture scaled down to 2x1, got %v", after.TextureSizes)
+	}
+
+	for axis := range before.Min {
+		if math.Abs(before.Min[axis]-after.Min[axis]) > 1e-4 || math.Abs(before.Max[axis]-after.Max[axis]) > 1e-4 {
+			t.Fatalf("expected the bounds to survive quantization, got %v %v instead of %v %v", after.Min, after.Max, before.Min, before.Max)
+		}
+	}
+
+	var animation animationRefs
+	if err := json.Unmarshal(reparsed.Animations[0], &animation); err != nil {
+		t.Fatal(err)
+	}
+	if node := animation.Channels[0].Target.Node; node == nil || reparsed.Nodes[*node].Translation == nil {
+		t.Errorf("expected the animation to target the translated node")
+	}
+	if input := reparsed.Accessors[animation.Samplers[0].Input]; input.Type != "SCALAR" || input.Count != 1 {
+		t.Errorf("expected the animation input to be rewritten, got %+v", input)
+	}
+}
+
+func TestOptimizeUnsupported(t *testing.T) {
+	doc, err := Parse(triangle(t, func(doc map[string]any) {
+		doc["extensionsUsed"] = []string{"KHR_materials_variants"}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := doc.Optimize(OptimizeOptions{Quantize: true}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected models with unknown extensions to be left alone, got %v", err)
+	}
+}
+
+func TestDownsizeRefusesHugeImages(t *testing.T) {
+	if _, err := downsize(pngHeader(30000, 30000), "image/png", 2048); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("expected the image to be refused before decoding, got %v", err)
+	}
+}
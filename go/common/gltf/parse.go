@@ -0,0 +1,171 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	glbMagic      = 0x46546c67 // "glTF"
+	glbHeaderSize = 12
+	chunkJSON     = 0x4e4f534a // "JSON"
+	chunkBIN      = 0x004e4942 // "BIN\x00"
+)
+
+// Parse reads a glb file or a gltf file with embedded resources and checks
+// it. Errors about the file wrap ErrInvalid.
+func Parse(data []byte) (*Document, error) {
+	if len(data) >= 4 && binary.LittleEndian.Uint32(data) == glbMagic {
+		return parseGLB(data)
+	}
+
+	if trimmed := bytes.TrimLeft(data, " \t\r\n\ufeff"); len(trimmed) > 0 && trimmed[0] == '{' {
+		doc, err := decodeJSON(trimmed)
+		if err != nil {
+			return nil, err
+		}
+		if err := doc.load(nil); err != nil {
+			return nil, err
+		}
+		return doc, nil
+	}
+
+	return nil, invalid("the file is neither a binary glb nor a gltf json file")
+}
+
+func parseGLB(data []byte) (*Document, error) {
+	if len(data) < glbHeaderSize {
+		return nil, invalid("the glb header is truncated")
+	}
+	if version := binary.LittleEndian.Uint32(data[4:]); version != 2 {
+		return nil, invalid("glb version %d is not supported, only version 2 is", version)
+	}
+	length := binary.LittleEndian.Uint32(data[8:])
+	if int64(length) > int64(len(data)) {
+		return nil, invalid("the file is truncated, the header declares %d bytes but there are %d", length, len(data))
+	}
+	if int(length) != len(data) {
+		return nil, invalid("the header declares %d bytes but the file has %d", length, len(data))
+	}
+
+	var jsonChunk, binChunk []byte
+	for offset, index := glbHeaderSize, 0; offset < len(data); index++ {
+		if offset+8 > len(data) {
+			return nil, invalid("chunk %d has a truncated header", index)
+		}
+		chunkLength := int(binary.LittleEndian.Uint32(data[offset:]))
+		chunkType := binary.LittleEndian.Uint32(data[offset+4:])
+		start := offset + 8
+		if chunkLength > len(data)-start {
+			return nil, invalid("chunk %d runs past the end of the file", index)
+		}
+		chunk := data[start : start+chunkLength]
+
+		switch {
+		case index == 0 && chunkType != chunkJSON:
+			return nil, invalid("the first chunk has to be the json chunk")
+		case index == 0:
+			jsonChunk = chunk
+		case index == 1 && chunkType == chunkBIN:
+			binChunk = chunk
+		}
+
+		// chunks are padded to four bytes
+		offset = start + (chunkLength+3)&^3
+	}
+	if jsonChunk == nil {
+		return nil, invalid("the json chunk is missing")
+	}
+
+	doc, err := decodeJSON(jsonChunk)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.load(binChunk); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+func decodeJSON(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, invalid("the json is malformed: %v", err)
+	}
+
+	if !strings.HasPrefix(doc.Asset.Version, "2.") {
+		return nil, invalid("glTF version %q is not supported, only 2.0 is", doc.Asset.Version)
+	}
+	if doc.Asset.MinVersion != "" && doc.Asset.MinVersion != "2.0" {
+		return nil, invalid("the model needs at least glTF %s", doc.Asset.MinVersion)
+	}
+
+	return &doc, nil
+}
+
+// load resolves the buffers and checks the document, bin is the binary chunk
+// of a glb file
+func (d *Document) load(bin []byte) error {
+	d.data = make([][]byte, len(d.Buffers))
+	for i, buffer := range d.Buffers {
+		data, err := bufferData(i, buffer, bin)
+		if err != nil {
+			return err
+		}
+		d.data[i] = data[:buffer.ByteLength]
+	}
+
+	return d.validate()
+}
+
+func bufferData(index int, buffer *Buffer, bin []byte) ([]byte, error) {
+	if buffer.ByteLength < 1 {
+		return nil, invalid("buffer %d is empty", index)
+	}
+
+	var data []byte
+	switch {
+	case buffer.URI == "" && index == 0 && bin != nil:
+		data = bin
+	case buffer.URI == "":
+		return nil, invalid("buffer %d refers to a binary chunk the file does not have", index)
+	case strings.HasPrefix(buffer.URI, "data:"):
+		decoded, err := decodeDataURI(buffer.URI)
+		if err != nil {
+			return nil, invalid("buffer %d: %v", index, err)
+		}
+		data = decoded
+	default:
+		return nil, invalid("buffer %d refers to the external file %q, upload a self-contained .glb instead", index, buffer.URI)
+	}
+
+	if len(data) < buffer.ByteLength {
+		return nil, invalid("buffer %d declares %d bytes but has %d", index, buffer.ByteLength, len(data))
+	}
+
+	return data, nil
+}
+
+// decodeDataURI decodes a base64 data URI, the media type is not checked
+func decodeDataURI(uri string) ([]byte, error) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return nil, fmt.Errorf("only base64 data URIs are supported")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("the data URI is not valid base64")
+	}
+
+	return data, nil
+}
+
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalid, fmt.Sprintf(format, args...))
+}
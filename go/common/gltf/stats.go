@@ -0,0 +1,252 @@
+package gltf
+
+import "math"
+
+// Stats describe the contents of a model. Counts are taken over the scene,
+// so a mesh placed twice counts twice. Lengths are in meters, the unit of
+// glTF.
+type Stats struct {
+	Triangles    int
+	Vertices     int
+	Materials    int
+	Textures     int
+	TextureSizes []ImageSize
+	// Min and Max are the corners of the bounding box of the scene
+	Min, Max [3]float64
+	// Scale is the scale of the first root node, where exporters put the
+	// conversion from the units of the modeling tool
+	Scale [3]float64
+}
+
+type ImageSize struct {
+	Width  int
+	Height int
+}
+
+// Dimensions are the width, height and depth of the bounding box
+func (s *Stats) Dimensions() [3]float64 {
+	return [3]float64{s.Max[0] - s.Min[0], s.Max[1] - s.Min[1], s.Max[2] - s.Min[2]}
+}
+
+// mat4 is a column major transform
+type mat4 [16]float64
+
+var identity = mat4{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+
+// Stats walks the default scene, or the first one. Files without scenes have
+// their meshes counted once each.
+func (d *Document) Stats() (*Stats, error) {
+	stats := &Stats{
+		Materials:    len(d.Materials),
+		Textures:     len(d.Textures),
+		TextureSizes: make([]ImageSize, 0, len(d.Images)),
+		Scale:        [3]float64{1, 1, 1},
+	}
+	for i := range d.Images {
+		width, height, err := d.ImageSize(i)
+		if err != nil {
+			return nil, err
+		}
+		stats.TextureSizes = append(stats.TextureSizes, ImageSize{Width: width, Height: height})
+	}
+
+	bounds := newBounds()
+	if roots := d.rootNodes(); len(roots) > 0 {
+		stats.Scale = d.Nodes[roots[0]].scale()
+	}
+	for _, instance := range d.MeshInstances() {
+		if err := d.addMesh(stats, bounds, instance.Mesh, instance.World); err != nil {
+			return nil, err
+		}
+	}
+
+	if bounds.empty() {
+		return stats, nil
+	}
+	stats.Min, stats.Max = bounds.min, bounds.max
+	return stats, nil
+}
+
+// rootNodes are the nodes of the scene that is shown
+func (d *Document) rootNodes() []int {
+	switch {
+	case d.Scene != nil:
+		return d.Scenes[*d.Scene].Nodes
+	case len(d.Scenes) > 0:
+		return d.Scenes[0].Nodes
+	}
+	return nil
+}
+
+// MeshInstance is a mesh placed in the scene, World is its column major
+// transform
+type MeshInstance struct {
+	Mesh  int
+	World [16]float64
+}
+
+// MeshInstances lists the meshes of the default scene, or the first one,
+// with their transforms. Files without scenes have each mesh placed once.
+func (d *Document) MeshInstances() []MeshInstance {
+	instances := make([]MeshInstance, 0)
+	if len(d.Scenes) == 0 {
+		for i := range d.Meshes {
+			instances = append(instances, MeshInstance{Mesh: i, World: identity})
+		}
+		return instances
+	}
+
+	var visit func(index int, parent mat4)
+	visit = func(index int, parent mat4) {
+		node := d.Nodes[index]
+		world := parent.mul(node.local())
+		if node.Mesh != nil {
+			instances = append(instances, MeshInstance{Mesh: *node.Mesh, World: world})
+		}
+		for _, child := range node.Children {
+			visit(child, world)
+		}
+	}
+	for _, root := range d.rootNodes() {
+		visit(root, identity)
+	}
+
+	return instances
+}
+
+func (d *Document) addMesh(stats *Stats, bounds *bounds, index int, world mat4) error {
+	for _, primitive := range d.Meshes[index].Primitives {
+		positions := d.Accessors[primitive.Attributes["POSITION"]]
+		stats.Vertices += positions.Count
+
+		count := positions.Count
+		if primitive.Indices != nil {
+			count = d.Accessors[*primitive.Indices].Count
+		}
+		switch primitive.PrimitiveMode() {
+		case ModeTriangles:
+			stats.Triangles += count / 3
+		case ModeTriangleStrip, ModeTriangleFan:
+			stats.Triangles += max(count-2, 0)
+		}
+
+		low, high, err := d.positionBounds(primitive.Attributes["POSITION"])
+		if err != nil {
+			return err
+		}
+		for corner := 0; corner < 8; corner++ {
+			point := high
+			for axis := 0; axis < 3; axis++ {
+				if corner&(1<<axis) == 0 {
+					point[axis] = low[axis]
+				}
+			}
+			bounds.add(world.apply(point))
+		}
+	}
+
+	return nil
+}
+
+// positionBounds are the min and max of a position accessor, which the spec
+// requires, read from the data when an exporter left them out
+func (d *Document) positionBounds(index int) ([3]float64, [3]float64, error) {
+	accessor := d.Accessors[index]
+	if len(accessor.Min) == 3 && len(accessor.Max) == 3 {
+		return [3]float64(accessor.Min), [3]float64(accessor.Max), nil
+	}
+
+	values, err := d.ReadFloats(index)
+	if err != nil {
+		return [3]float64{}, [3]float64{}, err
+	}
+	b := newBounds()
+	for i := 0; i+2 < len(values); i += 3 {
+		b.add([3]float64{values[i], values[i+1], values[i+2]})
+	}
+
+	return b.min, b.max, nil
+}
+
+// local is the transform of the node relative to its parent
+func (n *Node) local() mat4 {
+	if len(n.Matrix) == 16 {
+		return mat4(n.Matrix)
+	}
+
+	t, r, s := [3]float64{}, [4]float64{0, 0, 0, 1}, [3]float64{1, 1, 1}
+	if len(n.Translation) == 3 {
+		t = [3]float64(n.Translation)
+	}
+	if len(n.Rotation) == 4 {
+		r = [4]float64(n.Rotation)
+	}
+	if len(n.Scale) == 3 {
+		s = [3]float64(n.Scale)
+	}
+
+	x, y, z, w := r[0], r[1], r[2], r[3]
+	return mat4{
+		(1 - 2*(y*y+z*z)) * s[0], 2 * (x*y + z*w) * s[0], 2 * (x*z - y*w) * s[0], 0,
+		2 * (x*y - z*w) * s[1], (1 - 2*(x*x+z*z)) * s[1], 2 * (y*z + x*w) * s[1], 0,
+		2 * (x*z + y*w) * s[2], 2 * (y*z - x*w) * s[2], (1 - 2*(x*x+y*y)) * s[2], 0,
+		t[0], t[1], t[2], 1,
+	}
+}
+
+// scale is the scale of the node, taken from the length of the axes of its
+// matrix when it has one
+func (n *Node) scale() [3]float64 {
+	if len(n.Scale) == 3 {
+		return [3]float64(n.Scale)
+	}
+	if len(n.Matrix) == 16 {
+		m := n.Matrix
+		return [3]float64{
+			math.Sqrt(m[0]*m[0] + m[1]*m[1] + m[2]*m[2]),
+			math.Sqrt(m[4]*m[4] + m[5]*m[5] + m[6]*m[6]),
+			math.Sqrt(m[8]*m[8] + m[9]*m[9] + m[10]*m[10]),
+		}
+	}
+	return [3]float64{1, 1, 1}
+}
+
+func (m mat4) mul(o mat4) mat4 {
+	var r mat4
+	for col := 0; col < 4; col++ {
+		for row := 0; row < 4; row++ {
+			for k := 0; k < 4; k++ {
+				r[col*4+row] += m[k*4+row] * o[col*4+k]
+			}
+		}
+	}
+	return r
+}
+
+func (m mat4) apply(p [3]float64) [3]float64 {
+	return [3]float64{
+		m[0]*p[0] + m[4]*p[1] + m[8]*p[2] + m[12],
+		m[1]*p[0] + m[5]*p[1] + m[9]*p[2] + m[13],
+		m[2]*p[0] + m[6]*p[1] + m[10]*p[2] + m[14],
+	}
+}
+
+type bounds struct {
+	min, max [3]float64
+}
+
+func newBounds() *bounds {
+	inf := math.Inf(1)
+	return &bounds{min: [3]float64{inf, inf, inf}, max: [3]float64{-inf, -inf, -inf}}
+}
+
+func (b *bounds) add(p [3]float64) {
+	for i := range p {
+		b.min[i] = math.Min(b.min[i], p[i])
+		b.max[i] = math.Max(b.max[i], p[i])
+	}
+}
+
+func (b *bounds) empty() bool {
+	return b.min[0] > b.max[0]
+}
@@ -0,0 +1,101 @@
+package gltf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// jpegQuality is used for textures that are scaled down
+const jpegQuality = 90
+
+// imageMimeType is the declared type of an image, or the one sniffed from its
+// data for images in data URIs
+func imageMimeType(data []byte, declared string) string {
+	switch {
+	case declared != "":
+		return declared
+	case bytes.HasPrefix(data, ktx2Identifier):
+		return "image/ktx2"
+	}
+	return http.DetectContentType(data)
+}
+
+// downsize scales png and jpeg images down so their longer side is at most
+// size pixels. Smaller images and other formats are returned as they are.
+func downsize(data []byte, mimeType string, size int) ([]byte, error) {
+	if size < 1 || (mimeType != "image/png" && mimeType != "image/jpeg") {
+		return data, nil
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if config.Width <= size && config.Height <= size {
+		return data, nil
+	}
+	if config.Width > MaxImagePixels/max(config.Height, 1) {
+		return nil, fmt.Errorf("the image is %dx%d, larger than %d pixels", config.Width, config.Height, MaxImagePixels)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	width, height := size, max(config.Height*size/config.Width, 1)
+	if config.Height > config.Width {
+		width, height = max(config.Width*size/config.Height, 1), size
+	}
+	scaled := boxScale(src, width, height)
+
+	out := &bytes.Buffer{}
+	if mimeType == "image/png" {
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(out, scaled)
+	} else {
+		err = jpeg.Encode(out, scaled, &jpeg.Options{Quality: jpegQuality})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	return out.Bytes(), nil
+}
+
+// boxScale shrinks an image by averaging the source pixels each target pixel
+// covers
+func boxScale(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcHeight/height, max((y+1)*srcHeight/height, y*srcHeight/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcWidth/width, max((x+1)*srcWidth/width, x*srcWidth/width+1)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride+x0*4 : sy*rgba.Stride+x1*4]
+				for i, value := range row {
+					sum[i%4] += int(value)
+				}
+			}
+
+			count := (y1 - y0) * (x1 - x0)
+			offset := y*dst.Stride + x*4
+			for i := range sum {
+				dst.Pix[offset+i] = uint8((sum[i] + count/2) / count)
+			}
+		}
+	}
+
+	return dst
+}
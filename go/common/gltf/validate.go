@@ -0,0 +1,307 @@
+package gltf
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// MaxImagePixels bounds the resolution of textures, decoding one allocates
+// four bytes for every pixel
+const MaxImagePixels = 8192 * 8192
+
+// maxAccessorComponents bounds the values an accessor may hold, every value is
+// read into a float64 so this keeps a single accessor under 128 MiB
+const maxAccessorComponents = 1 << 24
+
+// validate checks that every index points at something and every byte range
+// stays within its buffer
+func (d *Document) validate() error {
+	for i, view := range d.BufferViews {
+		if !inRange(view.Buffer, len(d.Buffers)) {
+			return invalid("buffer view %d refers to the missing buffer %d", i, view.Buffer)
+		}
+		if view.ByteOffset < 0 || view.ByteLength < 1 || view.ByteLength > d.Buffers[view.Buffer].ByteLength-view.ByteOffset {
+			return invalid("buffer view %d lies outside buffer %d", i, view.Buffer)
+		}
+		if view.ByteStride != 0 && (view.ByteStride < 4 || view.ByteStride > 252 || view.ByteStride%4 != 0) {
+			return invalid("buffer view %d has the invalid stride %d", i, view.ByteStride)
+		}
+	}
+
+	for i, accessor := range d.Accessors {
+		if err := d.validateAccessor(i, accessor); err != nil {
+			return err
+		}
+	}
+
+	for i, mesh := range d.Meshes {
+		if len(mesh.Primitives) == 0 {
+			return invalid("mesh %d has no primitives", i)
+		}
+		for j, primitive := range mesh.Primitives {
+			if err := d.validatePrimitive(i, j, primitive); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := d.validateNodes(); err != nil {
+		return err
+	}
+
+	for i := range d.Images {
+		width, height, err := d.ImageSize(i)
+		if err != nil {
+			return err
+		}
+		if width > MaxImagePixels/height {
+			return invalid("image %d is %dx%d, larger than the %d pixels a texture may have", i, width, height, MaxImagePixels)
+		}
+	}
+
+	for i, texture := range d.Textures {
+		source := texture.ImageSource()
+		if source == nil {
+			return invalid("texture %d has no image", i)
+		}
+		if !inRange(*source, len(d.Images)) {
+			return invalid("texture %d refers to the missing image %d", i, *source)
+		}
+		if texture.Source != nil && !inRange(*texture.Source, len(d.Images)) {
+			return invalid("texture %d refers to the missing image %d", i, *texture.Source)
+		}
+	}
+
+	for i, material := range d.Materials {
+		for _, info := range material.TextureInfos() {
+			if !inRange(info.Index, len(d.Textures)) {
+				return invalid("material %d refers to the missing texture %d", i, info.Index)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *Document) validateAccessor(index int, accessor *Accessor) error {
+	if _, ok := componentSizes[accessor.ComponentType]; !ok {
+		return invalid("accessor %d has the unknown component type %d", index, accessor.ComponentType)
+	}
+	if _, ok := typeComponents[accessor.Type]; !ok {
+		return invalid("accessor %d has the unknown type %q", index, accessor.Type)
+	}
+	if accessor.Count < 1 {
+		return invalid("accessor %d is empty", index)
+	}
+	// checked before anything is multiplied by the count, so the byte ranges
+	// below cannot overflow
+	if accessor.Count > maxAccessorComponents/accessor.Components() {
+		return invalid("accessor %d has %d elements, more than the %d components a model may have", index, accessor.Count, maxAccessorComponents)
+	}
+	if accessor.Sparse != nil {
+		if err := d.validateSparse(index, accessor); err != nil {
+			return err
+		}
+	}
+	if accessor.BufferView == nil {
+		// the values are zeros, or come from a sparse accessor or an extension
+		// such as mesh compression
+		return nil
+	}
+
+	if !inRange(*accessor.BufferView, len(d.BufferViews)) {
+		return invalid("accessor %d refers to the missing buffer view %d", index, *accessor.BufferView)
+	}
+	view := d.BufferViews[*accessor.BufferView]
+	if !fits(accessor.ByteOffset, max(view.ByteStride, accessor.ElementSize()), accessor.Count-1, accessor.ElementSize(), view.ByteLength) {
+		return invalid("accessor %d reads past the end of buffer view %d", index, *accessor.BufferView)
+	}
+
+	return nil
+}
+
+// validateSparse checks the elements a sparse accessor overrides stay within
+// the accessor and their indices and values within their buffer views
+func (d *Document) validateSparse(index int, accessor *Accessor) error {
+	var s sparse
+	if err := json.Unmarshal(accessor.Sparse, &s); err != nil {
+		return invalid("the sparse part of accessor %d is malformed", index)
+	}
+	if s.Count < 1 || s.Count > accessor.Count {
+		return invalid("the sparse part of accessor %d overrides %d of its %d elements", index, s.Count, accessor.Count)
+	}
+	if !inRange(s.Indices.BufferView, len(d.BufferViews)) || !inRange(s.Values.BufferView, len(d.BufferViews)) {
+		return invalid("the sparse part of accessor %d refers to missing buffer views", index)
+	}
+	if !slices.Contains([]int{ComponentUnsignedByte, ComponentUnsignedShort, ComponentUnsignedInt}, s.Indices.ComponentType) {
+		return invalid("the sparse indices of accessor %d are not unsigned integers", index)
+	}
+
+	indexSize := componentSizes[s.Indices.ComponentType]
+	if !fits(s.Indices.ByteOffset, indexSize, s.Count-1, indexSize, d.BufferViews[s.Indices.BufferView].ByteLength) ||
+		!fits(s.Values.ByteOffset, accessor.ElementSize(), s.Count-1, accessor.ElementSize(), d.BufferViews[s.Values.BufferView].ByteLength) {
+		return invalid("the sparse part of accessor %d reads past its buffer views", index)
+	}
+
+	return nil
+}
+
+// fits reports whether count+1 elements of size bytes, stride bytes apart and
+// starting at offset, end within length bytes. Count and stride are bounded by
+// the callers, offset may be anything the file declares.
+func fits(offset, stride, count, size, length int) bool {
+	if offset < 0 || offset > length {
+		return false
+	}
+
+	return stride*count+size <= length-offset
+}
+
+func (d *Document) validatePrimitive(mesh, index int, primitive *Primitive) error {
+	position, ok := primitive.Attributes["POSITION"]
+	if !ok {
+		return invalid("primitive %d of mesh %d has no positions", index, mesh)
+	}
+
+	for name, accessor := range primitive.Attributes {
+		if !inRange(accessor, len(d.Accessors)) {
+			return invalid("attribute %s of mesh %d refers to the missing accessor %d", name, mesh, accessor)
+		}
+	}
+	// quantized meshes may store positions as integers
+	quantized := slices.Contains(d.ExtensionsUsed, "KHR_mesh_quantization")
+	if a := d.Accessors[position]; a.Type != "VEC3" || (a.ComponentType != ComponentFloat && !quantized) {
+		return invalid("the positions of mesh %d are not float vectors", mesh)
+	}
+
+	if primitive.Indices != nil {
+		if !inRange(*primitive.Indices, len(d.Accessors)) {
+			return invalid("the indices of mesh %d refer to the missing accessor %d", mesh, *primitive.Indices)
+		}
+		indices := d.Accessors[*primitive.Indices]
+		if indices.Type != "SCALAR" || !slices.Contains([]int{ComponentUnsignedByte, ComponentUnsignedShort, ComponentUnsignedInt}, indices.ComponentType) {
+			return invalid("the indices of mesh %d are not unsigned integers", mesh)
+		}
+	}
+
+	if primitive.Material != nil && !inRange(*primitive.Material, len(d.Materials)) {
+		return invalid("mesh %d refers to the missing material %d", mesh, *primitive.Material)
+	}
+	if mode := primitive.PrimitiveMode(); mode < ModePoints || mode > ModeTriangleFan {
+		return invalid("mesh %d has the unknown primitive mode %d", mesh, mode)
+	}
+
+	return nil
+}
+
+// validateNodes checks the node references and that the nodes form trees,
+// no node may have two parents or be its own ancestor
+func (d *Document) validateNodes() error {
+	parents := make([]int, len(d.Nodes))
+	for i := range parents {
+		parents[i] = -1
+	}
+
+	for i, node := range d.Nodes {
+		if node.Mesh != nil && !inRange(*node.Mesh, len(d.Meshes)) {
+			return invalid("node %d refers to the missing mesh %d", i, *node.Mesh)
+		}
+		if node.Matrix != nil && len(node.Matrix) != 16 ||
+			node.Translation != nil && len(node.Translation) != 3 ||
+			node.Rotation != nil && len(node.Rotation) != 4 ||
+			node.Scale != nil && len(node.Scale) != 3 {
+			return invalid("node %d has a malformed transform", i)
+		}
+
+		for _, child := range node.Children {
+			if !inRange(child, len(d.Nodes)) {
+				return invalid("node %d refers to the missing child %d", i, child)
+			}
+			if parents[child] != -1 {
+				return invalid("node %d has more than one parent", child)
+			}
+			parents[child] = i
+		}
+	}
+
+	for i := range d.Nodes {
+		for node, steps := parents[i], 0; node != -1; node, steps = parents[node], steps+1 {
+			if node == i || steps > len(d.Nodes) {
+				return invalid("node %d is its own ancestor", i)
+			}
+		}
+	}
+
+	for i, scene := range d.Scenes {
+		for _, node := range scene.Nodes {
+			if !inRange(node, len(d.Nodes)) {
+				return invalid("scene %d refers to the missing node %d", i, node)
+			}
+		}
+	}
+	if d.Scene != nil && !inRange(*d.Scene, len(d.Scenes)) {
+		return invalid("the default scene %d is missing", *d.Scene)
+	}
+
+	return nil
+}
+
+// ImageSource is the image of the texture, textures in webp or ktx2 name it
+// in their extension
+func (t *Texture) ImageSource() *int {
+	for _, name := range []string{"EXT_texture_webp", "KHR_texture_basisu", "MSFT_texture_dds"} {
+		raw, ok := t.Extensions[name]
+		if !ok {
+			continue
+		}
+		var extension struct {
+			Source *int `json:"source"`
+		}
+		if json.Unmarshal(raw, &extension) == nil && extension.Source != nil {
+			return extension.Source
+		}
+	}
+
+	return t.Source
+}
+
+// TextureInfos lists the textures the material uses
+func (m *Material) TextureInfos() []*TextureInfo {
+	infos := make([]*TextureInfo, 0, 5)
+	if pbr := m.PBRMetallicRoughness; pbr != nil {
+		infos = append(infos, pbr.BaseColorTexture, pbr.MetallicRoughnessTexture)
+	}
+	infos = append(infos, m.NormalTexture, m.OcclusionTexture, m.EmissiveTexture)
+
+	return slices.DeleteFunc(infos, func(info *TextureInfo) bool { return info == nil })
+}
+
+// ImageData returns the encoded bytes of an image
+func (d *Document) ImageData(index int) ([]byte, error) {
+	image := d.Images[index]
+	switch {
+	case image.BufferView != nil:
+		if !inRange(*image.BufferView, len(d.BufferViews)) {
+			return nil, invalid("image %d refers to the missing buffer view %d", index, *image.BufferView)
+		}
+		if image.MimeType == "" {
+			return nil, invalid("image %d has no mime type", index)
+		}
+		return d.BufferViewData(*image.BufferView), nil
+	case strings.HasPrefix(image.URI, "data:"):
+		data, err := decodeDataURI(image.URI)
+		if err != nil {
+			return nil, invalid("image %d: %v", index, err)
+		}
+		return data, nil
+	case image.URI != "":
+		return nil, invalid("image %d refers to the external file %q, upload a self-contained .glb instead", index, image.URI)
+	default:
+		return nil, invalid("image %d has no data", index)
+	}
+}
+
+func inRange(index, length int) bool {
+	return index >= 0 && index < length
+}
@@ -0,0 +1,91 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// EncodeGLB writes the document as a glb file. The document has to keep its
+// data in one embedded buffer, as parsed glb files and optimized documents do.
+func (d *Document) EncodeGLB() ([]byte, error) {
+	if len(d.Buffers) > 1 || len(d.Buffers) == 1 && d.Buffers[0].URI != "" {
+		return nil, fmt.Errorf("only documents with a single embedded buffer can be written as glb")
+	}
+
+	jsonChunk, err := json.Marshal(d)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the glTF json: %w", err)
+	}
+	jsonChunk = pad(jsonChunk, ' ')
+
+	var binChunk []byte
+	if len(d.Buffers) == 1 {
+		binChunk = pad(d.data[0][:d.Buffers[0].ByteLength], 0)
+	}
+
+	length := glbHeaderSize + 8 + len(jsonChunk)
+	if binChunk != nil {
+		length += 8 + len(binChunk)
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, length))
+	binary.Write(out, binary.LittleEndian, []uint32{glbMagic, 2, uint32(length), uint32(len(jsonChunk)), chunkJSON})
+	out.Write(jsonChunk)
+	if binChunk != nil {
+		binary.Write(out, binary.LittleEndian, []uint32{uint32(len(binChunk)), chunkBIN})
+		out.Write(binChunk)
+	}
+
+	return out.Bytes(), nil
+}
+
+// pad fills data up to a multiple of four bytes, glb chunks and buffer views
+// start at such offsets
+func pad(data []byte, filler byte) []byte {
+	for len(data)%4 != 0 {
+		data = append(data, filler)
+	}
+	return data
+}
+
+// writeElement encodes the components of one element, the reverse of
+// readElement. Values are rounded and clamped to the component type.
+func writeElement(data []byte, values []float64, componentType int, normalized bool) {
+	size := componentSizes[componentType]
+	for i, value := range values {
+		b := data[i*size:]
+		switch componentType {
+		case ComponentByte:
+			if normalized {
+				value *= 127
+			}
+			b[0] = byte(int8(clampRound(value, math.MinInt8, math.MaxInt8)))
+		case ComponentUnsignedByte:
+			if normalized {
+				value *= 255
+			}
+			b[0] = byte(clampRound(value, 0, math.MaxUint8))
+		case ComponentShort:
+			if normalized {
+				value *= 32767
+			}
+			binary.LittleEndian.PutUint16(b, uint16(int16(clampRound(value, math.MinInt16, math.MaxInt16))))
+		case ComponentUnsignedShort:
+			if normalized {
+				value *= 65535
+			}
+			binary.LittleEndian.PutUint16(b, uint16(clampRound(value, 0, math.MaxUint16)))
+		case ComponentUnsignedInt:
+			binary.LittleEndian.PutUint32(b, uint32(clampRound(value, 0, math.MaxUint32)))
+		case ComponentFloat:
+			binary.LittleEndian.PutUint32(b, math.Float32bits(float32(value)))
+		}
+	}
+}
+
+func clampRound(value, low, high float64) float64 {
+	return math.Max(low, math.Min(high, math.Round(value)))
+}
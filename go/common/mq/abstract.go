@@ -2,6 +2,7 @@ package mq
 
 import (
 	"context"
+	"fmt"
 )
 
 type IMqProvider interface {
@@ -10,3 +11,20 @@ type IMqProvider interface {
 	Publish(queue string, data interface{}) error
 	Subscribe(context context.Context, queue string, callback func(data []byte) error) error
 }
+
+var (
+	_ IMqProvider = (*RabbitmqMqProvider)(nil)
+	_ IMqProvider = (*MemoryMqProvider)(nil)
+)
+
+// handle runs a subscriber callback and turns a panic into an error, so one
+// bad message fails on its own instead of taking the consumer down
+func handle(callback func(data []byte) error, data []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("message handler panicked: %v", r)
+		}
+	}()
+
+	return callback(data)
+}
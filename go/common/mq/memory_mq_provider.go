@@ -0,0 +1,105 @@
+package mq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var ErrProviderClosed = errors.New("mq provider is closed")
+
+// MemoryMqProvider is an in-process queue for running without a broker. Messages
+// live in buffered channels, so they are lost when the process exits.
+type MemoryMqProvider struct {
+	queues     map[string]chan []byte
+	bufferSize int
+	closed     bool
+	mu         sync.RWMutex
+}
+
+func NewMemoryMqProvider(bufferSize int) *MemoryMqProvider {
+	if bufferSize <= 0 {
+		bufferSize = 100
+	}
+
+	return &MemoryMqProvider{
+		queues:     make(map[string]chan []byte),
+		bufferSize: bufferSize,
+	}
+}
+
+func (m *MemoryMqProvider) Connect(connectionString string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = false
+	return nil
+}
+
+func (m *MemoryMqProvider) Disconnect() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+}
+
+func (m *MemoryMqProvider) Publish(queue string, data interface{}) error {
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	closed := m.closed
+	m.mu.RUnlock()
+	if closed {
+		return ErrProviderClosed
+	}
+
+	select {
+	case m.channel(queue) <- bytes:
+		return nil
+	default:
+		return fmt.Errorf("failed to publish message: queue %s is full", queue)
+	}
+}
+
+// Subscribe consumes the queue until the context is cancelled. Every call starts
+// one consumer, so calling it several times processes messages concurrently.
+func (m *MemoryMqProvider) Subscribe(ctx context.Context, queue string, callback func(data []byte) error) error {
+	messages := m.channel(queue)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case data := <-messages:
+				_ = handle(callback, data)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (m *MemoryMqProvider) channel(queue string) chan []byte {
+	m.mu.RLock()
+	ch, ok := m.queues[queue]
+	m.mu.RUnlock()
+	if ok {
+		return ch
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if ch, ok = m.queues[queue]; !ok {
+		ch = make(chan []byte, m.bufferSize)
+		m.queues[queue] = ch
+	}
+
+	return ch
+}
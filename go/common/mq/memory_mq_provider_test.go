@@ -0,0 +1,110 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryProviderRoutesByQueue(t *testing.T) {
+	assertQueuesAreIsolated(t, NewMemoryMqProvider(0), []string{"menu.scan", "model.optimize", "model.usdz"})
+}
+
+func TestMemoryProviderKeepsMessagesPublishedBeforeSubscribe(t *testing.T) {
+	provider := NewMemoryMqProvider(1)
+	if err := provider.Publish("menu.scan", "early"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan string, 1)
+	provider.Subscribe(ctx, "menu.scan", func(data []byte) error {
+		received <- string(data)
+		return nil
+	})
+
+	select {
+	case data := <-received:
+		if data != `"early"` {
+			t.Errorf("expected \"early\", got %s", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message published before subscribing was lost")
+	}
+}
+
+func TestMemoryProviderPublishErrors(t *testing.T) {
+	provider := NewMemoryMqProvider(1)
+	if err := provider.Publish("menu.scan", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := provider.Publish("menu.scan", 2); err == nil {
+		t.Error("expected an error when the queue is full")
+	}
+	if err := provider.Publish("menu.scan", func() {}); err == nil {
+		t.Error("expected an error for data that is not json")
+	}
+
+	provider.Disconnect()
+	if err := provider.Publish("model.optimize", 1); !errors.Is(err, ErrProviderClosed) {
+		t.Errorf("expected ErrProviderClosed, got %v", err)
+	}
+
+	provider.Connect("")
+	if err := provider.Publish("model.optimize", 1); err != nil {
+		t.Errorf("expected publishing after reconnecting, got %v", err)
+	}
+}
+
+func TestMemoryProviderStopsOnCancel(t *testing.T) {
+	provider := NewMemoryMqProvider(1)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	received := make(chan string, 1)
+	provider.Subscribe(ctx, "menu.scan", func(data []byte) error {
+		received <- string(data)
+		return nil
+	})
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+
+	if err := provider.Publish("menu.scan", "late"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case data := <-received:
+		t.Errorf("expected the cancelled consumer to stop, got %s", data)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMemoryProviderSurvivesPanickingHandler(t *testing.T) {
+	provider := NewMemoryMqProvider(2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan string, 1)
+	provider.Subscribe(ctx, "model.optimize", func(data []byte) error {
+		if string(data) == `"bad"` {
+			panic("malformed model")
+		}
+		received <- string(data)
+		return nil
+	})
+
+	provider.Publish("model.optimize", "bad")
+	provider.Publish("model.optimize", "good")
+
+	select {
+	case data := <-received:
+		if data != `"good"` {
+			t.Errorf("expected \"good\", got %s", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the consumer stopped after a handler panicked")
+	}
+}
@@ -1,32 +1,47 @@
 package mq
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/streadway/amqp"
 )
 
+// amqpChannel is the part of *amqp.Channel the provider uses, tests replace it
+// with an in-memory exchange
+type amqpChannel interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+	NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation
+}
+
+// RabbitmqMqProvider routes every queue through the exchange with the queue name
+// as the routing key, so services sharing one provider only receive their own
+// messages.
 type RabbitmqMqProvider struct {
 	connection *amqp.Connection
-	channel    *amqp.Channel
-	queue      amqp.Queue
+	channel    amqpChannel
 	config     RabbitMqConfig
+	declared   map[string]bool
+	mu         sync.Mutex
 }
 
 type RabbitMqConfig struct {
 	URL          string
 	Exchange     string
-	ExchangeType string // typically "fanout" for event broadcasting
-	QueueName    string
-	BindingKey   string
+	ExchangeType string // "direct", queues are bound by their name
 	Durable      bool
 	Reliable     bool
 }
 
 func NewRabbitmqMqProvider(config RabbitMqConfig) (*RabbitmqMqProvider, error) {
-	var provider = &RabbitmqMqProvider{config: config}
+	var provider = &RabbitmqMqProvider{config: config, declared: make(map[string]bool)}
 
 	err := provider.Connect(config.URL)
 	if err != nil {
@@ -70,12 +85,18 @@ func (r *RabbitmqMqProvider) Disconnect() {
 	r.connection.Close()
 }
 
+// Publish declares the queue before the first message to it, so messages
+// published before a worker subscribes are kept instead of being unroutable
 func (r *RabbitmqMqProvider) Publish(queue string, data interface{}) error {
 	bytes, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
 
+	if err := r.declareQueue(queue); err != nil {
+		return err
+	}
+
 	// headers := amqp.Table{
 	//     "event_id":        event.EventID.String(),
 	//     "event_type":      event.EventType,
@@ -85,10 +106,10 @@ func (r *RabbitmqMqProvider) Publish(queue string, data interface{}) error {
 	// }
 
 	err = r.channel.Publish(
-		r.config.Exchange,   // exchange
-		r.config.BindingKey, // routing key
-		false,               // mandatory
-		false,               // immediate
+		r.config.Exchange, // exchange
+		queue,             // routing key
+		false,             // mandatory
+		false,             // immediate
 		amqp.Publishing{
 			// Headers:         headers,
 			ContentType:     "application/json",
@@ -113,52 +134,73 @@ func (r *RabbitmqMqProvider) Publish(queue string, data interface{}) error {
 	return nil
 }
 
-func (r *RabbitmqMqProvider) Subscribe(queue string, callback func(data []byte) error) error {
-	err := r.declareQueue()
+func (r *RabbitmqMqProvider) Subscribe(ctx context.Context, queue string, callback func(data []byte) error) error {
+	err := r.declareQueue(queue)
 	if err != nil {
 		return err
 	}
 
 	msgs, err := r.channel.Consume(
-		r.queue.Name, // queue
-		"",           // consumer
-		false,        // auto-ack
-		false,        // exclusive
-		false,        // no-local
-		false,        // no-wait
-		nil,          // args
+		queue, // queue
+		"",    // consumer
+		false, // auto-ack
+		false, // exclusive
+		false, // no-local
+		false, // no-wait
+		nil,   // args
 	)
 	if err != nil {
 		return err
 	}
 
 	go func() {
-		for msg := range msgs {
-			_ = callback(msg.Body)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+
+				if err := handle(callback, msg.Body); err != nil {
+					_ = msg.Nack(false, false)
+					continue
+				}
+				_ = msg.Ack(false)
+			}
 		}
 	}()
 
 	return nil
 }
 
-func (r *RabbitmqMqProvider) declareQueue() error {
+// declareQueue declares the queue and binds it to the exchange with its own
+// name, once per provider
+func (r *RabbitmqMqProvider) declareQueue(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.declared[name] {
+		return nil
+	}
+
 	queue, err := r.channel.QueueDeclare(
-		r.config.QueueName, // name
-		r.config.Durable,   // durable
-		false,              // delete when unused
-		false,              // exclusive
-		false,              // no-wait
-		nil,                // arguments
+		name,             // name
+		r.config.Durable, // durable
+		false,            // delete when unused
+		false,            // exclusive
+		false,            // no-wait
+		nil,              // arguments
 	)
 	if err != nil {
 		return fmt.Errorf("failed to declare queue: %v", err)
 	}
 
-	r.queue = queue
 	err = r.channel.QueueBind(
-		queue.Name,          // queue name
-		r.config.BindingKey, // routing key
-		r.config.Exchange,   // exchange
+		queue.Name,        // queue name
+		name,              // routing key
+		r.config.Exchange, // exchange
 		false,
 		nil,
 	)
@@ -166,5 +208,6 @@ func (r *RabbitmqMqProvider) declareQueue() error {
 		return fmt.Errorf("failed to bind queue: %v", err)
 	}
 
+	r.declared[name] = true
 	return nil
 }
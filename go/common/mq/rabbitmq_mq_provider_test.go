@@ -0,0 +1,166 @@
+package mq
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// fakeExchange is a direct exchange, messages go to the queues bound with their
+// routing key
+type fakeExchange struct {
+	queues   map[string]chan amqp.Delivery
+	bindings map[string][]string
+	mu       sync.Mutex
+}
+
+func newFakeExchange() *fakeExchange {
+	return &fakeExchange{
+		queues:   make(map[string]chan amqp.Delivery),
+		bindings: make(map[string][]string),
+	}
+}
+
+func (f *fakeExchange) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
+	return nil
+}
+
+func (f *fakeExchange) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.queues[name]; !ok {
+		f.queues[name] = make(chan amqp.Delivery, 10)
+	}
+	return amqp.Queue{Name: name}, nil
+}
+
+func (f *fakeExchange) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.bindings[key] = append(f.bindings[key], name)
+	return nil
+}
+
+func (f *fakeExchange) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, name := range f.bindings[key] {
+		f.queues[name] <- amqp.Delivery{Acknowledger: fakeAcknowledger{}, Body: msg.Body}
+	}
+	return nil
+}
+
+func (f *fakeExchange) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.queues[queue], nil
+}
+
+func (f *fakeExchange) NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation {
+	return confirm
+}
+
+type fakeAcknowledger struct{}
+
+func (fakeAcknowledger) Ack(tag uint64, multiple bool) error                { return nil }
+func (fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error { return nil }
+func (fakeAcknowledger) Reject(tag uint64, requeue bool) error              { return nil }
+
+// assertQueuesAreIsolated publishes to every queue and checks each subscriber
+// only receives the messages published to its own queue
+func assertQueuesAreIsolated(t *testing.T, provider IMqProvider, queues []string) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(map[string]chan string)
+	for _, queue := range queues {
+		ch := make(chan string, 10)
+		received[queue] = ch
+		err := provider.Subscribe(ctx, queue, func(data []byte) error {
+			ch <- string(data)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, queue := range queues {
+		if err := provider.Publish(queue, queue); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, queue := range queues {
+		select {
+		case data := <-received[queue]:
+			if data != `"`+queue+`"` {
+				t.Errorf("queue %s received %s", queue, data)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("queue %s received nothing", queue)
+		}
+	}
+
+	// nothing else may arrive once every queue got its own message
+	time.Sleep(50 * time.Millisecond)
+	for _, queue := range queues {
+		select {
+		case data := <-received[queue]:
+			t.Errorf("queue %s received a second message %s", queue, data)
+		default:
+		}
+	}
+}
+
+func TestRabbitmqProviderRoutesByQueue(t *testing.T) {
+	provider := &RabbitmqMqProvider{
+		channel:  newFakeExchange(),
+		config:   RabbitMqConfig{Exchange: "bidi", ExchangeType: "direct"},
+		declared: make(map[string]bool),
+	}
+
+	assertQueuesAreIsolated(t, provider, []string{"menu.scan", "model.optimize", "model.usdz"})
+}
+
+func TestRabbitmqProviderKeepsMessagesPublishedBeforeSubscribe(t *testing.T) {
+	provider := &RabbitmqMqProvider{
+		channel:  newFakeExchange(),
+		config:   RabbitMqConfig{Exchange: "bidi", ExchangeType: "direct"},
+		declared: make(map[string]bool),
+	}
+
+	if err := provider.Publish("model.optimize", "early"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan string, 1)
+	err := provider.Subscribe(ctx, "model.optimize", func(data []byte) error {
+		received <- string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case data := <-received:
+		if data != `"early"` {
+			t.Errorf("expected \"early\", got %s", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message published before subscribing was lost")
+	}
+}
@@ -0,0 +1,88 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+)
+
+// IOcrEngine extracts text and word geometry from a single image
+type IOcrEngine interface {
+	Name() string
+	Recognize(ctx context.Context, imagePath string) (*Page, error)
+}
+
+// IPdfReader splits a pdf into pages. Pages with an embedded text layer are read
+// directly, the others are rendered to images for an IOcrEngine.
+type IPdfReader interface {
+	ReadPages(ctx context.Context, pdfPath string) ([]*PdfPage, error)
+	RenderPage(ctx context.Context, pdfPath string, pageNumber int, outputPath string) error
+}
+
+// PdfPage is a single page of a pdf. Text is nil when the page has no usable
+// text layer and has to be rendered and recognized instead.
+type PdfPage struct {
+	Number int
+	Text   *Page
+}
+
+// Page is the result of recognizing a single image
+type Page struct {
+	Number int    `json:"number"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Text   string `json:"text"`
+	Words  []Word `json:"words"`
+}
+
+// Word is a single recognized word with its position on the page
+type Word struct {
+	Text       string      `json:"text"`
+	Box        BoundingBox `json:"box"`
+	Confidence float64     `json:"confidence"` // 0..1
+	Block      int         `json:"block"`
+	Paragraph  int         `json:"paragraph"`
+	Line       int         `json:"line"`
+}
+
+// BoundingBox is an axis aligned rectangle in page pixels
+type BoundingBox struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+var (
+	ErrEngineNotAvailable    = errors.New("ocr engine is not available")
+	ErrEmptyImage            = errors.New("image path is empty")
+	ErrPdfReaderNotAvailable = errors.New("pdf reader is not available")
+)
+
+// Right returns the x coordinate of the right edge of the box
+func (b BoundingBox) Right() int {
+	return b.X + b.Width
+}
+
+// Bottom returns the y coordinate of the bottom edge of the box
+func (b BoundingBox) Bottom() int {
+	return b.Y + b.Height
+}
+
+// Union returns the smallest box containing both boxes
+func (b BoundingBox) Union(other BoundingBox) BoundingBox {
+	if b.Width == 0 && b.Height == 0 {
+		return other
+	}
+	if other.Width == 0 && other.Height == 0 {
+		return b
+	}
+
+	x := min(b.X, other.X)
+	y := min(b.Y, other.Y)
+	return BoundingBox{
+		X:      x,
+		Y:      y,
+		Width:  max(b.Right(), other.Right()) - x,
+		Height: max(b.Bottom(), other.Bottom()) - y,
+	}
+}
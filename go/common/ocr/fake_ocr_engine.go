@@ -0,0 +1,159 @@
+package ocr
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	fakeCharWidth  = 12
+	fakeLineHeight = 24
+	fakeMargin     = 40
+)
+
+// FakeOcrEngine is a deterministic engine for tests and offline development.
+// It lays the configured text out on a monospaced grid, so the words it returns
+// carry realistic bounding boxes without running a real OCR engine.
+type FakeOcrEngine struct {
+	pages map[string]string
+	mu    sync.RWMutex
+}
+
+// NewFakeOcrEngine creates a fake engine. Pages are keyed by image file name;
+// images without an entry fall back to a "<image>.txt" file next to the image.
+func NewFakeOcrEngine(pages map[string]string) *FakeOcrEngine {
+	if pages == nil {
+		pages = make(map[string]string)
+	}
+
+	return &FakeOcrEngine{pages: pages}
+}
+
+func (f *FakeOcrEngine) Name() string {
+	return "fake"
+}
+
+// SetPage registers the text returned for the given image file name
+func (f *FakeOcrEngine) SetPage(imageName string, text string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.pages[imageName] = text
+}
+
+func (f *FakeOcrEngine) Recognize(ctx context.Context, imagePath string) (*Page, error) {
+	if imagePath == "" {
+		return nil, ErrEmptyImage
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	text, err := f.lookup(imagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return LayoutText(text), nil
+}
+
+func (f *FakeOcrEngine) lookup(imagePath string) (string, error) {
+	f.mu.RLock()
+	text, ok := f.pages[imagePath]
+	if !ok {
+		text, ok = f.pages[filepath.Base(imagePath)]
+	}
+	f.mu.RUnlock()
+
+	if ok {
+		return text, nil
+	}
+
+	bytes, err := os.ReadFile(imagePath + ".txt")
+	if err != nil {
+		return "", fmt.Errorf("fake ocr has no text for %s", filepath.Base(imagePath))
+	}
+
+	return string(bytes), nil
+}
+
+// LayoutText places text on a monospaced grid and returns it as a recognized page.
+// Every character is fakeCharWidth pixels wide and every line fakeLineHeight pixels
+// tall, so spacing in the source text is preserved in the word boxes.
+func LayoutText(text string) *Page {
+	page := &Page{Number: 1, Words: make([]Word, 0)}
+	block, line := 1, 0
+	maxColumns := 0
+
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for row, raw := range lines {
+		if strings.TrimSpace(raw) == "" {
+			if line > 0 {
+				block++
+				line = 0
+			}
+			continue
+		}
+
+		line++
+		maxColumns = max(maxColumns, utf8.RuneCountInString(raw))
+		for _, token := range tokenize(raw) {
+			page.Words = append(page.Words, Word{
+				Text: token.text,
+				Box: BoundingBox{
+					X:      fakeMargin + token.column*fakeCharWidth,
+					Y:      fakeMargin + row*fakeLineHeight,
+					Width:  utf8.RuneCountInString(token.text) * fakeCharWidth,
+					Height: fakeLineHeight - 4,
+				},
+				Confidence: 1,
+				Block:      block,
+				Paragraph:  1,
+				Line:       line,
+			})
+		}
+	}
+
+	page.Width = 2*fakeMargin + maxColumns*fakeCharWidth
+	page.Height = 2*fakeMargin + len(lines)*fakeLineHeight
+	page.Text = TextFromWords(page.Words)
+	return page
+}
+
+type token struct {
+	text   string
+	column int
+}
+
+func tokenize(line string) []token {
+	tokens := make([]token, 0)
+	column, start := 0, -1
+	var current []rune
+
+	for _, r := range line {
+		if r == ' ' || r == '\t' {
+			if start >= 0 {
+				tokens = append(tokens, token{text: string(current), column: start})
+				start, current = -1, current[:0]
+			}
+		} else {
+			if start < 0 {
+				start = column
+			}
+			current = append(current, r)
+		}
+		column++
+	}
+
+	if start >= 0 {
+		tokens = append(tokens, token{text: string(current), column: start})
+	}
+
+	return tokens
+}
@@ -0,0 +1,74 @@
+package ocr
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLayoutText(t *testing.T) {
+	page := LayoutText("STARTERS\nSoup   $6.50\n\nMAINS\n")
+
+	want := []Word{
+		{Text: "STARTERS", Box: BoundingBox{X: fakeMargin, Y: fakeMargin, Width: 8 * fakeCharWidth, Height: fakeLineHeight - 4}, Block: 1, Line: 1},
+		{Text: "Soup", Box: BoundingBox{X: fakeMargin, Y: fakeMargin + fakeLineHeight, Width: 4 * fakeCharWidth, Height: fakeLineHeight - 4}, Block: 1, Line: 2},
+		{Text: "$6.50", Box: BoundingBox{X: fakeMargin + 7*fakeCharWidth, Y: fakeMargin + fakeLineHeight, Width: 5 * fakeCharWidth, Height: fakeLineHeight - 4}, Block: 1, Line: 2},
+		{Text: "MAINS", Box: BoundingBox{X: fakeMargin, Y: fakeMargin + 3*fakeLineHeight, Width: 5 * fakeCharWidth, Height: fakeLineHeight - 4}, Block: 2, Line: 1},
+	}
+	if len(page.Words) != len(want) {
+		t.Fatalf("expected %d words, got %+v", len(want), page.Words)
+	}
+	for i, w := range want {
+		w.Confidence, w.Paragraph = 1, 1
+		if page.Words[i] != w {
+			t.Errorf("word %d: expected %+v, got %+v", i, w, page.Words[i])
+		}
+	}
+
+	if page.Text != "STARTERS\nSoup $6.50\n\nMAINS" {
+		t.Errorf("unexpected text %q", page.Text)
+	}
+	if page.Width != 2*fakeMargin+12*fakeCharWidth || page.Height != 2*fakeMargin+5*fakeLineHeight {
+		t.Errorf("unexpected page size %dx%d", page.Width, page.Height)
+	}
+}
+
+func TestFakeOcrEngineRecognize(t *testing.T) {
+	dir := t.TempDir()
+	withText := filepath.Join(dir, "menu.jpg")
+	if err := os.WriteFile(withText+".txt", []byte("Soup $6.50"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := NewFakeOcrEngine(map[string]string{"page1.png": "Garlic Bread $4.00"})
+	tests := []struct {
+		name string
+		path string
+		text string
+		err  bool
+	}{
+		{"by file name", filepath.Join(dir, "page1.png"), "Garlic Bread $4.00", false},
+		{"text file next to the image", withText, "Soup $6.50", false},
+		{"unknown image", filepath.Join(dir, "missing.png"), "", true},
+		{"empty path", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := engine.Recognize(context.Background(), tt.path)
+			if tt.err {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Text != tt.text {
+				t.Errorf("expected %q, got %q", tt.text, page.Text)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// minTextLayerWords is the number of words a pdf page needs before its embedded
+// text is trusted. Scanned pages often carry a few stray glyphs such as a page
+// number, and those still have to go through OCR.
+const minTextLayerWords = 3
+
+// PopplerPdfReader reads pdf files with the poppler command line tools. The text
+// layer comes from pdftotext and pages without one are rendered with pdftoppm.
+type PopplerPdfReader struct {
+	config PopplerConfig
+}
+
+type PopplerConfig struct {
+	BinDir string // directory holding pdftotext and pdftoppm, defaults to PATH
+	Dpi    int    // resolution of rendered pages and of text layer boxes, defaults to 300
+}
+
+func NewPopplerPdfReader(config PopplerConfig) *PopplerPdfReader {
+	if config.Dpi == 0 {
+		config.Dpi = 300
+	}
+
+	return &PopplerPdfReader{config: config}
+}
+
+func (p *PopplerPdfReader) ReadPages(ctx context.Context, pdfPath string) ([]*PdfPage, error) {
+	output, err := p.run(ctx, "pdftotext", "-bbox-layout", "-enc", "UTF-8", pdfPath, "-")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseBboxLayout(bytes.NewReader(output), float64(p.config.Dpi)/72)
+}
+
+func (p *PopplerPdfReader) RenderPage(ctx context.Context, pdfPath string, pageNumber int, outputPath string) error {
+	number := strconv.Itoa(pageNumber)
+	prefix := strings.TrimSuffix(outputPath, filepath.Ext(outputPath))
+
+	_, err := p.run(ctx, "pdftoppm",
+		"-f", number, "-l", number,
+		"-r", strconv.Itoa(p.config.Dpi),
+		"-png", "-singlefile",
+		pdfPath, prefix,
+	)
+	return err
+}
+
+func (p *PopplerPdfReader) run(ctx context.Context, tool string, args ...string) ([]byte, error) {
+	path := tool
+	if p.config.BinDir != "" {
+		path = filepath.Join(p.config.BinDir, tool)
+	}
+
+	if _, err := exec.LookPath(path); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPdfReaderNotAvailable, err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s failed: %v: %s", tool, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+type bboxDocument struct {
+	Pages []struct {
+		Width  float64 `xml:"width,attr"`
+		Height float64 `xml:"height,attr"`
+		Flows  []struct {
+			Blocks []struct {
+				Lines []struct {
+					Words []struct {
+						XMin float64 `xml:"xMin,attr"`
+						YMin float64 `xml:"yMin,attr"`
+						XMax float64 `xml:"xMax,attr"`
+						YMax float64 `xml:"yMax,attr"`
+						Text string  `xml:",chardata"`
+					} `xml:"word"`
+				} `xml:"line"`
+			} `xml:"block"`
+		} `xml:"flow"`
+	} `xml:"body>doc>page"`
+}
+
+// parseBboxLayout converts the xhtml written by pdftotext -bbox-layout into one
+// PdfPage per pdf page. Coordinates are in points and get multiplied by scale so
+// text layer pages line up with rendered ones. Flows, blocks and lines map onto
+// the block, paragraph and line numbers of the words.
+func parseBboxLayout(r io.Reader, scale float64) ([]*PdfPage, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	var doc bboxDocument
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to read pdf text layer: %w", err)
+	}
+
+	scaled := func(value float64) int {
+		return int(math.Round(value * scale))
+	}
+
+	pages := make([]*PdfPage, 0, len(doc.Pages))
+	for i, source := range doc.Pages {
+		page := &Page{
+			Number: i + 1,
+			Width:  scaled(source.Width),
+			Height: scaled(source.Height),
+			Words:  make([]Word, 0),
+		}
+
+		for f, flow := range source.Flows {
+			for b, block := range flow.Blocks {
+				for l, line := range block.Lines {
+					for _, word := range line.Words {
+						text := strings.TrimSpace(word.Text)
+						if text == "" {
+							continue
+						}
+
+						page.Words = append(page.Words, Word{
+							Text: text,
+							Box: BoundingBox{
+								X:      scaled(word.XMin),
+								Y:      scaled(word.YMin),
+								Width:  scaled(word.XMax) - scaled(word.XMin),
+								Height: scaled(word.YMax) - scaled(word.YMin),
+							},
+							Confidence: 1,
+							Block:      f + 1,
+							Paragraph:  b + 1,
+							Line:       l + 1,
+						})
+					}
+				}
+			}
+		}
+
+		pdfPage := &PdfPage{Number: i + 1}
+		if len(page.Words) >= minTextLayerWords {
+			page.Text = TextFromWords(page.Words)
+			pdfPage.Text = page
+		}
+		pages = append(pages, pdfPage)
+	}
+
+	return pages, nil
+}
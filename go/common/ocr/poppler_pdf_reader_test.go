@@ -0,0 +1,88 @@
+package ocr
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseBboxLayout(t *testing.T) {
+	file, err := os.Open("testdata/bbox_layout.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	pages, err := parseBboxLayout(file, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 3 {
+		t.Fatalf("expected 3 pages, got %d", len(pages))
+	}
+
+	page := pages[0].Text
+	if pages[0].Number != 1 || page == nil {
+		t.Fatalf("expected the first page to use its text layer, got %+v", pages[0])
+	}
+	if page.Width != 1191 || page.Height != 1684 {
+		t.Errorf("expected the page size scaled to 1191x1684, got %dx%d", page.Width, page.Height)
+	}
+	if page.Text != "STARTERS\n\nSoup $6.50\nTomato & basil\n\nMAINS" {
+		t.Errorf("unexpected text %q", page.Text)
+	}
+
+	soup := page.Words[1]
+	want := Word{Text: "Soup", Box: BoundingBox{X: 114, Y: 160, Width: 46, Height: 24}, Confidence: 1, Block: 1, Paragraph: 2, Line: 1}
+	if soup != want {
+		t.Errorf("expected %+v, got %+v", want, soup)
+	}
+	if mains := page.Words[len(page.Words)-1]; mains.Block != 2 || mains.Paragraph != 1 || mains.Line != 1 {
+		t.Errorf("expected the second flow to start a new block, got %+v", mains)
+	}
+
+	// a lone page number and an empty page fall back to rendering
+	for _, pdfPage := range pages[1:] {
+		if pdfPage.Text != nil {
+			t.Errorf("expected page %d to be rendered, got text %q", pdfPage.Number, pdfPage.Text.Text)
+		}
+	}
+}
+
+func TestParseBboxLayoutTextLayerThreshold(t *testing.T) {
+	layout := func(words ...string) string {
+		var sb strings.Builder
+		sb.WriteString("<html><body><doc><page width=\"100\" height=\"100\"><flow><block><line>")
+		for _, word := range words {
+			sb.WriteString(`<word xMin="1" yMin="1" xMax="2" yMax="2">` + word + "</word>")
+		}
+		sb.WriteString("</line></block></flow></page></doc></body></html>")
+		return sb.String()
+	}
+
+	tests := []struct {
+		name      string
+		words     []string
+		textLayer bool
+	}{
+		{"no words", nil, false},
+		{"below threshold", []string{"Page", "2"}, false},
+		{"blank words do not count", []string{"Page", " ", "2"}, false},
+		{"at threshold", []string{"Soup", "of", "$6.50"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages, err := parseBboxLayout(strings.NewReader(layout(tt.words...)), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pages) != 1 {
+				t.Fatalf("expected 1 page, got %d", len(pages))
+			}
+			if (pages[0].Text != nil) != tt.textLayer {
+				t.Errorf("expected text layer %v, got %+v", tt.textLayer, pages[0].Text)
+			}
+		})
+	}
+}
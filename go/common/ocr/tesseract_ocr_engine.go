@@ -0,0 +1,150 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+type TesseractOcrEngine struct {
+	config TesseractConfig
+}
+
+type TesseractConfig struct {
+	Path        string // tesseract binary, defaults to "tesseract" on PATH
+	Languages   string // e.g. "eng+tur"
+	PageSegMode int    // tesseract --psm value, defaults to 3 (fully automatic)
+}
+
+func NewTesseractOcrEngine(config TesseractConfig) *TesseractOcrEngine {
+	if config.Path == "" {
+		config.Path = "tesseract"
+	}
+	if config.Languages == "" {
+		config.Languages = "eng"
+	}
+	if config.PageSegMode == 0 {
+		config.PageSegMode = 3
+	}
+
+	return &TesseractOcrEngine{config: config}
+}
+
+func (t *TesseractOcrEngine) Name() string {
+	return "tesseract"
+}
+
+func (t *TesseractOcrEngine) Recognize(ctx context.Context, imagePath string) (*Page, error) {
+	if imagePath == "" {
+		return nil, ErrEmptyImage
+	}
+
+	if _, err := exec.LookPath(t.config.Path); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEngineNotAvailable, err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.config.Path,
+		imagePath, "stdout",
+		"-l", t.config.Languages,
+		"--psm", strconv.Itoa(t.config.PageSegMode),
+		"tsv",
+	)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("tesseract failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return parseTesseractTsv(&stdout)
+}
+
+// parseTesseractTsv converts tesseract's tsv output into a Page. The columns are
+// level, page_num, block_num, par_num, line_num, word_num, left, top, width, height, conf, text
+func parseTesseractTsv(r io.Reader) (*Page, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = '\t'
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+
+	page := &Page{Number: 1, Words: make([]Word, 0)}
+	header := true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tesseract output: %w", err)
+		}
+
+		if header {
+			header = false
+			continue
+		}
+		if len(record) < 11 {
+			continue
+		}
+
+		values := make([]int, 10)
+		for i := 0; i < 10; i++ {
+			values[i], _ = strconv.Atoi(record[i])
+		}
+
+		level := values[0]
+		box := BoundingBox{X: values[6], Y: values[7], Width: values[8], Height: values[9]}
+		if level == 1 {
+			page.Width = box.Width
+			page.Height = box.Height
+			continue
+		}
+		if level != 5 || len(record) < 12 {
+			continue
+		}
+
+		text := strings.TrimSpace(record[11])
+		conf, _ := strconv.ParseFloat(record[10], 64)
+		if text == "" || conf < 0 {
+			continue
+		}
+
+		page.Words = append(page.Words, Word{
+			Text:       text,
+			Box:        box,
+			Confidence: conf / 100,
+			Block:      values[2],
+			Paragraph:  values[3],
+			Line:       values[4],
+		})
+	}
+
+	page.Text = TextFromWords(page.Words)
+	return page, nil
+}
+
+// TextFromWords joins words into lines using their block, paragraph and line numbers
+func TextFromWords(words []Word) string {
+	var sb strings.Builder
+	for i, word := range words {
+		if i > 0 {
+			prev := words[i-1]
+			switch {
+			case prev.Block != word.Block || prev.Paragraph != word.Paragraph:
+				sb.WriteString("\n\n")
+			case prev.Line != word.Line:
+				sb.WriteString("\n")
+			default:
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString(word.Text)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,121 @@
+package ocr
+
+import (
+	"strings"
+	"testing"
+)
+
+const tsvHeader = "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n"
+
+func TestParseTesseractTsv(t *testing.T) {
+	tests := []struct {
+		name  string
+		rows  []string
+		text  string
+		words int
+		width int
+	}{
+		{
+			name: "page size from the page row",
+			rows: []string{
+				"1\t1\t0\t0\t0\t0\t0\t0\t1240\t1754\t-1\t",
+				"5\t1\t1\t1\t1\t1\t40\t40\t60\t20\t96.5\tSoup",
+			},
+			text:  "Soup",
+			words: 1,
+			width: 1240,
+		},
+		{
+			name: "rows without confidence are skipped",
+			rows: []string{
+				"2\t1\t1\t0\t0\t0\t40\t40\t200\t20\t-1\t",
+				"5\t1\t1\t1\t1\t1\t40\t40\t60\t20\t-1\tghost",
+				"5\t1\t1\t1\t1\t2\t40\t40\t60\t20\t95\t ",
+				"5\t1\t1\t1\t1\t2\t110\t40\t60\t20\t91\tBread",
+			},
+			text:  "Bread",
+			words: 1,
+		},
+		{
+			name: "words on one line",
+			rows: []string{
+				"5\t1\t1\t1\t1\t1\t40\t40\t60\t20\t90\tGarlic",
+				"5\t1\t1\t1\t1\t2\t110\t40\t60\t20\t90\tBread",
+				"5\t1\t1\t1\t1\t3\t400\t40\t60\t20\t90\t$4.00",
+			},
+			text:  "Garlic Bread $4.00",
+			words: 3,
+		},
+		{
+			name: "lines and blocks",
+			rows: []string{
+				"5\t1\t1\t1\t1\t1\t40\t40\t60\t20\t90\tSTARTERS",
+				"5\t1\t1\t1\t2\t1\t40\t70\t60\t20\t90\tSoup",
+				"5\t1\t2\t1\t1\t1\t40\t200\t60\t20\t90\tMAINS",
+			},
+			text:  "STARTERS\nSoup\n\nMAINS",
+			words: 3,
+		},
+		{
+			name: "short rows are ignored",
+			rows: []string{
+				"5\t1\t1",
+				"5\t1\t1\t1\t1\t1\t40\t40\t60\t20\t90\tSoup",
+			},
+			text:  "Soup",
+			words: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := parseTesseractTsv(strings.NewReader(tsvHeader + strings.Join(tt.rows, "\n") + "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Text != tt.text {
+				t.Errorf("expected text %q, got %q", tt.text, page.Text)
+			}
+			if len(page.Words) != tt.words {
+				t.Errorf("expected %d words, got %d", tt.words, len(page.Words))
+			}
+			if tt.width != 0 && page.Width != tt.width {
+				t.Errorf("expected width %d, got %d", tt.width, page.Width)
+			}
+		})
+	}
+}
+
+func TestParseTesseractTsvWord(t *testing.T) {
+	page, err := parseTesseractTsv(strings.NewReader(tsvHeader + "5\t1\t3\t2\t4\t1\t40\t50\t60\t20\t87\tSoup\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Word{Text: "Soup", Box: BoundingBox{X: 40, Y: 50, Width: 60, Height: 20}, Confidence: 0.87, Block: 3, Paragraph: 2, Line: 4}
+	if len(page.Words) != 1 || page.Words[0] != want {
+		t.Errorf("expected %+v, got %+v", want, page.Words)
+	}
+}
+
+func TestTextFromWords(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []Word
+		text  string
+	}{
+		{"empty", nil, ""},
+		{"same line", []Word{{Text: "a", Block: 1, Paragraph: 1, Line: 1}, {Text: "b", Block: 1, Paragraph: 1, Line: 1}}, "a b"},
+		{"new line", []Word{{Text: "a", Block: 1, Paragraph: 1, Line: 1}, {Text: "b", Block: 1, Paragraph: 1, Line: 2}}, "a\nb"},
+		{"new paragraph", []Word{{Text: "a", Block: 1, Paragraph: 1, Line: 1}, {Text: "b", Block: 1, Paragraph: 2, Line: 1}}, "a\n\nb"},
+		{"new block", []Word{{Text: "a", Block: 1, Paragraph: 1, Line: 1}, {Text: "b", Block: 2, Paragraph: 1, Line: 1}}, "a\n\nb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if text := TextFromWords(tt.words); text != tt.text {
+				t.Errorf("expected %q, got %q", tt.text, text)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package preprocess
+
+import (
+	"image"
+	"math"
+)
+
+// minSkewAngle is the smallest skew in degrees worth rotating the image for
+const minSkewAngle = 0.1
+
+// maxSkewSamples caps the number of dark pixels used to estimate the skew
+const maxSkewSamples = 200000
+
+// Deskew estimates the angle of the text lines and rotates the image so they
+// become horizontal. It returns the corrected image and the detected angle in
+// degrees, positive when lines were running down to the right.
+func Deskew(gray *image.Gray, maxAngle float64) (*image.Gray, float64) {
+	angle := EstimateSkew(gray, maxAngle)
+	if math.Abs(angle) < minSkewAngle {
+		return gray, angle
+	}
+
+	return Rotate(gray, -angle), angle
+}
+
+// EstimateSkew finds the angle, within plus or minus maxAngle degrees, at which
+// the dark pixels project onto the fewest and fullest rows. Text lines give the
+// sharpest profile when the projection runs along them.
+func EstimateSkew(gray *image.Gray, maxAngle float64) float64 {
+	if maxAngle <= 0 {
+		maxAngle = 15
+	}
+
+	xs, ys := darkPixels(gray)
+	if len(xs) == 0 {
+		return 0
+	}
+
+	best := searchSkew(xs, ys, -maxAngle, maxAngle, 0.5)
+	return searchSkew(xs, ys, best-0.5, best+0.5, 0.05)
+}
+
+func searchSkew(xs, ys []float64, from, to, step float64) float64 {
+	bestAngle, bestScore := 0.0, -1.0
+	for angle := from; angle <= to+step/2; angle += step {
+		score := projectionScore(xs, ys, angle)
+		// prefer the angle closest to zero when profiles are equally sharp
+		if score > bestScore || (score == bestScore && math.Abs(angle) < math.Abs(bestAngle)) {
+			bestAngle, bestScore = angle, score
+		}
+	}
+
+	return math.Round(bestAngle*100) / 100
+}
+
+// projectionScore sums the squared row counts of the pixels projected along the
+// given angle, which is largest when the rows line up with the text
+func projectionScore(xs, ys []float64, angle float64) float64 {
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+
+	minRow, maxRow := math.Inf(1), math.Inf(-1)
+	rows := make([]float64, len(xs))
+	for i := range xs {
+		rows[i] = ys[i]*cos - xs[i]*sin
+		minRow = math.Min(minRow, rows[i])
+		maxRow = math.Max(maxRow, rows[i])
+	}
+
+	bins := make([]float64, int(maxRow-minRow)+1)
+	for _, row := range rows {
+		bins[int(row-minRow)]++
+	}
+
+	score := 0.0
+	for _, count := range bins {
+		score += count * count
+	}
+
+	return score
+}
+
+// darkPixels returns the coordinates of the pixels darker than the Otsu
+// threshold, sampled on a grid so large images stay cheap
+func darkPixels(gray *image.Gray) ([]float64, []float64) {
+	w, h := gray.Bounds().Dx(), gray.Bounds().Dy()
+	threshold := otsuThreshold(histogram(gray))
+
+	step := 1
+	for (w/step)*(h/step) > maxSkewSamples*4 {
+		step++
+	}
+
+	xs, ys := make([]float64, 0), make([]float64, 0)
+	for y := 0; y < h; y += step {
+		for x := 0; x < w; x += step {
+			if int(gray.Pix[y*gray.Stride+x]) <= threshold {
+				xs = append(xs, float64(x))
+				ys = append(ys, float64(y))
+			}
+		}
+	}
+
+	// a dark page with light text has more dark pixels than ink, which would
+	// flatten the profile
+	if len(xs) > (w/step)*(h/step)/2 {
+		return nil, nil
+	}
+
+	return xs, ys
+}
+
+// Rotate turns the image clockwise by angle degrees around its center. The
+// canvas grows to fit the rotated corners and new pixels are white.
+func Rotate(gray *image.Gray, angle float64) *image.Gray {
+	w, h := gray.Bounds().Dx(), gray.Bounds().Dy()
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+
+	dw := int(math.Ceil(math.Abs(float64(w)*cos) + math.Abs(float64(h)*sin)))
+	dh := int(math.Ceil(math.Abs(float64(w)*sin) + math.Abs(float64(h)*cos)))
+
+	cx, cy := float64(w)/2, float64(h)/2
+	dcx, dcy := float64(dw)/2, float64(dh)/2
+
+	out := image.NewGray(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			// rotate the output pixel center back into the source
+			rx, ry := float64(x)+0.5-dcx, float64(y)+0.5-dcy
+			sx := rx*cos + ry*sin + cx - 0.5
+			sy := -rx*sin + ry*cos + cy - 0.5
+			out.Pix[y*out.Stride+x] = bilinear(gray, sx, sy)
+		}
+	}
+
+	return out
+}
+
+// bilinear samples the image at a fractional position, treating everything
+// outside of it as white
+func bilinear(gray *image.Gray, x, y float64) uint8 {
+	w, h := gray.Bounds().Dx(), gray.Bounds().Dy()
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+	fx, fy := x-float64(x0), y-float64(y0)
+
+	at := func(x, y int) float64 {
+		if x < 0 || y < 0 || x >= w || y >= h {
+			return 255
+		}
+		return float64(gray.Pix[y*gray.Stride+x])
+	}
+
+	top := at(x0, y0)*(1-fx) + at(x0+1, y0)*fx
+	bottom := at(x0, y0+1)*(1-fx) + at(x0+1, y0+1)*fx
+	return uint8(math.Round(top*(1-fy) + bottom*fy))
+}
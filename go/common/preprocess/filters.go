@@ -0,0 +1,200 @@
+package preprocess
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Downscale shrinks the image so its longest side is at most maxDimension,
+// averaging the source pixels that fall into each output pixel
+func Downscale(img image.Image, maxDimension int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if maxDimension <= 0 || max(w, h) <= maxDimension {
+		return img
+	}
+
+	dw, dh := maxDimension, h*maxDimension/w
+	if h > w {
+		dw, dh = w*maxDimension/h, maxDimension
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	src := toRGBA(img)
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					sum[0] += int(src.Pix[i])
+					sum[1] += int(src.Pix[i+1])
+					sum[2] += int(src.Pix[i+2])
+					sum[3] += int(src.Pix[i+3])
+					i += 4
+				}
+			}
+
+			count := (x1 - x0) * (y1 - y0)
+			j := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				dst.Pix[j+c] = uint8(sum[c] / count)
+			}
+		}
+	}
+
+	return dst
+}
+
+// Grayscale converts the image to 8-bit luma with its origin at zero
+func Grayscale(img image.Image) *image.Gray {
+	bounds := img.Bounds()
+	gray := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(gray, gray.Bounds(), img, bounds.Min, draw.Src)
+	return gray
+}
+
+// NormalizeContrast stretches the histogram so the darkest and brightest pixels,
+// ignoring the clip share at both ends, span the full 0..255 range
+func NormalizeContrast(gray *image.Gray, clip float64) *image.Gray {
+	hist := histogram(gray)
+	total := len(gray.Pix)
+	if total == 0 {
+		return gray
+	}
+
+	limit := int(float64(total) * clip)
+	low, high := 0, 255
+	for count := 0; low < 255; low++ {
+		count += hist[low]
+		if count > limit {
+			break
+		}
+	}
+	for count := 0; high > 0; high-- {
+		count += hist[high]
+		if count > limit {
+			break
+		}
+	}
+	if high <= low {
+		return gray
+	}
+
+	var lut [256]uint8
+	for v := 0; v < 256; v++ {
+		switch {
+		case v <= low:
+			lut[v] = 0
+		case v >= high:
+			lut[v] = 255
+		default:
+			lut[v] = uint8((v - low) * 255 / (high - low))
+		}
+	}
+
+	out := image.NewGray(gray.Bounds())
+	for i, v := range gray.Pix {
+		out.Pix[i] = lut[v]
+	}
+
+	return out
+}
+
+// Binarize turns the image black and white with an adaptive threshold: a pixel
+// becomes black when it is darker than the mean of its window by more than the
+// sensitivity share. Comparing against the neighbourhood instead of one global
+// threshold keeps text readable under shadows and glare.
+func Binarize(gray *image.Gray, window int, sensitivity float64) *image.Gray {
+	w, h := gray.Bounds().Dx(), gray.Bounds().Dy()
+	if window <= 0 {
+		window = max(max(w, h)/16, 15)
+	}
+	if sensitivity <= 0 {
+		sensitivity = 0.15
+	}
+
+	// integral[y][x] holds the sum of all pixels above and left of x, y
+	stride := w + 1
+	integral := make([]int64, stride*(h+1))
+	for y := 0; y < h; y++ {
+		var row int64
+		for x := 0; x < w; x++ {
+			row += int64(gray.Pix[y*gray.Stride+x])
+			integral[(y+1)*stride+x+1] = integral[y*stride+x+1] + row
+		}
+	}
+
+	half := window / 2
+	out := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := max(y-half, 0), min(y+half+1, h)
+		for x := 0; x < w; x++ {
+			x0, x1 := max(x-half, 0), min(x+half+1, w)
+
+			count := int64((x1 - x0) * (y1 - y0))
+			sum := integral[y1*stride+x1] - integral[y0*stride+x1] - integral[y1*stride+x0] + integral[y0*stride+x0]
+			value := int64(gray.Pix[y*gray.Stride+x])
+
+			if float64(value*count) < float64(sum)*(1-sensitivity) {
+				out.Pix[y*out.Stride+x] = 0
+			} else {
+				out.Pix[y*out.Stride+x] = 255
+			}
+		}
+	}
+
+	return out
+}
+
+func histogram(gray *image.Gray) [256]int {
+	var hist [256]int
+	w, h := gray.Bounds().Dx(), gray.Bounds().Dy()
+	for y := 0; y < h; y++ {
+		row := gray.Pix[y*gray.Stride : y*gray.Stride+w]
+		for _, v := range row {
+			hist[v]++
+		}
+	}
+
+	return hist
+}
+
+// otsuThreshold returns the threshold that best separates the histogram into a
+// dark and a bright class
+func otsuThreshold(hist [256]int) int {
+	total, sum := 0, 0
+	for v, count := range hist {
+		total += count
+		sum += v * count
+	}
+
+	best, threshold := 0.0, 127
+	weightDark, sumDark := 0, 0
+	for v := 0; v < 256; v++ {
+		weightDark += hist[v]
+		if weightDark == 0 {
+			continue
+		}
+
+		weightBright := total - weightDark
+		if weightBright == 0 {
+			break
+		}
+
+		sumDark += v * hist[v]
+		meanDark := float64(sumDark) / float64(weightDark)
+		meanBright := float64(sum-sumDark) / float64(weightBright)
+
+		between := float64(weightDark) * float64(weightBright) * (meanDark - meanBright) * (meanDark - meanBright)
+		if between > best {
+			best, threshold = between, v
+		}
+	}
+
+	return threshold
+}
@@ -0,0 +1,148 @@
+package preprocess
+
+import (
+	"bufio"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"io"
+)
+
+const exifOrientationTag = 0x0112
+
+// ReadOrientation returns the EXIF orientation of a jpeg, or 1 when the image
+// has none or is not a jpeg
+func ReadOrientation(r io.Reader) int {
+	reader := bufio.NewReader(r)
+
+	var soi [2]byte
+	if _, err := io.ReadFull(reader, soi[:]); err != nil || soi[0] != 0xFF || soi[1] != 0xD8 {
+		return 1
+	}
+
+	for {
+		var marker [4]byte
+		if _, err := io.ReadFull(reader, marker[:]); err != nil || marker[0] != 0xFF {
+			return 1
+		}
+
+		// start of scan, the metadata segments are all behind us
+		if marker[1] == 0xDA {
+			return 1
+		}
+
+		length := int(binary.BigEndian.Uint16(marker[2:])) - 2
+		if length < 0 {
+			return 1
+		}
+
+		segment := make([]byte, length)
+		if _, err := io.ReadFull(reader, segment); err != nil {
+			return 1
+		}
+
+		if marker[1] == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return parseExifOrientation(segment[6:])
+		}
+	}
+}
+
+// parseExifOrientation looks up the orientation tag in the first IFD of a tiff
+// structure
+func parseExifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+
+	return 1
+}
+
+// Orient turns an image stored with the given EXIF orientation upright
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	src := toRGBA(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	// source returns the source pixel shown at x, y of the upright image
+	source := func(x, y int) (int, int) {
+		switch orientation {
+		case 2:
+			return w - 1 - x, y
+		case 3:
+			return w - 1 - x, h - 1 - y
+		case 4:
+			return x, h - 1 - y
+		case 5:
+			return y, x
+		case 6:
+			return y, h - 1 - x
+		case 7:
+			return w - 1 - y, h - 1 - x
+		default:
+			return w - 1 - y, x
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			sx, sy := source(x, y)
+			i := src.PixOffset(sx, sy)
+			j := dst.PixOffset(x, y)
+			copy(dst.Pix[j:j+4], src.Pix[i:i+4])
+		}
+	}
+
+	return dst
+}
+
+// toRGBA returns the image as an RGBA with its origin at zero
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
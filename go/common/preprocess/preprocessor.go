@@ -0,0 +1,178 @@
+package preprocess
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Options selects the preprocessing steps. Contrast normalization, deskew and
+// binarization work on the grayscale image, so enabling any of them implies
+// Grayscale.
+type Options struct {
+	AutoOrient          bool    // apply the EXIF orientation of jpeg photos
+	MaxDimension        int     // downscale so the longest side fits, 0 keeps the size
+	Grayscale           bool    // convert to 8-bit luma
+	NormalizeContrast   bool    // stretch the histogram to the full range
+	ContrastClip        float64 // share of the darkest and brightest pixels ignored when stretching
+	Deskew              bool    // rotate text lines back to horizontal
+	MaxSkewAngle        float64 // degrees searched in both directions by Deskew
+	Binarize            bool    // adaptive black and white threshold
+	BinarizeWindow      int     // neighbourhood size of the threshold, 0 picks 1/16 of the longest side
+	BinarizeSensitivity float64 // how much darker than its neighbourhood a pixel must be to turn black
+}
+
+// Step names accepted by ParseSteps
+const (
+	StepOrient    = "orient"
+	StepDownscale = "downscale"
+	StepGrayscale = "grayscale"
+	StepContrast  = "contrast"
+	StepDeskew    = "deskew"
+	StepBinarize  = "binarize"
+)
+
+// DefaultOptions enables every step with values that suit phone photos of menus
+func DefaultOptions() Options {
+	return Options{
+		AutoOrient:          true,
+		MaxDimension:        3000,
+		Grayscale:           true,
+		NormalizeContrast:   true,
+		ContrastClip:        0.01,
+		Deskew:              true,
+		MaxSkewAngle:        15,
+		Binarize:            true,
+		BinarizeSensitivity: 0.15,
+	}
+}
+
+// ParseSteps returns the default options with only the given comma separated
+// steps enabled. An empty list enables every step and "none" disables them all.
+func ParseSteps(steps string) (Options, error) {
+	options := DefaultOptions()
+	steps = strings.TrimSpace(steps)
+	if steps == "" {
+		return options, nil
+	}
+
+	options.AutoOrient = false
+	options.Grayscale = false
+	options.NormalizeContrast = false
+	options.Deskew = false
+	options.Binarize = false
+	maxDimension := options.MaxDimension
+	options.MaxDimension = 0
+
+	if steps == "none" {
+		return options, nil
+	}
+
+	for _, step := range strings.Split(steps, ",") {
+		switch strings.ToLower(strings.TrimSpace(step)) {
+		case StepOrient:
+			options.AutoOrient = true
+		case StepDownscale:
+			options.MaxDimension = maxDimension
+		case StepGrayscale:
+			options.Grayscale = true
+		case StepContrast:
+			options.NormalizeContrast = true
+		case StepDeskew:
+			options.Deskew = true
+		case StepBinarize:
+			options.Binarize = true
+		case "":
+		default:
+			return options, fmt.Errorf("unknown preprocessing step %q", step)
+		}
+	}
+
+	return options, nil
+}
+
+// Preprocessor cleans up photographed menu pages before they are recognized
+type Preprocessor struct {
+	options Options
+}
+
+func NewPreprocessor(options Options) *Preprocessor {
+	return &Preprocessor{options: options}
+}
+
+// Enabled reports whether any step would change the image
+func (p *Preprocessor) Enabled() bool {
+	o := p.options
+	return o.AutoOrient || o.MaxDimension > 0 || o.Grayscale || o.NormalizeContrast || o.Deskew || o.Binarize
+}
+
+// ProcessFile reads a jpeg or png image, runs the enabled steps and writes the
+// result to outputPath as png
+func (p *Preprocessor) ProcessFile(inputPath string, outputPath string) error {
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to open image: %w", err)
+	}
+	defer file.Close()
+
+	orientation := 1
+	if p.options.AutoOrient {
+		orientation = ReadOrientation(file)
+		if _, err := file.Seek(0, 0); err != nil {
+			return fmt.Errorf("failed to read image: %w", err)
+		}
+	}
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create output image: %w", err)
+	}
+	defer output.Close()
+
+	if err := png.Encode(output, p.Process(img, orientation)); err != nil {
+		return fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	return nil
+}
+
+// Process runs the enabled steps on a decoded image. Orientation is the EXIF
+// orientation tag, 1 when the image is stored upright.
+func (p *Preprocessor) Process(img image.Image, orientation int) image.Image {
+	o := p.options
+	if o.AutoOrient {
+		img = Orient(img, orientation)
+	}
+	if o.MaxDimension > 0 {
+		img = Downscale(img, o.MaxDimension)
+	}
+	if !o.Grayscale && !o.NormalizeContrast && !o.Deskew && !o.Binarize {
+		return img
+	}
+
+	gray := Grayscale(img)
+	if o.NormalizeContrast {
+		gray = NormalizeContrast(gray, o.ContrastClip)
+	}
+	if o.Deskew {
+		gray, _ = Deskew(gray, o.MaxSkewAngle)
+	}
+	if o.Binarize {
+		gray = Binarize(gray, o.BinarizeWindow, o.BinarizeSensitivity)
+	}
+
+	return gray
+}
@@ -0,0 +1,174 @@
+package preprocess
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadOrientation(t *testing.T) {
+	file, err := os.Open("testdata/orientation_6.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if orientation := ReadOrientation(file); orientation != 6 {
+		t.Errorf("expected orientation 6, got %d", orientation)
+	}
+
+	png, err := os.Open("testdata/skewed.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer png.Close()
+
+	if orientation := ReadOrientation(png); orientation != 1 {
+		t.Errorf("expected orientation 1 for a png, got %d", orientation)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// the fixture is 64x32 with a black square in its stored top left corner,
+	// orientation 6 means it is shown turned clockwise
+	img := loadFixture(t, "orientation_6.jpg")
+	upright := Grayscale(Orient(img, 6))
+
+	if size := upright.Bounds().Size(); size != image.Pt(32, 64) {
+		t.Fatalf("expected 32x64, got %v", size)
+	}
+	if v := upright.GrayAt(24, 8).Y; v > 64 {
+		t.Errorf("expected the square in the top right corner, got %d there", v)
+	}
+	if v := upright.GrayAt(8, 8).Y; v < 192 {
+		t.Errorf("expected white in the top left corner, got %d", v)
+	}
+}
+
+func TestDeskew(t *testing.T) {
+	gray := Grayscale(loadFixture(t, "skewed.png"))
+
+	deskewed, angle := Deskew(gray, 15)
+	if math.Abs(angle-4) > 0.3 {
+		t.Errorf("expected a skew of about 4 degrees, got %.2f", angle)
+	}
+
+	if residual := EstimateSkew(deskewed, 15); math.Abs(residual) > 0.3 {
+		t.Errorf("expected a straight page after deskew, still %.2f degrees off", residual)
+	}
+}
+
+func TestBinarizeUnevenLight(t *testing.T) {
+	// text lines start at y=30 every 32 pixels and are 10 pixels high, the
+	// background runs from dark on the left to glare on the right
+	gray := Grayscale(loadFixture(t, "uneven_light.png"))
+	binary := Binarize(gray, 0, 0.15)
+
+	for _, half := range []struct {
+		name   string
+		x0, x1 int
+	}{{"dark", 20, 200}, {"bright", 200, 380}} {
+		black := 0
+		for x := half.x0; x < half.x1; x++ {
+			if binary.GrayAt(x, 35).Y == 0 {
+				black++
+			}
+			if binary.GrayAt(x, 50).Y != 255 {
+				t.Fatalf("%s half: background at %d,50 turned black", half.name, x)
+			}
+		}
+
+		if ratio := float64(black) / float64(half.x1-half.x0); ratio < 0.5 {
+			t.Errorf("%s half: only %.0f%% of the text line is black", half.name, ratio*100)
+		}
+	}
+}
+
+func TestNormalizeContrast(t *testing.T) {
+	gray := NormalizeContrast(Grayscale(loadFixture(t, "low_contrast.png")), 0.01)
+
+	low, high := 255, 0
+	for _, v := range gray.Pix {
+		low, high = min(low, int(v)), max(high, int(v))
+	}
+	if low != 0 || high != 255 {
+		t.Errorf("expected the full range, got %d..%d", low, high)
+	}
+}
+
+func TestDownscale(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 4000, 1000))
+	for i := range img.Pix {
+		img.Pix[i] = 200
+	}
+
+	small := Downscale(img, 2000)
+	if size := small.Bounds().Size(); size != image.Pt(2000, 500) {
+		t.Fatalf("expected 2000x500, got %v", size)
+	}
+	if c := color.GrayModel.Convert(small.At(10, 10)).(color.Gray); c.Y != 200 {
+		t.Errorf("expected averaged pixels to keep their value, got %d", c.Y)
+	}
+
+	if Downscale(img, 5000) != image.Image(img) {
+		t.Errorf("images within the limit should be left alone")
+	}
+}
+
+func TestParseSteps(t *testing.T) {
+	options, err := ParseSteps("orient, deskew")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !options.AutoOrient || !options.Deskew || options.Binarize || options.MaxDimension != 0 {
+		t.Errorf("unexpected options %+v", options)
+	}
+
+	if options, _ := ParseSteps("none"); NewPreprocessor(options).Enabled() {
+		t.Errorf("none should disable every step")
+	}
+
+	if _, err := ParseSteps("sharpen"); err == nil {
+		t.Errorf("expected an error for an unknown step")
+	}
+}
+
+func TestProcessFile(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "page.png")
+	if err := NewPreprocessor(DefaultOptions()).ProcessFile("testdata/orientation_6.jpg", output); err != nil {
+		t.Fatal(err)
+	}
+
+	img := loadFixture(t, output)
+	if size := img.Bounds().Size(); size != image.Pt(32, 64) {
+		t.Errorf("expected the upright 32x64 page, got %v", size)
+	}
+	if _, ok := img.(*image.Gray); !ok {
+		t.Errorf("expected a grayscale png, got %T", img)
+	}
+}
+
+func loadFixture(t *testing.T, name string) image.Image {
+	t.Helper()
+
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join("testdata", name)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return img
+}
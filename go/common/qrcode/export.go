@@ -0,0 +1,419 @@
+package qrcode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	goqrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+	FormatPDF = "pdf"
+
+	DefaultDPI       = 300
+	MinDPI           = 72
+	MaxDPI           = 1200
+	DefaultWidthMM   = 50.0
+	MinWidthMM       = 10.0
+	MaxWidthMM       = 1000.0
+	DefaultQuietZone = 4
+	MaxQuietZone     = 16
+	// MaxExportPixels caps the width of PNG exports, larger prints should use
+	// the vector formats
+	MaxExportPixels = 6000
+
+	// captionRatio is the height of the caption band under the code relative
+	// to the code width
+	captionRatio = 0.18
+	// maxEmbeddedLogo caps the pixel size of logos embedded in vector exports
+	maxEmbeddedLogo = 1024
+	mmPerInch       = 25.4
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported export format, expected png, svg or pdf")
+	ErrExportTooLarge    = errors.New("export is too large, lower the dpi or the width")
+)
+
+// ExportOptions controls a print ready export of a QR code. The code including
+// its quiet zone is WidthMM wide, a caption adds a band below it. The Size of
+// the embedded Options only sets how large the logo is relative to the code.
+type ExportOptions struct {
+	Options
+	Format string
+	// DPI is the resolution PNG exports are rendered at and tagged with
+	DPI     int
+	WidthMM float64
+	// QuietZone is the blank margin around the code in modules. Scanners need
+	// four, a frame or caption with enough white around the code can do with less.
+	QuietZone int
+	Caption   string
+}
+
+// ContentType is the mime type of the export format
+func ContentType(format string) string {
+	switch format {
+	case FormatSVG:
+		return "image/svg+xml"
+	case FormatPDF:
+		return "application/pdf"
+	default:
+		return "image/png"
+	}
+}
+
+// Export renders the content as a QR code for print: a vector SVG, a vector PDF
+// page sized to the code, or a PNG at the requested DPI. PNG modules are whole
+// pixels, so the printed width can be a little off the requested one.
+func Export(content string, opts ExportOptions) ([]byte, error) {
+	l, err := newLayout(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	switch l.format {
+	case FormatSVG:
+		return l.svg()
+	case FormatPDF:
+		return l.pdf()
+	case FormatPNG:
+		return l.png()
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// layout is the code in module units: the modules, the quiet zone around them
+// and the caption band below
+type layout struct {
+	format     string
+	dpi        int
+	widthMM    float64
+	modules    [][]bool
+	quietZone  int
+	foreground color.Color
+	background color.Color
+	logo       image.Image
+	// logoRatio is the logo size relative to the width of the code
+	logoRatio float64
+	caption   string
+}
+
+func newLayout(content string, opts ExportOptions) (*layout, error) {
+	if content == "" {
+		return nil, ErrEmptyContent
+	}
+
+	format := strings.ToLower(strings.TrimSpace(opts.Format))
+	if format == "" {
+		format = FormatPNG
+	}
+	if format != FormatPNG && format != FormatSVG && format != FormatPDF {
+		return nil, ErrUnsupportedFormat
+	}
+
+	foreground, err := parseColor(opts.ForegroundColor, color.Black)
+	if err != nil {
+		return nil, err
+	}
+	background, err := parseColor(opts.BackgroundColor, color.White)
+	if err != nil {
+		return nil, err
+	}
+
+	level := parseLevel(opts.ErrorCorrection)
+	if opts.Logo != nil && level < goqrcode.High {
+		level = goqrcode.High
+	}
+
+	code, err := goqrcode.New(content, level)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create qr code: %w", err)
+	}
+	code.DisableBorder = true
+
+	l := &layout{
+		format:     format,
+		dpi:        clampInt(opts.DPI, DefaultDPI, MinDPI, MaxDPI),
+		widthMM:    DefaultWidthMM,
+		modules:    code.Bitmap(),
+		quietZone:  max(0, min(opts.QuietZone, MaxQuietZone)),
+		foreground: foreground,
+		background: background,
+		logo:       opts.Logo,
+		caption:    strings.TrimSpace(opts.Caption),
+	}
+	if opts.WidthMM > 0 {
+		l.widthMM = max(MinWidthMM, min(opts.WidthMM, MaxWidthMM))
+	}
+	if l.logo != nil {
+		size := clampInt(opts.Size, DefaultSize, MinSize, MaxSize)
+		l.logoRatio = float64(logoSize(opts.LogoSize, size)) / float64(size)
+	}
+
+	return l, nil
+}
+
+// width is the width of the code with its quiet zone in modules
+func (l *layout) width() int {
+	return len(l.modules) + 2*l.quietZone
+}
+
+// height is the height of the export in modules, including the caption band
+func (l *layout) height() float64 {
+	if l.caption == "" {
+		return float64(l.width())
+	}
+
+	return float64(l.width()) * (1 + captionRatio)
+}
+
+// runs calls fn for every horizontal run of dark modules, in module units
+// from the top left corner of the quiet zone
+func (l *layout) runs(fn func(x, y, length int)) {
+	for y, row := range l.modules {
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fn(start+l.quietZone, y+l.quietZone, x-start)
+		}
+	}
+}
+
+func (l *layout) png() ([]byte, error) {
+	moduleSize := max(1, int(math.Round(l.widthMM/mmPerInch*float64(l.dpi)/float64(l.width()))))
+	width := moduleSize * l.width()
+	if width > MaxExportPixels {
+		return nil, ErrExportTooLarge
+	}
+	height := int(math.Round(l.height() * float64(moduleSize)))
+
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(l.background), image.Point{}, draw.Src)
+
+	ink := image.NewUniform(l.foreground)
+	l.runs(func(x, y, length int) {
+		run := image.Rect(x*moduleSize, y*moduleSize, (x+length)*moduleSize, (y+1)*moduleSize)
+		draw.Draw(canvas, run, ink, image.Point{}, draw.Src)
+	})
+
+	if l.logo != nil {
+		drawLogo(canvas, l.logo, int(l.logoRatio*float64(width)), image.Pt(width/2, width/2), l.background)
+	}
+
+	if l.caption != "" {
+		text := asciiText(l.caption)
+		band := height - width
+		scale := max(1, band/2/glyphHeight)
+		for scale > 1 && textWidth(text, scale) > width*9/10 {
+			scale--
+		}
+		origin := image.Pt((width-textWidth(text, scale))/2, width+(band-glyphHeight*scale)/2)
+		drawText(canvas, text, origin, scale, l.foreground)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, canvas); err != nil {
+		return nil, fmt.Errorf("failed to encode qr code: %w", err)
+	}
+
+	return withDPI(buf.Bytes(), l.dpi), nil
+}
+
+func (l *layout) svg() ([]byte, error) {
+	width, height := l.width(), l.height()
+	foreground, background := hexColor(l.foreground), hexColor(l.background)
+
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%smm" height="%smm" viewBox="0 0 %d %s" shape-rendering="crispEdges">`+"\n",
+		formatFloat(l.widthMM), formatFloat(l.widthMM*height/float64(width)), width, formatFloat(height))
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", background)
+
+	buf.WriteString(`<path fill="` + foreground + `" d="`)
+	l.runs(func(x, y, length int) {
+		fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", x, y, length, length)
+	})
+	buf.WriteString(`"/>` + "\n")
+
+	if l.logo != nil {
+		data, logoWidth, logoHeight, err := l.embeddedLogo()
+		if err != nil {
+			return nil, err
+		}
+
+		size := l.logoRatio * float64(width)
+		w, h := fitInto(float64(logoWidth), float64(logoHeight), size, size)
+		padding := math.Max(size/10, 0.5)
+		center := float64(width) / 2
+		fmt.Fprintf(&buf, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`+"\n",
+			formatFloat(center-size/2-padding), formatFloat(center-size/2-padding), formatFloat(size+2*padding), formatFloat(size+2*padding), background)
+		fmt.Fprintf(&buf, `<image x="%s" y="%s" width="%s" height="%s" href="data:image/png;base64,%s"/>`+"\n",
+			formatFloat(center-w/2), formatFloat(center-h/2), formatFloat(w), formatFloat(h), base64.StdEncoding.EncodeToString(data))
+	}
+
+	if l.caption != "" {
+		band := height - float64(width)
+		fmt.Fprintf(&buf, `<text x="%s" y="%s" font-family="Helvetica, Arial, sans-serif" font-weight="bold" font-size="%s" text-anchor="middle" dominant-baseline="central" fill="%s">`,
+			formatFloat(float64(width)/2), formatFloat(float64(width)+band/2), formatFloat(band/2), foreground)
+		if err := xml.EscapeText(&buf, []byte(l.caption)); err != nil {
+			return nil, fmt.Errorf("failed to write caption: %w", err)
+		}
+		buf.WriteString("</text>\n")
+	}
+
+	buf.WriteString("</svg>\n")
+	return buf.Bytes(), nil
+}
+
+func (l *layout) pdf() ([]byte, error) {
+	module := l.widthMM / float64(l.width())
+	pageHeight := l.height() * module
+
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		OrientationStr: "P",
+		UnitStr:        "mm",
+		Size:           gofpdf.SizeType{Wd: l.widthMM, Ht: pageHeight},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+
+	r, g, b := rgb(l.background)
+	pdf.SetFillColor(r, g, b)
+	pdf.Rect(0, 0, l.widthMM, pageHeight, "F")
+
+	r, g, b = rgb(l.foreground)
+	pdf.SetFillColor(r, g, b)
+	l.runs(func(x, y, length int) {
+		pdf.Rect(float64(x)*module, float64(y)*module, float64(length)*module, module, "F")
+	})
+
+	if l.logo != nil {
+		data, logoWidth, logoHeight, err := l.embeddedLogo()
+		if err != nil {
+			return nil, err
+		}
+
+		size := l.logoRatio * l.widthMM
+		w, h := fitInto(float64(logoWidth), float64(logoHeight), size, size)
+		padding := math.Max(size/10, module/2)
+		center := l.widthMM / 2
+
+		r, g, b = rgb(l.background)
+		pdf.SetFillColor(r, g, b)
+		pdf.Rect(center-size/2-padding, center-size/2-padding, size+2*padding, size+2*padding, "F")
+		pdf.RegisterImageOptionsReader("logo", gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(data))
+		pdf.ImageOptions("logo", center-w/2, center-h/2, w, h, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	}
+
+	if l.caption != "" {
+		band := pageHeight - l.widthMM
+		translate := pdf.UnicodeTranslatorFromDescriptor("")
+		caption := translate(l.caption)
+
+		// font sizes are in points, the band in millimeters
+		fontSize := band / 2 * 72 / mmPerInch
+		pdf.SetFont("Helvetica", "B", fontSize)
+		for fontSize > 4 && pdf.GetStringWidth(caption) > l.widthMM*0.9 {
+			fontSize--
+			pdf.SetFontSize(fontSize)
+		}
+
+		r, g, b = rgb(l.foreground)
+		pdf.SetTextColor(r, g, b)
+		pdf.SetXY(0, l.widthMM)
+		pdf.CellFormat(l.widthMM, band, caption, "", 0, "C", false, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render qr code: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// embeddedLogo encodes the logo for the vector formats, downscaled to what the
+// export can show
+func (l *layout) embeddedLogo() ([]byte, int, int, error) {
+	pixels := int(l.logoRatio * l.widthMM / mmPerInch * float64(l.dpi))
+	logo := shrink(l.logo, max(64, min(pixels, maxEmbeddedLogo)))
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, logo); err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to encode logo: %w", err)
+	}
+
+	return buf.Bytes(), logo.Bounds().Dx(), logo.Bounds().Dy(), nil
+}
+
+// withDPI adds a pHYs chunk to the png, so print software sizes it right
+// instead of assuming 72 dpi. It goes right after IHDR, which is always the
+// first chunk.
+func withDPI(data []byte, dpi int) []byte {
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4
+	if len(data) < ihdrEnd {
+		return data
+	}
+
+	pixelsPerMeter := uint32(math.Round(float64(dpi) / mmPerInch * 1000))
+	chunk := make([]byte, 4+4+9+4)
+	binary.BigEndian.PutUint32(chunk[0:], 9)
+	copy(chunk[4:], "pHYs")
+	binary.BigEndian.PutUint32(chunk[8:], pixelsPerMeter)
+	binary.BigEndian.PutUint32(chunk[12:], pixelsPerMeter)
+	chunk[16] = 1 // the unit is the meter
+	binary.BigEndian.PutUint32(chunk[17:], crc32.ChecksumIEEE(chunk[4:17]))
+
+	out := make([]byte, 0, len(data)+len(chunk))
+	out = append(out, data[:ihdrEnd]...)
+	out = append(out, chunk...)
+	return append(out, data[ihdrEnd:]...)
+}
+
+func clampInt(value, fallback, low, high int) int {
+	if value <= 0 {
+		return fallback
+	}
+
+	return max(low, min(value, high))
+}
+
+func rgb(c color.Color) (int, int, int) {
+	r, g, b, _ := c.RGBA()
+	return int(r >> 8), int(g >> 8), int(b >> 8)
+}
+
+func hexColor(c color.Color) string {
+	r, g, b := rgb(c)
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+// formatFloat writes coordinates with at most three decimals
+func formatFloat(value float64) string {
+	s := fmt.Sprintf("%.3f", value)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
@@ -0,0 +1,178 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+
+	goqrcode "github.com/skip2/go-qrcode"
+	"golang.org/x/image/draw"
+)
+
+const (
+	DefaultSize     = 200
+	DefaultLogoSize = 40
+	MinSize         = 64
+	MaxSize         = 2048
+)
+
+var (
+	ErrEmptyContent = errors.New("qr code content is empty")
+	ErrInvalidColor = errors.New("invalid color, expected #RGB or #RRGGBB")
+)
+
+// Options controls how a QR code is rendered. Zero values fall back to the
+// defaults the menu editor uses: 200px, error correction M, black on white and
+// a 40px logo.
+type Options struct {
+	Size            int
+	ErrorCorrection string
+	ForegroundColor string
+	BackgroundColor string
+	// Logo is drawn in the middle of the code on a padded background when set
+	Logo     image.Image
+	LogoSize int
+}
+
+// Generate renders the content as a QR code and returns it as a PNG
+func Generate(content string, opts Options) ([]byte, error) {
+	img, err := Render(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode qr code: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Render renders the content as a QR code image
+func Render(content string, opts Options) (image.Image, error) {
+	if content == "" {
+		return nil, ErrEmptyContent
+	}
+
+	size := opts.Size
+	if size <= 0 {
+		size = DefaultSize
+	}
+	size = max(MinSize, min(size, MaxSize))
+
+	foreground, err := parseColor(opts.ForegroundColor, color.Black)
+	if err != nil {
+		return nil, err
+	}
+	background, err := parseColor(opts.BackgroundColor, color.White)
+	if err != nil {
+		return nil, err
+	}
+
+	level := parseLevel(opts.ErrorCorrection)
+	// a logo hides modules in the middle of the code, it needs enough error
+	// correction to still be readable
+	if opts.Logo != nil && level < goqrcode.High {
+		level = goqrcode.High
+	}
+
+	code, err := goqrcode.New(content, level)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create qr code: %w", err)
+	}
+	code.ForegroundColor = foreground
+	code.BackgroundColor = background
+
+	rendered := code.Image(size)
+	canvas := image.NewRGBA(rendered.Bounds())
+	draw.Draw(canvas, canvas.Bounds(), rendered, rendered.Bounds().Min, draw.Src)
+
+	if opts.Logo != nil {
+		bounds := canvas.Bounds()
+		drawLogo(canvas, opts.Logo, logoSize(opts.LogoSize, size), image.Pt(bounds.Dx()/2, bounds.Dy()/2), background)
+	}
+
+	return canvas, nil
+}
+
+// drawLogo shrinks the logo to fit a logoSize square, keeping its aspect
+// ratio, and draws it around center on a background colored pad
+func drawLogo(canvas *image.RGBA, logo image.Image, logoSize int, center image.Point, background color.Color) {
+	padding := max(2, logoSize/10)
+	padSize := logoSize + 2*padding
+	pad := image.Rect(0, 0, padSize, padSize).Add(center.Sub(image.Pt(padSize/2, padSize/2)))
+	draw.Draw(canvas, pad, image.NewUniform(background), image.Point{}, draw.Src)
+
+	logo = shrink(logo, logoSize)
+	logoBounds := logo.Bounds()
+	target := image.Rect(0, 0, logoBounds.Dx(), logoBounds.Dy()).Add(center.Sub(image.Pt(logoBounds.Dx()/2, logoBounds.Dy()/2)))
+	draw.Draw(canvas, target, logo, logoBounds.Min, draw.Over)
+}
+
+// shrink scales the image down so its longest side is at most maxDimension,
+// smaller images are returned as they are
+func shrink(img image.Image, maxDimension int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if maxDimension <= 0 || max(w, h) <= maxDimension {
+		return img
+	}
+
+	dw, dh := maxDimension, h*maxDimension/w
+	if h > w {
+		dw, dh = w*maxDimension/h, maxDimension
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, max(dw, 1), max(dh, 1)))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// logoSize keeps the logo under a quarter of the code width, beyond that the
+// code can not be read even with the highest error correction
+func logoSize(requested, size int) int {
+	if requested <= 0 {
+		requested = DefaultLogoSize
+	}
+
+	return min(requested, size/4)
+}
+
+func parseLevel(value string) goqrcode.RecoveryLevel {
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case "L":
+		return goqrcode.Low
+	case "Q":
+		return goqrcode.High
+	case "H":
+		return goqrcode.Highest
+	default:
+		return goqrcode.Medium
+	}
+}
+
+func parseColor(value string, fallback color.Color) (color.Color, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "#")
+	if value == "" {
+		return fallback, nil
+	}
+
+	if len(value) == 3 {
+		value = string([]byte{value[0], value[0], value[1], value[1], value[2], value[2]})
+	}
+	if len(value) != 6 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidColor, value)
+	}
+
+	rgb, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidColor, value)
+	}
+
+	return color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 0xff}, nil
+}
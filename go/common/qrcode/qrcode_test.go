@@ -0,0 +1,161 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	data, err := Generate("https://bidi-menu.com/menu/view/1", Options{
+		Size:            300,
+		ForegroundColor: "#1a2b3c",
+		BackgroundColor: "#fff",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != 300 || bounds.Dy() != 300 {
+		t.Fatalf("expected a 300x300 code, got %v", bounds)
+	}
+
+	foreground, background := color.RGBA{0x1a, 0x2b, 0x3c, 0xff}, color.RGBA{0xff, 0xff, 0xff, 0xff}
+	colors := map[color.RGBA]int{}
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 300; x++ {
+			colors[color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)]++
+		}
+	}
+	if len(colors) != 2 || colors[foreground] == 0 || colors[background] == 0 {
+		t.Errorf("expected only the foreground and background colors, got %v", colors)
+	}
+}
+
+func TestRenderWithLogo(t *testing.T) {
+	logoColor := color.RGBA{0xff, 0, 0, 0xff}
+	logo := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for i := 0; i < len(logo.Pix); i += 4 {
+		copy(logo.Pix[i:], []uint8{logoColor.R, logoColor.G, logoColor.B, logoColor.A})
+	}
+
+	img, err := Render("https://bidi-menu.com/menu/view/1", Options{Size: 400, Logo: logo, LogoSize: 80})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := color.RGBAModel.Convert(img.At(200, 200)); got != logoColor {
+		t.Errorf("expected the logo in the middle, got %v", got)
+	}
+	// the logo keeps its aspect ratio, 80x40, on a white pad
+	if got := color.RGBAModel.Convert(img.At(200, 200-30)); got != (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+		t.Errorf("expected the pad above the logo, got %v", got)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	if _, err := Render("", Options{}); !errors.Is(err, ErrEmptyContent) {
+		t.Errorf("expected ErrEmptyContent, got %v", err)
+	}
+	if _, err := Render("content", Options{ForegroundColor: "blue"}); !errors.Is(err, ErrInvalidColor) {
+		t.Errorf("expected ErrInvalidColor, got %v", err)
+	}
+}
+
+func TestSheet(t *testing.T) {
+	code, err := Generate("https://bidi-menu.com/menu/view/1?table=abc", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cards := make([]SheetCard, CardsPerPage+1)
+	for i := range cards {
+		cards[i] = SheetCard{Label: "Table", QRCode: code}
+	}
+
+	logo := image.NewRGBA(image.Rect(0, 0, 120, 40))
+	pdf, err := Sheet(cards, SheetOptions{Title: "Café Bidi", Logo: logo, Caption: "Scan to see the menu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(pdf, []byte("%PDF")) {
+		t.Fatalf("expected a pdf, got %q", pdf[:min(len(pdf), 8)])
+	}
+	if pages := bytes.Count(pdf, []byte("/Type /Page\n")); pages != 2 {
+		t.Errorf("expected 2 pages, got %d", pages)
+	}
+
+	if _, err := Sheet(nil, SheetOptions{}); !errors.Is(err, ErrEmptySheet) {
+		t.Errorf("expected ErrEmptySheet, got %v", err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	content := "https://bidi-menu.com/s/abc1234"
+
+	data, err := Export(content, ExportOptions{Format: FormatPNG, DPI: 600, WidthMM: 40, QuietZone: DefaultQuietZone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 40mm at 600 dpi is about 945 pixels, rounded to whole modules
+	if width := img.Bounds().Dx(); width < 900 || width > 990 || img.Bounds().Dy() != width {
+		t.Errorf("unexpected png size %v", img.Bounds())
+	}
+	if !bytes.Contains(data[:64], []byte("pHYs")) {
+		t.Errorf("expected the png to carry its dpi")
+	}
+
+	captioned, err := Export(content, ExportOptions{Format: FormatPNG, WidthMM: 40, Caption: "Menü"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err = png.Decode(bytes.NewReader(captioned))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dy() <= img.Bounds().Dx() {
+		t.Errorf("expected a caption band under the code, got %v", img.Bounds())
+	}
+
+	svg, err := Export(content, ExportOptions{Format: FormatSVG, WidthMM: 30, Caption: "Fish & Chips", Options: Options{ForegroundColor: "#123456"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`width="30mm"`, `fill="#123456"`, "Fish &amp; Chips", "<path"} {
+		if !bytes.Contains(svg, []byte(expected)) {
+			t.Errorf("svg is missing %s", expected)
+		}
+	}
+
+	pdf, err := Export(content, ExportOptions{Format: FormatPDF, Options: Options{Logo: image.NewRGBA(image.Rect(0, 0, 50, 20))}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(pdf, []byte("%PDF")) {
+		t.Errorf("expected a pdf")
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	if _, err := Export("menu", ExportOptions{Format: "eps"}); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+	if _, err := Export("menu", ExportOptions{DPI: MaxDPI, WidthMM: MaxWidthMM}); !errors.Is(err, ErrExportTooLarge) {
+		t.Errorf("expected ErrExportTooLarge, got %v", err)
+	}
+	if _, err := Export("", ExportOptions{}); !errors.Is(err, ErrEmptyContent) {
+		t.Errorf("expected ErrEmptyContent, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// A4 page split in four table tent cards, sizes in millimeters
+const (
+	pageWidth    = 210.0
+	pageHeight   = 297.0
+	cardColumns  = 2
+	cardRows     = 2
+	cardWidth    = pageWidth / cardColumns
+	cardHeight   = pageHeight / cardRows
+	sheetQRSize  = 70.0
+	sheetLogoMax = 22.0
+
+	// CardsPerPage is how many QR codes fit on a page of the sheet
+	CardsPerPage = cardColumns * cardRows
+)
+
+var ErrEmptySheet = errors.New("sheet has no cards")
+
+// SheetCard is one table on the sheet
+type SheetCard struct {
+	Label string
+	// QRCode is the png of the table's QR code
+	QRCode []byte
+}
+
+type SheetOptions struct {
+	// Title is printed under the logo, usually the restaurant name
+	Title   string
+	Logo    image.Image
+	Caption string
+}
+
+// Sheet lays the cards out on A4 pages, four to a page with cut lines between
+// them. The built in PDF fonts only cover Western European characters, others
+// are replaced.
+func Sheet(cards []SheetCard, opts SheetOptions) ([]byte, error) {
+	if len(cards) == 0 {
+		return nil, ErrEmptySheet
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetTitle(opts.Title, true)
+	translate := pdf.UnicodeTranslatorFromDescriptor("")
+
+	logoName := ""
+	logoWidth, logoHeight := 0.0, 0.0
+	if opts.Logo != nil {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, opts.Logo); err != nil {
+			return nil, fmt.Errorf("failed to encode logo: %w", err)
+		}
+
+		logoName = "logo"
+		pdf.RegisterImageOptionsReader(logoName, gofpdf.ImageOptions{ImageType: "PNG"}, &buf)
+
+		bounds := opts.Logo.Bounds()
+		logoWidth, logoHeight = fitInto(float64(bounds.Dx()), float64(bounds.Dy()), cardWidth-20, sheetLogoMax)
+	}
+
+	for i, card := range cards {
+		if i%CardsPerPage == 0 {
+			pdf.AddPage()
+			drawCutLines(pdf)
+		}
+
+		slot := i % CardsPerPage
+		x := float64(slot%cardColumns) * cardWidth
+		y := float64(slot/cardColumns) * cardHeight
+		top := y + 12
+
+		if logoName != "" {
+			pdf.ImageOptions(logoName, x+(cardWidth-logoWidth)/2, top, logoWidth, logoHeight, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+			top += logoHeight + 4
+		}
+
+		if opts.Title != "" {
+			pdf.SetFont("Helvetica", "", 12)
+			pdf.SetTextColor(60, 60, 60)
+			pdf.SetXY(x+5, top)
+			pdf.CellFormat(cardWidth-10, 6, translate(opts.Title), "", 0, "C", false, 0, "")
+			top += 8
+		}
+
+		name := fmt.Sprintf("qr-%d", i)
+		pdf.RegisterImageOptionsReader(name, gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(card.QRCode))
+		pdf.ImageOptions(name, x+(cardWidth-sheetQRSize)/2, top, sheetQRSize, sheetQRSize, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+		top += sheetQRSize + 4
+
+		label := translate(card.Label)
+		fontSize := 22.0
+		pdf.SetFont("Helvetica", "B", fontSize)
+		for fontSize > 8 && pdf.GetStringWidth(label) > cardWidth-10 {
+			fontSize--
+			pdf.SetFontSize(fontSize)
+		}
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetXY(x+5, top)
+		pdf.CellFormat(cardWidth-10, 10, label, "", 0, "C", false, 0, "")
+		top += 12
+
+		if opts.Caption != "" {
+			pdf.SetFont("Helvetica", "", 10)
+			pdf.SetTextColor(100, 100, 100)
+			pdf.SetXY(x+5, top)
+			pdf.CellFormat(cardWidth-10, 5, translate(opts.Caption), "", 0, "C", false, 0, "")
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render sheet: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func drawCutLines(pdf *gofpdf.Fpdf) {
+	pdf.SetDrawColor(180, 180, 180)
+	pdf.SetLineWidth(0.2)
+	pdf.SetDashPattern([]float64{2, 2}, 0)
+	for column := 1; column < cardColumns; column++ {
+		pdf.Line(float64(column)*cardWidth, 0, float64(column)*cardWidth, pageHeight)
+	}
+	for row := 1; row < cardRows; row++ {
+		pdf.Line(0, float64(row)*cardHeight, pageWidth, float64(row)*cardHeight)
+	}
+	pdf.SetDashPattern([]float64{}, 0)
+}
+
+// fitInto scales width and height down to fit the box, keeping the ratio
+func fitInto(width, height, maxWidth, maxHeight float64) (float64, float64) {
+	if width <= 0 || height <= 0 {
+		return maxWidth, maxHeight
+	}
+
+	scale := min(maxWidth/width, maxHeight/height)
+	return width * scale, height * scale
+}
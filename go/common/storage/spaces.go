@@ -1,9 +1,13 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -60,6 +64,10 @@ func (s *spacesService) SaveGlbModel(file *multipart.FileHeader, modelID uuid.UU
 
 	filename := fmt.Sprintf("%s%s", modelID.String(), ext)
 	path := fmt.Sprintf("models/glb/%s", filename)
+	contentType := "model/gltf-binary"
+	if ext == ".gltf" {
+		contentType = "model/gltf+json"
+	}
 
 	src, err := file.Open()
 	if err != nil {
@@ -71,6 +79,73 @@ func (s *spacesService) SaveGlbModel(file *multipart.FileHeader, modelID uuid.UU
 		Bucket:      aws.String(s.config.Bucket),
 		Key:         aws.String(path),
 		Body:        src,
+		ContentType: aws.String(contentType),
+		ACL:         types.ObjectCannedACLPublicRead,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+func (s *spacesService) ReadGlbModel(modelID uuid.UUID) ([]byte, error) {
+	var lastErr error
+	for _, ext := range AllowedGlbFormats {
+		data, err := s.readObject(fmt.Sprintf("models/glb/%s%s", modelID.String(), ext))
+		if err == nil {
+			return data, nil
+		}
+		if errors.Is(err, ErrFileTooLarge) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// ReadFile takes keys and the CDN urls objects are served from, urls of other
+// hosts are not read
+func (s *spacesService) ReadFile(location string) ([]byte, error) {
+	key := strings.TrimPrefix(location, s.config.CDNDomain)
+	if strings.Contains(key, "://") {
+		return nil, ErrNotStored
+	}
+	return s.readObject(strings.TrimPrefix(path.Clean("/"+key), "/"))
+}
+
+// readObject downloads an object of at most MaxFileSize bytes
+func (s *spacesService) readObject(key string) ([]byte, error) {
+	output, err := s.client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(output.Body, MaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxFileSize {
+		return nil, ErrFileTooLarge
+	}
+	return data, nil
+}
+
+func (s *spacesService) SaveOptimizedGlbModel(data []byte, modelID uuid.UUID) (string, error) {
+	if len(data) > MaxFileSize {
+		return "", ErrFileTooLarge
+	}
+
+	path := fmt.Sprintf("models/glb/%s%s", modelID.String(), optimizedGlbSuffix)
+
+	_, err := s.client.PutObject(context.TODO(), &s3.PutObjectInput{
+		Bucket:      aws.String(s.config.Bucket),
+		Key:         aws.String(path),
+		Body:        bytes.NewReader(data),
 		ContentType: aws.String("model/gltf-binary"),
 		ACL:         types.ObjectCannedACLPublicRead,
 	})
@@ -114,6 +189,27 @@ func (s *spacesService) SaveUsdzModel(file *multipart.FileHeader, modelID uuid.U
 	return path, nil
 }
 
+func (s *spacesService) SaveGeneratedUsdzModel(data []byte, modelID uuid.UUID) (string, error) {
+	if len(data) > MaxFileSize {
+		return "", ErrFileTooLarge
+	}
+
+	path := fmt.Sprintf("models/usdz/%s.usdz", modelID.String())
+
+	_, err := s.client.PutObject(context.TODO(), &s3.PutObjectInput{
+		Bucket:      aws.String(s.config.Bucket),
+		Key:         aws.String(path),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("model/vnd.usdz+zip"),
+		ACL:         types.ObjectCannedACLPublicRead,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
 func (s *spacesService) SaveThumbnail(file *multipart.FileHeader, modelID uuid.UUID) (string, error) {
 	if file.Size > MaxFileSize {
 		return "", ErrFileTooLarge
@@ -147,46 +243,142 @@ func (s *spacesService) SaveThumbnail(file *multipart.FileHeader, modelID uuid.U
 	return path, nil
 }
 
+func (s *spacesService) SaveRenderedThumbnail(data []byte, modelID uuid.UUID, variant, ext string) (string, error) {
+	if len(data) > MaxFileSize {
+		return "", ErrFileTooLarge
+	}
+	if !isAllowedFormat(ext, RenderedThumbnailFormats) {
+		return "", ErrInvalidFormat
+	}
+
+	path := fmt.Sprintf("thumbnails/%s", thumbnailFilename(modelID, variant, ext))
+
+	_, err := s.client.PutObject(context.TODO(), &s3.PutObjectInput{
+		Bucket:      aws.String(s.config.Bucket),
+		Key:         aws.String(path),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(fmt.Sprintf("image/%s", strings.TrimPrefix(ext, "."))),
+		ACL:         types.ObjectCannedACLPublicRead,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+func (s *spacesService) SaveQRCode(data []byte, id uuid.UUID) (string, error) {
+	if len(data) > MaxFileSize {
+		return "", ErrFileTooLarge
+	}
+
+	path := fmt.Sprintf("qrcodes/%s.png", id.String())
+
+	_, err := s.client.PutObject(context.TODO(), &s3.PutObjectInput{
+		Bucket:      aws.String(s.config.Bucket),
+		Key:         aws.String(path),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("image/png"),
+		ACL:         types.ObjectCannedACLPublicRead,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
 func (s *spacesService) DeleteGlbModel(modelID uuid.UUID) error {
-	// Try to delete with all possible extensions
-	for _, ext := range AllowedGlbFormats {
-		path := fmt.Sprintf("models/glb/%s%s", modelID.String(), ext)
-		_, err := s.client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
-			Bucket: aws.String(s.config.Bucket),
-			Key:    aws.String(path),
-		})
-		if err == nil {
-			return nil
+	return s.deleteObjectWithAnyExt(fmt.Sprintf("models/glb/%s", modelID.String()), AllowedGlbFormats)
+}
+
+func (s *spacesService) DeleteOptimizedGlbModel(modelID uuid.UUID) error {
+	return s.deleteObject(fmt.Sprintf("models/glb/%s%s", modelID.String(), optimizedGlbSuffix))
+}
+
+func (s *spacesService) DeleteUsdzModel(modelID uuid.UUID) error {
+	return s.deleteObjectWithAnyExt(fmt.Sprintf("models/usdz/%s", modelID.String()), AllowedUsdzFormats)
+}
+
+func (s *spacesService) DeleteThumbnail(modelID uuid.UUID) error {
+	variants, err := s.client.ListObjectsV2(context.TODO(), &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.config.Bucket),
+		Prefix: aws.String(fmt.Sprintf("thumbnails/%s_", modelID.String())),
+	})
+	if err != nil {
+		return err
+	}
+	for _, object := range variants.Contents {
+		if err := s.deleteObject(aws.ToString(object.Key)); err != nil {
+			return err
 		}
 	}
-	return nil // Don't return error if file doesn't exist
+
+	return s.deleteObjectWithAnyExt(fmt.Sprintf("thumbnails/%s", modelID.String()), AllowedImageFormats)
 }
 
-func (s *spacesService) DeleteUsdzModel(modelID uuid.UUID) error {
-	// Try to delete with all possible extensions
-	for _, ext := range AllowedUsdzFormats {
-		path := fmt.Sprintf("models/usdz/%s%s", modelID.String(), ext)
-		_, err := s.client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+// ReplaceModelFiles copies the staged objects to the model's keys, objects
+// cannot be renamed
+func (s *spacesService) ReplaceModelFiles(stagingID, modelID uuid.UUID) error {
+	var staged []string
+	for _, prefix := range []string{"models/glb/", "models/usdz/", "thumbnails/"} {
+		objects, err := s.client.ListObjectsV2(context.TODO(), &s3.ListObjectsV2Input{
 			Bucket: aws.String(s.config.Bucket),
-			Key:    aws.String(path),
+			Prefix: aws.String(prefix + stagingID.String()),
 		})
-		if err == nil {
-			return nil
+		if err != nil {
+			return err
+		}
+		for _, object := range objects.Contents {
+			staged = append(staged, aws.ToString(object.Key))
+		}
+	}
+
+	if err := s.DeleteGlbModel(modelID); err != nil {
+		return err
+	}
+	if err := s.DeleteUsdzModel(modelID); err != nil {
+		return err
+	}
+	if err := s.DeleteThumbnail(modelID); err != nil {
+		return err
+	}
+	for _, key := range staged {
+		_, err := s.client.CopyObject(context.TODO(), &s3.CopyObjectInput{
+			Bucket:     aws.String(s.config.Bucket),
+			CopySource: aws.String(s.config.Bucket + "/" + key),
+			Key:        aws.String(strings.Replace(key, stagingID.String(), modelID.String(), 1)),
+			ACL:        types.ObjectCannedACLPublicRead,
+		})
+		if err != nil {
+			return err
+		}
+		if err := s.deleteObject(key); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func (s *spacesService) DeleteThumbnail(modelID uuid.UUID) error {
-	// Try to delete with all possible extensions
-	for _, ext := range AllowedImageFormats {
-		path := fmt.Sprintf("thumbnails/%s%s", modelID.String(), ext)
-		_, err := s.client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
-			Bucket: aws.String(s.config.Bucket),
-			Key:    aws.String(path),
-		})
-		if err == nil {
-			return nil
+func (s *spacesService) DeleteQRCode(id uuid.UUID) error {
+	return s.deleteObject(fmt.Sprintf("qrcodes/%s.png", id.String()))
+}
+
+// deleteObject removes an object, deleting a missing key succeeds
+func (s *spacesService) deleteObject(key string) error {
+	_, err := s.client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+// deleteObjectWithAnyExt removes the object with every allowed extension, an
+// upload may have replaced one with another
+func (s *spacesService) deleteObjectWithAnyExt(key string, allowedExts []string) error {
+	for _, ext := range allowedExts {
+		if err := s.deleteObject(key + ext); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -196,6 +388,10 @@ func (s *spacesService) GetPublicGlbPath(modelID uuid.UUID) string {
 	return fmt.Sprintf("%s/models/glb/%s", s.config.CDNDomain, modelID.String())
 }
 
+func (s *spacesService) GetPublicOptimizedGlbPath(modelID uuid.UUID) string {
+	return fmt.Sprintf("%s/models/glb/%s.optimized", s.config.CDNDomain, modelID.String())
+}
+
 func (s *spacesService) GetPublicUsdzPath(modelID uuid.UUID) string {
 	return fmt.Sprintf("%s/models/usdz/%s", s.config.CDNDomain, modelID.String())
 }
@@ -203,3 +399,7 @@ func (s *spacesService) GetPublicUsdzPath(modelID uuid.UUID) string {
 func (s *spacesService) GetPublicThumbnailPath(modelID uuid.UUID) string {
 	return fmt.Sprintf("%s/thumbnails/%s", s.config.CDNDomain, modelID.String())
 }
+
+func (s *spacesService) GetPublicQRCodePath(id uuid.UUID) string {
+	return fmt.Sprintf("%s/qrcodes/%s.png", s.config.CDNDomain, id.String())
+}
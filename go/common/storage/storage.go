@@ -17,33 +17,68 @@ const (
 	StorageRoot    = "storage"
 	ModelsPath     = "storage/models"
 	ThumbnailsPath = "storage/thumbnails"
+	QRCodesPath    = "storage/qrcodes"
 
 	// Public URLs for static file serving
 	PublicPrefix     = "/static"
 	PublicModels     = "/static/models"
 	PublicThumbnails = "/static/thumbnails"
+	PublicQRCodes    = "/static/qrcodes"
 )
 
+// optimizedGlbSuffix names the optimized variant of a glb file
+const optimizedGlbSuffix = ".optimized.glb"
+
 var (
 	AllowedGlbFormats   = []string{".glb", ".gltf"}
 	AllowedUsdzFormats  = []string{".usdz"}
 	AllowedImageFormats = []string{".png", ".jpg", ".jpeg"}
+	// RenderedThumbnailFormats are the formats thumbnails drawn from a model
+	// are stored in
+	RenderedThumbnailFormats = []string{".png", ".webp"}
 
 	ErrFileTooLarge     = errors.New("file size exceeds maximum limit of 10MB")
 	ErrInvalidFormat    = errors.New("invalid file format")
 	ErrStorageNotExists = errors.New("storage directory does not exist")
+	// ErrNotStored is returned for locations outside of the storage, such as
+	// urls of other hosts
+	ErrNotStored = errors.New("file is not in storage")
 )
 
 type StorageService interface {
 	SaveGlbModel(file *multipart.FileHeader, modelID uuid.UUID) (string, error)
 	SaveUsdzModel(file *multipart.FileHeader, modelID uuid.UUID) (string, error)
+	// SaveGeneratedUsdzModel stores a usdz file converted from the glb file in
+	// place of an uploaded one
+	SaveGeneratedUsdzModel(data []byte, modelID uuid.UUID) (string, error)
 	SaveThumbnail(file *multipart.FileHeader, modelID uuid.UUID) (string, error)
+	// SaveRenderedThumbnail stores a thumbnail drawn from the model. Without a
+	// variant it takes the place of an uploaded thumbnail, variants such as
+	// "256" are kept next to it.
+	SaveRenderedThumbnail(data []byte, modelID uuid.UUID, variant, ext string) (string, error)
 	DeleteGlbModel(modelID uuid.UUID) error
+	// ReadGlbModel returns the uploaded glb or gltf file
+	ReadGlbModel(modelID uuid.UUID) ([]byte, error)
+	// SaveOptimizedGlbModel stores the optimized variant next to the uploaded
+	// file
+	SaveOptimizedGlbModel(data []byte, modelID uuid.UUID) (string, error)
+	DeleteOptimizedGlbModel(modelID uuid.UUID) error
+	GetPublicOptimizedGlbPath(modelID uuid.UUID) string
 	DeleteUsdzModel(modelID uuid.UUID) error
+	// DeleteThumbnail removes the thumbnail and its rendered variants
 	DeleteThumbnail(modelID uuid.UUID) error
+	// ReplaceModelFiles moves the glb, usdz and thumbnail files stored under
+	// stagingID over the files of the model, which are removed first
+	ReplaceModelFiles(stagingID, modelID uuid.UUID) error
 	GetPublicGlbPath(modelID uuid.UUID) string
 	GetPublicUsdzPath(modelID uuid.UUID) string
 	GetPublicThumbnailPath(modelID uuid.UUID) string
+	SaveQRCode(data []byte, id uuid.UUID) (string, error)
+	DeleteQRCode(id uuid.UUID) error
+	GetPublicQRCodePath(id uuid.UUID) string
+	// ReadFile returns a stored file by its key or by the public path it is
+	// served from
+	ReadFile(location string) ([]byte, error)
 }
 
 type storageService struct{}
@@ -70,7 +105,7 @@ func (s *storageService) SaveGlbModel(file *multipart.FileHeader, modelID uuid.U
 		return "", err
 	}
 
-	return s.GetPublicGlbPath(modelID), nil
+	return publicPath(path), nil
 }
 
 func (s *storageService) SaveUsdzModel(file *multipart.FileHeader, modelID uuid.UUID) (string, error) {
@@ -90,7 +125,20 @@ func (s *storageService) SaveUsdzModel(file *multipart.FileHeader, modelID uuid.
 		return "", err
 	}
 
-	return s.GetPublicUsdzPath(modelID), nil
+	return publicPath(path), nil
+}
+
+func (s *storageService) SaveGeneratedUsdzModel(data []byte, modelID uuid.UUID) (string, error) {
+	if len(data) > MaxFileSize {
+		return "", ErrFileTooLarge
+	}
+
+	path := filepath.Join(ModelsPath, "usdz", modelID.String()+".usdz")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+
+	return publicPath(path), nil
 }
 
 func (s *storageService) SaveThumbnail(file *multipart.FileHeader, modelID uuid.UUID) (string, error) {
@@ -110,13 +158,97 @@ func (s *storageService) SaveThumbnail(file *multipart.FileHeader, modelID uuid.
 		return "", err
 	}
 
-	return s.GetPublicThumbnailPath(modelID), nil
+	return publicPath(path), nil
+}
+
+func (s *storageService) SaveRenderedThumbnail(data []byte, modelID uuid.UUID, variant, ext string) (string, error) {
+	if len(data) > MaxFileSize {
+		return "", ErrFileTooLarge
+	}
+	if !isAllowedFormat(ext, RenderedThumbnailFormats) {
+		return "", ErrInvalidFormat
+	}
+
+	path := filepath.Join(ThumbnailsPath, thumbnailFilename(modelID, variant, ext))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+
+	return publicPath(path), nil
+}
+
+func (s *storageService) ReadGlbModel(modelID uuid.UUID) ([]byte, error) {
+	for _, ext := range AllowedGlbFormats {
+		data, err := os.ReadFile(filepath.Join(ModelsPath, "glb", modelID.String()+ext))
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+func (s *storageService) ReadFile(location string) ([]byte, error) {
+	if strings.Contains(location, "://") {
+		return nil, ErrNotStored
+	}
+
+	// the cleaned path cannot leave the storage root
+	name := filepath.Clean("/" + strings.TrimPrefix(location, PublicPrefix+"/"))
+	path := filepath.Join(StorageRoot, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > MaxFileSize {
+		return nil, ErrFileTooLarge
+	}
+	return os.ReadFile(path)
+}
+
+func (s *storageService) SaveOptimizedGlbModel(data []byte, modelID uuid.UUID) (string, error) {
+	if len(data) > MaxFileSize {
+		return "", ErrFileTooLarge
+	}
+
+	path := filepath.Join(ModelsPath, "glb", modelID.String()+optimizedGlbSuffix)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+
+	return publicPath(path), nil
+}
+
+// SaveQRCode stores the rendered png of a menu or table QR code, replacing the
+// previous one
+func (s *storageService) SaveQRCode(data []byte, id uuid.UUID) (string, error) {
+	if len(data) > MaxFileSize {
+		return "", ErrFileTooLarge
+	}
+
+	path := filepath.Join(QRCodesPath, id.String()+".png")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+
+	return publicPath(path), nil
 }
 
 func (s *storageService) GetPublicGlbPath(modelID uuid.UUID) string {
 	return fmt.Sprintf("%s/glb/%s", PublicModels, modelID.String())
 }
 
+// GetPublicOptimizedGlbPath leaves out the extension like the other model
+// paths, the file is named <id>.optimized.glb
+func (s *storageService) GetPublicOptimizedGlbPath(modelID uuid.UUID) string {
+	return fmt.Sprintf("%s/glb/%s.optimized", PublicModels, modelID.String())
+}
+
 func (s *storageService) GetPublicUsdzPath(modelID uuid.UUID) string {
 	return fmt.Sprintf("%s/usdz/%s", PublicModels, modelID.String())
 }
@@ -125,22 +257,73 @@ func (s *storageService) GetPublicThumbnailPath(modelID uuid.UUID) string {
 	return fmt.Sprintf("%s/%s", PublicThumbnails, modelID.String())
 }
 
+func (s *storageService) GetPublicQRCodePath(id uuid.UUID) string {
+	return fmt.Sprintf("%s/%s.png", PublicQRCodes, id.String())
+}
+
 func (s *storageService) DeleteGlbModel(modelID uuid.UUID) error {
 	return deleteFileWithAnyExt(filepath.Join(ModelsPath, "glb", modelID.String()), AllowedGlbFormats)
 }
 
+func (s *storageService) DeleteOptimizedGlbModel(modelID uuid.UUID) error {
+	return removeFile(filepath.Join(ModelsPath, "glb", modelID.String()+optimizedGlbSuffix))
+}
+
 func (s *storageService) DeleteUsdzModel(modelID uuid.UUID) error {
 	return deleteFileWithAnyExt(filepath.Join(ModelsPath, "usdz", modelID.String()), AllowedUsdzFormats)
 }
 
 func (s *storageService) DeleteThumbnail(modelID uuid.UUID) error {
+	variants, err := filepath.Glob(filepath.Join(ThumbnailsPath, modelID.String()+"_*"))
+	if err != nil {
+		return err
+	}
+	for _, path := range variants {
+		if err := removeFile(path); err != nil {
+			return err
+		}
+	}
+
 	return deleteFileWithAnyExt(filepath.Join(ThumbnailsPath, modelID.String()), AllowedImageFormats)
 }
 
+func (s *storageService) ReplaceModelFiles(stagingID, modelID uuid.UUID) error {
+	var staged []string
+	for _, dir := range []string{filepath.Join(ModelsPath, "glb"), filepath.Join(ModelsPath, "usdz"), ThumbnailsPath} {
+		paths, err := filepath.Glob(filepath.Join(dir, stagingID.String()+"*"))
+		if err != nil {
+			return err
+		}
+		staged = append(staged, paths...)
+	}
+
+	if err := s.DeleteGlbModel(modelID); err != nil {
+		return err
+	}
+	if err := s.DeleteUsdzModel(modelID); err != nil {
+		return err
+	}
+	if err := s.DeleteThumbnail(modelID); err != nil {
+		return err
+	}
+	for _, path := range staged {
+		name := modelID.String() + strings.TrimPrefix(filepath.Base(path), stagingID.String())
+		if err := os.Rename(path, filepath.Join(filepath.Dir(path), name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *storageService) DeleteQRCode(id uuid.UUID) error {
+	return removeFile(filepath.Join(QRCodesPath, id.String()+".png"))
+}
+
 func ensureStorageDirs() {
 	os.MkdirAll(filepath.Join(ModelsPath, "glb"), 0755)
 	os.MkdirAll(filepath.Join(ModelsPath, "usdz"), 0755)
 	os.MkdirAll(ThumbnailsPath, 0755)
+	os.MkdirAll(QRCodesPath, 0755)
 }
 
 func saveFile(file *multipart.FileHeader, path string) error {
@@ -165,14 +348,38 @@ func saveFile(file *multipart.FileHeader, path string) error {
 	return err
 }
 
+// thumbnailFilename names a thumbnail, <id>_<variant><ext> for variants
+func thumbnailFilename(modelID uuid.UUID, variant, ext string) string {
+	if variant == "" {
+		return modelID.String() + ext
+	}
+	return fmt.Sprintf("%s_%s%s", modelID.String(), variant, ext)
+}
+
+// publicPath is the url a file under the storage root is served from by
+// StaticFileMiddleware, with its extension like the keys of S3 storage
+func publicPath(path string) string {
+	return PublicPrefix + strings.TrimPrefix(filepath.ToSlash(path), StorageRoot)
+}
+
+// removeFile deletes a file, files that are already gone are not an error like
+// they are not for S3 storage
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// deleteFileWithAnyExt removes the file with every allowed extension, an
+// upload may have replaced one with another
 func deleteFileWithAnyExt(basePath string, allowedExts []string) error {
 	for _, ext := range allowedExts {
-		path := basePath + ext
-		if err := os.Remove(path); err == nil {
-			return nil
+		if err := removeFile(basePath + ext); err != nil {
+			return err
 		}
 	}
-	return os.ErrNotExist
+	return nil
 }
 
 func isAllowedFormat(ext string, allowedFormats []string) bool {
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// inTempDir runs the test from an empty directory, local storage writes under
+// the working directory
+func inTempDir(t *testing.T) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestLocalStoragePublicPaths(t *testing.T) {
+	inTempDir(t)
+	s := NewStorageService()
+	modelID := uuid.New()
+
+	tests := []struct {
+		name string
+		save func() (string, error)
+		path string
+	}{
+		{"usdz", func() (string, error) { return s.SaveGeneratedUsdzModel([]byte("usdz"), modelID) }, "/static/models/usdz/" + modelID.String() + ".usdz"},
+		{"optimized glb", func() (string, error) { return s.SaveOptimizedGlbModel([]byte("glb"), modelID) }, "/static/models/glb/" + modelID.String() + ".optimized.glb"},
+		{"thumbnail", func() (string, error) { return s.SaveRenderedThumbnail([]byte("png"), modelID, "", ".png") }, "/static/thumbnails/" + modelID.String() + ".png"},
+		{"thumbnail variant", func() (string, error) { return s.SaveRenderedThumbnail([]byte("webp"), modelID, "256", ".webp") }, "/static/thumbnails/" + modelID.String() + "_256.webp"},
+		{"qr code", func() (string, error) { return s.SaveQRCode([]byte("png"), modelID) }, "/static/qrcodes/" + modelID.String() + ".png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := tt.save()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if path != tt.path {
+				t.Errorf("expected %q, got %q", tt.path, path)
+			}
+			// the path is served from the storage root with the prefix removed
+			if _, err := os.Stat(filepath.Join(StorageRoot, path[len(PublicPrefix):])); err != nil {
+				t.Errorf("expected the file behind %q: %v", path, err)
+			}
+		})
+	}
+}
+
+func TestLocalStorageDeletes(t *testing.T) {
+	inTempDir(t)
+	s := NewStorageService()
+	modelID := uuid.New()
+
+	if _, err := s.SaveGeneratedUsdzModel([]byte("usdz"), modelID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SaveRenderedThumbnail([]byte("png"), modelID, "512", ".png"); err != nil {
+		t.Fatal(err)
+	}
+
+	// deleting twice removes the files first and then finds nothing, neither
+	// is an error
+	for i := 0; i < 2; i++ {
+		deletes := map[string]func(uuid.UUID) error{
+			"glb":           s.DeleteGlbModel,
+			"optimized glb": s.DeleteOptimizedGlbModel,
+			"usdz":          s.DeleteUsdzModel,
+			"thumbnail":     s.DeleteThumbnail,
+			"qr code":       s.DeleteQRCode,
+		}
+		for name, remove := range deletes {
+			if err := remove(modelID); err != nil {
+				t.Errorf("deleting the %s: %v", name, err)
+			}
+		}
+	}
+
+	leftover, _ := filepath.Glob(filepath.Join(StorageRoot, "*", "*", modelID.String()+"*"))
+	thumbnails, _ := filepath.Glob(filepath.Join(ThumbnailsPath, modelID.String()+"*"))
+	if len(leftover)+len(thumbnails) != 0 {
+		t.Errorf("expected every file to be deleted, found %v %v", leftover, thumbnails)
+	}
+}
+
+func TestLocalStorageReadFile(t *testing.T) {
+	inTempDir(t)
+	s := NewStorageService()
+	id := uuid.New()
+
+	path, err := s.SaveQRCode([]byte("png"), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("secret", []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		location string
+		found    bool
+	}{
+		{"public path", path, true},
+		{"key", "qrcodes/" + id.String() + ".png", true},
+		{"path out of the storage root", "/static/../secret", false},
+		{"key out of the storage root", "../../secret", false},
+		{"url", "http://169.254.169.254/latest/meta-data", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := s.ReadFile(tt.location)
+			if tt.found != (err == nil) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if tt.found && string(data) != "png" {
+				t.Errorf("expected the stored file, got %q", data)
+			}
+		})
+	}
+}
+
+func TestLocalStorageReplaceModelFiles(t *testing.T) {
+	inTempDir(t)
+	s := NewStorageService()
+	modelID, stagingID := uuid.New(), uuid.New()
+
+	files := map[string]string{
+		// the live model, uploaded as gltf with a usdz file and drawn thumbnails
+		filepath.Join(ModelsPath, "glb", modelID.String()+".gltf"):  "old",
+		filepath.Join(ModelsPath, "usdz", modelID.String()+".usdz"): "old",
+		filepath.Join(ThumbnailsPath, modelID.String()+".png"):      "old",
+		filepath.Join(ThumbnailsPath, modelID.String()+"_512.webp"): "old",
+		// the update, a glb file with an uploaded thumbnail
+		filepath.Join(ModelsPath, "glb", stagingID.String()+".glb"): "new",
+		filepath.Join(ThumbnailsPath, stagingID.String()+".jpg"):    "new",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.ReplaceModelFiles(stagingID, modelID); err != nil {
+		t.Fatal(err)
+	}
+
+	modelFiles, _ := filepath.Glob(filepath.Join(ModelsPath, "*", "*"))
+	thumbnails, _ := filepath.Glob(filepath.Join(ThumbnailsPath, "*"))
+	want := []string{
+		filepath.Join(ModelsPath, "glb", modelID.String()+".glb"),
+		filepath.Join(ThumbnailsPath, modelID.String()+".jpg"),
+	}
+	if left := append(modelFiles, thumbnails...); len(left) != len(want) {
+		t.Fatalf("expected only %v to be left, got %v", want, left)
+	}
+	for _, path := range want {
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != "new" {
+			t.Errorf("expected the staged file at %s, got %q %v", path, data, err)
+		}
+	}
+}
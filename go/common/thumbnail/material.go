@@ -0,0 +1,165 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"strconv"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/gltf"
+)
+
+// defaultColor is used for primitives without a material, a light gray
+// instead of the white of the spec so the shading shows
+var defaultColor = [4]float64{0.6, 0.6, 0.6, 1}
+
+// material is the part of a glTF material the renderer uses, colors are
+// linear
+type material struct {
+	baseColor [4]float64
+	emissive  [3]float64
+	texture   *texture
+	texCoord  string
+	mask      bool
+	cutoff    float64
+	blend     bool
+}
+
+// texture keeps a decoded base color texture. Textures in formats the
+// standard library cannot decode, such as webp and ktx2, are left out and
+// the color factor is used alone.
+type texture struct {
+	width, height int
+	pix           []uint8
+}
+
+// srgbToLinear maps the 8 bit sRGB values of textures to linear light
+var srgbToLinear = func() [256]float64 {
+	var table [256]float64
+	for i := range table {
+		v := float64(i) / 255
+		if v <= 0.04045 {
+			table[i] = v / 12.92
+		} else {
+			table[i] = math.Pow((v+0.055)/1.055, 2.4)
+		}
+	}
+	return table
+}()
+
+func linearToSrgb(v float64) uint8 {
+	v = math.Max(0, math.Min(1, v))
+	if v <= 0.0031308 {
+		v *= 12.92
+	} else {
+		v = 1.055*math.Pow(v, 1/2.4) - 0.055
+	}
+	return uint8(math.Round(v * 255))
+}
+
+func (r *renderer) material(index *int) *material {
+	key := -1
+	if index != nil {
+		key = *index
+	}
+	if m, ok := r.materials[key]; ok {
+		return m
+	}
+
+	m := &material{baseColor: defaultColor, texCoord: "TEXCOORD_0"}
+	if index != nil {
+		source := r.doc.Materials[*index]
+		m.baseColor = [4]float64{1, 1, 1, 1}
+		if pbr := source.PBRMetallicRoughness; pbr != nil {
+			if len(pbr.BaseColorFactor) == 4 {
+				m.baseColor = [4]float64(pbr.BaseColorFactor)
+			}
+			if info := pbr.BaseColorTexture; info != nil {
+				m.texture = r.texture(info.Index)
+				m.texCoord = "TEXCOORD_" + strconv.Itoa(info.TexCoord)
+			}
+		}
+		if len(source.EmissiveFactor) == 3 {
+			m.emissive = [3]float64(source.EmissiveFactor)
+		}
+		switch source.AlphaMode {
+		case "MASK":
+			m.mask, m.cutoff = true, 0.5
+			if source.AlphaCutoff != nil {
+				m.cutoff = *source.AlphaCutoff
+			}
+		case "BLEND":
+			m.blend = true
+		}
+	}
+
+	r.materials[key] = m
+	return m
+}
+
+func (r *renderer) texture(index int) *texture {
+	source := r.doc.Textures[index].ImageSource()
+	if source == nil {
+		return nil
+	}
+	data, err := r.doc.ImageData(*source)
+	if err != nil {
+		return nil
+	}
+	// parsed documents never hold larger images, the check keeps a document
+	// built some other way from allocating gigabytes
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width < 1 || config.Height < 1 || config.Width > gltf.MaxImagePixels/config.Height {
+		return nil
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+
+	bounds := decoded.Bounds()
+	nrgba := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(nrgba, nrgba.Bounds(), decoded, bounds.Min, draw.Src)
+	return &texture{width: bounds.Dx(), height: bounds.Dy(), pix: nrgba.Pix}
+}
+
+// sample reads the texture with bilinear filtering and repeat wrapping, the
+// default sampler of glTF
+func (t *texture) sample(u, v float64) [4]float64 {
+	x := u*float64(t.width) - 0.5
+	y := v*float64(t.height) - 0.5
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+
+	var out [4]float64
+	for _, tap := range [4]struct {
+		dx, dy int
+		weight float64
+	}{
+		{0, 0, (1 - fx) * (1 - fy)},
+		{1, 0, fx * (1 - fy)},
+		{0, 1, (1 - fx) * fy},
+		{1, 1, fx * fy},
+	} {
+		px := wrap(int(x0)+tap.dx, t.width)
+		py := wrap(int(y0)+tap.dy, t.height)
+		p := t.pix[(py*t.width+px)*4:]
+		out[0] += srgbToLinear[p[0]] * tap.weight
+		out[1] += srgbToLinear[p[1]] * tap.weight
+		out[2] += srgbToLinear[p[2]] * tap.weight
+		out[3] += float64(p[3]) / 255 * tap.weight
+	}
+
+	return out
+}
+
+func wrap(i, n int) int {
+	i %= n
+	if i < 0 {
+		i += n
+	}
+	return i
+}
@@ -0,0 +1,333 @@
+package thumbnail
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/gltf"
+)
+
+// ambient is the light every surface gets, the rest comes from a light above
+// and to the left of the camera
+const ambient = 0.35
+
+// light points at the light in view space, where x is right, y is up and z
+// points at the camera
+var light = normalize([3]float64{-0.4, 0.8, 0.6})
+
+// vertex is a vertex of a primitive in view space and on the screen
+type vertex struct {
+	view   [3]float64
+	x, y   float64
+	depth  float64
+	normal [3]float64
+	uv     [2]float64
+	color  [4]float64
+}
+
+// renderer draws into a supersampled buffer of premultiplied linear colors
+type renderer struct {
+	doc       *gltf.Document
+	size      int
+	materials map[int]*material
+
+	// right, up and back are the axes of the camera, center is the middle
+	// of the bounding box
+	center, right, up, back [3]float64
+	scale, midX, midY       float64
+
+	color []float32
+	depth []float32
+}
+
+func newRenderer(doc *gltf.Document, options Options, low, high [3]float64) *renderer {
+	yaw := options.Yaw * math.Pi / 180
+	pitch := math.Max(-maxPitch, math.Min(maxPitch, options.Pitch)) * math.Pi / 180
+	back := [3]float64{math.Sin(yaw) * math.Cos(pitch), math.Sin(pitch), math.Cos(yaw) * math.Cos(pitch)}
+	right := normalize(cross([3]float64{0, 1, 0}, back))
+
+	size := options.Size * supersample
+	r := &renderer{
+		doc:       doc,
+		size:      size,
+		materials: map[int]*material{},
+		center:    [3]float64{(low[0] + high[0]) / 2, (low[1] + high[1]) / 2, (low[2] + high[2]) / 2},
+		right:     right,
+		up:        cross(back, right),
+		back:      back,
+		color:     make([]float32, size*size*4),
+		depth:     make([]float32, size*size),
+	}
+	for i := range r.depth {
+		r.depth[i] = float32(math.Inf(1))
+	}
+
+	// frame the corners of the bounding box as the camera sees them
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for corner := 0; corner < 8; corner++ {
+		point := high
+		for axis := 0; axis < 3; axis++ {
+			if corner&(1<<axis) == 0 {
+				point[axis] = low[axis]
+			}
+		}
+		view := r.toView(point)
+		minX, maxX = math.Min(minX, view[0]), math.Max(maxX, view[0])
+		minY, maxY = math.Min(minY, view[1]), math.Max(maxY, view[1])
+	}
+	extent := math.Max(maxX-minX, maxY-minY)
+	if extent <= 0 {
+		extent = 1
+	}
+	r.scale = float64(size) * (1 - 2*margin) / extent
+	r.midX, r.midY = (minX+maxX)/2, (minY+maxY)/2
+
+	return r
+}
+
+func (r *renderer) toView(p [3]float64) [3]float64 {
+	d := [3]float64{p[0] - r.center[0], p[1] - r.center[1], p[2] - r.center[2]}
+	return [3]float64{dot(d, r.right), dot(d, r.up), dot(d, r.back)}
+}
+
+// drawPrimitive draws the triangles of a primitive placed by world, other
+// topologies are skipped
+func (r *renderer) drawPrimitive(primitive *gltf.Primitive, world [16]float64) error {
+	mode := primitive.PrimitiveMode()
+	if mode != gltf.ModeTriangles && mode != gltf.ModeTriangleStrip && mode != gltf.ModeTriangleFan {
+		return nil
+	}
+	m := r.material(primitive.Material)
+
+	vertices, err := r.vertices(primitive, m, world)
+	if err != nil {
+		return err
+	}
+	var indices []uint32
+	if primitive.Indices != nil {
+		if indices, err = r.doc.ReadIndices(*primitive.Indices); err != nil {
+			return fmt.Errorf("failed to read indices: %w", err)
+		}
+	} else {
+		indices = make([]uint32, len(vertices))
+		for i := range indices {
+			indices[i] = uint32(i)
+		}
+	}
+
+	triangle := func(a, b, c uint32) {
+		n := uint32(len(vertices))
+		if a < n && b < n && c < n {
+			r.drawTriangle(&vertices[a], &vertices[b], &vertices[c], m)
+		}
+	}
+	switch mode {
+	case gltf.ModeTriangles:
+		for i := 0; i+2 < len(indices); i += 3 {
+			triangle(indices[i], indices[i+1], indices[i+2])
+		}
+	case gltf.ModeTriangleStrip:
+		for i := 0; i+2 < len(indices); i++ {
+			triangle(indices[i], indices[i+1], indices[i+2])
+		}
+	case gltf.ModeTriangleFan:
+		for i := 1; i+1 < len(indices); i++ {
+			triangle(indices[0], indices[i], indices[i+1])
+		}
+	}
+
+	return nil
+}
+
+// vertices reads the attributes of a primitive and moves its positions and
+// normals into view space
+func (r *renderer) vertices(primitive *gltf.Primitive, m *material, world [16]float64) ([]vertex, error) {
+	positions, err := r.doc.ReadFloats(primitive.Attributes["POSITION"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read positions: %w", err)
+	}
+	vertices := make([]vertex, len(positions)/3)
+	for i := range vertices {
+		p := [3]float64(positions[i*3 : i*3+3])
+		view := r.toView([3]float64{
+			world[0]*p[0] + world[4]*p[1] + world[8]*p[2] + world[12],
+			world[1]*p[0] + world[5]*p[1] + world[9]*p[2] + world[13],
+			world[2]*p[0] + world[6]*p[1] + world[10]*p[2] + world[14],
+		})
+		vertices[i] = vertex{
+			view:  view,
+			x:     float64(r.size)/2 + (view[0]-r.midX)*r.scale,
+			y:     float64(r.size)/2 - (view[1]-r.midY)*r.scale,
+			depth: -view[2],
+			color: [4]float64{1, 1, 1, 1},
+		}
+	}
+
+	if index, ok := primitive.Attributes["NORMAL"]; ok {
+		normals, err := r.doc.ReadFloats(index)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read normals: %w", err)
+		}
+		for i := 0; i < len(vertices) && i*3+2 < len(normals); i++ {
+			n := normals[i*3 : i*3+3]
+			worldNormal := [3]float64{
+				world[0]*n[0] + world[4]*n[1] + world[8]*n[2],
+				world[1]*n[0] + world[5]*n[1] + world[9]*n[2],
+				world[2]*n[0] + world[6]*n[1] + world[10]*n[2],
+			}
+			vertices[i].normal = normalize([3]float64{dot(worldNormal, r.right), dot(worldNormal, r.up), dot(worldNormal, r.back)})
+		}
+	}
+	if index, ok := primitive.Attributes[m.texCoord]; ok && m.texture != nil {
+		uvs, err := r.doc.ReadFloats(index)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read texture coordinates: %w", err)
+		}
+		for i := 0; i < len(vertices) && i*2+1 < len(uvs); i++ {
+			vertices[i].uv = [2]float64(uvs[i*2 : i*2+2])
+		}
+	}
+	if index, ok := primitive.Attributes["COLOR_0"]; ok {
+		colors, err := r.doc.ReadFloats(index)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vertex colors: %w", err)
+		}
+		components := r.doc.Accessors[index].Components()
+		for i := 0; i < len(vertices) && (i+1)*components <= len(colors); i++ {
+			copy(vertices[i].color[:], colors[i*components:(i+1)*components])
+		}
+	}
+
+	return vertices, nil
+}
+
+// drawTriangle fills the samples whose centers the triangle covers, nearer
+// than what was drawn there before. Both sides of every triangle are drawn
+// and lit, exporters are loose about winding and doubleSided.
+func (r *renderer) drawTriangle(a, b, c *vertex, m *material) {
+	area := edge(a.x, a.y, b.x, b.y, c.x, c.y)
+	if math.Abs(area) < 1e-12 || math.IsNaN(area) {
+		return
+	}
+
+	faceNormal := normalize(cross(sub(b.view, a.view), sub(c.view, a.view)))
+	minX := max(int(math.Floor(math.Min(a.x, math.Min(b.x, c.x)))), 0)
+	maxX := min(int(math.Ceil(math.Max(a.x, math.Max(b.x, c.x)))), r.size-1)
+	minY := max(int(math.Floor(math.Min(a.y, math.Min(b.y, c.y)))), 0)
+	maxY := min(int(math.Ceil(math.Max(a.y, math.Max(b.y, c.y)))), r.size-1)
+
+	for y := minY; y <= maxY; y++ {
+		py := float64(y) + 0.5
+		for x := minX; x <= maxX; x++ {
+			px := float64(x) + 0.5
+			wa := edge(b.x, b.y, c.x, c.y, px, py) / area
+			wb := edge(c.x, c.y, a.x, a.y, px, py) / area
+			wc := 1 - wa - wb
+			if wa < 0 || wb < 0 || wc < 0 {
+				continue
+			}
+
+			i := y*r.size + x
+			depth := float32(wa*a.depth + wb*b.depth + wc*c.depth)
+			if depth >= r.depth[i] {
+				continue
+			}
+
+			color := m.baseColor
+			for k := range color {
+				color[k] *= wa*a.color[k] + wb*b.color[k] + wc*c.color[k]
+			}
+			if m.texture != nil {
+				texel := m.texture.sample(wa*a.uv[0]+wb*b.uv[0]+wc*c.uv[0], wa*a.uv[1]+wb*b.uv[1]+wc*c.uv[1])
+				for k := range color {
+					color[k] *= texel[k]
+				}
+			}
+			if m.mask && color[3] < m.cutoff {
+				continue
+			}
+
+			normal := faceNormal
+			if a.normal != [3]float64{} {
+				normal = normalize([3]float64{
+					wa*a.normal[0] + wb*b.normal[0] + wc*c.normal[0],
+					wa*a.normal[1] + wb*b.normal[1] + wc*c.normal[1],
+					wa*a.normal[2] + wb*b.normal[2] + wc*c.normal[2],
+				})
+			}
+			shade := ambient + (1-ambient)*math.Abs(dot(normal, light))
+
+			pixel := r.color[i*4 : i*4+4]
+			if !m.blend {
+				r.depth[i] = depth
+				for k := 0; k < 3; k++ {
+					pixel[k] = float32(color[k]*shade + m.emissive[k])
+				}
+				pixel[3] = 1
+				continue
+			}
+			// blended surfaces are drawn after the rest and do not hide
+			// what is behind them
+			alpha := math.Max(0, math.Min(1, color[3]))
+			for k := 0; k < 3; k++ {
+				pixel[k] = float32((color[k]*shade+m.emissive[k])*alpha + float64(pixel[k])*(1-alpha))
+			}
+			pixel[3] = float32(alpha + float64(pixel[3])*(1-alpha))
+		}
+	}
+}
+
+// resolve averages the samples of each pixel and converts them to sRGB
+func (r *renderer) resolve() *image.NRGBA {
+	size := r.size / supersample
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			var sum [4]float64
+			for sy := 0; sy < supersample; sy++ {
+				for sx := 0; sx < supersample; sx++ {
+					sample := r.color[((y*supersample+sy)*r.size+x*supersample+sx)*4:]
+					for k := range sum {
+						sum[k] += float64(sample[k])
+					}
+				}
+			}
+			if sum[3] == 0 {
+				continue
+			}
+
+			offset := img.PixOffset(x, y)
+			for k := 0; k < 3; k++ {
+				img.Pix[offset+k] = linearToSrgb(sum[k] / sum[3])
+			}
+			img.Pix[offset+3] = uint8(math.Round(math.Min(1, sum[3]/supersample/supersample) * 255))
+		}
+	}
+
+	return img
+}
+
+func edge(ax, ay, bx, by, px, py float64) float64 {
+	return (bx-ax)*(py-ay) - (by-ay)*(px-ax)
+}
+
+func dot(a, b [3]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func sub(a, b [3]float64) [3]float64 {
+	return [3]float64{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+func cross(a, b [3]float64) [3]float64 {
+	return [3]float64{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+
+func normalize(v [3]float64) [3]float64 {
+	length := math.Sqrt(dot(v, v))
+	if length == 0 {
+		return v
+	}
+	return [3]float64{v[0] / length, v[1] / length, v[2] / length}
+}
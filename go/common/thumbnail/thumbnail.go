@@ -0,0 +1,104 @@
+// Package thumbnail draws preview images of glTF models on the CPU. The
+// model is framed by its bounding box from a configurable angle, with flat
+// lighting and its base colors, on a transparent background.
+package thumbnail
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/gltf"
+)
+
+// ErrEmpty is returned for models without triangles to draw
+var ErrEmpty = errors.New("the model has nothing to draw")
+
+const (
+	// supersample is the number of samples per pixel along each axis
+	supersample = 2
+	// margin is left around the model on each side, as a share of the size
+	margin = 0.06
+	// maxPitch keeps the camera off the poles, where yaw has no meaning
+	maxPitch = 89
+)
+
+// Options place the camera. Zero yaw and pitch look at the front of the
+// model, along -Z, positive yaw turns the camera to the right of the model and
+// positive pitch raises it.
+type Options struct {
+	// Size is the width and height of the image in pixels
+	Size int
+	// Yaw and Pitch are in degrees
+	Yaw, Pitch float64
+}
+
+// Render draws the default scene of the document
+func Render(doc *gltf.Document, options Options) (*image.NRGBA, error) {
+	if options.Size < 1 {
+		return nil, fmt.Errorf("thumbnail size must be positive, got %d", options.Size)
+	}
+
+	stats, err := doc.Stats()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model stats: %w", err)
+	}
+	if stats.Triangles == 0 {
+		return nil, ErrEmpty
+	}
+
+	r := newRenderer(doc, options, stats.Min, stats.Max)
+	for _, blend := range []bool{false, true} {
+		for _, instance := range doc.MeshInstances() {
+			for _, primitive := range doc.Meshes[instance.Mesh].Primitives {
+				if r.material(primitive.Material).blend != blend {
+					continue
+				}
+				if err := r.drawPrimitive(primitive, instance.World); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return r.resolve(), nil
+}
+
+// Resize scales a thumbnail down to size pixels, averaging the pixels each
+// target pixel covers weighted by their alpha
+func Resize(src *image.NRGBA, size int) *image.NRGBA {
+	bounds := src.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		y0, y1 := y*srcHeight/size, max((y+1)*srcHeight/size, y*srcHeight/size+1)
+		for x := 0; x < size; x++ {
+			x0, x1 := x*srcWidth/size, max((x+1)*srcWidth/size, x*srcWidth/size+1)
+
+			var sum [4]float64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					p := src.Pix[src.PixOffset(bounds.Min.X+sx, bounds.Min.Y+sy):]
+					alpha := float64(p[3])
+					sum[0] += float64(p[0]) * alpha
+					sum[1] += float64(p[1]) * alpha
+					sum[2] += float64(p[2]) * alpha
+					sum[3] += alpha
+				}
+			}
+			if sum[3] == 0 {
+				continue
+			}
+
+			count := float64((y1 - y0) * (x1 - x0))
+			offset := dst.PixOffset(x, y)
+			for i := 0; i < 3; i++ {
+				dst.Pix[offset+i] = uint8(math.Round(sum[i] / sum[3]))
+			}
+			dst.Pix[offset+3] = uint8(math.Round(sum[3] / count))
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,141 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"math"
+	"testing"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/gltf"
+)
+
+// square is a red unit square facing +Z, turned by the yaw of its node
+func square(t *testing.T, yaw float64) *gltf.Document {
+	t.Helper()
+
+	bin := make([]byte, 48)
+	for i, v := range []float32{-1, -1, 0, 1, -1, 0, 1, 1, 0, -1, 1, 0} {
+		binary.LittleEndian.PutUint32(bin[i*4:], math.Float32bits(v))
+	}
+	half := yaw * math.Pi / 360
+	doc := map[string]any{
+		"asset":       map[string]any{"version": "2.0"},
+		"scene":       0,
+		"scenes":      []any{map[string]any{"nodes": []int{0}}},
+		"nodes":       []any{map[string]any{"mesh": 0, "rotation": []float64{0, math.Sin(half), 0, math.Cos(half)}}},
+		"meshes":      []any{map[string]any{"primitives": []any{map[string]any{"attributes": map[string]int{"POSITION": 0}, "material": 0, "mode": gltf.ModeTriangleFan}}}},
+		"materials":   []any{map[string]any{"pbrMetallicRoughness": map[string]any{"baseColorFactor": []float64{1, 0, 0, 1}}}},
+		"accessors":   []any{map[string]any{"bufferView": 0, "componentType": gltf.ComponentFloat, "count": 4, "type": "VEC3", "min": []float64{-1, -1, 0}, "max": []float64{1, 1, 0}}},
+		"bufferViews": []any{map[string]any{"buffer": 0, "byteLength": len(bin)}},
+		"buffers":     []any{map[string]any{"byteLength": len(bin), "uri": "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(bin)}},
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := gltf.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaw     float64
+		options Options
+	}{
+		{"front", 0, Options{Size: 32}},
+		{"back", 0, Options{Size: 32, Yaw: 180}},
+		{"turned model", 90, Options{Size: 32, Yaw: 90}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Render(square(t, tt.yaw), tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if img.Bounds().Dx() != 32 || img.Bounds().Dy() != 32 {
+				t.Fatalf("expected a 32 pixel image, got %v", img.Bounds())
+			}
+
+			center := img.NRGBAAt(16, 16)
+			if center.A != 255 || center.R < 100 || center.G != 0 || center.B != 0 {
+				t.Errorf("expected the middle to be red, got %v", center)
+			}
+			if corner := img.NRGBAAt(0, 0); corner.A != 0 {
+				t.Errorf("expected a transparent margin, got %v", corner)
+			}
+			// the square fills the frame but the margin
+			if edge := img.NRGBAAt(3, 16); edge.A != 255 {
+				t.Errorf("expected the square to reach the margin, got %v", edge)
+			}
+		})
+	}
+}
+
+func TestResize(t *testing.T) {
+	img, err := Render(square(t, 0), Options{Size: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	small := Resize(img, 16)
+	if small.Bounds().Dx() != 16 {
+		t.Fatalf("expected a 16 pixel image, got %v", small.Bounds())
+	}
+	if c := small.NRGBAAt(8, 8); c != img.NRGBAAt(32, 32) {
+		t.Errorf("expected flat areas to keep their color, got %v and %v", c, img.NRGBAAt(32, 32))
+	}
+	if c := small.NRGBAAt(0, 0); c.A != 0 {
+		t.Errorf("expected the margin to stay transparent, got %v", c)
+	}
+}
+
+// pngData is a data URI of a png with the given size. Huge sizes only get a
+// header, enough for the size to be read but not for the pixels to be decoded.
+func pngData(t *testing.T, width, height int) string {
+	t.Helper()
+
+	out := &bytes.Buffer{}
+	if width*height <= 64 {
+		if err := png.Encode(out, image.NewNRGBA(image.Rect(0, 0, width, height))); err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		chunk := &bytes.Buffer{}
+		chunk.WriteString("IHDR")
+		binary.Write(chunk, binary.BigEndian, []uint32{uint32(width), uint32(height)})
+		chunk.Write([]byte{8, 6, 0, 0, 0})
+		out.WriteString("\x89PNG\r\n\x1a\n")
+		binary.Write(out, binary.BigEndian, uint32(chunk.Len()-4))
+		out.Write(chunk.Bytes())
+		binary.Write(out, binary.BigEndian, crc32.ChecksumIEEE(chunk.Bytes()))
+	}
+
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(out.Bytes())
+}
+
+func TestTextureSkipsHugeImages(t *testing.T) {
+	doc := square(t, 0)
+	doc.Images = []*gltf.Image{{URI: pngData(t, 4, 2)}}
+	doc.Textures = []*gltf.Texture{{Source: new(int)}}
+	r := &renderer{doc: doc}
+
+	if tex := r.texture(0); tex == nil || tex.width != 4 || tex.height != 2 {
+		t.Fatalf("expected the 4x2 texture, got %+v", tex)
+	}
+
+	doc.Images[0].URI = pngData(t, 30000, 30000)
+	if tex := r.texture(0); tex != nil {
+		t.Errorf("expected a 30000x30000 texture to be left out, got %dx%d", tex.width, tex.height)
+	}
+}
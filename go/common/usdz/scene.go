@@ -0,0 +1,451 @@
+package usdz
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/gltf"
+)
+
+const (
+	// materialsPath holds the materials, defaultMaterial is bound to
+	// primitives without one
+	materialsPath   = "/Model/Materials"
+	defaultMaterial = "DefaultMaterial"
+)
+
+// textureExtensions are the image formats USDZ packages may hold
+var textureExtensions = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpg",
+}
+
+// wrapModes map the wrap modes of glTF samplers to UsdUVTexture
+var wrapModes = map[int]string{
+	33071: "clamp",
+	33648: "mirror",
+	10497: "repeat",
+}
+
+// sceneWriter writes the USDA layer and collects the textures it refers to
+type sceneWriter struct {
+	doc      *gltf.Document
+	out      *strings.Builder
+	textures []file
+	// texturePaths are the packaged files of the images, empty for images in
+	// formats USDZ does not allow
+	texturePaths map[int]string
+}
+
+func newSceneWriter(doc *gltf.Document) *sceneWriter {
+	return &sceneWriter{doc: doc, out: &strings.Builder{}, texturePaths: map[int]string{}}
+}
+
+func (s *sceneWriter) write() ([]byte, error) {
+	s.printf("#usda 1.0\n(\n")
+	s.printf("    defaultPrim = \"Model\"\n    metersPerUnit = 1\n    upAxis = \"Y\"\n)\n\n")
+	s.printf("def Xform \"Model\" (\n    kind = \"component\"\n)\n{\n")
+
+	s.printf("    def Scope \"Materials\"\n    {\n")
+	s.writeMaterial(defaultMaterial, nil)
+	for i, material := range s.doc.Materials {
+		s.writeMaterial(fmt.Sprintf("Material_%d", i), material)
+	}
+	s.printf("    }\n")
+
+	for i, instance := range s.doc.MeshInstances() {
+		s.printf("\n    def Xform \"Instance_%d\"\n    {\n", i)
+		m := instance.World
+		s.printf("        matrix4d xformOp:transform = ( (%s, %s, %s, %s), (%s, %s, %s, %s), (%s, %s, %s, %s), (%s, %s, %s, %s) )\n",
+			num(m[0]), num(m[1]), num(m[2]), num(m[3]), num(m[4]), num(m[5]), num(m[6]), num(m[7]),
+			num(m[8]), num(m[9]), num(m[10]), num(m[11]), num(m[12]), num(m[13]), num(m[14]), num(m[15]))
+		s.printf("        uniform token[] xformOpOrder = [\"xformOp:transform\"]\n")
+		for j, primitive := range s.doc.Meshes[instance.Mesh].Primitives {
+			if err := s.writeMesh(fmt.Sprintf("Primitive_%d", j), primitive); err != nil {
+				return nil, fmt.Errorf("mesh %d primitive %d: %w", instance.Mesh, j, err)
+			}
+		}
+		s.printf("    }\n")
+	}
+
+	s.printf("}\n")
+	return []byte(s.out.String()), nil
+}
+
+// writeMesh writes a primitive as a mesh of triangles, strips and fans are
+// unrolled and points and lines are left out
+func (s *sceneWriter) writeMesh(name string, primitive *gltf.Primitive) error {
+	mode := primitive.PrimitiveMode()
+	if mode != gltf.ModeTriangles && mode != gltf.ModeTriangleStrip && mode != gltf.ModeTriangleFan {
+		return nil
+	}
+
+	positions, err := s.doc.ReadFloats(primitive.Attributes["POSITION"])
+	if err != nil {
+		return fmt.Errorf("failed to read positions: %w", err)
+	}
+	triangles, err := s.triangles(primitive, mode, len(positions)/3)
+	if err != nil {
+		return err
+	}
+	if len(triangles) == 0 {
+		return nil
+	}
+
+	material, doubleSided := defaultMaterial, false
+	if primitive.Material != nil {
+		material = fmt.Sprintf("Material_%d", *primitive.Material)
+		doubleSided = s.doc.Materials[*primitive.Material].DoubleSided
+	}
+
+	s.printf("\n        def Mesh \"%s\" (\n            prepend apiSchemas = [\"MaterialBindingAPI\"]\n        )\n        {\n", name)
+	s.printf("            uniform bool doubleSided = %d\n", boolInt(doubleSided))
+	s.printf("            float3[] extent = [%s, %s]\n", tuple(componentMin(positions, 3)), tuple(componentMax(positions, 3)))
+	s.printf("            int[] faceVertexCounts = [%s]\n", strings.TrimSuffix(strings.Repeat("3, ", len(triangles)/3), ", "))
+	s.printf("            int[] faceVertexIndices = [%s]\n", joinInts(triangles))
+	s.printf("            rel material:binding = <%s/%s>\n", materialsPath, material)
+	s.printf("            point3f[] points = [%s]\n", tuples(positions, 3, nil))
+
+	if index, ok := primitive.Attributes["NORMAL"]; ok {
+		normals, err := s.doc.ReadFloats(index)
+		if err != nil {
+			return fmt.Errorf("failed to read normals: %w", err)
+		}
+		s.printf("            normal3f[] normals = [%s] (\n                interpolation = \"vertex\"\n            )\n", tuples(normals, 3, nil))
+	}
+	for set, primvar := range []string{"st", "st1"} {
+		index, ok := primitive.Attributes["TEXCOORD_"+strconv.Itoa(set)]
+		if !ok {
+			continue
+		}
+		uvs, err := s.doc.ReadFloats(index)
+		if err != nil {
+			return fmt.Errorf("failed to read texture coordinates: %w", err)
+		}
+		// glTF puts the origin of textures at the top, USD at the bottom
+		flip := func(i int, v float64) float64 {
+			if i == 1 {
+				return 1 - v
+			}
+			return v
+		}
+		s.printf("            texCoord2f[] primvars:%s = [%s] (\n                interpolation = \"vertex\"\n            )\n", primvar, tuples(uvs, 2, flip))
+	}
+
+	s.printf("            uniform token subdivisionScheme = \"none\"\n        }\n")
+	return nil
+}
+
+// triangles lists the vertex indices of the triangles of a primitive,
+// skipping triangles that refer to missing vertices
+func (s *sceneWriter) triangles(primitive *gltf.Primitive, mode, vertices int) ([]uint32, error) {
+	var indices []uint32
+	if primitive.Indices != nil {
+		var err error
+		if indices, err = s.doc.ReadIndices(*primitive.Indices); err != nil {
+			return nil, fmt.Errorf("failed to read indices: %w", err)
+		}
+	} else {
+		indices = make([]uint32, vertices)
+		for i := range indices {
+			indices[i] = uint32(i)
+		}
+	}
+
+	triangles := make([]uint32, 0, len(indices))
+	add := func(a, b, c uint32) {
+		n := uint32(vertices)
+		if a < n && b < n && c < n {
+			triangles = append(triangles, a, b, c)
+		}
+	}
+	switch mode {
+	case gltf.ModeTriangles:
+		for i := 0; i+2 < len(indices); i += 3 {
+			add(indices[i], indices[i+1], indices[i+2])
+		}
+	case gltf.ModeTriangleStrip:
+		// every other triangle of a strip is flipped to keep the winding
+		for i := 0; i+2 < len(indices); i++ {
+			if i%2 == 0 {
+				add(indices[i], indices[i+1], indices[i+2])
+			} else {
+				add(indices[i+1], indices[i], indices[i+2])
+			}
+		}
+	case gltf.ModeTriangleFan:
+		for i := 1; i+1 < len(indices); i++ {
+			add(indices[0], indices[i], indices[i+1])
+		}
+	}
+
+	return triangles, nil
+}
+
+// writeMaterial writes a UsdPreviewSurface for the material, the default
+// material of glTF for nil
+func (s *sceneWriter) writeMaterial(name string, material *gltf.Material) {
+	path := materialsPath + "/" + name
+	baseColor := []float64{1, 1, 1, 1}
+	metallic, roughness := 1.0, 1.0
+	emissive := []float64{0, 0, 0}
+	var pbr *gltf.PBRMetallicRoughness
+	alphaMode := "OPAQUE"
+	if material != nil {
+		pbr = material.PBRMetallicRoughness
+		if len(material.EmissiveFactor) == 3 {
+			emissive = material.EmissiveFactor
+		}
+		if material.AlphaMode != "" {
+			alphaMode = material.AlphaMode
+		}
+	}
+	if pbr != nil {
+		if len(pbr.BaseColorFactor) == 4 {
+			baseColor = pbr.BaseColorFactor
+		}
+		if pbr.MetallicFactor != nil {
+			metallic = *pbr.MetallicFactor
+		}
+		if pbr.RoughnessFactor != nil {
+			roughness = *pbr.RoughnessFactor
+		}
+	}
+
+	// inputs holds the surface inputs, shaders the texture readers they are
+	// connected to
+	inputs := []string{
+		fmt.Sprintf("color3f inputs:diffuseColor = %s", tuple(baseColor[:3])),
+		fmt.Sprintf("float inputs:metallic = %s", num(metallic)),
+		fmt.Sprintf("float inputs:roughness = %s", num(roughness)),
+		fmt.Sprintf("color3f inputs:emissiveColor = %s", tuple(emissive)),
+	}
+	if alphaMode != "OPAQUE" {
+		inputs = append(inputs, fmt.Sprintf("float inputs:opacity = %s", num(baseColor[3])))
+	}
+	if alphaMode == "MASK" {
+		cutoff := 0.5
+		if material.AlphaCutoff != nil {
+			cutoff = *material.AlphaCutoff
+		}
+		inputs = append(inputs, fmt.Sprintf("float inputs:opacityThreshold = %s", num(cutoff)))
+	}
+
+	shaders := &strings.Builder{}
+	readers := map[int]bool{}
+	texture := func(shader string, info *gltf.TextureInfo, colorSpace string, scale, bias []float64) bool {
+		if info == nil {
+			return false
+		}
+		file := s.texture(info.Index)
+		if file == "" {
+			return false
+		}
+
+		readers[info.TexCoord] = true
+		wrapS, wrapT := s.wrapModes(info.Index)
+		fmt.Fprintf(shaders, "\n            def Shader \"%s\"\n            {\n", shader)
+		fmt.Fprintf(shaders, "                uniform token info:id = \"UsdUVTexture\"\n")
+		fmt.Fprintf(shaders, "                asset inputs:file = @%s@\n", file)
+		fmt.Fprintf(shaders, "                token inputs:sourceColorSpace = \"%s\"\n", colorSpace)
+		fmt.Fprintf(shaders, "                float2 inputs:st.connect = <%s/TexCoord%d.outputs:result>\n", path, info.TexCoord)
+		fmt.Fprintf(shaders, "                token inputs:wrapS = \"%s\"\n                token inputs:wrapT = \"%s\"\n", wrapS, wrapT)
+		if scale != nil {
+			fmt.Fprintf(shaders, "                float4 inputs:scale = %s\n", tuple(scale))
+		}
+		if bias != nil {
+			fmt.Fprintf(shaders, "                float4 inputs:bias = %s\n", tuple(bias))
+		}
+		fmt.Fprintf(shaders, "                float3 outputs:rgb\n                float outputs:r\n                float outputs:g\n                float outputs:b\n                float outputs:a\n            }\n")
+		return true
+	}
+
+	if pbr != nil && texture("BaseColor", pbr.BaseColorTexture, "sRGB", baseColor, nil) {
+		inputs = append(inputs, fmt.Sprintf("color3f inputs:diffuseColor.connect = <%s/BaseColor.outputs:rgb>", path))
+		if alphaMode != "OPAQUE" {
+			inputs = append(inputs, fmt.Sprintf("float inputs:opacity.connect = <%s/BaseColor.outputs:a>", path))
+		}
+	}
+	if pbr != nil && texture("MetallicRoughness", pbr.MetallicRoughnessTexture, "raw", []float64{1, roughness, metallic, 1}, nil) {
+		inputs = append(inputs,
+			fmt.Sprintf("float inputs:metallic.connect = <%s/MetallicRoughness.outputs:b>", path),
+			fmt.Sprintf("float inputs:roughness.connect = <%s/MetallicRoughness.outputs:g>", path))
+	}
+	if material != nil {
+		if info := material.NormalTexture; info != nil {
+			strength := 1.0
+			if info.Scale != nil {
+				strength = *info.Scale
+			}
+			if texture("Normal", info, "raw", []float64{2 * strength, 2 * strength, 2, 1}, []float64{-strength, -strength, -1, 0}) {
+				inputs = append(inputs, fmt.Sprintf("normal3f inputs:normal.connect = <%s/Normal.outputs:rgb>", path))
+			}
+		}
+		if texture("Occlusion", material.OcclusionTexture, "raw", nil, nil) {
+			inputs = append(inputs, fmt.Sprintf("float inputs:occlusion.connect = <%s/Occlusion.outputs:r>", path))
+		}
+		if texture("Emissive", material.EmissiveTexture, "sRGB", append(append([]float64{}, emissive...), 1), nil) {
+			inputs = append(inputs, fmt.Sprintf("color3f inputs:emissiveColor.connect = <%s/Emissive.outputs:rgb>", path))
+		}
+	}
+
+	s.printf("        def Material \"%s\"\n        {\n", name)
+	s.printf("            token outputs:surface.connect = <%s/Surface.outputs:surface>\n\n", path)
+	s.printf("            def Shader \"Surface\"\n            {\n                uniform token info:id = \"UsdPreviewSurface\"\n")
+	for _, input := range inputs {
+		s.printf("                %s\n", input)
+	}
+	s.printf("                token outputs:surface\n            }\n")
+	s.printf("%s", shaders.String())
+	for set := 0; set < 2; set++ {
+		if !readers[set] {
+			continue
+		}
+		varname := "st"
+		if set == 1 {
+			varname = "st1"
+		}
+		s.printf("\n            def Shader \"TexCoord%d\"\n            {\n", set)
+		s.printf("                uniform token info:id = \"UsdPrimvarReader_float2\"\n")
+		s.printf("                token inputs:varname = \"%s\"\n", varname)
+		s.printf("                float2 outputs:result\n            }\n")
+	}
+	s.printf("        }\n")
+}
+
+// texture returns the packaged file of the image of a texture, adding it on
+// first use. Textures in other formats than png and jpeg are left out.
+func (s *sceneWriter) texture(index int) string {
+	source := s.doc.Textures[index].ImageSource()
+	if source == nil {
+		return ""
+	}
+	if path, ok := s.texturePaths[*source]; ok {
+		return path
+	}
+
+	s.texturePaths[*source] = ""
+	data, err := s.doc.ImageData(*source)
+	if err != nil {
+		return ""
+	}
+	mimeType := s.doc.Images[*source].MimeType
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	ext, ok := textureExtensions[mimeType]
+	if !ok {
+		return ""
+	}
+
+	path := fmt.Sprintf("textures/texture_%d.%s", *source, ext)
+	s.texturePaths[*source] = path
+	s.textures = append(s.textures, file{name: path, data: data})
+	return path
+}
+
+// wrapModes reads the wrap modes of the sampler of a texture, repeat when it
+// has none
+func (s *sceneWriter) wrapModes(index int) (string, string) {
+	wrapS, wrapT := "repeat", "repeat"
+	sampler := s.doc.Textures[index].Sampler
+	if sampler == nil || *sampler < 0 || *sampler >= len(s.doc.Samplers) {
+		return wrapS, wrapT
+	}
+
+	var modes struct {
+		WrapS int `json:"wrapS"`
+		WrapT int `json:"wrapT"`
+	}
+	if json.Unmarshal(s.doc.Samplers[*sampler], &modes) != nil {
+		return wrapS, wrapT
+	}
+	if mode, ok := wrapModes[modes.WrapS]; ok {
+		wrapS = mode
+	}
+	if mode, ok := wrapModes[modes.WrapT]; ok {
+		wrapT = mode
+	}
+	return wrapS, wrapT
+}
+
+func (s *sceneWriter) printf(format string, args ...any) {
+	fmt.Fprintf(s.out, format, args...)
+}
+
+// num formats a value with the precision of the float it is stored as
+func num(v float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "0"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 32)
+}
+
+func tuple(values []float64) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = num(v)
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// tuples formats values in groups of size, edit changes each component when
+// set
+func tuples(values []float64, size int, edit func(i int, v float64) float64) string {
+	out := &strings.Builder{}
+	group := make([]float64, size)
+	for i := 0; i+size <= len(values); i += size {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		copy(group, values[i:i+size])
+		if edit != nil {
+			for k := range group {
+				group[k] = edit(k, group[k])
+			}
+		}
+		out.WriteString(tuple(group))
+	}
+	return out.String()
+}
+
+func componentMin(values []float64, size int) []float64 {
+	result := make([]float64, size)
+	for k := range result {
+		result[k] = math.Inf(1)
+		for i := k; i < len(values); i += size {
+			result[k] = math.Min(result[k], values[i])
+		}
+	}
+	return result
+}
+
+func componentMax(values []float64, size int) []float64 {
+	result := make([]float64, size)
+	for k := range result {
+		result[k] = math.Inf(-1)
+		for i := k; i < len(values); i += size {
+			result[k] = math.Max(result[k], values[i])
+		}
+	}
+	return result
+}
+
+func joinInts(values []uint32) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.FormatUint(uint64(v), 10)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
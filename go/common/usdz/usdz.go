@@ -0,0 +1,96 @@
+// Package usdz converts glTF models to USDZ packages for AR Quick Look on
+// iOS. The scene is written as USDA with UsdPreviewSurface materials, png and
+// jpeg textures are packed next to it. Animations, skins and morph targets
+// are left out, the package shows the model at rest.
+package usdz
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/gltf"
+)
+
+const (
+	// sceneFile is the root layer, the first file of the package
+	sceneFile = "model.usda"
+	// alignment is the boundary the data of every file starts on, so the
+	// package can be mapped and read in place
+	alignment = 64
+	// paddingExtraID marks the extra field that pads headers to the alignment,
+	// the id usdzip uses
+	paddingExtraID = 0x1986
+	// localHeaderSize is the fixed part of a zip local file header
+	localHeaderSize = 30
+)
+
+type file struct {
+	name string
+	data []byte
+}
+
+// Convert writes the default scene of the document as a USDZ package
+func Convert(doc *gltf.Document) ([]byte, error) {
+	s := newSceneWriter(doc)
+	scene, err := s.write()
+	if err != nil {
+		return nil, err
+	}
+
+	files := append([]file{{name: sceneFile, data: scene}}, s.textures...)
+	return pack(files)
+}
+
+// pack writes the files into a zip archive the way USDZ requires: stored
+// without compression, without data descriptors and with the data of each
+// file aligned
+func pack(files []file) ([]byte, error) {
+	out := &bytes.Buffer{}
+	w := zip.NewWriter(out)
+
+	offset := 0
+	for _, f := range files {
+		header := &zip.FileHeader{
+			Name:               f.name,
+			Method:             zip.Store,
+			CRC32:              crc32.ChecksumIEEE(f.data),
+			CompressedSize64:   uint64(len(f.data)),
+			UncompressedSize64: uint64(len(f.data)),
+			Extra:              padding(offset + localHeaderSize + len(f.name)),
+		}
+
+		fw, err := w.CreateRaw(header)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %s to the package: %w", f.name, err)
+		}
+		if _, err := fw.Write(f.data); err != nil {
+			return nil, fmt.Errorf("failed to add %s to the package: %w", f.name, err)
+		}
+		offset += localHeaderSize + len(f.name) + len(header.Extra) + len(f.data)
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write the package: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// padding is the extra field that moves data starting at offset to the next
+// aligned offset, none when it is aligned already
+func padding(offset int) []byte {
+	if offset%alignment == 0 {
+		return nil
+	}
+
+	size := alignment - (offset+4)%alignment
+	if size == alignment {
+		size = 0
+	}
+	extra := make([]byte, 4+size)
+	binary.LittleEndian.PutUint16(extra, paddingExtraID)
+	binary.LittleEndian.PutUint16(extra[2:], uint16(size))
+	return extra
+}
@@ -0,0 +1,126 @@
+package usdz
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/gltf"
+)
+
+// texturedSquare is a unit square drawn as a fan with a png base color
+// texture
+func texturedSquare(t *testing.T) *gltf.Document {
+	t.Helper()
+
+	bin := make([]byte, 80)
+	for i, v := range []float32{-1, -1, 0, 1, -1, 0, 1, 1, 0, -1, 1, 0, 0, 1, 1, 1, 1, 0, 0, 0} {
+		binary.LittleEndian.PutUint32(bin[i*4:], math.Float32bits(v))
+	}
+	img := &bytes.Buffer{}
+	if err := png.Encode(img, image.NewNRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := map[string]any{
+		"asset":  map[string]any{"version": "2.0"},
+		"scene":  0,
+		"scenes": []any{map[string]any{"nodes": []int{0}}},
+		"nodes":  []any{map[string]any{"mesh": 0, "translation": []float64{0, 2, 0}}},
+		"meshes": []any{map[string]any{"primitives": []any{map[string]any{
+			"attributes": map[string]int{"POSITION": 0, "TEXCOORD_0": 1}, "material": 0, "mode": gltf.ModeTriangleFan,
+		}}}},
+		"materials": []any{map[string]any{"pbrMetallicRoughness": map[string]any{"baseColorTexture": map[string]any{"index": 0}}}},
+		"textures":  []any{map[string]any{"source": 0, "sampler": 0}},
+		"samplers":  []any{map[string]any{"wrapS": 33071}},
+		"images":    []any{map[string]any{"uri": "data:image/png;base64," + base64.StdEncoding.EncodeToString(img.Bytes())}},
+		"accessors": []any{
+			map[string]any{"bufferView": 0, "componentType": gltf.ComponentFloat, "count": 4, "type": "VEC3", "min": []float64{-1, -1, 0}, "max": []float64{1, 1, 0}},
+			map[string]any{"bufferView": 0, "byteOffset": 48, "componentType": gltf.ComponentFloat, "count": 4, "type": "VEC2"},
+		},
+		"bufferViews": []any{map[string]any{"buffer": 0, "byteLength": len(bin)}},
+		"buffers":     []any{map[string]any{"byteLength": len(bin), "uri": "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(bin)}},
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := gltf.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestConvert(t *testing.T) {
+	data, err := Convert(texturedSquare(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.File) != 2 || r.File[0].Name != sceneFile || r.File[1].Name != "textures/texture_0.png" {
+		names := []string{}
+		for _, f := range r.File {
+			names = append(names, f.Name)
+		}
+		t.Fatalf("expected the scene and the texture, got %v", names)
+	}
+
+	for _, f := range r.File {
+		if f.Method != zip.Store {
+			t.Errorf("expected %s to be stored, got method %d", f.Name, f.Method)
+		}
+		offset, err := f.DataOffset()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if offset%alignment != 0 {
+			t.Errorf("expected the data of %s to be aligned, starts at %d", f.Name, offset)
+		}
+	}
+
+	rc, err := r.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	scene, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`defaultPrim = "Model"`,
+		"int[] faceVertexCounts = [3, 3]",
+		"int[] faceVertexIndices = [0, 1, 2, 0, 2, 3]",
+		"(0, 0), (1, 0), (1, 1), (0, 1)",
+		"(1, 0, 0, 0), (0, 1, 0, 0), (0, 0, 1, 0), (0, 2, 0, 1)",
+		"asset inputs:file = @textures/texture_0.png@",
+		`token inputs:wrapS = "clamp"`,
+		"rel material:binding = </Model/Materials/Material_0>",
+	} {
+		if !strings.Contains(string(scene), want) {
+			t.Errorf("expected the scene to contain %q, got\n%s", want, scene)
+		}
+	}
+}
+
+func TestPadding(t *testing.T) {
+	for offset := 0; offset < 2*alignment; offset++ {
+		if got := offset + len(padding(offset)); got%alignment != 0 {
+			t.Errorf("padding at %d ends at %d", offset, got)
+		}
+	}
+}
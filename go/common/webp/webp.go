@@ -0,0 +1,456 @@
+// Package webp writes images as lossless WebP files, the VP8L format. Only
+// what thumbnails need is used: the subtract green transform and copies of
+// the previous pixel or the one above, which covers flat backgrounds and
+// transparent margins well.
+package webp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math/bits"
+	"slices"
+	"sort"
+)
+
+const (
+	// maxDimension is the largest width or height VP8L can describe
+	maxDimension = 1 << 14
+
+	signature              = 0x2f
+	transformSubtractGreen = 2
+
+	// numLengthCodes follow the 256 green literals in the first prefix code
+	numLengthCodes   = 24
+	numDistanceCodes = 40
+	maxCopyLength    = 4096
+	minCopyLength    = 3
+
+	maxCodeLength           = 15
+	maxCodeLengthCodeLength = 7
+	numCodeLengthCodes      = 19
+
+	// distanceAbove and distancePrevious are the distance codes of the pixel
+	// one row up and the one to the left
+	distanceAbove    = 1
+	distancePrevious = 2
+)
+
+// codeLengthOrder is the order code length code lengths are written in
+var codeLengthOrder = [numCodeLengthCodes]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// token is either a literal pixel or, when length is set, a copy of earlier
+// pixels
+type token struct {
+	argb     uint32
+	length   int
+	distance int
+}
+
+// Encode writes the image as a lossless WebP file
+func Encode(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > maxDimension || height > maxDimension {
+		return fmt.Errorf("webp images are 1 to %d pixels wide and high, got %dx%d", maxDimension, width, height)
+	}
+
+	pixels, hasAlpha := argbPixels(img)
+	for i, argb := range pixels {
+		green := argb >> 8 & 0xff
+		red := (argb>>16 - green) & 0xff
+		blue := (argb - green) & 0xff
+		pixels[i] = argb&0xff00ff00 | red<<16 | blue
+	}
+	tokens := tokenize(pixels, width)
+
+	green := make([]int, 256+numLengthCodes)
+	red, blue, alpha := make([]int, 256), make([]int, 256), make([]int, 256)
+	distance := make([]int, numDistanceCodes)
+	for _, t := range tokens {
+		if t.length == 0 {
+			alpha[t.argb>>24]++
+			red[t.argb>>16&0xff]++
+			green[t.argb>>8&0xff]++
+			blue[t.argb&0xff]++
+			continue
+		}
+		lengthPrefix, _, _ := prefixEncode(t.length)
+		distancePrefix, _, _ := prefixEncode(t.distance)
+		green[256+lengthPrefix]++
+		distance[distancePrefix]++
+	}
+
+	bw := &bitWriter{}
+	bw.write(signature, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	bw.write(boolBit(hasAlpha), 1)
+	bw.write(0, 3)
+	bw.write(1, 1)
+	bw.write(transformSubtractGreen, 2)
+	bw.write(0, 1)
+	// no color cache and a single set of prefix codes for the whole image
+	bw.write(0, 1)
+	bw.write(0, 1)
+
+	greenCode := writePrefixCode(bw, green)
+	redCode := writePrefixCode(bw, red)
+	blueCode := writePrefixCode(bw, blue)
+	alphaCode := writePrefixCode(bw, alpha)
+	distanceCode := writePrefixCode(bw, distance)
+
+	for _, t := range tokens {
+		if t.length == 0 {
+			greenCode.write(bw, int(t.argb>>8&0xff))
+			redCode.write(bw, int(t.argb>>16&0xff))
+			blueCode.write(bw, int(t.argb&0xff))
+			alphaCode.write(bw, int(t.argb>>24))
+			continue
+		}
+		prefix, extraBits, extra := prefixEncode(t.length)
+		greenCode.write(bw, 256+prefix)
+		bw.write(extra, extraBits)
+		prefix, extraBits, extra = prefixEncode(t.distance)
+		distanceCode.write(bw, prefix)
+		bw.write(extra, extraBits)
+	}
+
+	data := bw.bytes()
+	padding := len(data) % 2
+	header := make([]byte, 20)
+	copy(header, "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(12+len(data)+padding))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if padding == 1 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+// argbPixels are the non premultiplied pixels of the image packed as ARGB,
+// fully transparent pixels are made black so they compress together
+func argbPixels(img image.Image) ([]uint32, bool) {
+	bounds := img.Bounds()
+	pixels := make([]uint32, 0, bounds.Dx()*bounds.Dy())
+	hasAlpha := false
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A == 0 {
+				c = color.NRGBA{}
+			}
+			hasAlpha = hasAlpha || c.A != 0xff
+			pixels = append(pixels, uint32(c.A)<<24|uint32(c.R)<<16|uint32(c.G)<<8|uint32(c.B))
+		}
+	}
+
+	return pixels, hasAlpha
+}
+
+// tokenize replaces runs of pixels that repeat the previous pixel or the row
+// above with copies, taking the longer of the two
+func tokenize(pixels []uint32, width int) []token {
+	tokens := make([]token, 0, len(pixels)/4)
+	for i := 0; i < len(pixels); {
+		length, distance := 0, 0
+		if i >= 1 {
+			length, distance = matchLength(pixels, i, 1), distancePrevious
+		}
+		if i >= width {
+			if above := matchLength(pixels, i, width); above > length {
+				length, distance = above, distanceAbove
+			}
+		}
+
+		if length < minCopyLength {
+			tokens = append(tokens, token{argb: pixels[i]})
+			i++
+			continue
+		}
+		tokens = append(tokens, token{length: length, distance: distance})
+		i += length
+	}
+
+	return tokens
+}
+
+func matchLength(pixels []uint32, i, offset int) int {
+	n := 0
+	for i+n < len(pixels) && n < maxCopyLength && pixels[i+n] == pixels[i+n-offset] {
+		n++
+	}
+	return n
+}
+
+// prefixEncode splits a length or distance code into its prefix symbol and
+// the extra bits that follow it
+func prefixEncode(value int) (int, uint, uint32) {
+	v := value - 1
+	if v < 4 {
+		return v, 0, 0
+	}
+	high := bits.Len(uint(v)) - 1
+	second := v >> (high - 1) & 1
+	extraBits := uint(high - 1)
+	return 2*high + second, extraBits, uint32(v) & (1<<extraBits - 1)
+}
+
+// prefixCode holds the bit reversed canonical codes of an alphabet, symbols
+// of a code with a single symbol take no bits
+type prefixCode struct {
+	lengths []int
+	codes   []uint32
+}
+
+func (c *prefixCode) write(bw *bitWriter, symbol int) {
+	bw.write(c.codes[symbol], uint(c.lengths[symbol]))
+}
+
+// writePrefixCode picks a code for the symbol counts and writes it, as a
+// simple code when at most two symbols below 256 are used
+func writePrefixCode(bw *bitWriter, counts []int) *prefixCode {
+	used := make([]int, 0, 2)
+	for symbol, count := range counts {
+		if count > 0 {
+			used = append(used, symbol)
+		}
+	}
+
+	if len(used) <= 2 && (len(used) == 0 || used[len(used)-1] < 256) {
+		if len(used) == 0 {
+			used = append(used, 0)
+		}
+		code := &prefixCode{lengths: make([]int, len(counts)), codes: make([]uint32, len(counts))}
+		bw.write(1, 1)
+		bw.write(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(used[0]), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(used[0]), 8)
+		}
+		if len(used) == 2 {
+			bw.write(uint32(used[1]), 8)
+			code.lengths[used[0]], code.lengths[used[1]] = 1, 1
+			code.codes[used[1]] = 1
+		}
+		return code
+	}
+
+	lengths := codeLengths(counts, maxCodeLength)
+	bw.write(0, 1)
+	writeCodeLengths(bw, lengths)
+	return &prefixCode{lengths: lengths, codes: canonicalCodes(lengths)}
+}
+
+// writeCodeLengths writes the code lengths of a normal code, themselves
+// prefix coded, with runs of zeros shortened
+func writeCodeLengths(bw *bitWriter, lengths []int) {
+	type run struct {
+		symbol int
+		extra  uint32
+	}
+	runs := make([]run, 0, len(lengths))
+	for i := 0; i < len(lengths); {
+		if lengths[i] != 0 {
+			runs = append(runs, run{symbol: lengths[i]})
+			i++
+			continue
+		}
+
+		zeros := 0
+		for i+zeros < len(lengths) && lengths[i+zeros] == 0 {
+			zeros++
+		}
+		i += zeros
+		for zeros >= 11 {
+			n := min(zeros, 138)
+			runs = append(runs, run{symbol: 18, extra: uint32(n - 11)})
+			zeros -= n
+		}
+		if zeros >= 3 {
+			runs = append(runs, run{symbol: 17, extra: uint32(zeros - 3)})
+			zeros = 0
+		}
+		for ; zeros > 0; zeros-- {
+			runs = append(runs, run{symbol: 0})
+		}
+	}
+
+	counts := make([]int, numCodeLengthCodes)
+	for _, r := range runs {
+		counts[r.symbol]++
+	}
+	codeLengthLengths := codeLengths(counts, maxCodeLengthCodeLength)
+	codeLengthCodes := canonicalCodes(codeLengthLengths)
+
+	written := numCodeLengthCodes
+	for written > 4 && codeLengthLengths[codeLengthOrder[written-1]] == 0 {
+		written--
+	}
+	bw.write(uint32(written-4), 4)
+	for _, symbol := range codeLengthOrder[:written] {
+		bw.write(uint32(codeLengthLengths[symbol]), 3)
+	}
+	// the lengths of the whole alphabet follow
+	bw.write(0, 1)
+
+	for _, r := range runs {
+		bw.write(codeLengthCodes[r.symbol], uint(codeLengthLengths[r.symbol]))
+		switch r.symbol {
+		case 17:
+			bw.write(r.extra, 3)
+		case 18:
+			bw.write(r.extra, 7)
+		}
+	}
+}
+
+// codeLengths builds a Huffman code for the counts no longer than limit,
+// flattening the counts until it fits. At least two symbols get a code, a
+// code with one symbol of length one would be read as taking no bits.
+func codeLengths(counts []int, limit int) []int {
+	counts = slices.Clone(counts)
+	used := 0
+	for _, count := range counts {
+		if count > 0 {
+			used++
+		}
+	}
+	for symbol := 0; used < 2; symbol++ {
+		if counts[symbol] == 0 {
+			counts[symbol] = 1
+			used++
+		}
+	}
+
+	for {
+		lengths := huffmanLengths(counts)
+		if slices.Max(lengths) <= limit {
+			return lengths
+		}
+		for i, count := range counts {
+			if count > 0 {
+				counts[i] = (count + 1) / 2
+			}
+		}
+	}
+}
+
+// huffmanLengths are the depths of the symbols in a Huffman tree, built with
+// the two queue method over the leaves sorted by count
+func huffmanLengths(counts []int) []int {
+	leaves := make([]int, 0, len(counts))
+	for symbol, count := range counts {
+		if count > 0 {
+			leaves = append(leaves, symbol)
+		}
+	}
+	sort.SliceStable(leaves, func(i, j int) bool { return counts[leaves[i]] < counts[leaves[j]] })
+
+	// nodes are the leaves followed by the internal nodes in the order they
+	// are made, so parents always come after their children
+	n := len(leaves)
+	weights := make([]int, n, 2*n-1)
+	for i, symbol := range leaves {
+		weights[i] = counts[symbol]
+	}
+	parents := make([]int, 2*n-1)
+	nextLeaf, nextInternal := 0, n
+	smallest := func() int {
+		if nextLeaf < n && (nextInternal >= len(weights) || weights[nextLeaf] <= weights[nextInternal]) {
+			nextLeaf++
+			return nextLeaf - 1
+		}
+		nextInternal++
+		return nextInternal - 1
+	}
+	for len(weights) < 2*n-1 {
+		a, b := smallest(), smallest()
+		parents[a], parents[b] = len(weights), len(weights)
+		weights = append(weights, weights[a]+weights[b])
+	}
+
+	depths := make([]int, 2*n-1)
+	for i := 2*n - 3; i >= 0; i-- {
+		depths[i] = depths[parents[i]] + 1
+	}
+	lengths := make([]int, len(counts))
+	for i, symbol := range leaves {
+		lengths[symbol] = depths[i]
+	}
+
+	return lengths
+}
+
+// canonicalCodes assigns codes in order of length and then symbol, bit
+// reversed since the stream is read from the lowest bit
+func canonicalCodes(lengths []int) []uint32 {
+	var count, next [maxCodeLength + 1]int
+	for _, length := range lengths {
+		if length > 0 {
+			count[length]++
+		}
+	}
+	code := 0
+	for length := 1; length <= maxCodeLength; length++ {
+		code = (code + count[length-1]) << 1
+		next[length] = code
+	}
+
+	codes := make([]uint32, len(lengths))
+	for symbol, length := range lengths {
+		if length == 0 {
+			continue
+		}
+		codes[symbol] = uint32(bits.Reverse32(uint32(next[length])) >> (32 - length))
+		next[length]++
+	}
+
+	return codes
+}
+
+// bitWriter packs values from the lowest bit up
+type bitWriter struct {
+	buf  []byte
+	acc  uint64
+	bits uint
+}
+
+func (w *bitWriter) write(value uint32, bits uint) {
+	w.acc |= uint64(value) << w.bits
+	w.bits += bits
+	for w.bits >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.bits -= 8
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.bits > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.bits = 0, 0
+	}
+	return w.buf
+}
+
+func boolBit(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,78 @@
+package webp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	xwebp "golang.org/x/image/webp"
+)
+
+func TestEncode(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	tests := []struct {
+		name  string
+		width int
+		fill  func(x, y int) color.NRGBA
+	}{
+		{"single pixel", 1, func(x, y int) color.NRGBA { return color.NRGBA{10, 20, 30, 255} }},
+		{"flat", 37, func(x, y int) color.NRGBA { return color.NRGBA{200, 100, 50, 255} }},
+		{"gradient", 64, func(x, y int) color.NRGBA { return color.NRGBA{uint8(x * 4), uint8(y * 4), uint8(x + y), 255} }},
+		{"noise", 50, func(x, y int) color.NRGBA {
+			return color.NRGBA{uint8(random.Intn(256)), uint8(random.Intn(256)), uint8(random.Intn(256)), uint8(random.Intn(256))}
+		}},
+		{"thumbnail", 120, func(x, y int) color.NRGBA {
+			if (x-60)*(x-60)+(y-60)*(y-60) > 40*40 {
+				return color.NRGBA{}
+			}
+			return color.NRGBA{uint8(100 + x/4), 80, uint8(200 - y/4), 255}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			height := tt.width/2 + 1
+			img := image.NewNRGBA(image.Rect(0, 0, tt.width, height))
+			for y := 0; y < height; y++ {
+				for x := 0; x < tt.width; x++ {
+					img.SetNRGBA(x, y, tt.fill(x, y))
+				}
+			}
+
+			out := &bytes.Buffer{}
+			if err := Encode(out, img); err != nil {
+				t.Fatal(err)
+			}
+			// an independent decoder checks the files are valid webp
+			decoded, err := xwebp.Decode(bytes.NewReader(out.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decoded.Bounds() != img.Bounds() {
+				t.Fatalf("expected %v, got %v", img.Bounds(), decoded.Bounds())
+			}
+			for y := 0; y < height; y++ {
+				for x := 0; x < tt.width; x++ {
+					want, got := img.NRGBAAt(x, y), color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+					if want.A == 0 {
+						want = color.NRGBA{}
+					}
+					if want != got {
+						t.Fatalf("pixel %d,%d: expected %v, got %v", x, y, want, got)
+					}
+				}
+			}
+			if tt.name == "thumbnail" && out.Len() > len(img.Pix)/8 {
+				t.Errorf("expected flat areas to compress, got %d bytes for %d pixels", out.Len(), len(img.Pix)/4)
+			}
+		})
+	}
+}
+
+func TestEncodeRejectsLargeImages(t *testing.T) {
+	if err := Encode(&bytes.Buffer{}, image.NewNRGBA(image.Rect(0, 0, maxDimension+1, 1))); err == nil {
+		t.Error("expected an error")
+	}
+}
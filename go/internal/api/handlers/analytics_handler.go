@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/api/middleware"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AnalyticsHandler struct {
+	analyticsService services.AnalyticsService
+}
+
+// TrackEventRequest is one thing a guest did on the menu page, item opens carry
+// the item and AR launches the model
+type TrackEventRequest struct {
+	Type    models.AnalyticsEventType `json:"type" binding:"required,oneof=menu_view item_open ar_launch"`
+	ItemID  *uuid.UUID                `json:"itemId"`
+	ModelID *uuid.UUID                `json:"modelId"`
+}
+
+// TrackEventsRequest represents the request body the public menu page sends
+// its events with. The table token comes from the table QR code.
+type TrackEventsRequest struct {
+	MenuID     uuid.UUID              `json:"menuId" binding:"required"`
+	Source     models.AnalyticsSource `json:"source" binding:"omitempty,oneof=qr table link direct"`
+	TableToken string                 `json:"tableToken" binding:"omitempty,max=64"`
+	Events     []*TrackEventRequest   `json:"events" binding:"required,min=1,max=50,dive"`
+}
+
+func NewAnalyticsHandler(analyticsService services.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		analyticsService: analyticsService,
+	}
+}
+
+// RegisterRoutes registers the public event endpoint, it gets a tighter rate
+// limit than the rest of the API since anyone can call it
+func (h *AnalyticsHandler) RegisterRoutes(v1 *gin.RouterGroup) {
+	v1.POST("/events", middleware.RateLimit(5, 20), h.TrackEvents)
+}
+
+// @Summary Track menu events
+// @Description Record menu views, item detail opens and AR launches from the public menu page. Events are counted in memory and stored in batches.
+// @Tags analytics
+// @Accept json
+// @Param request body TrackEventsRequest true "Events"
+// @Success 202
+// @Failure 400 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Router /events [post]
+func (h *AnalyticsHandler) TrackEvents(c *gin.Context) {
+	var req TrackEventsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	batch := &models.AnalyticsBatch{
+		MenuID:     req.MenuID,
+		Source:     req.Source,
+		TableToken: req.TableToken,
+		UserAgent:  c.Request.UserAgent(),
+		Events:     make([]*models.AnalyticsEvent, 0, len(req.Events)),
+	}
+	for _, event := range req.Events {
+		batch.Events = append(batch.Events, &models.AnalyticsEvent{Type: event.Type, ItemID: event.ItemID, ModelID: event.ModelID})
+	}
+
+	if err := h.analyticsService.Track(c.Request.Context(), batch); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/services/impl"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestTrackEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &memoryAnalyticsRepository{}
+	service := impl.NewAnalyticsService(repo)
+
+	router := gin.New()
+	NewAnalyticsHandler(service).RegisterRoutes(router.Group("/api/v1"))
+
+	post := func(value any) int {
+		body, contentType := jsonBody(value)()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/events", body)
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	menuID, modelID := uuid.New(), uuid.New()
+	if code := post(map[string]any{
+		"menuId": menuID,
+		"source": "qr",
+		"events": []map[string]any{{"type": "menu_view"}, {"type": "ar_launch", "modelId": modelID}},
+	}); code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", code)
+	}
+
+	for _, invalid := range []map[string]any{
+		{"menuId": menuID, "events": []map[string]any{}},
+		{"menuId": menuID, "source": "email", "events": []map[string]any{{"type": "menu_view"}}},
+		{"menuId": menuID, "events": []map[string]any{{"type": "ar_launch"}}},
+		{"events": []map[string]any{{"type": "menu_view"}}},
+	} {
+		if code := post(invalid); code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %v, got %d", invalid, code)
+		}
+	}
+
+	if err := service.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var views, launches int64
+	for _, counter := range repo.counters {
+		if counter.MenuID != menuID || counter.Source != models.AnalyticsSourceQR {
+			t.Errorf("unexpected counter %+v", counter)
+		}
+		switch counter.Type {
+		case models.AnalyticsEventMenuView:
+			views += counter.Count
+		case models.AnalyticsEventARLaunch:
+			launches += counter.Count
+		}
+	}
+	if views != 1 || launches != 1 {
+		t.Errorf("expected one view and one launch, got %d and %d", views, launches)
+	}
+}
+
+type memoryAnalyticsRepository struct {
+	counters []*models.AnalyticsCounter
+}
+
+func (r *memoryAnalyticsRepository) IncrementCounters(ctx context.Context, counters []*models.AnalyticsCounter) error {
+	r.counters = append(r.counters, counters...)
+	return nil
+}
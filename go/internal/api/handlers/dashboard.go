@@ -1,96 +1,98 @@
 package handlers
 
 import (
-	"context"
 	"net/http"
 	"time"
 
-	"github.com/ahmetkoprulu/bidi-menu/common/data"
+	"github.com/ahmetkoprulu/bidi-menu/internal/api/middleware"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/services"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-type DashboardResponse struct {
-	Stats struct {
-		TotalMenus  int64 `json:"totalMenus"`
-		ActiveMenus int64 `json:"activeMenus"`
-		TotalViews  int64 `json:"totalViews"` // Will be implemented later
-	} `json:"stats"`
-	RecentMenus []RecentMenu `json:"recentMenus"`
-}
-
-type RecentMenu struct {
-	ID        string    `json:"id"`
-	Label     string    `json:"label"`
-	UpdatedAt time.Time `json:"updatedAt"`
-	Views     int64     `json:"views"` // Will be implemented later
+// DashboardRequest selects the dashboard period, the dates are YYYY-MM-DD in
+// the client's timezone and default to the last 30 days
+type DashboardRequest struct {
+	ClientID string `form:"clientId"`
+	From     string `form:"from"`
+	To       string `form:"to"`
+	Interval string `form:"interval" binding:"omitempty,oneof=hour day week"`
 }
 
 type DashboardHandler struct {
-	db *data.PgDbContext
+	dashboardService services.DashboardService
 }
 
-func NewDashboardHandler(db *data.PgDbContext) *DashboardHandler {
-	return &DashboardHandler{db: db}
+func NewDashboardHandler(dashboardService services.DashboardService) *DashboardHandler {
+	return &DashboardHandler{dashboardService: dashboardService}
 }
 
 func (h *DashboardHandler) RegisterRoutes(router *gin.RouterGroup) {
 	router.GET("/dashboard", h.GetDashboardData)
 }
 
+// @Summary Get dashboard
+// @Description Get menu views, item opens and AR launches of a period as a series, with the top items and AR models, the view to AR conversion and the change against the period before
+// @Tags dashboard
+// @Produce json
+// @Param clientId query string false "Client ID, the caller's client by default"
+// @Param from query string false "First day, YYYY-MM-DD"
+// @Param to query string false "Last day, YYYY-MM-DD"
+// @Param interval query string false "Series interval" Enums(hour, day, week)
+// @Success 200 {object} models.Dashboard
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /dashboard [get]
+// @Security Bearer
 func (h *DashboardHandler) GetDashboardData(c *gin.Context) {
-	var response DashboardResponse
+	var req DashboardRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
 
-	// Get client ID from token
-	clientID := c.MustGet("clientID").(uuid.UUID)
+	tenant := middleware.GetTenant(c)
+	clientID := tenant.ClientID
+	if req.ClientID != "" {
+		id, err := uuid.Parse(req.ClientID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid clientId"})
+			return
+		}
+		clientID = id
+	}
 
-	// Get total menus count for the client
-	err := h.db.QueryRow(context.Background(),
-		"SELECT COUNT(*) FROM menus WHERE client_id = $1",
-		clientID).Scan(&response.Stats.TotalMenus)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get total menus count"})
+	query := &models.DashboardQuery{Interval: models.AnalyticsInterval(req.Interval)}
+	var err error
+	if query.From, err = parseDate(req.From); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid from, expected YYYY-MM-DD"})
+		return
+	}
+	if query.To, err = parseDate(req.To); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid to, expected YYYY-MM-DD"})
 		return
 	}
 
-	// Get active menus count for the client
-	err = h.db.QueryRow(context.Background(),
-		"SELECT COUNT(*) FROM menus WHERE client_id = $1 ", // AND is_active = true
-		clientID).Scan(&response.Stats.ActiveMenus)
+	dashboard, err := h.dashboardService.GetDashboard(c.Request.Context(), tenant, clientID, query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get active menus count"})
+		respondError(c, err, "client not found")
 		return
 	}
 
-	// Set total views to 0 for now
-	response.Stats.TotalViews = 0
+	c.JSON(http.StatusOK, dashboard)
+}
 
-	// Get recent menus for the client
-	rows, err := h.db.Query(context.Background(), `
-		SELECT id, label, updated_at 
-		FROM menus 
-		WHERE client_id = $1
-		ORDER BY updated_at DESC 
-		LIMIT 5
-	`, clientID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get recent menus"})
-		return
+// parseDate parses an optional YYYY-MM-DD date
+func parseDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
 	}
-	defer rows.Close()
 
-	var recentMenus []RecentMenu
-	for rows.Next() {
-		var menu RecentMenu
-		err := rows.Scan(&menu.ID, &menu.Label, &menu.UpdatedAt)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan recent menu"})
-			return
-		}
-		menu.Views = 0 // Will be implemented later
-		recentMenus = append(recentMenus, menu)
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
 	}
 
-	response.RecentMenus = recentMenus
-	c.JSON(http.StatusOK, response)
+	return &date, nil
 }
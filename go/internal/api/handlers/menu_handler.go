@@ -1,48 +1,118 @@
 package handlers
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 
+	"github.com/ahmetkoprulu/bidi-menu/common/qrcode"
 	"github.com/ahmetkoprulu/bidi-menu/internal/api/middleware"
 	"github.com/ahmetkoprulu/bidi-menu/internal/models"
 	"github.com/ahmetkoprulu/bidi-menu/internal/services"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"golang.org/x/text/language"
 )
 
 type MenuHandler struct {
 	menuService services.MenuService
+	scanService services.ScanService
+}
+
+// ExportQRCodeRequest represents the query of a QR code export, width is in
+// millimeters and the quiet zone in modules
+type ExportQRCodeRequest struct {
+	Format    string  `form:"format" binding:"omitempty,oneof=png svg pdf"`
+	DPI       int     `form:"dpi" binding:"omitempty,min=72,max=1200"`
+	Width     float64 `form:"width" binding:"omitempty,min=10,max=1000"`
+	QuietZone *int    `form:"quietZone" binding:"omitempty,min=0,max=16"`
+	Caption   string  `form:"caption" binding:"omitempty,max=60"`
 }
 
 // CreateCategoryRequest represents the request body for creating a category
 type CreateCategoryRequest struct {
-	Name string `json:"name" binding:"required"`
+	Name         string              `json:"name" binding:"required"`
+	Status       models.MenuStatus   `json:"status" binding:"omitempty,oneof=active inactive"`
+	Translations models.Translations `json:"translations"`
+}
+
+// UpdateCategoryRequest represents the request body for renaming a category,
+// the translated names are replaced as a whole
+type UpdateCategoryRequest struct {
+	Name         string              `json:"name" binding:"required"`
+	Translations models.Translations `json:"translations"`
 }
 
 // UpdateCategoryOrderRequest represents the request body for updating category order
 type UpdateCategoryOrderRequest struct {
-	Order int `json:"order" binding:"required"`
+	Order int `json:"order" binding:"min=0"`
+}
+
+// MenuItemOptionsRequest holds the choices of a menu item. With variants the
+// item's price is the price of the default variant.
+type MenuItemOptionsRequest struct {
+	Variants       []*models.MenuItemVariant `json:"variants" binding:"omitempty,max=20"`
+	ModifierGroups []*models.ModifierGroup   `json:"modifierGroups" binding:"omitempty,max=20"`
+}
+
+// MenuItemFactsRequest is the dietary information of a menu item, allergens
+// are the 14 EU allergens and the spice level runs from 0 to 3
+type MenuItemFactsRequest struct {
+	Allergens   []models.Allergen      `json:"allergens" binding:"omitempty,max=14,dive,oneof=gluten crustaceans eggs fish peanuts soybeans milk nuts celery mustard sesame sulphites lupin molluscs"`
+	DietaryTags []models.DietaryTag    `json:"dietaryTags" binding:"omitempty,max=4,dive,oneof=vegan vegetarian halal gluten_free"`
+	SpiceLevel  int                    `json:"spiceLevel" binding:"min=0,max=3"`
+	Calories    *int                   `json:"calories" binding:"omitempty,min=0"`
+	Nutrition   *models.NutritionFacts `json:"nutrition"`
 }
 
 // CreateMenuItemRequest represents the request body for creating a menu item
 type CreateMenuItemRequest struct {
-	CategoryID  uuid.UUID `json:"categoryId" binding:"required"`
-	Name        string    `json:"name" binding:"required"`
-	Description string    `json:"description"`
-	Price       float64   `json:"price" binding:"required"`
+	CategoryID   uuid.UUID           `json:"categoryId" binding:"required"`
+	Name         string              `json:"name" binding:"required"`
+	Description  string              `json:"description"`
+	Price        float64             `json:"price" binding:"min=0"`
+	Images       []string            `json:"images"`
+	ModelID      *uuid.UUID          `json:"modelId"`
+	Status       models.MenuStatus   `json:"status" binding:"omitempty,oneof=active inactive"`
+	Translations models.Translations `json:"translations"`
+	MenuItemOptionsRequest
+	MenuItemFactsRequest
 }
 
-// UpdateMenuItemRequest represents the request body for updating a menu item
+// UpdateMenuItemRequest represents the request body for updating a menu item,
+// the variants, modifier groups, dietary information and translations are
+// replaced as a whole
 type UpdateMenuItemRequest struct {
-	Name        string  `json:"name" binding:"required"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price" binding:"required"`
+	Name         string              `json:"name" binding:"required"`
+	Description  string              `json:"description"`
+	Price        float64             `json:"price" binding:"min=0"`
+	ModelID      *uuid.UUID          `json:"modelId"`
+	Translations models.Translations `json:"translations"`
+	MenuItemOptionsRequest
+	MenuItemFactsRequest
+}
+
+// MenuFilterRequest narrows the public menu down to the items a guest can
+// eat. Exclude and diet are comma separated allergens and dietary tags.
+type MenuFilterRequest struct {
+	Exclude     string `form:"exclude"`
+	Diet        string `form:"diet"`
+	MaxSpice    *int   `form:"maxSpice" binding:"omitempty,min=0,max=3"`
+	MaxCalories *int   `form:"maxCalories" binding:"omitempty,min=0"`
+	// Lang picks the language of the menu over the Accept-Language header
+	Lang string `form:"lang"`
+}
+
+// MoveMenuItemRequest represents the request body for moving a menu item to a
+// position in a category of the same menu
+type MoveMenuItemRequest struct {
+	CategoryID uuid.UUID `json:"categoryId" binding:"required"`
+	Order      int       `json:"order" binding:"min=0"`
 }
 
 // UpdateItemImagesRequest represents the request body for updating item images
@@ -54,64 +124,142 @@ type UpdateItemImagesRequest struct {
 type ReorderCategoriesRequest struct {
 	Categories []struct {
 		ID    uuid.UUID `json:"id" binding:"required"`
-		Order int       `json:"order" binding:"required"`
-	} `json:"categories" binding:"required"`
+		Order int       `json:"order" binding:"min=0"`
+	} `json:"categories" binding:"required,min=1,dive"`
 }
 
 // UpdateCategoryStatusRequest represents the request body for updating category status
 type UpdateCategoryStatusRequest struct {
-	Status models.MenuStatus `json:"status" binding:"required"`
+	Status models.MenuStatus `json:"status" binding:"required,oneof=active inactive"`
 }
 
 // UpdateItemsStatusRequest represents the request body for updating items status
 type UpdateItemsStatusRequest struct {
-	ItemIDs []uuid.UUID       `json:"itemIds" binding:"required"`
-	Status  models.MenuStatus `json:"status" binding:"required"`
+	ItemIDs []uuid.UUID       `json:"itemIds" binding:"required,min=1"`
+	Status  models.MenuStatus `json:"status" binding:"required,oneof=active inactive"`
 }
 
-// ScanMenuResponse represents the response for menu scanning
-type ScanMenuResponse struct {
-	Menu   *models.Menu `json:"menu"`
-	Errors []string     `json:"errors,omitempty"`
+// ScanJobResponse represents the response for an enqueued menu scan. The job is
+// polled through GET /menu/scan/:jobId until it is completed or failed.
+type ScanJobResponse struct {
+	JobID  uuid.UUID            `json:"jobId"`
+	Status models.ScanJobStatus `json:"status"`
+	Errors []string             `json:"errors,omitempty"`
 }
 
-func NewMenuHandler(menuService services.MenuService) *MenuHandler {
+func NewMenuHandler(menuService services.MenuService, scanService services.ScanService) *MenuHandler {
 	return &MenuHandler{
 		menuService: menuService,
+		scanService: scanService,
 	}
 }
 
 // RegisterRoutes registers all routes for menu operations
 func (h *MenuHandler) RegisterRoutes(router *gin.RouterGroup, v1 *gin.RouterGroup) {
 	v1.GET("/menu/:id", h.GetMenuById)
+	v1.GET("/locales", h.GetLocales)
 
 	menu := router.Group("/menu")
 	{
 		menu.POST("", h.CreateMenu)
 		menu.POST("/scan", h.ScanMenu)
+		menu.GET("/scan/:jobId", h.GetScanJob)
 		menu.GET("", h.GetMenu)
 		menu.DELETE("/:id", h.DeleteMenu)
+
+		menu.GET("/:id/draft", h.GetDraftMenu)
+		menu.GET("/:id/translations/missing", h.GetMissingTranslations)
+		menu.GET("/:id/qr/export", h.ExportQRCode)
+		menu.POST("/:id/publish", h.PublishMenu)
+		menu.GET("/:id/versions", h.GetMenuVersions)
+		menu.GET("/:id/versions/diff", h.DiffMenuVersions)
+		menu.GET("/:id/versions/:version", h.GetMenuVersion)
+		menu.POST("/:id/versions/:version/rollback", h.RollbackMenu)
+
+		menu.POST("/:id/categories", h.CreateCategory)
+		menu.PUT("/categories/order", h.ReorderCategories)
+		menu.PUT("/categories/:categoryId", h.UpdateCategory)
+		menu.PUT("/categories/:categoryId/order", h.UpdateCategoryOrder)
+		menu.PUT("/categories/:categoryId/status", h.UpdateCategoryStatus)
+		menu.DELETE("/categories/:categoryId", h.DeleteCategory)
+
+		menu.POST("/items", h.CreateMenuItem)
+		menu.PUT("/items/status", h.UpdateItemsStatus)
+		menu.PUT("/items/:itemId", h.UpdateMenuItem)
+		menu.PUT("/items/:itemId/move", h.MoveMenuItem)
+		menu.PUT("/items/:itemId/images", h.UpdateItemImages)
+		menu.DELETE("/items/:itemId", h.DeleteMenuItem)
 	}
 }
 
 func (h *MenuHandler) DeleteMenu(c *gin.Context) {
-	id := c.Param("id")
-	err := h.menuService.DeleteMenu(c.Request.Context(), uuid.MustParse(id))
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid menu id"})
+		return
+	}
+
+	err = h.menuService.DeleteMenu(c.Request.Context(), middleware.GetTenant(c), id)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "menu not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
+
+	c.Status(http.StatusNoContent)
 }
 
+// @Summary Get published menu
+// @Description Get the published version of a menu for guests, optionally only with the items that fit their diet. The menu is served in the first locale of lang or Accept-Language it is offered in, or else in its default locale, texts that are not translated stay in the default locale.
+// @Tags menu
+// @Produce json
+// @Param id path string true "Menu ID"
+// @Param lang query string false "Locale such as tr, en, de, ar or he, takes precedence over Accept-Language"
+// @Param Accept-Language header string false "Preferred locales"
+// @Param exclude query string false "Comma separated allergens the items must not contain"
+// @Param diet query string false "Comma separated dietary tags the items must all have"
+// @Param maxSpice query int false "Highest spice level, 0 to 3"
+// @Param maxCalories query int false "Most calories, items without calories are kept"
+// @Success 200 {object} models.Menu
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/{id} [get]
 func (h *MenuHandler) GetMenuById(c *gin.Context) {
-	id := c.Param("id")
-	menu, err := h.menuService.GetMenuById(c.Request.Context(), uuid.MustParse(id))
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid menu id"})
+		return
+	}
+
+	var req MenuFilterRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	filter, err := req.toFilter()
 	if err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, menu)
+	// guests only ever see the published version
+	menu, err := h.menuService.GetPublishedMenu(c.Request.Context(), id)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "menu not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	locale := negotiateLocale(menu, req.Lang, c.GetHeader("Accept-Language"))
+	c.Header("Content-Language", locale)
+	c.Header("Vary", "Accept-Language")
+	c.JSON(http.StatusOK, menu.Localize(locale).FilterItems(filter))
 }
 
 func (h *MenuHandler) CreateMenu(c *gin.Context) {
@@ -121,15 +269,12 @@ func (h *MenuHandler) CreateMenu(c *gin.Context) {
 		return
 	}
 
-	clientID := c.MustGet(middleware.ClientIDKey).(uuid.UUID)
-	roles := c.MustGet(middleware.UserRoleKey).([]string)
-
-	if !slices.Contains(roles, "admin") && clientID != model.ClientID {
-		c.JSON(http.StatusForbidden, ErrorResponse{Error: "you are not authorized to create a menu"})
+	// menus of other clients are reported as missing
+	menuID, err := h.menuService.SaveMenu(c.Request.Context(), middleware.GetTenant(c), &model)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "menu not found"})
 		return
 	}
-
-	menuID, err := h.menuService.SaveMenu(context.Background(), model)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
@@ -149,7 +294,7 @@ func (h *MenuHandler) CreateMenu(c *gin.Context) {
 // @Router /menu [get]
 // @Security Bearer
 func (h *MenuHandler) GetMenu(c *gin.Context) {
-	clientID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	clientID := middleware.GetTenant(c).ClientID
 	menu, err := h.menuService.GetMenu(c.Request.Context(), clientID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
@@ -160,17 +305,17 @@ func (h *MenuHandler) GetMenu(c *gin.Context) {
 }
 
 // @Summary Scan menu images
-// @Description Upload and scan menu images to extract menu items
+// @Description Upload menu images and enqueue a job that extracts the menu items
 // @Tags menu
 // @Accept multipart/form-data
 // @Produce json
-// @Param images formData file true "Menu images to scan (multiple files allowed)"
-// @Success 200 {object} ScanMenuResponse
+// @Param images formData file true "Menu images or pdf files to scan (multiple files allowed)"
+// @Success 202 {object} ScanJobResponse
 // @Failure 400 {object} ErrorResponse
 // @Router /menu/scan [post]
 // @Security Bearer
 func (h *MenuHandler) ScanMenu(c *gin.Context) {
-	clientID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	clientID := middleware.GetTenant(c).ClientID
 
 	// Get uploaded files
 	form, err := c.MultipartForm()
@@ -185,11 +330,18 @@ func (h *MenuHandler) ScanMenu(c *gin.Context) {
 		return
 	}
 
+	// Files are kept until the scan worker has processed them
+	uploadDir, err := h.scanService.CreateUploadDir()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to prepare upload directory"})
+		return
+	}
+
 	// Validate file types
 	var imagePaths []string
 	var uploadErrors []string
 
-	for _, file := range files {
+	for i, file := range files {
 		// Check file extension
 		ext := filepath.Ext(file.Filename)
 		if !isAllowedImageType(ext) {
@@ -198,40 +350,819 @@ func (h *MenuHandler) ScanMenu(c *gin.Context) {
 			continue
 		}
 
-		// Save file temporarily
-		tempPath := filepath.Join(os.TempDir(), uuid.New().String()+ext)
-		if err := c.SaveUploadedFile(file, tempPath); err != nil {
+		// Prefix with the index so pages keep their upload order
+		path := filepath.Join(uploadDir, fmt.Sprintf("%03d%s", i, strings.ToLower(ext)))
+		if err := c.SaveUploadedFile(file, path); err != nil {
 			uploadErrors = append(uploadErrors,
 				fmt.Sprintf("failed to save file %s: %v", file.Filename, err))
 			continue
 		}
 
-		imagePaths = append(imagePaths, tempPath)
-		// Defer cleanup of temporary files
-		defer os.Remove(tempPath)
+		imagePaths = append(imagePaths, path)
 	}
 
 	if len(imagePaths) == 0 {
+		os.RemoveAll(uploadDir)
 		c.JSON(http.StatusBadRequest, ErrorResponse{
 			Error: "no valid images were uploaded: " + strings.Join(uploadErrors, "; "),
 		})
 		return
 	}
 
-	// Process the images
-	menu, err := h.menuService.ScanMenu(c.Request.Context(), clientID, imagePaths)
+	job, err := h.scanService.EnqueueScan(c.Request.Context(), clientID, uploadDir, imagePaths)
 	if err != nil {
-		uploadErrors = append(uploadErrors, fmt.Sprintf("menu scanning failed: %v", err))
+		os.RemoveAll(uploadDir)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
 	}
 
-	// Return response with any errors that occurred during processing
-	c.JSON(http.StatusOK, ScanMenuResponse{
-		Menu:   menu,
+	c.JSON(http.StatusAccepted, ScanJobResponse{
+		JobID:  job.ID,
+		Status: job.Status,
 		Errors: uploadErrors,
 	})
 }
 
-// isAllowedImageType checks if the file extension is allowed
+// @Summary Get menu scan job
+// @Description Get the status, progress and, once completed, the result of a menu scan
+// @Tags menu
+// @Produce json
+// @Param jobId path string true "Scan job ID"
+// @Success 200 {object} models.ScanJob
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/scan/{jobId} [get]
+// @Security Bearer
+func (h *MenuHandler) GetScanJob(c *gin.Context) {
+	clientID := middleware.GetTenant(c).ClientID
+	jobID, err := uuid.Parse(c.Param("jobId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid job id"})
+		return
+	}
+
+	job, err := h.scanService.GetScanJob(c.Request.Context(), clientID, jobID)
+	if err != nil {
+		respondError(c, err, "scan job not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// @Summary Create category
+// @Description Append a category to the end of a menu
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Param id path string true "Menu ID"
+// @Param request body CreateCategoryRequest true "Category"
+// @Success 201 {object} models.MenuCategory
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/{id}/categories [post]
+// @Security Bearer
+func (h *MenuHandler) CreateCategory(c *gin.Context) {
+	menuID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	category := models.MenuCategory{
+		MenuID:       menuID,
+		Name:         req.Name,
+		Status:       req.Status,
+		MenuItems:    make([]*models.MenuCategoryItem, 0),
+		Translations: req.Translations,
+	}
+	if category.Status == "" {
+		category.Status = models.MenuStatusActive
+	}
+
+	if err := h.menuService.CreateCategory(c.Request.Context(), middleware.GetTenant(c), &category); err != nil {
+		respondError(c, err, "menu not found")
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+// @Summary Rename category
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Param categoryId path string true "Category ID"
+// @Param request body UpdateCategoryRequest true "Category"
+// @Success 200 {object} models.MenuCategory
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/categories/{categoryId} [put]
+// @Security Bearer
+func (h *MenuHandler) UpdateCategory(c *gin.Context) {
+	categoryID, ok := parseIDParam(c, "categoryId")
+	if !ok {
+		return
+	}
+
+	var req UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	category := models.MenuCategory{ID: categoryID, Name: req.Name, Translations: req.Translations}
+	if err := h.menuService.UpdateCategory(c.Request.Context(), middleware.GetTenant(c), &category); err != nil {
+		respondError(c, err, "category not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// @Summary Move category
+// @Description Set the position of a category within its menu
+// @Tags menu
+// @Accept json
+// @Param categoryId path string true "Category ID"
+// @Param request body UpdateCategoryOrderRequest true "Position"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/categories/{categoryId}/order [put]
+// @Security Bearer
+func (h *MenuHandler) UpdateCategoryOrder(c *gin.Context) {
+	categoryID, ok := parseIDParam(c, "categoryId")
+	if !ok {
+		return
+	}
+
+	var req UpdateCategoryOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.menuService.UpdateCategoryOrder(c.Request.Context(), middleware.GetTenant(c), categoryID, req.Order); err != nil {
+		respondError(c, err, "category not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Reorder categories
+// @Description Set the positions of several categories at once, nothing changes if one of them is not found
+// @Tags menu
+// @Accept json
+// @Param request body ReorderCategoriesRequest true "Positions"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/categories/order [put]
+// @Security Bearer
+func (h *MenuHandler) ReorderCategories(c *gin.Context) {
+	var req ReorderCategoriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	categoryOrders := make(map[uuid.UUID]int, len(req.Categories))
+	for _, category := range req.Categories {
+		categoryOrders[category.ID] = category.Order
+	}
+
+	if err := h.menuService.ReorderCategories(c.Request.Context(), middleware.GetTenant(c), categoryOrders); err != nil {
+		respondError(c, err, "category not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Toggle category
+// @Description Activate or deactivate a category together with its items
+// @Tags menu
+// @Accept json
+// @Param categoryId path string true "Category ID"
+// @Param request body UpdateCategoryStatusRequest true "Status"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/categories/{categoryId}/status [put]
+// @Security Bearer
+func (h *MenuHandler) UpdateCategoryStatus(c *gin.Context) {
+	categoryID, ok := parseIDParam(c, "categoryId")
+	if !ok {
+		return
+	}
+
+	var req UpdateCategoryStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.menuService.UpdateCategoryStatus(c.Request.Context(), middleware.GetTenant(c), categoryID, req.Status); err != nil {
+		respondError(c, err, "category not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Delete category
+// @Description Delete a category and its items
+// @Tags menu
+// @Param categoryId path string true "Category ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/categories/{categoryId} [delete]
+// @Security Bearer
+func (h *MenuHandler) DeleteCategory(c *gin.Context) {
+	categoryID, ok := parseIDParam(c, "categoryId")
+	if !ok {
+		return
+	}
+
+	if err := h.menuService.DeleteCategory(c.Request.Context(), middleware.GetTenant(c), categoryID); err != nil {
+		respondError(c, err, "category not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Create menu item
+// @Description Append an item to the end of a category
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Param request body CreateMenuItemRequest true "Menu item"
+// @Success 201 {object} models.MenuCategoryItem
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/items [post]
+// @Security Bearer
+func (h *MenuHandler) CreateMenuItem(c *gin.Context) {
+	var req CreateMenuItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	item := models.MenuCategoryItem{
+		Name:         req.Name,
+		Description:  req.Description,
+		Price:        req.Price,
+		Images:       req.Images,
+		ModelID:      req.ModelID,
+		Status:       req.Status,
+		Translations: req.Translations,
+	}
+	item.Variants, item.ModifierGroups = req.Variants, req.ModifierGroups
+	req.MenuItemFactsRequest.apply(&item)
+
+	if err := h.menuService.CreateMenuItem(c.Request.Context(), middleware.GetTenant(c), req.CategoryID, &item); err != nil {
+		respondError(c, err, "category not found")
+		return
+	}
+
+	c.JSON(http.StatusCreated, item)
+}
+
+// @Summary Update menu item
+// @Description Change the name, description, prices, choices, model and dietary information of an item
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Param itemId path string true "Menu item ID"
+// @Param request body UpdateMenuItemRequest true "Menu item"
+// @Success 200 {object} models.MenuCategoryItem
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/items/{itemId} [put]
+// @Security Bearer
+func (h *MenuHandler) UpdateMenuItem(c *gin.Context) {
+	itemID, ok := parseIDParam(c, "itemId")
+	if !ok {
+		return
+	}
+
+	var req UpdateMenuItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	item := models.MenuCategoryItem{
+		ID:           itemID,
+		Name:         req.Name,
+		Description:  req.Description,
+		Price:        req.Price,
+		ModelID:      req.ModelID,
+		Translations: req.Translations,
+	}
+	item.Variants, item.ModifierGroups = req.Variants, req.ModifierGroups
+	req.MenuItemFactsRequest.apply(&item)
+
+	if err := h.menuService.UpdateMenuItem(c.Request.Context(), middleware.GetTenant(c), &item); err != nil {
+		respondError(c, err, "menu item not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+// @Summary Move menu item
+// @Description Move an item to a position in a category of the same menu
+// @Tags menu
+// @Accept json
+// @Param itemId path string true "Menu item ID"
+// @Param request body MoveMenuItemRequest true "Target category and position"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/items/{itemId}/move [put]
+// @Security Bearer
+func (h *MenuHandler) MoveMenuItem(c *gin.Context) {
+	itemID, ok := parseIDParam(c, "itemId")
+	if !ok {
+		return
+	}
+
+	var req MoveMenuItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.menuService.MoveMenuItem(c.Request.Context(), middleware.GetTenant(c), itemID, req.CategoryID, req.Order); err != nil {
+		respondError(c, err, "menu item not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Update menu item images
+// @Tags menu
+// @Accept json
+// @Param itemId path string true "Menu item ID"
+// @Param request body UpdateItemImagesRequest true "Images"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/items/{itemId}/images [put]
+// @Security Bearer
+func (h *MenuHandler) UpdateItemImages(c *gin.Context) {
+	itemID, ok := parseIDParam(c, "itemId")
+	if !ok {
+		return
+	}
+
+	var req UpdateItemImagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.menuService.UpdateItemImages(c.Request.Context(), middleware.GetTenant(c), itemID, req.Images); err != nil {
+		respondError(c, err, "menu item not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Toggle menu items
+// @Description Activate or deactivate several items at once
+// @Tags menu
+// @Accept json
+// @Param request body UpdateItemsStatusRequest true "Items and status"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/items/status [put]
+// @Security Bearer
+func (h *MenuHandler) UpdateItemsStatus(c *gin.Context) {
+	var req UpdateItemsStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.menuService.UpdateItemsStatus(c.Request.Context(), middleware.GetTenant(c), req.ItemIDs, req.Status); err != nil {
+		respondError(c, err, "menu items not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Delete menu item
+// @Tags menu
+// @Param itemId path string true "Menu item ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/items/{itemId} [delete]
+// @Security Bearer
+func (h *MenuHandler) DeleteMenuItem(c *gin.Context) {
+	itemID, ok := parseIDParam(c, "itemId")
+	if !ok {
+		return
+	}
+
+	if err := h.menuService.DeleteMenuItem(c.Request.Context(), middleware.GetTenant(c), itemID); err != nil {
+		respondError(c, err, "menu item not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Get draft menu
+// @Description Get the editable version of a menu, which may differ from the published one
+// @Tags menu
+// @Produce json
+// @Param id path string true "Menu ID"
+// @Success 200 {object} models.Menu
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/{id}/draft [get]
+// @Security Bearer
+func (h *MenuHandler) GetDraftMenu(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	menu, err := h.menuService.GetDraftMenu(c.Request.Context(), middleware.GetTenant(c), id)
+	if err != nil {
+		respondError(c, err, "menu not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, menu)
+}
+
+// @Summary List locales
+// @Description List the locales menus can be offered in with their script and text direction
+// @Tags menu
+// @Produce json
+// @Success 200 {array} models.LocaleInfo
+// @Router /locales [get]
+func (h *MenuHandler) GetLocales(c *gin.Context) {
+	c.JSON(http.StatusOK, models.SupportedLocaleInfos())
+}
+
+// @Summary List missing translations
+// @Description List the texts of the draft menu that have no translation in the other locales it is offered in
+// @Tags menu
+// @Produce json
+// @Param id path string true "Menu ID"
+// @Param locale query string false "Only list the texts missing in this locale"
+// @Success 200 {object} models.TranslationReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/{id}/translations/missing [get]
+// @Security Bearer
+func (h *MenuHandler) GetMissingTranslations(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	report, err := h.menuService.GetMissingTranslations(c.Request.Context(), middleware.GetTenant(c), id, c.Query("locale"))
+	if err != nil {
+		respondError(c, err, "menu not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Export menu QR code
+// @Description Download the menu's QR code for print as vector SVG, vector PDF or PNG at a DPI, with its customization and the client logo. Exports are cached and tagged with an ETag.
+// @Tags menu
+// @Produce image/png
+// @Produce image/svg+xml
+// @Produce application/pdf
+// @Param id path string true "Menu ID"
+// @Param format query string false "png, svg or pdf, png by default"
+// @Param dpi query int false "Resolution of png exports, 300 by default"
+// @Param width query number false "Printed width in millimeters including the quiet zone, 50 by default"
+// @Param quietZone query int false "Blank margin in modules, 4 by default"
+// @Param caption query string false "Caption under the code, such as Scan for AR menu"
+// @Success 200 {file} binary
+// @Success 304
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/{id}/qr/export [get]
+// @Security Bearer
+func (h *MenuHandler) ExportQRCode(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req ExportQRCodeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	opts := qrcode.ExportOptions{
+		Format:    req.Format,
+		DPI:       req.DPI,
+		WidthMM:   req.Width,
+		QuietZone: qrcode.DefaultQuietZone,
+		Caption:   req.Caption,
+	}
+	if req.QuietZone != nil {
+		opts.QuietZone = *req.QuietZone
+	}
+
+	export, err := h.menuService.ExportQRCode(c.Request.Context(), middleware.GetTenant(c), id, opts)
+	if err != nil {
+		respondError(c, err, "menu not found")
+		return
+	}
+
+	etag := `"` + export.ETag + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="menu-%s-qr.%s"`, id, export.Format))
+	c.Data(http.StatusOK, export.ContentType, export.Data)
+}
+
+// @Summary Publish menu
+// @Description Store the draft as a new immutable version and serve it to guests
+// @Tags menu
+// @Produce json
+// @Param id path string true "Menu ID"
+// @Success 201 {object} models.MenuVersion
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/{id}/publish [post]
+// @Security Bearer
+func (h *MenuHandler) PublishMenu(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	version, err := h.menuService.PublishMenu(c.Request.Context(), middleware.GetTenant(c), id)
+	if err != nil {
+		respondError(c, err, "menu not found")
+		return
+	}
+
+	c.JSON(http.StatusCreated, version)
+}
+
+// @Summary List menu versions
+// @Description List the published versions of a menu, newest first
+// @Tags menu
+// @Produce json
+// @Param id path string true "Menu ID"
+// @Success 200 {array} models.MenuVersion
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/{id}/versions [get]
+// @Security Bearer
+func (h *MenuHandler) GetMenuVersions(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	versions, err := h.menuService.GetMenuVersions(c.Request.Context(), middleware.GetTenant(c), id)
+	if err != nil {
+		respondError(c, err, "menu not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// @Summary Get menu version
+// @Description Get a version of a menu with its snapshot
+// @Tags menu
+// @Produce json
+// @Param id path string true "Menu ID"
+// @Param version path int true "Version"
+// @Success 200 {object} models.MenuVersion
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/{id}/versions/{version} [get]
+// @Security Bearer
+func (h *MenuHandler) GetMenuVersion(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	version, ok := parseVersion(c, c.Param("version"))
+	if !ok {
+		return
+	}
+
+	menuVersion, err := h.menuService.GetMenuVersion(c.Request.Context(), middleware.GetTenant(c), id, version)
+	if err != nil {
+		respondError(c, err, "menu version not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, menuVersion)
+}
+
+// @Summary Diff menu versions
+// @Description List the changes between two versions, or between a version and the draft when to is omitted
+// @Tags menu
+// @Produce json
+// @Param id path string true "Menu ID"
+// @Param from query int true "Older version"
+// @Param to query int false "Newer version, the draft when omitted"
+// @Success 200 {object} models.MenuVersionDiff
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/{id}/versions/diff [get]
+// @Security Bearer
+func (h *MenuHandler) DiffMenuVersions(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	from, ok := parseVersion(c, c.Query("from"))
+	if !ok {
+		return
+	}
+
+	var to *int
+	if c.Query("to") != "" {
+		version, ok := parseVersion(c, c.Query("to"))
+		if !ok {
+			return
+		}
+		to = &version
+	}
+
+	diff, err := h.menuService.DiffMenuVersions(c.Request.Context(), middleware.GetTenant(c), id, from, to)
+	if err != nil {
+		respondError(c, err, "menu version not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// @Summary Roll back menu
+// @Description Restore the draft to an earlier version and publish it as a new version
+// @Tags menu
+// @Produce json
+// @Param id path string true "Menu ID"
+// @Param version path int true "Version to restore"
+// @Success 201 {object} models.MenuVersion
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/{id}/versions/{version}/rollback [post]
+// @Security Bearer
+func (h *MenuHandler) RollbackMenu(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	version, ok := parseVersion(c, c.Param("version"))
+	if !ok {
+		return
+	}
+
+	menuVersion, err := h.menuService.RollbackMenu(c.Request.Context(), middleware.GetTenant(c), id, version)
+	if err != nil {
+		respondError(c, err, "menu version not found")
+		return
+	}
+
+	c.JSON(http.StatusCreated, menuVersion)
+}
+
+// parseVersion reads a menu version number and answers with 400 when it is
+// not a positive number
+func parseVersion(c *gin.Context, value string) (int, bool) {
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid version"})
+		return 0, false
+	}
+
+	return version, true
+}
+
+// parseIDParam reads a uuid path parameter and answers with 400 when it is malformed
+func parseIDParam(c *gin.Context, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid " + name})
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
+
+// respondError answers with 404 for missing resources and resources of other
+// clients, and with 400 for everything else
+func respondError(c *gin.Context, err error, notFound string) {
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: notFound})
+		return
+	}
+
+	c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+}
+
+func (req *MenuItemFactsRequest) apply(item *models.MenuCategoryItem) {
+	item.Allergens = req.Allergens
+	item.DietaryTags = req.DietaryTags
+	item.SpiceLevel = req.SpiceLevel
+	item.Calories = req.Calories
+	item.Nutrition = req.Nutrition
+}
+
+func (req *MenuFilterRequest) toFilter() (*models.MenuItemFilter, error) {
+	filter := &models.MenuItemFilter{MaxSpiceLevel: req.MaxSpice, MaxCalories: req.MaxCalories}
+
+	for _, value := range splitList(req.Exclude) {
+		allergen := models.Allergen(value)
+		if !slices.Contains(models.Allergens, allergen) {
+			return nil, fmt.Errorf("unknown allergen %q", value)
+		}
+		filter.ExcludeAllergens = append(filter.ExcludeAllergens, allergen)
+	}
+	for _, value := range splitList(req.Diet) {
+		tag := models.DietaryTag(value)
+		if !slices.Contains(models.DietaryTags, tag) {
+			return nil, fmt.Errorf("unknown dietary tag %q", value)
+		}
+		filter.DietaryTags = append(filter.DietaryTags, tag)
+	}
+
+	return filter, nil
+}
+
+// negotiateLocale picks the locale the menu is served in, the lang parameter
+// comes before the Accept-Language header. A regional locale such as de-AT
+// falls back to its language, and the menu's default locale comes last.
+func negotiateLocale(menu *models.Menu, lang string, acceptLanguage string) string {
+	candidates := make([]language.Tag, 0)
+	if tag, err := language.Parse(lang); err == nil {
+		candidates = append(candidates, tag)
+	}
+	// tags come sorted by their quality
+	if tags, _, err := language.ParseAcceptLanguage(acceptLanguage); err == nil {
+		candidates = append(candidates, tags...)
+	}
+
+	offered := menu.OfferedLocales()
+	for _, tag := range candidates {
+		if locale := strings.ToLower(tag.String()); slices.Contains(offered, locale) {
+			return locale
+		}
+		if base, confidence := tag.Base(); confidence != language.No && slices.Contains(offered, base.String()) {
+			return base.String()
+		}
+	}
+
+	return menu.BaseLocale()
+}
+
+// splitList splits a comma separated query value, dropping empty entries
+func splitList(value string) []string {
+	values := make([]string, 0)
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+// isAllowedImageType checks if the file extension is allowed, pdf files are split into pages by the scan worker
 func isAllowedImageType(ext string) bool {
 	ext = strings.ToLower(ext)
 	allowedTypes := map[string]bool{
@@ -0,0 +1,499 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestPublicMenuServesPublishedVersion(t *testing.T) {
+	f := newTenantFixture(t)
+	menuPath := "/menu/" + f.menuID.String()
+
+	// edit the draft without publishing
+	body, contentType := jsonBody(map[string]any{"id": f.menuID, "clientID": clientA, "label": "Dinner"})()
+	if w := f.do(t, "client-a", http.MethodPost, "/menu", body, contentType); w.Code != http.StatusOK {
+		t.Fatalf("failed to save the draft: %d %s", w.Code, w.Body.String())
+	}
+
+	if label := publicMenu(t, f).Label; label != "Lunch" {
+		t.Fatalf("guests should still see the published menu, got %q", label)
+	}
+
+	w := f.do(t, "client-a", http.MethodGet, menuPath+"/versions/diff?from=1", nil, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to diff against the draft: %d %s", w.Code, w.Body.String())
+	}
+	var diff models.MenuVersionDiff
+	if err := json.Unmarshal(w.Body.Bytes(), &diff); err != nil {
+		t.Fatal(err)
+	}
+	if !hasChange(diff.Changes, models.MenuChangeUpdated, models.MenuChangeEntityMenu, "label") {
+		t.Errorf("expected the label change in the diff, got %+v", diff.Changes)
+	}
+
+	if w := f.do(t, "client-a", http.MethodPost, menuPath+"/publish", nil, ""); w.Code != http.StatusCreated {
+		t.Fatalf("failed to publish: %d %s", w.Code, w.Body.String())
+	}
+	if menu := publicMenu(t, f); menu.Label != "Dinner" || *menu.PublishedVersion != 2 {
+		t.Fatalf("expected version 2 labelled Dinner, got %q version %v", menu.Label, menu.PublishedVersion)
+	}
+
+	w = f.do(t, "client-a", http.MethodPost, menuPath+"/versions/1/rollback", nil, "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("failed to roll back: %d %s", w.Code, w.Body.String())
+	}
+	var version models.MenuVersion
+	if err := json.Unmarshal(w.Body.Bytes(), &version); err != nil {
+		t.Fatal(err)
+	}
+	if version.Version != 3 || version.SourceVersion == nil || *version.SourceVersion != 1 {
+		t.Errorf("expected version 3 restored from 1, got %+v", version)
+	}
+	if label := publicMenu(t, f).Label; label != "Lunch" {
+		t.Errorf("expected the rolled back menu to be served, got %q", label)
+	}
+
+	// older versions stay untouched
+	w = f.do(t, "client-a", http.MethodGet, menuPath+"/versions/2", nil, "")
+	if err := json.Unmarshal(w.Body.Bytes(), &version); err != nil {
+		t.Fatal(err)
+	}
+	if version.Snapshot == nil || version.Snapshot.Label != "Dinner" {
+		t.Errorf("version 2 changed after the rollback: %+v", version.Snapshot)
+	}
+}
+
+func TestNeverPublishedMenuIsNotPublic(t *testing.T) {
+	f := newTenantFixture(t)
+
+	body, contentType := jsonBody(map[string]any{"clientID": clientA, "label": "Brunch"})()
+	w := f.do(t, "client-a", http.MethodPost, "/menu", body, contentType)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to create the menu: %d %s", w.Code, w.Body.String())
+	}
+
+	var menu models.Menu
+	if err := json.Unmarshal(w.Body.Bytes(), &menu); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := f.do(t, "", http.MethodGet, "/menu/"+menu.ID.String(), nil, ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected a draft-only menu to be hidden, got %d", w.Code)
+	}
+	if w := f.do(t, "client-a", http.MethodGet, "/menu/"+menu.ID.String()+"/draft", nil, ""); w.Code != http.StatusOK {
+		t.Errorf("expected the owner to see the draft, got %d", w.Code)
+	}
+}
+
+func publicMenu(t *testing.T, f *tenantFixture) models.Menu {
+	t.Helper()
+
+	w := f.do(t, "", http.MethodGet, "/menu/"+f.menuID.String(), &bytes.Buffer{}, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to get the public menu: %d %s", w.Code, w.Body.String())
+	}
+
+	var menu models.Menu
+	if err := json.Unmarshal(w.Body.Bytes(), &menu); err != nil {
+		t.Fatal(err)
+	}
+	return menu
+}
+
+func hasChange(changes []*models.MenuChange, changeType models.MenuChangeType, entity models.MenuChangeEntity, field string) bool {
+	for _, change := range changes {
+		if change.Type == changeType && change.Entity == entity && change.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSaveMenuSetsQRCode(t *testing.T) {
+	f := newTenantFixture(t)
+
+	body, contentType := jsonBody(map[string]any{"clientID": clientA, "label": "Breakfast"})()
+	w := f.do(t, "client-a", http.MethodPost, "/menu", body, contentType)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to create the menu: %d %s", w.Code, w.Body.String())
+	}
+
+	var menu models.Menu
+	if err := json.Unmarshal(w.Body.Bytes(), &menu); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "/static/qrcodes/" + menu.ID.String() + ".png"; menu.QRCode != expected {
+		t.Errorf("expected qr code %q, got %q", expected, menu.QRCode)
+	}
+}
+
+func TestExportQRCode(t *testing.T) {
+	f := newTenantFixture(t)
+	path := "/menu/" + f.menuID.String() + "/qr/export"
+
+	w := f.do(t, "client-a", http.MethodGet, path+"?format=svg&width=80&caption=Scan+for+AR+menu", nil, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to export the qr code: %d %s", w.Code, w.Body.String())
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "image/svg+xml" {
+		t.Errorf("expected an svg, got %s", contentType)
+	}
+	if body := w.Body.String(); !strings.Contains(body, `width="80mm"`) || !strings.Contains(body, "Scan for AR menu") {
+		t.Errorf("export is missing the width or the caption: %s", body)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1"+path+"?format=svg", nil)
+	req.Header.Set("Authorization", "Bearer client-a")
+	req.Header.Set("If-None-Match", w.Header().Get("ETag"))
+	w = httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected a matching etag to be not modified, got %d", w.Code)
+	}
+
+	for _, query := range []string{"?format=eps", "?dpi=5000", "?quietZone=-1", "?width=5"} {
+		if w := f.do(t, "client-a", http.MethodGet, path+query, nil, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestPublicMenuDietaryFilter(t *testing.T) {
+	f := newTenantFixture(t)
+
+	createItem := func(item map[string]any) *httptest.ResponseRecorder {
+		item["categoryId"] = f.categoryID
+		body, contentType := jsonBody(item)()
+		return f.do(t, "client-a", http.MethodPost, "/menu/items", body, contentType)
+	}
+
+	for _, item := range []map[string]any{
+		{"name": "Falafel", "dietaryTags": []string{"vegetarian", "vegan", "vegan"}, "calories": 420},
+		{"name": "Lahmacun", "allergens": []string{"milk", "gluten"}, "spiceLevel": 2, "calories": 800, "nutrition": map[string]any{"protein": 30, "fat": 25}},
+	} {
+		if w := createItem(item); w.Code != http.StatusCreated {
+			t.Fatalf("failed to create %s: %d %s", item["name"], w.Code, w.Body.String())
+		}
+	}
+
+	for _, item := range []map[string]any{
+		{"name": "Vegan cheese", "dietaryTags": []string{"vegan"}, "allergens": []string{"milk"}},
+		{"name": "Bread", "dietaryTags": []string{"gluten_free"}, "allergens": []string{"gluten"}},
+		{"name": "Soup", "allergens": []string{"shellfish"}},
+		{"name": "Hot sauce", "spiceLevel": 5},
+		{"name": "Candy", "nutrition": map[string]any{"carbohydrates": 10, "sugars": 20}},
+	} {
+		if w := createItem(item); w.Code != http.StatusBadRequest {
+			t.Errorf("expected %s to be rejected, got %d", item["name"], w.Code)
+		}
+	}
+
+	if w := f.do(t, "client-a", http.MethodPost, "/menu/"+f.menuID.String()+"/publish", nil, ""); w.Code != http.StatusCreated {
+		t.Fatalf("failed to publish: %d %s", w.Code, w.Body.String())
+	}
+
+	names := func(query string) []string {
+		w := f.do(t, "", http.MethodGet, "/menu/"+f.menuID.String()+query, nil, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: %d %s", query, w.Code, w.Body.String())
+		}
+		var menu models.Menu
+		if err := json.Unmarshal(w.Body.Bytes(), &menu); err != nil {
+			t.Fatal(err)
+		}
+
+		names := make([]string, 0)
+		for _, category := range menu.Categories {
+			for _, item := range category.MenuItems {
+				names = append(names, item.Name)
+			}
+		}
+		return names
+	}
+
+	all := names("")
+	for query, expected := range map[string][]string{
+		"?exclude=milk":               slices.DeleteFunc(slices.Clone(all), func(name string) bool { return name == "Lahmacun" }),
+		"?diet=vegan":                 {"Falafel"},
+		"?diet=vegan,halal":           {},
+		"?maxSpice=1&exclude=nuts":    slices.DeleteFunc(slices.Clone(all), func(name string) bool { return name == "Lahmacun" }),
+		"?maxCalories=500&diet=vegan": {"Falafel"},
+	} {
+		if got := names(query); !slices.Equal(got, expected) {
+			t.Errorf("%s: expected %v, got %v", query, expected, got)
+		}
+	}
+	if !slices.Contains(all, "Lahmacun") || !slices.Contains(all, "Falafel") {
+		t.Fatalf("expected the new items on the menu, got %v", all)
+	}
+
+	for _, query := range []string{"?exclude=shellfish", "?diet=keto", "?maxSpice=4"} {
+		if w := f.do(t, "", http.MethodGet, "/menu/"+f.menuID.String()+query, nil, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestSaveMenuValidatesItemChoices(t *testing.T) {
+	f := newTenantFixture(t)
+
+	save := func(groups []map[string]any) *httptest.ResponseRecorder {
+		body, contentType := jsonBody(map[string]any{
+			"id":       f.menuID,
+			"clientID": clientA,
+			"label":    "Lunch",
+			"categories": []map[string]any{{
+				"id":   f.categoryID,
+				"name": "Mains",
+				"menuItems": []map[string]any{{
+					"name":           "Burger",
+					"price":          10,
+					"variants":       []map[string]any{{"name": "Single", "price": 10}, {"name": "Double", "price": 14}},
+					"modifierGroups": groups,
+				}},
+			}},
+		})()
+		return f.do(t, "client-a", http.MethodPost, "/menu", body, contentType)
+	}
+
+	w := save([]map[string]any{{"name": "Sauce", "minSelect": 1, "maxSelect": 3, "options": []map[string]any{{"name": "Ketchup"}}}})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "Sauce") {
+		t.Errorf("expected the impossible choice to be rejected, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = save([]map[string]any{{"name": "Sauce", "minSelect": 1, "maxSelect": 1, "options": []map[string]any{{"name": "Ketchup"}, {"name": "Mayo", "priceDelta": 0.5}}}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to save the menu: %d %s", w.Code, w.Body.String())
+	}
+	var menu models.Menu
+	if err := json.Unmarshal(w.Body.Bytes(), &menu); err != nil {
+		t.Fatal(err)
+	}
+	item := menu.Categories[0].MenuItems[0]
+	if len(item.ModifierGroups) != 1 || item.ModifierGroups[0].ID == uuid.Nil || item.ModifierGroups[0].Options[1].PriceDelta != 0.5 {
+		t.Errorf("expected the modifier group with ids in the response, got %+v", item.ModifierGroups)
+	}
+	if item.Variants[0].ID == uuid.Nil || item.Price != 10 {
+		t.Errorf("expected the first variant to set the price, got %+v", item)
+	}
+}
+
+func TestPublicMenuTranslations(t *testing.T) {
+	f := newTenantFixture(t)
+
+	menu := map[string]any{
+		"id":            f.menuID,
+		"clientId":      clientA,
+		"label":         "Öğle",
+		"description":   "Günün menüsü",
+		"defaultLocale": "tr",
+		"locales":       []string{"de", "en"},
+		"translations": map[string]any{
+			"en": map[string]any{"label": "Lunch"},
+			"de": map[string]any{"label": "Mittag", "description": "Tagesmenü"},
+		},
+		"categories": []map[string]any{{
+			"id":           f.categoryID,
+			"name":         "Ana yemekler",
+			"translations": map[string]any{"en": map[string]any{"name": "Mains"}},
+			"menuItems": []map[string]any{{
+				"id":          f.itemID,
+				"name":        "Köfte",
+				"description": "Izgara",
+				"price":       12,
+				"translations": map[string]any{
+					"en": map[string]any{"name": "Meatballs", "description": "  "},
+					"de": map[string]any{"name": "Frikadellen", "description": "Gegrillt"},
+				},
+			}},
+		}},
+	}
+	body, contentType := jsonBody(menu)()
+	if w := f.do(t, "client-a", http.MethodPost, "/menu", body, contentType); w.Code != http.StatusOK {
+		t.Fatalf("failed to save the menu: %d %s", w.Code, w.Body.String())
+	}
+	if w := f.do(t, "client-a", http.MethodPost, "/menu/"+f.menuID.String()+"/publish", nil, ""); w.Code != http.StatusCreated {
+		t.Fatalf("failed to publish: %d %s", w.Code, w.Body.String())
+	}
+
+	get := func(query, acceptLanguage string) (models.Menu, string) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/menu/"+f.menuID.String()+query, nil)
+		if acceptLanguage != "" {
+			req.Header.Set("Accept-Language", acceptLanguage)
+		}
+		w := httptest.NewRecorder()
+		f.router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s: %d %s", query, acceptLanguage, w.Code, w.Body.String())
+		}
+
+		var menu models.Menu
+		if err := json.Unmarshal(w.Body.Bytes(), &menu); err != nil {
+			t.Fatal(err)
+		}
+		return menu, w.Header().Get("Content-Language")
+	}
+
+	for _, tc := range []struct {
+		query, acceptLanguage, locale, label, description, category, item, itemDescription string
+	}{
+		{"", "", "tr", "Öğle", "Günün menüsü", "Ana yemekler", "Köfte", "Izgara"},
+		{"?lang=en", "de", "en", "Lunch", "Günün menüsü", "Mains", "Meatballs", "Izgara"},
+		{"", "fr-CH, fr;q=0.9, de;q=0.7, en;q=0.8", "en", "Lunch", "Günün menüsü", "Mains", "Meatballs", "Izgara"},
+		{"", "de-AT", "de", "Mittag", "Tagesmenü", "Ana yemekler", "Frikadellen", "Gegrillt"},
+		{"?lang=ar", "fr", "tr", "Öğle", "Günün menüsü", "Ana yemekler", "Köfte", "Izgara"},
+	} {
+		menu, locale := get(tc.query, tc.acceptLanguage)
+		item := menu.Categories[0].MenuItems[0]
+		got := []string{locale, menu.Locale, menu.Label, menu.Description, menu.Categories[0].Name, item.Name, item.Description}
+		expected := []string{tc.locale, tc.locale, tc.label, tc.description, tc.category, tc.item, tc.itemDescription}
+		if !slices.Equal(got, expected) {
+			t.Errorf("%q %q: expected %v, got %v", tc.query, tc.acceptLanguage, expected, got)
+		}
+		if menu.Translations != nil || item.Translations != nil {
+			t.Errorf("%q %q: translations should not be served", tc.query, tc.acceptLanguage)
+		}
+	}
+
+	w := f.do(t, "client-a", http.MethodGet, "/menu/"+f.menuID.String()+"/translations/missing", nil, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to list missing translations: %d %s", w.Code, w.Body.String())
+	}
+	var report models.TranslationReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	missing := make([]string, 0)
+	for _, m := range report.Missing {
+		missing = append(missing, m.Locale+" "+string(m.Entity)+" "+m.Field)
+	}
+	expected := []string{"en menu description", "en item description", "de category name"}
+	if report.DefaultLocale != "tr" || !slices.Equal(report.Locales, []string{"tr", "en", "de"}) || !slices.Equal(missing, expected) {
+		t.Errorf("expected %v in tr, en and de, got %v in %s %v", expected, missing, report.DefaultLocale, report.Locales)
+	}
+
+	for path, status := range map[string]int{
+		"/menu/" + f.menuID.String() + "/translations/missing?locale=de": http.StatusOK,
+		"/menu/" + f.menuID.String() + "/translations/missing?locale=ar": http.StatusBadRequest,
+	} {
+		if w := f.do(t, "client-a", http.MethodGet, path, nil, ""); w.Code != status {
+			t.Errorf("%s: expected %d, got %d", path, status, w.Code)
+		}
+	}
+	if w := f.do(t, "client-b", http.MethodGet, "/menu/"+f.menuID.String()+"/translations/missing", nil, ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected other clients to get 404, got %d", w.Code)
+	}
+
+	for name, change := range map[string]func(map[string]any){
+		"locale not offered":   func(m map[string]any) { m["translations"] = map[string]any{"ar": map[string]any{"label": "غداء"}} },
+		"unsupported locale":   func(m map[string]any) { m["locales"] = []string{"fr"} },
+		"translated menu name": func(m map[string]any) { m["translations"] = map[string]any{"en": map[string]any{"name": "Lunch"}} },
+		"unsupported default":  func(m map[string]any) { m["defaultLocale"] = "xx" },
+	} {
+		invalid := maps.Clone(menu)
+		change(invalid)
+		body, contentType := jsonBody(invalid)()
+		if w := f.do(t, "client-a", http.MethodPost, "/menu", body, contentType); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, w.Code)
+		}
+	}
+}
+
+func TestPublicMenuLocaleTheme(t *testing.T) {
+	f := newTenantFixture(t)
+
+	menu := map[string]any{
+		"id":       f.menuID,
+		"clientId": clientA,
+		"label":    "Lunch",
+		"locales":  []string{"ar"},
+		"customization": map[string]any{
+			"colors": map[string]any{"primary": "#ABC", "background": " #FFFFFF "},
+			"font":   map[string]any{"heading": "Playfair Display", "spacing": 8},
+			"fonts":  map[string]any{"ar": map[string]any{"body": "Cairo"}},
+		},
+	}
+	body, contentType := jsonBody(menu)()
+	if w := f.do(t, "client-a", http.MethodPost, "/menu", body, contentType); w.Code != http.StatusOK {
+		t.Fatalf("failed to save the menu: %d %s", w.Code, w.Body.String())
+	}
+	if w := f.do(t, "client-a", http.MethodPost, "/menu/"+f.menuID.String()+"/publish", nil, ""); w.Code != http.StatusCreated {
+		t.Fatalf("failed to publish: %d %s", w.Code, w.Body.String())
+	}
+
+	for query, expected := range map[string][]string{
+		"":         {"en", "Latn", "ltr", "Playfair Display", "Noto Sans"},
+		"?lang=ar": {"ar", "Arab", "rtl", "Noto Naskh Arabic", "Cairo"},
+	} {
+		w := f.do(t, "", http.MethodGet, "/menu/"+f.menuID.String()+query, nil, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q: %d %s", query, w.Code, w.Body.String())
+		}
+		var served models.Menu
+		if err := json.Unmarshal(w.Body.Bytes(), &served); err != nil {
+			t.Fatal(err)
+		}
+
+		font := served.Customization.Font
+		got := []string{served.Locale, served.Script, string(served.Direction), font.Heading, font.Body}
+		if !slices.Equal(got, expected) || font.Spacing != 8 || served.Customization.Fonts != nil {
+			t.Errorf("%q: expected %v with spacing 8, got %v %+v", query, expected, got, served.Customization)
+		}
+		if colors := served.Customization.Colors; colors.Primary != "#aabbcc" || colors.Background != "#ffffff" {
+			t.Errorf("%q: expected normalized colors, got %+v", query, colors)
+		}
+		if len(served.AvailableLocales) != 2 || served.AvailableLocales[1].Direction != models.TextDirectionRTL {
+			t.Errorf("%q: expected en and ar to switch to, got %v", query, served.AvailableLocales)
+		}
+	}
+
+	for name, customization := range map[string]map[string]any{
+		"color name":         {"colors": map[string]any{"primary": "red"}},
+		"qr color":           {"qrCode": map[string]any{"qrColor": "#12345"}},
+		"font injection":     {"font": map[string]any{"body": "Inter; color: red"}},
+		"spacing":            {"font": map[string]any{"spacing": 100}},
+		"locale not offered": {"fonts": map[string]any{"he": map[string]any{"body": "Rubik"}}},
+	} {
+		invalid := maps.Clone(menu)
+		invalid["customization"] = customization
+		body, contentType := jsonBody(invalid)()
+		if w := f.do(t, "client-a", http.MethodPost, "/menu", body, contentType); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, w.Code)
+		}
+	}
+}
+
+func TestGetScanJobStatus(t *testing.T) {
+	f := newTenantFixture(t)
+
+	tests := []struct {
+		name    string
+		jobID   uuid.UUID
+		repoErr error
+		status  int
+	}{
+		{"own job", f.jobID, nil, http.StatusOK},
+		{"missing job", uuid.New(), nil, http.StatusNotFound},
+		{"failed lookup", f.jobID, errors.New("connection refused"), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f.scanJobs.err = tt.repoErr
+			w := f.do(t, "client-a", http.MethodGet, "/menu/scan/"+tt.jobID.String(), nil, "")
+			if w.Code != tt.status {
+				t.Errorf("expected %d, got %d %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
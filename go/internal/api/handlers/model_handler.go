@@ -1,10 +1,13 @@
 package handlers
 
 import (
-	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
-	"slices"
 
+	"github.com/ahmetkoprulu/bidi-menu/common/gltf"
 	"github.com/ahmetkoprulu/bidi-menu/common/storage"
 	"github.com/ahmetkoprulu/bidi-menu/internal/api/middleware"
 	"github.com/ahmetkoprulu/bidi-menu/internal/models"
@@ -32,7 +35,6 @@ func (h *ModelHandler) RegisterRoutes(router *gin.RouterGroup) {
 	model := router.Group("/model")
 	{
 		model.POST("", h.SaveModel)
-		model.GET("", h.GetModel)
 		model.GET("/list", h.GetModels)
 		model.GET("/:id", h.GetModelById)
 		model.DELETE("/:id", h.DeleteModel)
@@ -40,8 +42,17 @@ func (h *ModelHandler) RegisterRoutes(router *gin.RouterGroup) {
 }
 
 func (h *ModelHandler) GetModelById(c *gin.Context) {
-	id := c.Param("id")
-	model, err := h.modelService.GetModelById(c.Request.Context(), uuid.MustParse(id))
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid model ID"})
+		return
+	}
+
+	model, err := h.modelService.GetModelById(c.Request.Context(), middleware.GetTenant(c), id)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Model not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
@@ -51,8 +62,7 @@ func (h *ModelHandler) GetModelById(c *gin.Context) {
 }
 
 func (h *ModelHandler) SaveModel(c *gin.Context) {
-	clientID := c.MustGet(middleware.ClientIDKey).(uuid.UUID)
-	roles := c.MustGet(middleware.UserRoleKey).([]string)
+	tenant := middleware.GetTenant(c)
 
 	// Get form data
 	glbFile, err := c.FormFile("glb")
@@ -61,21 +71,29 @@ func (h *ModelHandler) SaveModel(c *gin.Context) {
 		return
 	}
 
+	// A usdz file is generated from the model when none is uploaded
 	usdzFile, err := c.FormFile("usdz")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "USDZ file is required"})
+	if err != nil && !errors.Is(err, http.ErrMissingFile) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid USDZ file"})
 		return
 	}
 
+	// A thumbnail is drawn from the model when none is uploaded
 	thumbnailFile, err := c.FormFile("thumbnail")
+	if err != nil && !errors.Is(err, http.ErrMissingFile) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid thumbnail file"})
+		return
+	}
+
+	clientID, err := uuid.Parse(c.PostForm("clientId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "thumbnail file is required"})
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid client ID"})
 		return
 	}
 
 	model := models.Model{
 		Name:     c.PostForm("name"),
-		ClientID: uuid.MustParse(c.PostForm("clientId")),
+		ClientID: clientID,
 	}
 
 	if err := c.ShouldBind(&model); err != nil {
@@ -83,40 +101,80 @@ func (h *ModelHandler) SaveModel(c *gin.Context) {
 		return
 	}
 
-	if !slices.Contains(roles, "admin") && clientID != model.ClientID {
-		c.JSON(http.StatusForbidden, ErrorResponse{Error: "you are not authorized to create a model"})
+	// Check ownership before any file is written, models of other clients are
+	// reported as missing
+	if !tenant.Owns(model.ClientID) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Client not found"})
 		return
 	}
 
-	// Generate new UUID if not updating
 	isCreate := model.ID == nil
+	if !isCreate {
+		existing, err := h.modelService.GetModelById(c.Request.Context(), tenant, *model.ID)
+		if err != nil || existing.ClientID != model.ClientID {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Model not found"})
+			return
+		}
+	}
+
+	// The extension says nothing about the contents, the file is parsed before
+	// anything is stored
+	doc, metadata, err := readModelFile(glbFile)
+	if errors.Is(err, gltf.ErrInvalid) || errors.Is(err, storage.ErrFileTooLarge) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to read GLB file"})
+		return
+	}
+	model.Metadata = metadata
+	model.Optimization = &models.ModelOptimization{OriginalSize: glbFile.Size}
+
+	// Generate new UUID if not updating. The files of an update are stored
+	// under a staging id and only replace the model's files once the update is
+	// saved, a failed update leaves the model as it was.
 	if isCreate {
 		newID := uuid.New()
 		model.ID = &newID
 	}
+	fileID := *model.ID
+	if !isCreate {
+		fileID = uuid.New()
+	}
 
 	// Save GLB file
-	glbPath, err := h.storageService.SaveGlbModel(glbFile, *model.ID)
+	glbPath, err := h.storageService.SaveGlbModel(glbFile, fileID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Failed to save GLB file: " + err.Error()})
 		return
 	}
 
-	// Save USDZ file
-	usdzPath, err := h.storageService.SaveUsdzModel(usdzFile, *model.ID)
-	if err != nil {
-		// Cleanup GLB file if USDZ upload fails
-		h.storageService.DeleteGlbModel(*model.ID)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Failed to save USDZ file: " + err.Error()})
-		return
+	// Save USDZ file, or queue its conversion
+	var usdzPath string
+	if usdzFile != nil {
+		usdzPath, err = h.storageService.SaveUsdzModel(usdzFile, fileID)
+		if err != nil {
+			// Cleanup GLB file if USDZ upload fails
+			h.storageService.DeleteGlbModel(fileID)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Failed to save USDZ file: " + err.Error()})
+			return
+		}
+	} else {
+		model.UsdzConversion = &models.ModelConversion{Status: models.ModelConversionQueued}
 	}
 
-	// Save thumbnail
-	thumbnailPath, err := h.storageService.SaveThumbnail(thumbnailFile, *model.ID)
+	// Save thumbnail, or draw one from the model
+	var thumbnailPath string
+	if thumbnailFile != nil {
+		thumbnailPath, err = h.storageService.SaveThumbnail(thumbnailFile, fileID)
+	} else {
+		thumbnailPath, model.Thumbnails, err = h.modelService.RenderThumbnails(c.Request.Context(), fileID, doc)
+	}
 	if err != nil {
-		// Cleanup both model files if thumbnail upload fails
-		h.storageService.DeleteGlbModel(*model.ID)
-		h.storageService.DeleteUsdzModel(*model.ID)
+		// Cleanup both model files and any thumbnail drawn so far if the
+		// thumbnail fails
+		h.deleteModelFiles(fileID)
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Failed to save thumbnail: " + err.Error()})
 		return
 	}
@@ -127,12 +185,19 @@ func (h *ModelHandler) SaveModel(c *gin.Context) {
 	model.Thumbnail = thumbnailPath
 
 	// Save to database
-	modelID, err := h.modelService.SaveModel(context.Background(), model, isCreate)
+	var modelID *uuid.UUID
+	if isCreate {
+		modelID, err = h.modelService.SaveModel(c.Request.Context(), tenant, model, true)
+	} else {
+		modelID, err = h.modelService.ReplaceModel(c.Request.Context(), tenant, model, fileID)
+	}
 	if err != nil {
-		// Cleanup all files if database operation fails
-		h.storageService.DeleteGlbModel(*model.ID)
-		h.storageService.DeleteUsdzModel(*model.ID)
-		h.storageService.DeleteThumbnail(*model.ID)
+		// Cleanup all files written by the request if database operation fails
+		h.deleteModelFiles(fileID)
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Model not found"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -141,29 +206,92 @@ func (h *ModelHandler) SaveModel(c *gin.Context) {
 	c.JSON(http.StatusOK, model)
 }
 
-func (h *ModelHandler) GetModel(c *gin.Context) {
-	clientID := c.MustGet(middleware.ClientIDKey).(uuid.UUID)
-	model, err := h.modelService.GetModel(context.Background(), clientID)
+// deleteModelFiles removes the glb, usdz and thumbnail files stored under the
+// id
+func (h *ModelHandler) deleteModelFiles(id uuid.UUID) {
+	h.storageService.DeleteGlbModel(id)
+	h.storageService.DeleteUsdzModel(id)
+	h.storageService.DeleteThumbnail(id)
+}
+
+// readModelFile parses an uploaded glb or gltf file and describes its
+// contents. Errors about the file wrap gltf.ErrInvalid.
+func readModelFile(file *multipart.FileHeader) (*gltf.Document, *models.ModelMetadata, error) {
+	if file.Size > storage.MaxFileSize {
+		return nil, nil, storage.ErrFileTooLarge
+	}
+
+	src, err := file.Open()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
-		return
+		return nil, nil, fmt.Errorf("failed to open model file: %w", err)
 	}
-	c.JSON(http.StatusOK, model)
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, storage.MaxFileSize+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read model file: %w", err)
+	}
+	if len(data) > storage.MaxFileSize {
+		return nil, nil, storage.ErrFileTooLarge
+	}
+
+	doc, err := gltf.Parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	stats, err := doc.Stats()
+	if err != nil {
+		return nil, nil, err
+	}
+	if stats.Triangles == 0 {
+		return nil, nil, fmt.Errorf("%w: the model has no triangles", gltf.ErrInvalid)
+	}
+
+	metadata := &models.ModelMetadata{
+		Triangles:    stats.Triangles,
+		Vertices:     stats.Vertices,
+		Materials:    stats.Materials,
+		Textures:     stats.Textures,
+		TextureSizes: make([]*models.TextureSize, 0, len(stats.TextureSizes)),
+		Dimensions:   stats.Dimensions(),
+		Scale:        stats.Scale,
+	}
+	for _, size := range stats.TextureSizes {
+		metadata.TextureSizes = append(metadata.TextureSizes, &models.TextureSize{Width: size.Width, Height: size.Height})
+	}
+
+	return doc, metadata, nil
 }
 
+// GetModels lists the models of the client given by the clientId query, which
+// defaults to the caller's own client. Only admins may list other clients.
 func (h *ModelHandler) GetModels(c *gin.Context) {
-	clientID := c.Query("clientId")
-	models, err := h.modelService.GetModels(context.Background(), uuid.MustParse(clientID))
+	tenant := middleware.GetTenant(c)
+
+	clientID := tenant.ClientID
+	if query := c.Query("clientId"); query != "" {
+		id, err := uuid.Parse(query)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid client ID"})
+			return
+		}
+		clientID = id
+	}
+
+	ms, err := h.modelService.GetModels(c.Request.Context(), tenant, clientID)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Client not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, models)
+	c.JSON(http.StatusOK, ms)
 }
 
 func (h *ModelHandler) DeleteModel(c *gin.Context) {
-	clientID := c.MustGet(middleware.ClientIDKey).(uuid.UUID)
-	roles := c.MustGet(middleware.UserRoleKey).([]string)
+	tenant := middleware.GetTenant(c)
 	id := c.Param("id")
 
 	// Parse model ID
@@ -173,24 +301,21 @@ func (h *ModelHandler) DeleteModel(c *gin.Context) {
 		return
 	}
 
-	// Get model to check ownership
-	model, err := h.modelService.GetModelById(c.Request.Context(), modelID)
-	if err != nil {
+	// Models of other clients are reported as missing
+	if _, err := h.modelService.GetModelById(c.Request.Context(), tenant, modelID); err != nil {
 		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Model not found"})
 		return
 	}
 
-	// Check authorization
-	if !slices.Contains(roles, "admin") && clientID != model.ClientID {
-		c.JSON(http.StatusForbidden, ErrorResponse{Error: "You are not authorized to delete this model"})
-		return
-	}
-
 	// Delete model files first
 	if err := h.storageService.DeleteGlbModel(modelID); err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete GLB file"})
 		return
 	}
+	if err := h.storageService.DeleteOptimizedGlbModel(modelID); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete optimized GLB file"})
+		return
+	}
 	if err := h.storageService.DeleteUsdzModel(modelID); err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete USDZ file"})
 		return
@@ -201,13 +326,13 @@ func (h *ModelHandler) DeleteModel(c *gin.Context) {
 	}
 
 	// Delete model from menu
-	if err := h.menuService.RemoveModelFromMenuItems(context.Background(), modelID); err != nil {
+	if err := h.menuService.RemoveModelFromMenuItems(c.Request.Context(), modelID); err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete model from menu"})
 		return
 	}
 
 	// Delete model from database
-	if err := h.modelService.DeleteModel(c.Request.Context(), modelID); err != nil {
+	if err := h.modelService.DeleteModel(c.Request.Context(), tenant, modelID); err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete model"})
 		return
 	}
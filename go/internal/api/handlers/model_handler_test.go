@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+)
+
+func TestSaveModelRejectsInvalidGlb(t *testing.T) {
+	f := newTenantFixture(t)
+
+	// the form uploads a few plain bytes under a model file name
+	body, contentType := modelForm(clientA)()
+	w := f.do(t, "client-a", http.MethodPost, "/model", body, contentType)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected a bad request for a file that is not a model, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "neither a binary glb nor a gltf json file") {
+		t.Errorf("expected the reason in the response, got %s", w.Body.String())
+	}
+}
+
+// triangleGltf is a gltf file with its single triangle in a data URI buffer
+const triangleGltf = `{
+	"asset": {"version": "2.0"},
+	"scene": 0,
+	"scenes": [{"nodes": [0]}],
+	"nodes": [{"mesh": 0}],
+	"meshes": [{"primitives": [{"attributes": {"POSITION": 0}}]}],
+	"accessors": [{"bufferView": 0, "componentType": 5126, "count": 3, "type": "VEC3", "min": [0, 0, 0], "max": [1, 1, 0]}],
+	"bufferViews": [{"buffer": 0, "byteLength": 36}],
+	"buffers": [{"byteLength": 36, "uri": "data:application/octet-stream;base64,AAAAAAAAAAAAAAAAAACAPwAAAAAAAAAAAAAAAAAAgD8AAAAA"}]
+}`
+
+func TestSaveModelRendersMissingThumbnail(t *testing.T) {
+	f := newTenantFixture(t)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("name", "Triangle")
+	writer.WriteField("clientId", clientA.String())
+	part, _ := writer.CreateFormFile("glb", "triangle.gltf")
+	part.Write([]byte(triangleGltf))
+	part, _ = writer.CreateFormFile("usdz", "triangle.usdz")
+	part.Write([]byte("data"))
+	writer.Close()
+
+	w := f.do(t, "client-a", http.MethodPost, "/model", body, writer.FormDataContentType())
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the model to be saved, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var model models.Model
+	if err := json.Unmarshal(w.Body.Bytes(), &model); err != nil {
+		t.Fatal(err)
+	}
+	if model.Thumbnail != "thumbnails/"+model.ID.String()+".png" {
+		t.Errorf("expected the drawn png as the thumbnail, got %q", model.Thumbnail)
+	}
+	formats := make([]string, 0, len(model.Thumbnails))
+	for _, thumbnail := range model.Thumbnails {
+		formats = append(formats, thumbnail.Format)
+	}
+	if strings.Join(formats, ",") != "png,webp" {
+		t.Errorf("expected a png and a webp thumbnail, got %v", formats)
+	}
+}
+
+func TestSaveModelQueuesMissingUsdz(t *testing.T) {
+	f := newTenantFixture(t)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("name", "Triangle")
+	writer.WriteField("clientId", clientA.String())
+	part, _ := writer.CreateFormFile("glb", "triangle.gltf")
+	part.Write([]byte(triangleGltf))
+	writer.Close()
+
+	w := f.do(t, "client-a", http.MethodPost, "/model", body, writer.FormDataContentType())
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the model to be saved, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var model models.Model
+	if err := json.Unmarshal(w.Body.Bytes(), &model); err != nil {
+		t.Fatal(err)
+	}
+	stored := f.models.models[*model.ID]
+	if stored.UsdzFile != "" || stored.UsdzConversion == nil || stored.UsdzConversion.Status != models.ModelConversionQueued {
+		t.Errorf("expected the usdz conversion to be queued, got %q %+v", stored.UsdzFile, stored.UsdzConversion)
+	}
+}
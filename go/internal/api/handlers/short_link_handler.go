@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/api/middleware"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ShortLinkHandler struct {
+	shortLinkService services.ShortLinkService
+}
+
+// ShortLinkScheduleRequest sends guests to a menu on the given days, days run
+// from 0 for Sunday to 6 for Saturday and times are HH:MM
+type ShortLinkScheduleRequest struct {
+	MenuID    uuid.UUID `json:"menuId" binding:"required"`
+	Days      []int     `json:"days" binding:"required,min=1,max=7,dive,min=0,max=6"`
+	StartTime string    `json:"startTime" binding:"required"`
+	EndTime   string    `json:"endTime" binding:"required"`
+}
+
+// ShortLinkRequest represents the request body for creating or re-pointing a
+// short link
+type ShortLinkRequest struct {
+	ClientID  *uuid.UUID                  `json:"clientId"`
+	Label     string                      `json:"label" binding:"max=255"`
+	MenuID    *uuid.UUID                  `json:"menuId" binding:"required"`
+	Timezone  string                      `json:"timezone" binding:"max=64"`
+	Schedules []*ShortLinkScheduleRequest `json:"schedules" binding:"omitempty,max=50,dive"`
+}
+
+func NewShortLinkHandler(shortLinkService services.ShortLinkService) *ShortLinkHandler {
+	return &ShortLinkHandler{
+		shortLinkService: shortLinkService,
+	}
+}
+
+// RegisterRedirect registers the short link redirect, it lives at the root so
+// the printed addresses stay short
+func (h *ShortLinkHandler) RegisterRedirect(router *gin.RouterGroup) {
+	router.GET("/s/:code", h.Redirect)
+}
+
+// RegisterRoutes registers all routes for short link operations
+func (h *ShortLinkHandler) RegisterRoutes(router *gin.RouterGroup, v1 *gin.RouterGroup) {
+	v1.GET("/s/:code", h.ResolveShortLink)
+
+	links := router.Group("/links")
+	{
+		links.GET("", h.GetShortLinks)
+		links.POST("", h.CreateShortLink)
+		links.GET("/:id", h.GetShortLink)
+		links.PUT("/:id", h.UpdateShortLink)
+		links.DELETE("/:id", h.DeleteShortLink)
+	}
+}
+
+// @Summary Follow short link
+// @Description Redirect to the menu the short link currently leads to. The query string, such as the table token, is passed on and tagged with src=qr for analytics.
+// @Tags links
+// @Param code path string true "Short code"
+// @Success 302
+// @Failure 404 {object} ErrorResponse
+// @Router /s/{code} [get]
+func (h *ShortLinkHandler) Redirect(c *gin.Context) {
+	target, err := h.shortLinkService.Resolve(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		respondError(c, err, "link not found")
+		return
+	}
+
+	// short links are what QR codes print, the menu page reports the source
+	query := c.Request.URL.Query()
+	if query.Get("src") == "" {
+		query.Set("src", string(models.AnalyticsSourceQR))
+	}
+	location := target.URL + "?" + query.Encode()
+
+	// the target changes over time, so the redirect must not be cached
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, location)
+}
+
+// @Summary Resolve short link
+// @Description Find the menu a short link currently leads to
+// @Tags links
+// @Produce json
+// @Param code path string true "Short code"
+// @Success 200 {object} models.ShortLinkTarget
+// @Failure 404 {object} ErrorResponse
+// @Router /s/{code} [get]
+func (h *ShortLinkHandler) ResolveShortLink(c *gin.Context) {
+	target, err := h.shortLinkService.Resolve(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		respondError(c, err, "link not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, target)
+}
+
+// @Summary List short links
+// @Description List the short links of a client, the caller's client by default
+// @Tags links
+// @Produce json
+// @Param clientId query string false "Client ID"
+// @Success 200 {array} models.ShortLink
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /links [get]
+// @Security Bearer
+func (h *ShortLinkHandler) GetShortLinks(c *gin.Context) {
+	tenant := middleware.GetTenant(c)
+	clientID := tenant.ClientID
+	if value := c.Query("clientId"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid clientId"})
+			return
+		}
+		clientID = id
+	}
+
+	links, err := h.shortLinkService.GetShortLinks(c.Request.Context(), tenant, clientID)
+	if err != nil {
+		respondError(c, err, "client not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+// @Summary Get short link
+// @Description Get a short link with its schedules
+// @Tags links
+// @Produce json
+// @Param id path string true "Short link ID"
+// @Success 200 {object} models.ShortLink
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /links/{id} [get]
+// @Security Bearer
+func (h *ShortLinkHandler) GetShortLink(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	link, err := h.shortLinkService.GetShortLink(c.Request.Context(), middleware.GetTenant(c), id)
+	if err != nil {
+		respondError(c, err, "link not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+// @Summary Create short link
+// @Description Create a short link with a new code leading to a menu
+// @Tags links
+// @Accept json
+// @Produce json
+// @Param request body ShortLinkRequest true "Short link"
+// @Success 201 {object} models.ShortLink
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /links [post]
+// @Security Bearer
+func (h *ShortLinkHandler) CreateShortLink(c *gin.Context) {
+	var req ShortLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	tenant := middleware.GetTenant(c)
+	link := req.toShortLink()
+	link.ClientID = tenant.ClientID
+	if req.ClientID != nil {
+		link.ClientID = *req.ClientID
+	}
+
+	if err := h.shortLinkService.CreateShortLink(c.Request.Context(), tenant, link); err != nil {
+		respondError(c, err, "menu not found")
+		return
+	}
+
+	c.JSON(http.StatusCreated, link)
+}
+
+// @Summary Update short link
+// @Description Point a short link to another menu or change its schedules, the code and the printed QR codes stay the same
+// @Tags links
+// @Accept json
+// @Produce json
+// @Param id path string true "Short link ID"
+// @Param request body ShortLinkRequest true "Short link"
+// @Success 200 {object} models.ShortLink
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /links/{id} [put]
+// @Security Bearer
+func (h *ShortLinkHandler) UpdateShortLink(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req ShortLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	link := req.toShortLink()
+	link.ID = id
+	if err := h.shortLinkService.UpdateShortLink(c.Request.Context(), middleware.GetTenant(c), link); err != nil {
+		respondError(c, err, "link not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+// @Summary Delete short link
+// @Description Delete a short link, its QR codes stop resolving
+// @Tags links
+// @Param id path string true "Short link ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /links/{id} [delete]
+// @Security Bearer
+func (h *ShortLinkHandler) DeleteShortLink(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.shortLinkService.DeleteShortLink(c.Request.Context(), middleware.GetTenant(c), id); err != nil {
+		respondError(c, err, "link not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (req *ShortLinkRequest) toShortLink() *models.ShortLink {
+	link := &models.ShortLink{
+		Label:     req.Label,
+		MenuID:    req.MenuID,
+		Timezone:  req.Timezone,
+		Schedules: make([]*models.ShortLinkSchedule, 0, len(req.Schedules)),
+	}
+
+	for _, schedule := range req.Schedules {
+		link.Schedules = append(link.Schedules, &models.ShortLinkSchedule{
+			MenuID:    schedule.MenuID,
+			Days:      schedule.Days,
+			StartTime: schedule.StartTime,
+			EndTime:   schedule.EndTime,
+		})
+	}
+
+	return link
+}
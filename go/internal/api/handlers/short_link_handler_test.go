@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/repository"
+	"github.com/google/uuid"
+)
+
+func TestShortLinkRedirect(t *testing.T) {
+	f := newTenantFixture(t)
+	ctx := context.Background()
+
+	redirect := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		f.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	link := f.shortLinks.links[f.linkID]
+	w := redirect("/s/" + link.Code + "?table=abc")
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected a redirect, got %d: %s", w.Code, w.Body.String())
+	}
+	if location := w.Header().Get("Location"); location != "https://menu.test/menu/view/"+f.menuID.String()+"?src=qr&table=abc" {
+		t.Errorf("redirected to %s", location)
+	}
+
+	dinnerID, err := f.menus.CreateMenu(ctx, &models.Menu{ClientID: clientA, Label: "Dinner"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, contentType := jsonBody(map[string]any{"menuId": dinnerID, "label": "Front door"})()
+	w = f.do(t, "client-a", http.MethodPut, "/links/"+f.linkID.String(), body, contentType)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to re-point the link: %d %s", w.Code, w.Body.String())
+	}
+	var updated models.ShortLink
+	if err := json.Unmarshal(w.Body.Bytes(), &updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Code != link.Code || updated.URL != "https://go.test/s/"+link.Code {
+		t.Errorf("re-pointing changed the printed address: %+v", updated)
+	}
+
+	w = redirect("/s/" + link.Code)
+	if location := w.Header().Get("Location"); location != "https://menu.test/menu/view/"+dinnerID.String()+"?src=qr" {
+		t.Errorf("expected the re-pointed menu, redirected to %s", location)
+	}
+
+	body, contentType = jsonBody(map[string]any{
+		"menuId":    dinnerID,
+		"schedules": []map[string]any{{"menuId": f.menuID, "days": []int{1}, "startTime": "11:00", "endTime": "11:00"}},
+	})()
+	if w := f.do(t, "client-a", http.MethodPut, "/links/"+f.linkID.String(), body, contentType); w.Code != http.StatusBadRequest {
+		t.Errorf("expected an empty schedule to be rejected, got %d", w.Code)
+	}
+
+	if w := f.do(t, "client-a", http.MethodDelete, "/menu/"+dinnerID.String(), nil, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("failed to delete the menu: %d %s", w.Code, w.Body.String())
+	}
+	f.shortLinks.links[f.linkID].MenuID = nil
+	if w := redirect("/s/" + link.Code); w.Code != http.StatusNotFound {
+		t.Errorf("expected a link without a menu to be not found, got %d", w.Code)
+	}
+	if w := redirect("/s/unknown"); w.Code != http.StatusNotFound {
+		t.Errorf("expected an unknown code to be not found, got %d", w.Code)
+	}
+}
+
+// memoryShortLinkRepository scopes links by their client, like the sql
+// repository
+type memoryShortLinkRepository struct {
+	links map[uuid.UUID]*models.ShortLink
+}
+
+func (r *memoryShortLinkRepository) CreateShortLink(ctx context.Context, link *models.ShortLink) error {
+	for _, stored := range r.links {
+		if stored.Code == link.Code {
+			return models.ErrShortCodeTaken
+		}
+	}
+	link.ID, link.CreatedAt = uuid.New(), time.Now()
+	stored := *link
+	r.links[link.ID] = &stored
+	return nil
+}
+
+func (r *memoryShortLinkRepository) GetShortLinks(ctx context.Context, clientID uuid.UUID) ([]*models.ShortLink, error) {
+	links := make([]*models.ShortLink, 0)
+	for _, link := range r.links {
+		if link.ClientID == clientID {
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
+func (r *memoryShortLinkRepository) GetShortLink(ctx context.Context, id uuid.UUID, tenant models.Tenant) (*models.ShortLink, error) {
+	link, ok := r.links[id]
+	if !ok || !tenant.Owns(link.ClientID) {
+		return nil, models.ErrNotFound
+	}
+	stored := *link
+	return &stored, nil
+}
+
+func (r *memoryShortLinkRepository) GetShortLinkByCode(ctx context.Context, code string) (*models.ShortLink, error) {
+	for _, link := range r.links {
+		if link.Code == code {
+			stored := *link
+			return &stored, nil
+		}
+	}
+	return nil, models.ErrNotFound
+}
+
+func (r *memoryShortLinkRepository) GetMenuShortLink(ctx context.Context, menuID uuid.UUID) (*models.ShortLink, error) {
+	for _, link := range r.links {
+		if link.MenuID != nil && *link.MenuID == menuID {
+			stored := *link
+			return &stored, nil
+		}
+	}
+	return nil, models.ErrNotFound
+}
+
+func (r *memoryShortLinkRepository) UpdateShortLink(ctx context.Context, link *models.ShortLink, tenant models.Tenant) error {
+	stored, ok := r.links[link.ID]
+	if !ok || !tenant.Owns(stored.ClientID) {
+		return models.ErrNotFound
+	}
+	stored.Label, stored.MenuID, stored.Timezone, stored.Schedules = link.Label, link.MenuID, link.Timezone, link.Schedules
+	*link = *stored
+	return nil
+}
+
+func (r *memoryShortLinkRepository) DeleteShortLink(ctx context.Context, id uuid.UUID, tenant models.Tenant) error {
+	if _, err := r.GetShortLink(ctx, id, tenant); err != nil {
+		return err
+	}
+	delete(r.links, id)
+	return nil
+}
+
+// fakeClientRepository only knows clients without a timezone, the methods the
+// handlers under test do not reach are left unimplemented
+type fakeClientRepository struct {
+	repository.ClientRepository
+}
+
+func (fakeClientRepository) GetClient(ctx context.Context, clientID uuid.UUID) (*models.Client, error) {
+	return &models.Client{ID: clientID}, nil
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/api/middleware"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type TableHandler struct {
+	tableService services.TableService
+}
+
+// CreateTablesRequest represents the request body for adding tables to a menu,
+// either with the given labels or numbered with a prefix
+type CreateTablesRequest struct {
+	Labels []string `json:"labels" binding:"omitempty,max=200,dive,required,max=100"`
+	Count  int      `json:"count" binding:"omitempty,min=1,max=200"`
+	Prefix string   `json:"prefix" binding:"omitempty,max=90"`
+}
+
+// UpdateTableRequest represents the request body for renaming a table
+type UpdateTableRequest struct {
+	Label string `json:"label" binding:"required,max=100"`
+}
+
+func NewTableHandler(tableService services.TableService) *TableHandler {
+	return &TableHandler{
+		tableService: tableService,
+	}
+}
+
+// RegisterRoutes registers all routes for table operations
+func (h *TableHandler) RegisterRoutes(router *gin.RouterGroup, v1 *gin.RouterGroup) {
+	v1.GET("/tables/:token", h.ResolveTable)
+
+	menu := router.Group("/menu")
+	{
+		menu.GET("/:id/tables", h.GetTables)
+		menu.POST("/:id/tables", h.CreateTables)
+		menu.GET("/:id/tables/sheet", h.GetTableSheet)
+		menu.PUT("/tables/:tableId", h.UpdateTable)
+		menu.DELETE("/tables/:tableId", h.DeleteTable)
+		menu.GET("/tables/:tableId/qr", h.GetTableQRCode)
+	}
+}
+
+// @Summary List tables
+// @Description List the tables of a menu with their tokens and QR codes
+// @Tags tables
+// @Produce json
+// @Param id path string true "Menu ID"
+// @Success 200 {array} models.DiningTable
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/{id}/tables [get]
+// @Security Bearer
+func (h *TableHandler) GetTables(c *gin.Context) {
+	menuID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	tables, err := h.tableService.GetTables(c.Request.Context(), middleware.GetTenant(c), menuID)
+	if err != nil {
+		respondError(c, err, "menu not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, tables)
+}
+
+// @Summary Create tables
+// @Description Add tables to a menu, each with its own QR code. Either give the labels or a count of tables to number after the existing ones.
+// @Tags tables
+// @Accept json
+// @Produce json
+// @Param id path string true "Menu ID"
+// @Param request body CreateTablesRequest true "Tables"
+// @Success 201 {array} models.DiningTable
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/{id}/tables [post]
+// @Security Bearer
+func (h *TableHandler) CreateTables(c *gin.Context) {
+	menuID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req CreateTablesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if (len(req.Labels) == 0) == (req.Count == 0) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "either labels or count is required"})
+		return
+	}
+
+	tenant := middleware.GetTenant(c)
+	var tables []*models.DiningTable
+	var err error
+	if req.Count > 0 {
+		tables, err = h.tableService.CreateNumberedTables(c.Request.Context(), tenant, menuID, req.Count, req.Prefix)
+	} else {
+		tables, err = h.tableService.CreateTables(c.Request.Context(), tenant, menuID, req.Labels)
+	}
+	if err != nil {
+		respondError(c, err, "menu not found")
+		return
+	}
+
+	c.JSON(http.StatusCreated, tables)
+}
+
+// @Summary Update table
+// @Description Rename a table, its token and QR code stay the same
+// @Tags tables
+// @Accept json
+// @Produce json
+// @Param tableId path string true "Table ID"
+// @Param request body UpdateTableRequest true "Table"
+// @Success 200 {object} models.DiningTable
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/tables/{tableId} [put]
+// @Security Bearer
+func (h *TableHandler) UpdateTable(c *gin.Context) {
+	tableID, ok := parseIDParam(c, "tableId")
+	if !ok {
+		return
+	}
+
+	var req UpdateTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	table, err := h.tableService.UpdateTable(c.Request.Context(), middleware.GetTenant(c), tableID, req.Label)
+	if err != nil {
+		respondError(c, err, "table not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, table)
+}
+
+// @Summary Delete table
+// @Description Delete a table, its QR code stops resolving
+// @Tags tables
+// @Param tableId path string true "Table ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/tables/{tableId} [delete]
+// @Security Bearer
+func (h *TableHandler) DeleteTable(c *gin.Context) {
+	tableID, ok := parseIDParam(c, "tableId")
+	if !ok {
+		return
+	}
+
+	if err := h.tableService.DeleteTable(c.Request.Context(), middleware.GetTenant(c), tableID); err != nil {
+		respondError(c, err, "table not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Get table QR code
+// @Description Download the QR code of a table as a png
+// @Tags tables
+// @Produce png
+// @Param tableId path string true "Table ID"
+// @Success 200 {file} binary
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/tables/{tableId}/qr [get]
+// @Security Bearer
+func (h *TableHandler) GetTableQRCode(c *gin.Context) {
+	tableID, ok := parseIDParam(c, "tableId")
+	if !ok {
+		return
+	}
+
+	png, err := h.tableService.GetTableQRCode(c.Request.Context(), middleware.GetTenant(c), tableID)
+	if err != nil {
+		respondError(c, err, "table not found")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="table-%s.png"`, tableID))
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// @Summary Get printable table sheet
+// @Description Render a PDF with a cut-out card per table holding the QR code, the table label and the restaurant logo. Pick the tables with ids, or page through all of them with offset and limit.
+// @Tags tables
+// @Produce application/pdf
+// @Param id path string true "Menu ID"
+// @Param ids query string false "Comma separated table IDs"
+// @Param offset query int false "Tables to skip"
+// @Param limit query int false "Tables on the sheet, at most 100"
+// @Success 200 {file} binary
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /menu/{id}/tables/sheet [get]
+// @Security Bearer
+func (h *TableHandler) GetTableSheet(c *gin.Context) {
+	menuID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var tableIDs []uuid.UUID
+	if ids := c.Query("ids"); ids != "" {
+		for _, value := range strings.Split(ids, ",") {
+			id, err := uuid.Parse(strings.TrimSpace(value))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid table id"})
+				return
+			}
+			tableIDs = append(tableIDs, id)
+		}
+	}
+
+	offset, ok := parseQueryInt(c, "offset")
+	if !ok {
+		return
+	}
+	limit, ok := parseQueryInt(c, "limit")
+	if !ok {
+		return
+	}
+
+	pdf, err := h.tableService.GetTableSheet(c.Request.Context(), middleware.GetTenant(c), menuID, tableIDs, offset, limit)
+	if err != nil {
+		respondError(c, err, "menu not found")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="tables-%s.pdf"`, menuID))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// @Summary Resolve table
+// @Description Find the menu and table a scanned table token belongs to
+// @Tags tables
+// @Produce json
+// @Param token path string true "Table token"
+// @Success 200 {object} models.TableLocation
+// @Failure 404 {object} ErrorResponse
+// @Router /tables/{token} [get]
+func (h *TableHandler) ResolveTable(c *gin.Context) {
+	location, err := h.tableService.ResolveTable(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		respondError(c, err, "table not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, location)
+}
+
+// parseQueryInt reads an optional non-negative integer query parameter
+func parseQueryInt(c *gin.Context, name string) (int, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, true
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid " + name})
+		return 0, false
+	}
+
+	return number, true
+}
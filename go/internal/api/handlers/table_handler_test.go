@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestTablesAndSheet(t *testing.T) {
+	f := newTenantFixture(t)
+	tablesPath := "/menu/" + f.menuID.String() + "/tables"
+
+	body, contentType := jsonBody(map[string]any{"count": 3})()
+	w := f.do(t, "client-a", http.MethodPost, tablesPath, body, contentType)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("failed to create tables: %d %s", w.Code, w.Body.String())
+	}
+	var created []*models.DiningTable
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+
+	// numbering continues after the table from the fixture
+	labels := make([]string, 0, len(created))
+	for _, table := range created {
+		labels = append(labels, table.Label)
+		if table.Token == "" || table.QRCode == "" {
+			t.Errorf("expected a token and a qr code, got %+v", table)
+		}
+	}
+	if !slices.Equal(labels, []string{"Table 2", "Table 3", "Table 4"}) {
+		t.Errorf("unexpected labels %v", labels)
+	}
+
+	w = f.do(t, "", http.MethodGet, "/tables/"+created[1].Token, nil, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to resolve the token: %d %s", w.Code, w.Body.String())
+	}
+	var location models.TableLocation
+	if err := json.Unmarshal(w.Body.Bytes(), &location); err != nil {
+		t.Fatal(err)
+	}
+	if location.MenuID != f.menuID || location.TableID != created[1].ID || location.Label != "Table 3" {
+		t.Errorf("token resolved to %+v", location)
+	}
+
+	w = f.do(t, "client-a", http.MethodGet, tablesPath+"/sheet?offset=1&limit=2", nil, "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/pdf" {
+		t.Fatalf("failed to print the sheet: %d %s", w.Code, w.Body.String())
+	}
+
+	w = f.do(t, "client-a", http.MethodGet, tablesPath+"/sheet?ids="+uuid.NewString(), nil, "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a table of another menu, got %d", w.Code)
+	}
+
+	if w := f.do(t, "client-a", http.MethodDelete, "/menu/tables/"+created[0].ID.String(), nil, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("failed to delete the table: %d %s", w.Code, w.Body.String())
+	}
+	if w := f.do(t, "", http.MethodGet, "/tables/"+created[0].Token, nil, ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected the token of a deleted table to stop resolving, got %d", w.Code)
+	}
+}
+
+// memoryTableRepository scopes tables by the client of their menu, like the
+// joins in the sql repository
+type memoryTableRepository struct {
+	menus  *memoryMenuRepository
+	tables map[uuid.UUID]*models.DiningTable
+	order  []uuid.UUID
+}
+
+func (r *memoryTableRepository) owned(table *models.DiningTable, tenant models.Tenant) bool {
+	menu, ok := r.menus.menus[table.MenuID]
+	return ok && tenant.Owns(menu.ClientID)
+}
+
+func (r *memoryTableRepository) CreateTables(ctx context.Context, menuID uuid.UUID, tables []*models.DiningTable, tenant models.Tenant) error {
+	menu, ok := r.menus.menus[menuID]
+	if !ok || !tenant.Owns(menu.ClientID) {
+		return models.ErrNotFound
+	}
+	for _, table := range tables {
+		table.ID, table.MenuID, table.CreatedAt = uuid.New(), menuID, time.Now()
+		stored := *table
+		r.tables[table.ID] = &stored
+		r.order = append(r.order, table.ID)
+	}
+	return nil
+}
+
+func (r *memoryTableRepository) GetTables(ctx context.Context, menuID uuid.UUID, tenant models.Tenant) ([]*models.DiningTable, error) {
+	tables := make([]*models.DiningTable, 0)
+	for _, id := range r.order {
+		if table, ok := r.tables[id]; ok && table.MenuID == menuID && r.owned(table, tenant) {
+			tables = append(tables, table)
+		}
+	}
+	return tables, nil
+}
+
+func (r *memoryTableRepository) GetTable(ctx context.Context, tableID uuid.UUID, tenant models.Tenant) (*models.DiningTable, error) {
+	table, ok := r.tables[tableID]
+	if !ok || !r.owned(table, tenant) {
+		return nil, models.ErrNotFound
+	}
+	return table, nil
+}
+
+func (r *memoryTableRepository) GetTableByToken(ctx context.Context, token string) (*models.DiningTable, error) {
+	for _, table := range r.tables {
+		if table.Token == token {
+			return table, nil
+		}
+	}
+	return nil, models.ErrNotFound
+}
+
+func (r *memoryTableRepository) UpdateTable(ctx context.Context, table *models.DiningTable, tenant models.Tenant) error {
+	stored, err := r.GetTable(ctx, table.ID, tenant)
+	if err != nil {
+		return err
+	}
+	stored.Label = table.Label
+	*table = *stored
+	return nil
+}
+
+func (r *memoryTableRepository) UpdateTableQRCode(ctx context.Context, tableID uuid.UUID, qrCode string, tenant models.Tenant) error {
+	stored, err := r.GetTable(ctx, tableID, tenant)
+	if err != nil {
+		return err
+	}
+	stored.QRCode = qrCode
+	return nil
+}
+
+func (r *memoryTableRepository) DeleteTable(ctx context.Context, tableID uuid.UUID, tenant models.Tenant) error {
+	if _, err := r.GetTable(ctx, tableID, tenant); err != nil {
+		return err
+	}
+	delete(r.tables, tableID)
+	return nil
+}
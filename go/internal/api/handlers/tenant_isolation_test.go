@@ -0,0 +1,804 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/mq"
+	"github.com/ahmetkoprulu/bidi-menu/common/qrcode"
+	"github.com/ahmetkoprulu/bidi-menu/common/utils"
+	"github.com/ahmetkoprulu/bidi-menu/internal/api/middleware"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/services/impl"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	clientA = uuid.MustParse("aaaaaaaa-0000-0000-0000-000000000001")
+	clientB = uuid.MustParse("bbbbbbbb-0000-0000-0000-000000000002")
+	adminID = uuid.MustParse("cccccccc-0000-0000-0000-000000000003")
+)
+
+// tenantFixture is a router wired with the real services on top of in-memory
+// repositories. Menu, model and scan job lookups ignore the tenant on purpose,
+// so those checks have to come from the service and handler layers. Category
+// and item changes are scoped in a single statement by the repository, and the
+// in-memory one mirrors that.
+type tenantFixture struct {
+	router     *gin.Engine
+	menus      *memoryMenuRepository
+	models     *memoryModelRepository
+	tables     *memoryTableRepository
+	shortLinks *memoryShortLinkRepository
+	scanJobs   *memoryScanJobRepository
+	menuID     uuid.UUID
+	categoryID uuid.UUID
+	itemID     uuid.UUID
+	tableID    uuid.UUID
+	linkID     uuid.UUID
+	modelID    uuid.UUID
+	jobID      uuid.UUID
+}
+
+func newTenantFixture(t *testing.T) *tenantFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	menuRepo := &memoryMenuRepository{menus: map[uuid.UUID]*models.Menu{}, versions: map[uuid.UUID][]*models.MenuVersion{}}
+	modelRepo := &memoryModelRepository{models: map[uuid.UUID]models.Model{}}
+	scanJobRepo := &memoryScanJobRepository{jobs: map[uuid.UUID]*models.ScanJob{}}
+	tableRepo := &memoryTableRepository{menus: menuRepo, tables: map[uuid.UUID]*models.DiningTable{}}
+	shortLinkRepo := &memoryShortLinkRepository{links: map[uuid.UUID]*models.ShortLink{}}
+
+	shortLinkService := impl.NewShortLinkService(shortLinkRepo, menuRepo, fakeClientRepository{}, "https://menu.test", "https://go.test")
+	menuService := impl.NewMenuService(menuRepo, shortLinkService, fakeQRCodeService{})
+	modelService := impl.NewModelService(modelRepo, fakeStorageService{}, mq.NewMemoryMqProvider(1), models.ThumbnailConfig{})
+	scanService := impl.NewScanService(scanJobRepo, nil, mq.NewMemoryMqProvider(1), t.TempDir())
+	tableService := impl.NewTableService(tableRepo, menuRepo, fakeQRCodeService{})
+
+	router := gin.New()
+	v1 := router.Group("/api/v1")
+	protected := v1.Group("", middleware.AuthMiddleware(fakeAuthService{}))
+	NewMenuHandler(menuService, scanService).RegisterRoutes(protected, v1)
+	NewModelHandler(modelService, menuService, fakeStorageService{}).RegisterRoutes(protected)
+	NewTableHandler(tableService).RegisterRoutes(protected, v1)
+	shortLinkHandler := NewShortLinkHandler(shortLinkService)
+	shortLinkHandler.RegisterRoutes(protected, v1)
+	shortLinkHandler.RegisterRedirect(router.Group(""))
+
+	ctx := context.Background()
+	category := &models.MenuCategory{
+		ID:        uuid.New(),
+		Name:      "Mains",
+		MenuItems: []*models.MenuCategoryItem{{ID: uuid.New(), Name: "Burger", Price: 12}},
+	}
+	menuID, err := menuRepo.CreateMenu(ctx, &models.Menu{
+		ClientID:   clientA,
+		Label:      "Lunch",
+		Categories: []*models.MenuCategory{category},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := menuService.PublishMenu(ctx, models.Tenant{ClientID: clientA}, menuID); err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := tableService.CreateTables(ctx, models.Tenant{ClientID: clientA}, menuID, []string{"Terrace 1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	menu, err := menuRepo.GetMenuById(ctx, menuID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, err := shortLinkService.EnsureMenuLink(ctx, models.Tenant{ClientID: clientA}, menu)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modelID, err := modelRepo.CreateModel(ctx, &models.Model{ClientID: clientA, Name: "Burger"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := &models.ScanJob{ID: uuid.New(), ClientID: clientA, Status: models.ScanJobStatusQueued}
+	if err := scanJobRepo.CreateScanJob(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+
+	return &tenantFixture{
+		router:     router,
+		menus:      menuRepo,
+		models:     modelRepo,
+		tables:     tableRepo,
+		shortLinks: shortLinkRepo,
+		scanJobs:   scanJobRepo,
+		menuID:     menuID,
+		categoryID: category.ID,
+		itemID:     category.MenuItems[0].ID,
+		tableID:    tables[0].ID,
+		linkID:     link.ID,
+		modelID:    *modelID,
+		jobID:      job.ID,
+	}
+}
+
+func (f *tenantFixture) do(t *testing.T, token, method, path string, body *bytes.Buffer, contentType string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	if body == nil {
+		body = &bytes.Buffer{}
+	}
+
+	req := httptest.NewRequest(method, "/api/v1"+path, body)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCrossTenantAccessIsNotFound(t *testing.T) {
+	f := newTenantFixture(t)
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   func() (*bytes.Buffer, string)
+	}{
+		{"delete menu", http.MethodDelete, "/menu/" + f.menuID.String(), nil},
+		{"create menu for another client", http.MethodPost, "/menu", jsonBody(map[string]any{
+			"clientID": clientA, "label": "Injected",
+		})},
+		{"update menu of another client", http.MethodPost, "/menu", jsonBody(map[string]any{
+			"id": f.menuID, "clientID": clientA, "label": "Renamed",
+		})},
+		{"move menu of another client", http.MethodPost, "/menu", jsonBody(map[string]any{
+			"id": f.menuID, "clientID": clientB, "label": "Taken",
+		})},
+		{"get scan job", http.MethodGet, "/menu/scan/" + f.jobID.String(), nil},
+		{"get draft", http.MethodGet, "/menu/" + f.menuID.String() + "/draft", nil},
+		{"export qr code", http.MethodGet, "/menu/" + f.menuID.String() + "/qr/export?format=svg", nil},
+		{"publish menu", http.MethodPost, "/menu/" + f.menuID.String() + "/publish", nil},
+		{"list versions", http.MethodGet, "/menu/" + f.menuID.String() + "/versions", nil},
+		{"get version", http.MethodGet, "/menu/" + f.menuID.String() + "/versions/1", nil},
+		{"diff versions", http.MethodGet, "/menu/" + f.menuID.String() + "/versions/diff?from=1", nil},
+		{"roll back menu", http.MethodPost, "/menu/" + f.menuID.String() + "/versions/1/rollback", nil},
+		{"create category", http.MethodPost, "/menu/" + f.menuID.String() + "/categories", jsonBody(map[string]any{
+			"name": "Injected",
+		})},
+		{"rename category", http.MethodPut, "/menu/categories/" + f.categoryID.String(), jsonBody(map[string]any{
+			"name": "Renamed",
+		})},
+		{"move category", http.MethodPut, "/menu/categories/" + f.categoryID.String() + "/order", jsonBody(map[string]any{
+			"order": 3,
+		})},
+		{"reorder categories", http.MethodPut, "/menu/categories/order", jsonBody(map[string]any{
+			"categories": []map[string]any{{"id": f.categoryID, "order": 2}},
+		})},
+		{"toggle category", http.MethodPut, "/menu/categories/" + f.categoryID.String() + "/status", jsonBody(map[string]any{
+			"status": "inactive",
+		})},
+		{"delete category", http.MethodDelete, "/menu/categories/" + f.categoryID.String(), nil},
+		{"create item", http.MethodPost, "/menu/items", jsonBody(map[string]any{
+			"categoryId": f.categoryID, "name": "Injected", "price": 1,
+		})},
+		{"update item", http.MethodPut, "/menu/items/" + f.itemID.String(), jsonBody(map[string]any{
+			"name": "Renamed", "price": 1,
+		})},
+		{"move item", http.MethodPut, "/menu/items/" + f.itemID.String() + "/move", jsonBody(map[string]any{
+			"categoryId": f.categoryID, "order": 0,
+		})},
+		{"update item images", http.MethodPut, "/menu/items/" + f.itemID.String() + "/images", jsonBody(map[string]any{
+			"images": []string{"https://example.com/injected.png"},
+		})},
+		{"toggle items", http.MethodPut, "/menu/items/status", jsonBody(map[string]any{
+			"itemIds": []uuid.UUID{f.itemID}, "status": "inactive",
+		})},
+		{"delete item", http.MethodDelete, "/menu/items/" + f.itemID.String(), nil},
+		{"list tables", http.MethodGet, "/menu/" + f.menuID.String() + "/tables", nil},
+		{"create tables", http.MethodPost, "/menu/" + f.menuID.String() + "/tables", jsonBody(map[string]any{
+			"count": 2,
+		})},
+		{"rename table", http.MethodPut, "/menu/tables/" + f.tableID.String(), jsonBody(map[string]any{
+			"label": "Renamed",
+		})},
+		{"get table qr code", http.MethodGet, "/menu/tables/" + f.tableID.String() + "/qr", nil},
+		{"print table sheet", http.MethodGet, "/menu/" + f.menuID.String() + "/tables/sheet", nil},
+		{"delete table", http.MethodDelete, "/menu/tables/" + f.tableID.String(), nil},
+		{"list links", http.MethodGet, "/links?clientId=" + clientA.String(), nil},
+		{"get link", http.MethodGet, "/links/" + f.linkID.String(), nil},
+		{"create link for another client", http.MethodPost, "/links", jsonBody(map[string]any{
+			"clientId": clientA, "menuId": f.menuID,
+		})},
+		{"create link to another client's menu", http.MethodPost, "/links", jsonBody(map[string]any{
+			"menuId": f.menuID,
+		})},
+		{"re-point link", http.MethodPut, "/links/" + f.linkID.String(), jsonBody(map[string]any{
+			"menuId": f.menuID, "label": "Taken",
+		})},
+		{"delete link", http.MethodDelete, "/links/" + f.linkID.String(), nil},
+		{"get model", http.MethodGet, "/model/" + f.modelID.String(), nil},
+		{"list models", http.MethodGet, "/model/list?clientId=" + clientA.String(), nil},
+		{"create model for another client", http.MethodPost, "/model", modelForm(clientA)},
+		{"delete model", http.MethodDelete, "/model/" + f.modelID.String(), nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var body *bytes.Buffer
+			var contentType string
+			if tc.body != nil {
+				body, contentType = tc.body()
+			}
+
+			w := f.do(t, "client-b", tc.method, tc.path, body, contentType)
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("expected 404, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+
+	menu, err := f.menus.GetMenuById(context.Background(), f.menuID)
+	if err != nil {
+		t.Fatalf("menu of client a is gone: %v", err)
+	}
+	if menu.Label != "Lunch" || menu.ClientID != clientA {
+		t.Errorf("menu of client a was changed: %+v", menu)
+	}
+	if len(menu.Categories) != 1 || menu.Categories[0].Name != "Mains" || menu.Categories[0].Status != "" {
+		t.Errorf("categories of client a were changed: %+v", menu.Categories)
+	}
+	if items := menu.Categories[0].MenuItems; len(items) != 1 || items[0].Name != "Burger" || len(items[0].Images) != 0 || items[0].Status != "" {
+		t.Errorf("items of client a were changed: %+v", items)
+	}
+
+	if versions := f.menus.versions[f.menuID]; len(versions) != 1 {
+		t.Errorf("client b published client a's menu, %d versions", len(versions))
+	}
+
+	if table, ok := f.tables.tables[f.tableID]; !ok || table.Label != "Terrace 1" || len(f.tables.tables) != 1 {
+		t.Errorf("tables of client a were changed: %+v", f.tables.tables)
+	}
+
+	if link, ok := f.shortLinks.links[f.linkID]; !ok || link.Label != "Lunch" || len(f.shortLinks.links) != 1 {
+		t.Errorf("short links of client a were changed: %+v", f.shortLinks.links)
+	}
+
+	if _, ok := f.models.models[f.modelID]; !ok {
+		t.Errorf("model of client a is gone")
+	}
+	for _, model := range f.models.models {
+		if model.ClientID == clientA && model.ID != nil && *model.ID != f.modelID {
+			t.Errorf("client b created a model for client a")
+		}
+	}
+}
+
+func TestOwnMenuListExcludesOtherClients(t *testing.T) {
+	f := newTenantFixture(t)
+
+	w := f.do(t, "client-b", http.MethodGet, "/menu", nil, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var categories []*models.MenuCategory
+	if err := json.Unmarshal(w.Body.Bytes(), &categories); err != nil {
+		t.Fatal(err)
+	}
+	if len(categories) != 0 {
+		t.Errorf("client b sees %d categories of client a", len(categories))
+	}
+
+	w = f.do(t, "client-b", http.MethodGet, "/model/list", nil, "")
+	if w.Code != http.StatusOK || w.Body.String() != "[]" {
+		t.Errorf("expected an empty model list, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestOwnerAndAdminAccess(t *testing.T) {
+	f := newTenantFixture(t)
+
+	for _, tc := range []struct {
+		token  string
+		method string
+		path   string
+		status int
+	}{
+		{"client-a", http.MethodGet, "/model/" + f.modelID.String(), http.StatusOK},
+		{"client-a", http.MethodGet, "/model/list", http.StatusOK},
+		{"client-a", http.MethodGet, "/menu/scan/" + f.jobID.String(), http.StatusOK},
+		{"admin", http.MethodGet, "/model/" + f.modelID.String(), http.StatusOK},
+		{"admin", http.MethodGet, "/model/list?clientId=" + clientA.String(), http.StatusOK},
+		{"admin", http.MethodDelete, "/model/" + f.modelID.String(), http.StatusNoContent},
+		{"client-a", http.MethodDelete, "/menu/items/" + f.itemID.String(), http.StatusNoContent},
+		{"admin", http.MethodDelete, "/menu/categories/" + f.categoryID.String(), http.StatusNoContent},
+		{"client-a", http.MethodDelete, "/menu/" + f.menuID.String(), http.StatusNoContent},
+	} {
+		w := f.do(t, tc.token, tc.method, tc.path, nil, "")
+		if w.Code != tc.status {
+			t.Errorf("%s %s %s: expected %d, got %d: %s", tc.token, tc.method, tc.path, tc.status, w.Code, w.Body.String())
+		}
+	}
+
+	body, contentType := jsonBody(map[string]any{"clientID": clientA, "label": "Dinner"})()
+	if w := f.do(t, "admin", http.MethodPost, "/menu", body, contentType); w.Code != http.StatusOK {
+		t.Errorf("admin should create menus for any client, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func jsonBody(value any) func() (*bytes.Buffer, string) {
+	return func() (*bytes.Buffer, string) {
+		data, _ := json.Marshal(value)
+		return bytes.NewBuffer(data), "application/json"
+	}
+}
+
+func modelForm(clientID uuid.UUID) func() (*bytes.Buffer, string) {
+	return func() (*bytes.Buffer, string) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		writer.WriteField("name", "Injected")
+		writer.WriteField("clientId", clientID.String())
+		for _, field := range []string{"glb", "usdz", "thumbnail"} {
+			part, _ := writer.CreateFormFile(field, field+".bin")
+			part.Write([]byte("data"))
+		}
+		writer.Close()
+		return body, writer.FormDataContentType()
+	}
+}
+
+type fakeAuthService struct{}
+
+func (fakeAuthService) ValidateToken(ctx context.Context, token string) (*models.Client, *utils.Claims, error) {
+	switch token {
+	case "client-a":
+		return &models.Client{ID: clientA}, &utils.Claims{Roles: []string{"client"}}, nil
+	case "client-b":
+		return &models.Client{ID: clientB}, &utils.Claims{Roles: []string{"client"}}, nil
+	case "admin":
+		return &models.Client{ID: adminID}, &utils.Claims{Roles: []string{"admin"}}, nil
+	}
+	return nil, nil, errors.New("invalid token")
+}
+
+func (fakeAuthService) Register(ctx context.Context, req *models.RegisterRequest) (*models.Client, error) {
+	return nil, nil
+}
+
+func (fakeAuthService) Login(ctx context.Context, email, password string) (*models.Client, string, error) {
+	return nil, "", nil
+}
+
+func (fakeAuthService) CompleteInit(ctx context.Context, token string, req *models.RegisterRequest) error {
+	return nil
+}
+
+func (fakeAuthService) ResetPassword(ctx context.Context, clientID uuid.UUID, currentPassword, newPassword string) error {
+	return nil
+}
+
+func (fakeAuthService) GenerateToken(ctx context.Context, client *models.Client) (string, error) {
+	return "", nil
+}
+
+type fakeStorageService struct{}
+
+func (fakeStorageService) SaveGlbModel(file *multipart.FileHeader, modelID uuid.UUID) (string, error) {
+	return "/models/" + modelID.String() + ".glb", nil
+}
+
+func (fakeStorageService) SaveUsdzModel(file *multipart.FileHeader, modelID uuid.UUID) (string, error) {
+	return "/models/" + modelID.String() + ".usdz", nil
+}
+
+func (fakeStorageService) SaveGeneratedUsdzModel(data []byte, modelID uuid.UUID) (string, error) {
+	return "/models/" + modelID.String() + ".usdz", nil
+}
+
+func (fakeStorageService) SaveThumbnail(file *multipart.FileHeader, modelID uuid.UUID) (string, error) {
+	return "/thumbnails/" + modelID.String() + ".png", nil
+}
+
+func (fakeStorageService) SaveRenderedThumbnail(data []byte, modelID uuid.UUID, variant, ext string) (string, error) {
+	return "thumbnails/" + modelID.String() + variant + ext, nil
+}
+func (fakeStorageService) DeleteGlbModel(modelID uuid.UUID) error         { return nil }
+func (fakeStorageService) ReadGlbModel(modelID uuid.UUID) ([]byte, error) { return nil, os.ErrNotExist }
+func (fakeStorageService) SaveOptimizedGlbModel(data []byte, modelID uuid.UUID) (string, error) {
+	return "", nil
+}
+func (fakeStorageService) DeleteOptimizedGlbModel(modelID uuid.UUID) error      { return nil }
+func (fakeStorageService) GetPublicOptimizedGlbPath(modelID uuid.UUID) string   { return "" }
+func (fakeStorageService) DeleteUsdzModel(modelID uuid.UUID) error              { return nil }
+func (fakeStorageService) DeleteThumbnail(modelID uuid.UUID) error              { return nil }
+func (fakeStorageService) ReplaceModelFiles(stagingID, modelID uuid.UUID) error { return nil }
+func (fakeStorageService) GetPublicGlbPath(modelID uuid.UUID) string            { return "" }
+func (fakeStorageService) GetPublicUsdzPath(modelID uuid.UUID) string           { return "" }
+func (fakeStorageService) GetPublicThumbnailPath(modelID uuid.UUID) string      { return "" }
+func (fakeStorageService) SaveQRCode(data []byte, menuID uuid.UUID) (string, error) {
+	return "", nil
+}
+func (fakeStorageService) DeleteQRCode(menuID uuid.UUID) error         { return nil }
+func (fakeStorageService) GetPublicQRCodePath(menuID uuid.UUID) string { return "" }
+func (fakeStorageService) ReadFile(location string) ([]byte, error)    { return nil, os.ErrNotExist }
+
+type fakeQRCodeService struct{}
+
+func (fakeQRCodeService) GenerateMenuQRCode(ctx context.Context, menu *models.Menu) (string, error) {
+	return "/static/qrcodes/" + menu.ID.String() + ".png", nil
+}
+func (fakeQRCodeService) GenerateTableQRCode(ctx context.Context, menu *models.Menu, table *models.DiningTable) (string, error) {
+	return "/static/qrcodes/" + table.ID.String() + ".png", nil
+}
+func (fakeQRCodeService) RenderTableQRCode(ctx context.Context, menu *models.Menu, table *models.DiningTable) ([]byte, error) {
+	return []byte("png"), nil
+}
+func (fakeQRCodeService) RenderTableSheet(ctx context.Context, menu *models.Menu, tables []*models.DiningTable) ([]byte, error) {
+	return []byte("%PDF"), nil
+}
+func (fakeQRCodeService) ExportMenuQRCode(ctx context.Context, menu *models.Menu, opts qrcode.ExportOptions) (*models.QRCodeExport, error) {
+	data, err := qrcode.Export("/menu/view/"+menu.ID.String(), opts)
+	if err != nil {
+		return nil, err
+	}
+	return &models.QRCodeExport{Data: data, ContentType: qrcode.ContentType(opts.Format), Format: opts.Format, ETag: opts.Format + "-export"}, nil
+}
+func (fakeQRCodeService) DeleteQRCode(id uuid.UUID) error { return nil }
+
+type memoryMenuRepository struct {
+	menus    map[uuid.UUID]*models.Menu
+	versions map[uuid.UUID][]*models.MenuVersion
+}
+
+func (r *memoryMenuRepository) CreateMenu(ctx context.Context, menu *models.Menu) (uuid.UUID, error) {
+	id := uuid.New()
+	menu.ID = &id
+	stored := *menu
+	r.menus[id] = &stored
+	return id, nil
+}
+
+func (r *memoryMenuRepository) UpdateMenu(ctx context.Context, menu *models.Menu, tenant models.Tenant) error {
+	existing, ok := r.menus[*menu.ID]
+	if !ok {
+		return models.ErrNotFound
+	}
+	stored := *menu
+	stored.PublishedVersion = existing.PublishedVersion
+	stored.QRCode = existing.QRCode
+	r.menus[*menu.ID] = &stored
+	return nil
+}
+
+func (r *memoryMenuRepository) GetMenuWithCategories(ctx context.Context, clientID uuid.UUID) ([]*models.MenuCategory, error) {
+	categories := make([]*models.MenuCategory, 0)
+	for _, menu := range r.menus {
+		if menu.ClientID == clientID {
+			categories = append(categories, menu.Categories...)
+		}
+	}
+	return categories, nil
+}
+
+func (r *memoryMenuRepository) GetMenuById(ctx context.Context, id uuid.UUID) (*models.Menu, error) {
+	menu, ok := r.menus[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return menu, nil
+}
+
+func (r *memoryMenuRepository) DeleteMenu(ctx context.Context, id uuid.UUID, tenant models.Tenant) error {
+	delete(r.menus, id)
+	return nil
+}
+
+func (r *memoryMenuRepository) UpdateMenuQRCode(ctx context.Context, id uuid.UUID, qrCode string, tenant models.Tenant) error {
+	menu, ok := r.menus[id]
+	if !ok || !tenant.Owns(menu.ClientID) {
+		return models.ErrNotFound
+	}
+	menu.QRCode = qrCode
+	return nil
+}
+
+func (r *memoryMenuRepository) CreateCategory(ctx context.Context, category *models.MenuCategory, tenant models.Tenant) error {
+	menu, ok := r.menus[category.MenuID]
+	if !ok || !tenant.Owns(menu.ClientID) {
+		return models.ErrNotFound
+	}
+	category.ID = uuid.New()
+	menu.Categories = append(menu.Categories, category)
+	return nil
+}
+
+func (r *memoryMenuRepository) UpdateCategory(ctx context.Context, category *models.MenuCategory, tenant models.Tenant) error {
+	_, stored := r.findCategory(category.ID, tenant)
+	if stored == nil {
+		return models.ErrNotFound
+	}
+	stored.Name, stored.Translations = category.Name, category.Translations
+	return nil
+}
+
+func (r *memoryMenuRepository) UpdateCategoryOrder(ctx context.Context, categoryID uuid.UUID, order int, tenant models.Tenant) error {
+	return r.ReorderCategories(ctx, map[uuid.UUID]int{categoryID: order}, tenant)
+}
+
+func (r *memoryMenuRepository) DeleteCategory(ctx context.Context, categoryID uuid.UUID, tenant models.Tenant) error {
+	menu, stored := r.findCategory(categoryID, tenant)
+	if stored == nil {
+		return models.ErrNotFound
+	}
+	menu.Categories = slices.DeleteFunc(menu.Categories, func(c *models.MenuCategory) bool { return c == stored })
+	return nil
+}
+
+func (r *memoryMenuRepository) CreateMenuItem(ctx context.Context, categoryID uuid.UUID, item *models.MenuCategoryItem, tenant models.Tenant) error {
+	_, category := r.findCategory(categoryID, tenant)
+	if category == nil {
+		return models.ErrNotFound
+	}
+	item.ID = uuid.New()
+	category.MenuItems = append(category.MenuItems, item)
+	return nil
+}
+
+func (r *memoryMenuRepository) UpdateMenuItem(ctx context.Context, item *models.MenuCategoryItem, tenant models.Tenant) error {
+	_, stored := r.findItem(item.ID, tenant)
+	if stored == nil {
+		return models.ErrNotFound
+	}
+	stored.Name, stored.Description, stored.Price = item.Name, item.Description, item.Price
+	stored.Variants, stored.ModifierGroups = item.Variants, item.ModifierGroups
+	stored.Allergens, stored.DietaryTags, stored.SpiceLevel = item.Allergens, item.DietaryTags, item.SpiceLevel
+	stored.Calories, stored.Nutrition, stored.Translations = item.Calories, item.Nutrition, item.Translations
+	return nil
+}
+
+func (r *memoryMenuRepository) MoveMenuItem(ctx context.Context, itemID uuid.UUID, categoryID uuid.UUID, order int, tenant models.Tenant) error {
+	if _, item := r.findItem(itemID, tenant); item == nil {
+		return models.ErrNotFound
+	}
+	if _, category := r.findCategory(categoryID, tenant); category == nil {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+func (r *memoryMenuRepository) DeleteMenuItem(ctx context.Context, itemID uuid.UUID, tenant models.Tenant) error {
+	category, stored := r.findItem(itemID, tenant)
+	if stored == nil {
+		return models.ErrNotFound
+	}
+	category.MenuItems = slices.DeleteFunc(category.MenuItems, func(i *models.MenuCategoryItem) bool { return i == stored })
+	return nil
+}
+
+func (r *memoryMenuRepository) UpdateItemImages(ctx context.Context, itemID uuid.UUID, images []string, tenant models.Tenant) error {
+	_, stored := r.findItem(itemID, tenant)
+	if stored == nil {
+		return models.ErrNotFound
+	}
+	stored.Images = images
+	return nil
+}
+
+func (r *memoryMenuRepository) ReorderCategories(ctx context.Context, categoryOrders map[uuid.UUID]int, tenant models.Tenant) error {
+	for categoryID := range categoryOrders {
+		if _, category := r.findCategory(categoryID, tenant); category == nil {
+			return models.ErrNotFound
+		}
+	}
+	return nil
+}
+
+func (r *memoryMenuRepository) UpdateCategoryStatus(ctx context.Context, categoryID uuid.UUID, status models.MenuStatus, tenant models.Tenant) error {
+	_, stored := r.findCategory(categoryID, tenant)
+	if stored == nil {
+		return models.ErrNotFound
+	}
+	stored.Status = status
+	return nil
+}
+
+func (r *memoryMenuRepository) UpdateItemsStatus(ctx context.Context, itemIDs []uuid.UUID, status models.MenuStatus, tenant models.Tenant) error {
+	updated := 0
+	for _, itemID := range itemIDs {
+		if _, item := r.findItem(itemID, tenant); item != nil {
+			item.Status = status
+			updated++
+		}
+	}
+	if updated == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+func (r *memoryMenuRepository) findCategory(categoryID uuid.UUID, tenant models.Tenant) (*models.Menu, *models.MenuCategory) {
+	for _, menu := range r.menus {
+		for _, category := range menu.Categories {
+			if category.ID == categoryID && tenant.Owns(menu.ClientID) {
+				return menu, category
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryMenuRepository) findItem(itemID uuid.UUID, tenant models.Tenant) (*models.MenuCategory, *models.MenuCategoryItem) {
+	for _, menu := range r.menus {
+		for _, category := range menu.Categories {
+			for _, item := range category.MenuItems {
+				if item.ID == itemID && tenant.Owns(menu.ClientID) {
+					return category, item
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryMenuRepository) RemoveModelFromMenuItems(ctx context.Context, modelID uuid.UUID) error {
+	return nil
+}
+
+func (r *memoryMenuRepository) CreateMenuVersion(ctx context.Context, version *models.MenuVersion, tenant models.Tenant) error {
+	menu, ok := r.menus[version.MenuID]
+	if !ok {
+		return models.ErrNotFound
+	}
+
+	// keep a deep copy, like the stored json
+	data, _ := json.Marshal(version.Snapshot)
+	stored := *version
+	stored.Snapshot = nil
+	json.Unmarshal(data, &stored.Snapshot)
+
+	stored.Version = len(r.versions[version.MenuID]) + 1
+	version.Version = stored.Version
+	r.versions[version.MenuID] = append(r.versions[version.MenuID], &stored)
+	menu.PublishedVersion = &stored.Version
+	return nil
+}
+
+func (r *memoryMenuRepository) GetMenuVersions(ctx context.Context, menuID uuid.UUID, tenant models.Tenant) ([]*models.MenuVersion, error) {
+	return r.versions[menuID], nil
+}
+
+func (r *memoryMenuRepository) GetMenuVersion(ctx context.Context, menuID uuid.UUID, version int, tenant models.Tenant) (*models.MenuVersion, error) {
+	versions := r.versions[menuID]
+	if version < 1 || version > len(versions) {
+		return nil, models.ErrNotFound
+	}
+	return versions[version-1], nil
+}
+
+func (r *memoryMenuRepository) GetPublishedMenu(ctx context.Context, menuID uuid.UUID) (*models.Menu, error) {
+	menu, ok := r.menus[menuID]
+	if !ok || menu.PublishedVersion == nil {
+		return nil, models.ErrNotFound
+	}
+
+	published := *r.versions[menuID][*menu.PublishedVersion-1].Snapshot
+	published.PublishedVersion = menu.PublishedVersion
+	return &published, nil
+}
+
+type memoryModelRepository struct {
+	models map[uuid.UUID]models.Model
+}
+
+func (r *memoryModelRepository) CreateModel(ctx context.Context, model *models.Model) (*uuid.UUID, error) {
+	if model.ID == nil {
+		id := uuid.New()
+		model.ID = &id
+	}
+	r.models[*model.ID] = *model
+	return model.ID, nil
+}
+
+func (r *memoryModelRepository) UpdateModel(ctx context.Context, model *models.Model, tenant models.Tenant) error {
+	r.models[*model.ID] = *model
+	return nil
+}
+
+func (r *memoryModelRepository) UpdateModelOptimization(ctx context.Context, modelID uuid.UUID, optimizedGlbFile string, optimization *models.ModelOptimization) error {
+	model, ok := r.models[modelID]
+	if !ok {
+		return models.ErrNotFound
+	}
+	model.OptimizedGlbFile, model.Optimization = optimizedGlbFile, optimization
+	r.models[modelID] = model
+	return nil
+}
+
+func (r *memoryModelRepository) UpdateModelUsdzConversion(ctx context.Context, modelID uuid.UUID, usdzFile string, conversion *models.ModelConversion) error {
+	model, ok := r.models[modelID]
+	if !ok {
+		return models.ErrNotFound
+	}
+	model.UsdzFile, model.UsdzConversion = usdzFile, conversion
+	r.models[modelID] = model
+	return nil
+}
+
+func (r *memoryModelRepository) GetModel(ctx context.Context, modelID uuid.UUID) (models.Model, error) {
+	return r.GetModelById(ctx, modelID, models.Tenant{})
+}
+
+func (r *memoryModelRepository) GetModels(ctx context.Context, clientID uuid.UUID) ([]models.Model, error) {
+	ms := make([]models.Model, 0)
+	for _, model := range r.models {
+		if model.ClientID == clientID {
+			ms = append(ms, model)
+		}
+	}
+	return ms, nil
+}
+
+func (r *memoryModelRepository) DeleteModel(ctx context.Context, modelID uuid.UUID, tenant models.Tenant) error {
+	delete(r.models, modelID)
+	return nil
+}
+
+func (r *memoryModelRepository) GetModelById(ctx context.Context, modelID uuid.UUID, tenant models.Tenant) (models.Model, error) {
+	model, ok := r.models[modelID]
+	if !ok {
+		return models.Model{}, models.ErrNotFound
+	}
+	return model, nil
+}
+
+type memoryScanJobRepository struct {
+	jobs map[uuid.UUID]*models.ScanJob
+	err  error
+}
+
+func (r *memoryScanJobRepository) CreateScanJob(ctx context.Context, job *models.ScanJob) error {
+	r.jobs[job.ID] = job
+	return nil
+}
+
+func (r *memoryScanJobRepository) GetScanJob(ctx context.Context, id uuid.UUID, clientID uuid.UUID) (*models.ScanJob, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return job, nil
+}
+
+func (r *memoryScanJobRepository) UpdateScanJobStatus(ctx context.Context, id uuid.UUID, status models.ScanJobStatus, errorMessage *string) error {
+	return nil
+}
+
+func (r *memoryScanJobRepository) UpdateScanJobProgress(ctx context.Context, id uuid.UUID, processedPages int, totalPages int) error {
+	return nil
+}
+
+func (r *memoryScanJobRepository) CompleteScanJob(ctx context.Context, id uuid.UUID, result *models.ScanResult) error {
+	return nil
+}
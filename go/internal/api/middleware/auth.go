@@ -2,10 +2,13 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
 	"strings"
 
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
 	"github.com/ahmetkoprulu/bidi-menu/internal/services"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 const (
@@ -47,6 +50,17 @@ func AuthMiddleware(authService services.AuthService) gin.HandlerFunc {
 	}
 }
 
+// GetTenant returns the client the authenticated request acts for
+func GetTenant(c *gin.Context) models.Tenant {
+	roles, _ := c.Get(UserRoleKey)
+	roleList, _ := roles.([]string)
+
+	return models.Tenant{
+		ClientID: c.MustGet(ClientIDKey).(uuid.UUID),
+		IsAdmin:  slices.Contains(roleList, "admin"),
+	}
+}
+
 func RequireRole(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userRole := c.MustGet(UserRoleKey).(string)
@@ -2,7 +2,6 @@ package api
 
 import (
 	"context"
-	"log"
 	"net/http"
 	"time"
 
@@ -25,9 +24,14 @@ type Server struct {
 	authService      services.AuthService
 	clientService    services.ClientService
 	menuService      services.MenuService
+	scanService      services.ScanService
 	modelService     services.ModelService
 	adminService     services.AdminService
 	magicLinkService services.MagicLinkService
+	tableService     services.TableService
+	shortLinkService services.ShortLinkService
+	analyticsService services.AnalyticsService
+	dashboardService services.DashboardService
 	storageService   storage.StorageService
 	db               *data.PgDbContext
 }
@@ -36,25 +40,31 @@ func NewServer(
 	authService services.AuthService,
 	clientService services.ClientService,
 	menuService services.MenuService,
+	scanService services.ScanService,
 	modelService services.ModelService,
 	adminService services.AdminService,
 	magicLinkService services.MagicLinkService,
+	tableService services.TableService,
+	shortLinkService services.ShortLinkService,
+	analyticsService services.AnalyticsService,
+	dashboardService services.DashboardService,
+	storageService storage.StorageService,
 	db *data.PgDbContext,
 	config *models.Config,
 ) *Server {
-	storageService, err := storage.NewSpacesService(config.SpacesConfig)
-	if err != nil {
-		log.Fatalf("Failed to create storage service: %v", err)
-	}
-
 	server := &Server{
 		router:           gin.Default(),
 		authService:      authService,
 		clientService:    clientService,
 		menuService:      menuService,
+		scanService:      scanService,
 		modelService:     modelService,
 		adminService:     adminService,
 		magicLinkService: magicLinkService,
+		tableService:     tableService,
+		shortLinkService: shortLinkService,
+		analyticsService: analyticsService,
+		dashboardService: dashboardService,
 		storageService:   storageService,
 		db:               db,
 	}
@@ -64,23 +74,29 @@ func NewServer(
 	server.router.Use(middleware.CORSMiddleware())
 	server.router.Use(middleware.ErrorMiddleware())
 	server.router.Use(middleware.RateLimit(100, 200)) // 100 requests per second with burst of 200
-	server.router.Use(middleware.StaticFileMiddleware())
+	if config.StorageBackend == models.StorageBackendLocal {
+		server.router.Use(middleware.StaticFileMiddleware())
+	}
 
 	// Create handlers
 	authHandler := handlers.NewAuthHandler(authService)
 	clientHandler := handlers.NewClientHandler(clientService)
-	menuHandler := handlers.NewMenuHandler(menuService)
+	menuHandler := handlers.NewMenuHandler(menuService, scanService)
 	healthHandler := handlers.NewHealthHandler()
 	adminHandler := handlers.NewAdminHandler(adminService, clientService)
 	magicLinkHandler := handlers.NewMagicLinkHandler(magicLinkService, authService, clientService)
 	modelHandler := handlers.NewModelHandler(server.modelService, server.menuService, server.storageService)
-	dashboardHandler := handlers.NewDashboardHandler(db)
+	dashboardHandler := handlers.NewDashboardHandler(dashboardService)
+	tableHandler := handlers.NewTableHandler(tableService)
+	shortLinkHandler := handlers.NewShortLinkHandler(shortLinkService)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
 
 	// Auth middleware
 	authMiddleware := middleware.AuthMiddleware(authService)
 
-	// Health check endpoint (outside of API versioning)
+	// Health check and short link redirects (outside of API versioning)
 	healthHandler.RegisterRoutes(server.router.Group(""))
+	shortLinkHandler.RegisterRedirect(server.router.Group(""))
 
 	// API v1 routes
 	v1 := server.router.Group("/api/v1")
@@ -89,12 +105,15 @@ func NewServer(
 		authHandler.RegisterRoutes(v1)
 		adminHandler.RegisterRoutes(v1)
 		magicLinkHandler.RegisterRoutes(v1)
+		analyticsHandler.RegisterRoutes(v1)
 
 		// Protected routes
 		protected := v1.Group("", authMiddleware)
 		{
 			clientHandler.RegisterRoutes(protected)
 			menuHandler.RegisterRoutes(protected, v1)
+			tableHandler.RegisterRoutes(protected, v1)
+			shortLinkHandler.RegisterRoutes(protected, v1)
 			modelHandler.RegisterRoutes(protected)
 			dashboardHandler.RegisterRoutes(protected)
 		}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/ahmetkoprulu/bidi-menu/internal/models"
 	"github.com/joho/godotenv"
@@ -11,16 +13,26 @@ func LoadEnvironment() *models.Config {
 	godotenv.Load()
 
 	return &models.Config{
-		DatabaseURL:   os.Getenv("DATABASE_URL"),
-		DatabaseName:  os.Getenv("DATABASE_NAME"),
-		MqURL:         os.Getenv("MQ_URL"),
-		CacheURL:      os.Getenv("CACHE_URL"),
-		ElasticUrl:    os.Getenv("ELASTIC_URL"),
-		JWTSecret:     os.Getenv("JWT_SECRET"),
-		ServiceName:   os.Getenv("SERVICE_NAME"),
-		ServerPort:    os.Getenv("PORT"),
-		TesseractPath: os.Getenv("TESSERACT_PATH"),
-		BaseUrl:       os.Getenv("BASE_URL"),
+		DatabaseURL:        os.Getenv("DATABASE_URL"),
+		DatabaseName:       os.Getenv("DATABASE_NAME"),
+		MqURL:              os.Getenv("MQ_URL"),
+		CacheURL:           os.Getenv("CACHE_URL"),
+		ElasticUrl:         os.Getenv("ELASTIC_URL"),
+		JWTSecret:          os.Getenv("JWT_SECRET"),
+		ServiceName:        os.Getenv("SERVICE_NAME"),
+		ServerPort:         os.Getenv("PORT"),
+		TesseractPath:      os.Getenv("TESSERACT_PATH"),
+		PopplerPath:        os.Getenv("POPPLER_PATH"),
+		OcrEngine:          os.Getenv("OCR_ENGINE"),
+		OcrLanguages:       os.Getenv("OCR_LANGUAGES"),
+		OcrReviewThreshold: parseFloat(os.Getenv("OCR_REVIEW_THRESHOLD")),
+		ScanWorkers:        parseInt(os.Getenv("SCAN_WORKERS")),
+		ScanUploadDir:      os.Getenv("SCAN_UPLOAD_DIR"),
+		ModelWorkers:       parseInt(os.Getenv("MODEL_WORKERS")),
+		PreprocessSteps:    os.Getenv("PREPROCESS_STEPS"),
+		PreprocessMaxSize:  parseInt(os.Getenv("PREPROCESS_MAX_SIZE")),
+		BaseUrl:            os.Getenv("BASE_URL"),
+		ShortLinkBaseUrl:   getEnv("SHORT_LINK_BASE_URL", os.Getenv("BASE_URL")),
 		EmailConfig: models.EmailConfig{
 			SMTPHost:     os.Getenv("SMTP_HOST"),
 			SMTPPort:     os.Getenv("SMTP_PORT"),
@@ -28,6 +40,7 @@ func LoadEnvironment() *models.Config {
 			SMTPPassword: os.Getenv("SMTP_PASSWORD"),
 			FromEmail:    os.Getenv("FROM_EMAIL"),
 		},
+		StorageBackend: getEnv("STORAGE_BACKEND", defaultStorageBackend()),
 		SpacesConfig: models.SpacesConfig{
 			Region:          os.Getenv("SPACES_REGION"),
 			Bucket:          os.Getenv("SPACES_BUCKET"),
@@ -36,5 +49,60 @@ func LoadEnvironment() *models.Config {
 			SecretAccessKey: os.Getenv("SPACES_SECRET_ACCESS_KEY"),
 			CDNDomain:       os.Getenv("SPACES_CDN_DOMAIN"),
 		},
+		ThumbnailConfig: models.ThumbnailConfig{
+			Yaw:   parseFloat(getEnv("THUMBNAIL_YAW", "35")),
+			Pitch: parseFloat(getEnv("THUMBNAIL_PITCH", "25")),
+			Sizes: parseInts(getEnv("THUMBNAIL_SIZES", "256,512,1024")),
+		},
+	}
+}
+
+// defaultStorageBackend keeps deployments that configured a bucket before the
+// backend could be selected on S3, anything else stores files locally
+func defaultStorageBackend() string {
+	if os.Getenv("SPACES_BUCKET") != "" {
+		return models.StorageBackendS3
 	}
+
+	return models.StorageBackendLocal
+}
+
+// getEnv reads the variable, falling back when it is not set
+func getEnv(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+func parseFloat(value string) float64 {
+	result, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+
+	return result
+}
+
+func parseInt(value string) int {
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+
+	return result
+}
+
+// parseInts reads a comma separated list of positive numbers, skipping
+// anything else
+func parseInts(value string) []int {
+	result := make([]int, 0)
+	for _, part := range strings.Split(value, ",") {
+		if n := parseInt(strings.TrimSpace(part)); n > 0 {
+			result = append(result, n)
+		}
+	}
+
+	return result
 }
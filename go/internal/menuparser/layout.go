@@ -0,0 +1,220 @@
+package menuparser
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/ocr"
+)
+
+// line is a run of words that share a baseline within one column
+type line struct {
+	words []ocr.Word
+	box   ocr.BoundingBox
+}
+
+func (l *line) text() string {
+	parts := make([]string, len(l.words))
+	for i, w := range l.words {
+		parts[i] = w.Text
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// pageLines returns the lines of a page in reading order: columns left to right
+// and lines top to bottom within each column
+func pageLines(page *ocr.Page) []*line {
+	words := page.Words
+	if len(words) == 0 && strings.TrimSpace(page.Text) != "" {
+		words = ocr.LayoutText(page.Text).Words
+	}
+	if len(words) == 0 {
+		return nil
+	}
+
+	height := medianWordHeight(words)
+	lines := make([]*line, 0)
+	for _, column := range splitColumns(words, height) {
+		lines = append(lines, groupLines(column, height)...)
+	}
+
+	return lines
+}
+
+// groupLines clusters words into lines by vertical overlap of their centers
+func groupLines(words []ocr.Word, height int) []*line {
+	sorted := slices.Clone(words)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return centerY(sorted[i]) < centerY(sorted[j])
+	})
+
+	lines := make([]*line, 0)
+	for _, word := range sorted {
+		var current *line
+		if len(lines) > 0 {
+			current = lines[len(lines)-1]
+		}
+
+		if current == nil || abs(centerY(word)-(current.box.Y+current.box.Height/2)) > max(height/2, 1) {
+			current = &line{}
+			lines = append(lines, current)
+		}
+
+		current.words = append(current.words, word)
+		current.box = current.box.Union(word.Box)
+	}
+
+	for _, l := range lines {
+		sort.SliceStable(l.words, func(i, j int) bool {
+			return l.words[i].Box.X < l.words[j].Box.X
+		})
+	}
+
+	return lines
+}
+
+// splitColumns finds vertical gutters that run through (almost) every row of the
+// page and splits the words along them. A gutter is only accepted when the region
+// to its right holds real text, so a column of right aligned prices stays attached
+// to the item names it belongs to.
+func splitColumns(words []ocr.Word, height int) [][]ocr.Word {
+	rows := groupLines(words, height)
+	if len(rows) < 2 {
+		return [][]ocr.Word{words}
+	}
+
+	left, right := words[0].Box.X, words[0].Box.Right()
+	for _, w := range words {
+		left = min(left, w.Box.X)
+		right = max(right, w.Box.Right())
+	}
+
+	coverage := make([]int, right-left+1)
+	for _, row := range rows {
+		covered := make([]bool, len(coverage))
+		for _, w := range row.words {
+			for x := w.Box.X; x < w.Box.Right(); x++ {
+				covered[x-left] = true
+			}
+		}
+		for x, c := range covered {
+			if c {
+				coverage[x]++
+			}
+		}
+	}
+
+	// Rows such as a centered page title may cross a gutter, so tolerate a few
+	tolerance := len(rows) / 20
+	minGap := max(height*2, 1)
+
+	gutters := make([]int, 0)
+	start := -1
+	for x := 0; x < len(coverage); x++ {
+		if coverage[x] <= tolerance {
+			if start < 0 {
+				start = x
+			}
+			continue
+		}
+		if start >= 0 && x-start >= minGap {
+			gutters = append(gutters, left+(start+x)/2)
+		}
+		start = -1
+	}
+
+	// Each candidate is judged on the region up to the next candidate, so the gap
+	// between names and their prices never qualifies as a column break
+	cuts := make([]int, 0, len(gutters))
+	lastCut := math.MinInt
+	for i, gutter := range gutters {
+		next := math.MaxInt
+		if i+1 < len(gutters) {
+			next = gutters[i+1]
+		}
+
+		if isTextColumn(wordsBetween(words, lastCut, gutter), height) && isTextColumn(wordsBetween(words, gutter, next), height) {
+			cuts = append(cuts, gutter)
+			lastCut = gutter
+		}
+	}
+
+	columns := make([][]ocr.Word, 0, len(cuts)+1)
+	lastCut = math.MinInt
+	for _, cut := range append(cuts, math.MaxInt) {
+		columns = append(columns, wordsBetween(words, lastCut, cut))
+		lastCut = cut
+	}
+
+	return columns
+}
+
+// wordsBetween returns the words whose horizontal center lies in [from, to)
+func wordsBetween(words []ocr.Word, from, to int) []ocr.Word {
+	result := make([]ocr.Word, 0)
+	for _, w := range words {
+		center := w.Box.X + w.Box.Width/2
+		if center >= from && center < to {
+			result = append(result, w)
+		}
+	}
+
+	return result
+}
+
+// isTextColumn reports whether at least a quarter of the lines in the region
+// contain something other than prices and leaders
+func isTextColumn(words []ocr.Word, height int) bool {
+	lines := groupLines(words, height)
+	if len(lines) < 2 {
+		return false
+	}
+
+	textLines := 0
+	for _, l := range lines {
+		tokens := tokenize(l.words)
+		_, prices := extractPrices(tokens)
+		if len(prices) == 0 || len(nameTokens(tokens, prices)) > 0 {
+			textLines++
+		}
+	}
+
+	return textLines*4 >= len(lines)
+}
+
+func nameTokens(tokens []priceToken, prices []Price) []priceToken {
+	if len(prices) == 0 {
+		return tokens
+	}
+
+	return tokens[:prices[0].tokenIndex]
+}
+
+func medianWordHeight(words []ocr.Word) int {
+	heights := make([]int, 0, len(words))
+	for _, w := range words {
+		if w.Box.Height > 0 {
+			heights = append(heights, w.Box.Height)
+		}
+	}
+	if len(heights) == 0 {
+		return 1
+	}
+
+	slices.Sort(heights)
+	return heights[len(heights)/2]
+}
+
+func centerY(w ocr.Word) int {
+	return w.Box.Y + w.Box.Height/2
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,323 @@
+package menuparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/ocr"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/google/uuid"
+)
+
+const defaultCategoryName = "Menu"
+
+// Result is the structured menu recovered from OCR output
+type Result struct {
+	Currency   string      `json:"currency,omitempty"`
+	Categories []*Category `json:"categories"`
+}
+
+type Category struct {
+	Name  Field   `json:"name"`
+	Items []*Item `json:"items"`
+}
+
+type Item struct {
+	Name        Field   `json:"name"`
+	Description *Field  `json:"description,omitempty"`
+	Prices      []Price `json:"prices"`
+}
+
+// Field is a piece of extracted text with the OCR confidence of its words and
+// the area of the page it was read from
+type Field struct {
+	Value      string          `json:"value"`
+	Confidence float64         `json:"confidence"`
+	Page       int             `json:"page"`
+	Box        ocr.BoundingBox `json:"box"`
+
+	words int
+}
+
+// parsedLine is a layout line with its price group split off
+type parsedLine struct {
+	name   Field
+	prices []Price
+	height int
+}
+
+// Parse turns recognized pages into categories and items. Pages are read in
+// order and a category may continue from one page onto the next.
+func Parse(pages []*ocr.Page) *Result {
+	lines := make([]parsedLine, 0)
+	for _, page := range pages {
+		if page == nil {
+			continue
+		}
+
+		for _, l := range pageLines(page) {
+			tokens := tokenize(l.words)
+			nameEnd, prices := extractPrices(tokens)
+
+			name := newField(tokens[:nameEnd])
+			name.Value = cleanName(name.Value)
+			name.Page = page.Number
+			for i := range prices {
+				prices[i].Page = page.Number
+			}
+
+			lines = append(lines, parsedLine{
+				name:   name,
+				prices: prices,
+				height: l.box.Height,
+			})
+		}
+	}
+
+	result := &Result{Categories: make([]*Category, 0)}
+	headingHeight := medianLineHeight(lines) * 13 / 10
+
+	var category *Category
+	var item *Item
+	addItem := func(name Field, prices []Price) {
+		if category == nil {
+			// Nothing on the page named this category, so it always needs a review
+			category = &Category{Name: Field{Value: defaultCategoryName, Page: name.Page}, Items: make([]*Item, 0)}
+			result.Categories = append(result.Categories, category)
+		}
+
+		if prices == nil {
+			prices = make([]Price, 0)
+		}
+
+		item = &Item{Name: name, Prices: prices}
+		category.Items = append(category.Items, item)
+	}
+
+	for i, line := range lines {
+		switch {
+		case len(line.prices) > 0 && line.name.Value != "":
+			addItem(line.name, line.prices)
+
+		case len(line.prices) > 0:
+			// A price on a line of its own belongs to the item right above it
+			if item != nil && len(item.Prices) == 0 {
+				item.Prices = line.prices
+				for i := range item.Prices {
+					item.Prices[i].Confidence *= weakPricePenalty
+				}
+			}
+
+		case line.name.Value == "":
+			continue
+
+		case isHeading(line, headingHeight):
+			category = &Category{Name: line.name, Items: make([]*Item, 0)}
+			item = nil
+			result.Categories = append(result.Categories, category)
+
+		case i+1 < len(lines) && lines[i+1].name.Value == "" && len(lines[i+1].prices) > 0:
+			addItem(line.name, nil)
+
+		case item != nil:
+			if item.Description == nil {
+				description := line.name
+				item.Description = &description
+			} else {
+				item.Description.append(line.name)
+			}
+		}
+	}
+
+	result.Currency = dominantCurrency(result)
+	return result
+}
+
+// ToScanResult converts the result into a menu ready to be saved, together with
+// one scanned field per extracted value. The first price becomes the item price;
+// items with several prices also get one variant per price. Fields below the
+// review threshold are flagged and collected in the review queue.
+func (r *Result) ToScanResult(reviewThreshold float64) *models.ScanResult {
+	scan := &models.ScanResult{
+		Menu:     &models.Menu{Categories: make([]*models.MenuCategory, 0, len(r.Categories))},
+		Currency: r.Currency,
+		Fields:   make([]*models.ScannedField, 0),
+		Review:   make([]*models.ScannedField, 0),
+	}
+
+	addField := func(field *models.ScannedField) {
+		field.NeedsReview = field.Confidence < reviewThreshold
+		scan.Fields = append(scan.Fields, field)
+		if field.NeedsReview {
+			scan.Review = append(scan.Review, field)
+		}
+	}
+
+	for ci, c := range r.Categories {
+		category := &models.MenuCategory{
+			ID:        uuid.New(),
+			Name:      c.Name.Value,
+			MenuItems: make([]*models.MenuCategoryItem, 0, len(c.Items)),
+		}
+
+		categoryPath := fmt.Sprintf("categories[%d]", ci)
+		addField(newScannedField(categoryPath+".name", models.ScanFieldCategoryName, category.ID, nil, c.Name))
+
+		for ii, i := range c.Items {
+			item := &models.MenuCategoryItem{
+				ID:   uuid.New(),
+				Name: i.Name.Value,
+			}
+
+			itemPath := fmt.Sprintf("%s.menuItems[%d]", categoryPath, ii)
+			addField(newScannedField(itemPath+".name", models.ScanFieldItemName, category.ID, &item.ID, i.Name))
+
+			if i.Description != nil {
+				item.Description = i.Description.Value
+				addField(newScannedField(itemPath+".description", models.ScanFieldItemDescription, category.ID, &item.ID, *i.Description))
+			}
+
+			if len(i.Prices) == 0 {
+				// A missing price is always worth a look
+				addField(newScannedField(itemPath+".price", models.ScanFieldItemPrice, category.ID, &item.ID, Field{Page: i.Name.Page}))
+			} else {
+				item.Price = i.Prices[0].Amount
+				addField(newScannedField(itemPath+".price", models.ScanFieldItemPrice, category.ID, &item.ID, i.Prices[0].field()))
+			}
+
+			if len(i.Prices) > 1 {
+				for pi, p := range i.Prices {
+					item.Variants = append(item.Variants, &models.MenuItemVariant{Name: p.Label, Price: p.Amount})
+					path := fmt.Sprintf("%s.variants[%d].price", itemPath, pi)
+					addField(newScannedField(path, models.ScanFieldVariantPrice, category.ID, &item.ID, p.field()))
+				}
+			}
+
+			category.MenuItems = append(category.MenuItems, item)
+		}
+
+		scan.Menu.Categories = append(scan.Menu.Categories, category)
+	}
+
+	return scan
+}
+
+func newScannedField(path string, kind models.ScanFieldKind, categoryID uuid.UUID, itemID *uuid.UUID, field Field) *models.ScannedField {
+	return &models.ScannedField{
+		Path:       path,
+		Kind:       kind,
+		CategoryID: categoryID,
+		ItemID:     itemID,
+		Value:      field.Value,
+		Confidence: field.Confidence,
+		Page:       field.Page,
+		Box:        field.Box,
+	}
+}
+
+// field returns the price as a text field, formatted the way it is stored
+func (p Price) field() Field {
+	return Field{
+		Value:      strconv.FormatFloat(p.Amount, 'f', 2, 64),
+		Confidence: p.Confidence,
+		Page:       p.Page,
+		Box:        p.Box,
+	}
+}
+
+// isHeading treats short lines written in capitals, or set noticeably larger than
+// the body text, as category headings
+func isHeading(line parsedLine, headingHeight int) bool {
+	if headingHeight > 0 && line.height >= headingHeight {
+		return true
+	}
+
+	letters := 0
+	for _, r := range line.name.Value {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsLower(r) {
+				return false
+			}
+		}
+	}
+
+	return letters >= 2 && len(strings.Fields(line.name.Value)) <= 5
+}
+
+// newField joins tokens into a field. Its confidence is the mean confidence of
+// the words the tokens were cut from and its box is the union of their boxes.
+func newField(tokens []priceToken) Field {
+	field := Field{}
+	parts := make([]string, len(tokens))
+	seen := make(map[ocr.BoundingBox]bool)
+	total := 0.0
+
+	for i, t := range tokens {
+		parts[i] = t.text
+		if seen[t.word.Box] {
+			continue
+		}
+
+		seen[t.word.Box] = true
+		total += t.word.Confidence
+		field.words++
+		field.Box = field.Box.Union(t.word.Box)
+	}
+
+	field.Value = strings.Join(parts, " ")
+	if field.words > 0 {
+		field.Confidence = total / float64(field.words)
+	}
+
+	return field
+}
+
+// append extends the field with the text of another, e.g. a wrapped description line
+func (f *Field) append(other Field) {
+	total := f.Confidence*float64(f.words) + other.Confidence*float64(other.words)
+	f.words += other.words
+	if f.words > 0 {
+		f.Confidence = total / float64(f.words)
+	}
+
+	f.Value = strings.TrimSpace(f.Value + " " + other.Value)
+	f.Box = f.Box.Union(other.Box)
+}
+
+func cleanName(name string) string {
+	return strings.TrimRightFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune("-–:|/", r)
+	})
+}
+
+func medianLineHeight(lines []parsedLine) int {
+	words := make([]ocr.Word, 0, len(lines))
+	for _, l := range lines {
+		words = append(words, ocr.Word{Box: ocr.BoundingBox{Height: l.height}})
+	}
+
+	return medianWordHeight(words)
+}
+
+func dominantCurrency(result *Result) string {
+	counts := make(map[string]int)
+	best := ""
+	for _, c := range result.Categories {
+		for _, i := range c.Items {
+			for _, p := range i.Prices {
+				if p.Currency == "" {
+					continue
+				}
+				counts[p.Currency]++
+				if counts[p.Currency] > counts[best] || (counts[p.Currency] == counts[best] && p.Currency < best) {
+					best = p.Currency
+				}
+			}
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,153 @@
+package menuparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/ocr"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestParseGolden parses every fixture in testdata and compares the result with
+// its .golden.json file. Text fixtures are laid out by the fake OCR engine, with
+// pages separated by form feeds; json fixtures hold recognized pages verbatim.
+func TestParseGolden(t *testing.T) {
+	fixtures, err := filepath.Glob("testdata/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fixture := range fixtures {
+		if strings.HasSuffix(fixture, ".golden.json") {
+			continue
+		}
+
+		name := strings.TrimSuffix(filepath.Base(fixture), filepath.Ext(fixture))
+		t.Run(name, func(t *testing.T) {
+			pages := loadPages(t, fixture)
+			got, err := json.MarshalIndent(Parse(pages), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", name+".golden.json")
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("missing golden file, run with -update: %v", err)
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Errorf("result does not match %s\ngot:\n%s\nwant:\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+func TestToScanResultFlagsLowConfidenceFields(t *testing.T) {
+	page := ocr.LayoutText("Burger      12.50\nFries       4")
+	for i, word := range page.Words {
+		if word.Text == "Burger" {
+			page.Words[i].Confidence = 0.4
+		}
+	}
+
+	scan := Parse([]*ocr.Page{page}).ToScanResult(0.8)
+
+	flagged := make(map[string]bool)
+	for _, field := range scan.Review {
+		flagged[field.Path] = true
+	}
+
+	want := map[string]bool{
+		"categories[0].name":              true, // no heading on the page
+		"categories[0].menuItems[0].name": true, // low OCR confidence
+	}
+	for path := range want {
+		if !flagged[path] {
+			t.Errorf("expected %s to be flagged for review", path)
+		}
+	}
+	for path := range flagged {
+		if !want[path] {
+			t.Errorf("did not expect %s to be flagged for review", path)
+		}
+	}
+
+	if len(scan.Fields) != 5 {
+		t.Errorf("expected 5 fields, got %d", len(scan.Fields))
+	}
+	if id := scan.Review[1].ItemID; id == nil || *id != scan.Menu.Categories[0].MenuItems[0].ID {
+		t.Errorf("review field does not point at the scanned item")
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		text     string
+		amount   float64
+		currency string
+	}{
+		{"12.50", 12.50, ""},
+		{"12,50", 12.50, ""},
+		{"1.250,00", 1250, ""},
+		{"1,250.00", 1250, ""},
+		{"1.250", 1250, ""},
+		{"₺45", 45, "TRY"},
+		{"45TL", 45, "TRY"},
+		{"8,90€", 8.90, "EUR"},
+		{"£7.95", 7.95, "GBP"},
+		{"12,-", 12, ""},
+	}
+
+	for _, tt := range tests {
+		amount, currency, ok := parseAmount(tt.text)
+		if !ok || amount != tt.amount || currency != tt.currency {
+			t.Errorf("parseAmount(%q) = %v, %q, %v; want %v, %q", tt.text, amount, currency, ok, tt.amount, tt.currency)
+		}
+	}
+
+	for _, text := range []string{"abc", "12.5.2020", "123456", "4.50/6.00"} {
+		if _, _, ok := parseAmount(text); ok {
+			t.Errorf("parseAmount(%q) should not parse", text)
+		}
+	}
+}
+
+func loadPages(t *testing.T, path string) []*ocr.Page {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if filepath.Ext(path) == ".json" {
+		var pages []*ocr.Page
+		if err := json.Unmarshal(content, &pages); err != nil {
+			t.Fatal(err)
+		}
+		return pages
+	}
+
+	pages := make([]*ocr.Page, 0)
+	for i, text := range strings.Split(string(content), "\f") {
+		page := ocr.LayoutText(text)
+		page.Number = i + 1
+		pages = append(pages, page)
+	}
+
+	return pages
+}
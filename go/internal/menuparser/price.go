@@ -0,0 +1,252 @@
+package menuparser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/ocr"
+)
+
+var (
+	amountRegex   = regexp.MustCompile(`^([₺€£$])?(\d{1,3}(?:[.,]\d{3})+(?:[.,]\d{1,2})?|\d{1,5}(?:[.,]\d{1,2})?)(?:[.,]-)?([₺€£$]|TL|TRY|YTL|EUR|GBP|USD)?$`)
+	currencyRegex = regexp.MustCompile(`^([₺€£$]|TL|TRY|YTL|EUR|GBP|USD)$`)
+	leaderRegex   = regexp.MustCompile(`[.…·_]{2,}`)
+	unitRegex     = regexp.MustCompile(`^(\d+(?:[.,]\d+)?(?:cl|ml|l|lt|oz|g|gr|kg|cm|pcs|pc|adet)|\d/\d)$`)
+)
+
+var currencySymbols = map[string]string{
+	"₺":   "TRY",
+	"TL":  "TRY",
+	"TRY": "TRY",
+	"YTL": "TRY",
+	"€":   "EUR",
+	"EUR": "EUR",
+	"£":   "GBP",
+	"GBP": "GBP",
+	"$":   "USD",
+	"USD": "USD",
+}
+
+// sizeLabels are words that name a portion when they precede a price
+var sizeLabels = map[string]bool{
+	"s": true, "m": true, "l": true, "xs": true, "xl": true,
+	"small": true, "medium": true, "large": true, "regular": true, "reg": true,
+	"half": true, "full": true, "single": true, "double": true,
+	"tall": true, "grande": true, "venti": true,
+	"glass": true, "bottle": true, "carafe": true, "pint": true, "shot": true, "cup": true,
+	"küçük": true, "orta": true, "büyük": true, "tek": true, "duble": true,
+	"yarım": true, "tam": true, "porsiyon": true, "bardak": true, "kadeh": true, "şişe": true,
+	"klein": true, "mittel": true, "groß": true, "gross": true,
+	"petit": true, "moyen": true, "grand": true, "verre": true, "bouteille": true,
+}
+
+// weakPricePenalty scales the confidence of prices that might just be numbers:
+// bare integers and amounts found on a line of their own
+const weakPricePenalty = 0.9
+
+// Price is a single amount found on a menu line, optionally named by a size label
+type Price struct {
+	Label      string          `json:"label,omitempty"`
+	Amount     float64         `json:"amount"`
+	Currency   string          `json:"currency,omitempty"`
+	Confidence float64         `json:"confidence"`
+	Page       int             `json:"page"`
+	Box        ocr.BoundingBox `json:"box"`
+
+	tokenIndex int
+}
+
+// priceToken is a piece of a line after dot leaders and separators have been split off
+type priceToken struct {
+	text string
+	word ocr.Word
+}
+
+// tokenize splits the words of a line on dot leaders and price separators
+func tokenize(words []ocr.Word) []priceToken {
+	tokens := make([]priceToken, 0, len(words))
+	for _, word := range words {
+		for _, piece := range leaderRegex.Split(word.Text, -1) {
+			for _, part := range splitSeparators(piece) {
+				if part != "" {
+					tokens = append(tokens, priceToken{text: part, word: word})
+				}
+			}
+		}
+	}
+
+	return tokens
+}
+
+// splitSeparators splits "4.50/6.00" into "4.50", "/", "6.00" while leaving
+// fractions such as "1/2" intact
+func splitSeparators(text string) []string {
+	if unitRegex.MatchString(text) || !strings.ContainsAny(text, "/|") {
+		return []string{text}
+	}
+
+	parts := make([]string, 0)
+	current := strings.Builder{}
+	for _, r := range text {
+		if r == '/' || r == '|' {
+			parts = append(parts, current.String(), string(r))
+			current.Reset()
+			continue
+		}
+		current.WriteRune(r)
+	}
+
+	return append(parts, current.String())
+}
+
+// extractPrices reads the trailing price group of a line from right to left. It
+// understands currency symbols on either side of the amount, size labels
+// ("S 4.50 / L 6.00") and separators between several prices.
+func extractPrices(tokens []priceToken) (int, []Price) {
+	prices := make([]Price, 0)
+	i := len(tokens) - 1
+
+	for i >= 0 {
+		price, start, ok := readPrice(tokens, i)
+		if !ok {
+			break
+		}
+
+		if start > 0 && isSizeLabel(tokens[start-1].text) {
+			start--
+			price.Label = strings.TrimSuffix(tokens[start].text, ":")
+			price.tokenIndex = start
+		}
+
+		prices = append(prices, price)
+		i = start - 1
+
+		if i >= 0 && isSeparator(tokens[i].text) {
+			i--
+			continue
+		}
+
+		// Labeled prices may follow each other without separators ("Small 9 Large 12"),
+		// as may amounts that can only be prices ("4.50 6.00")
+		if i >= 0 {
+			_, _, ok := readPrice(tokens, i)
+			if ok && (price.Label != "" || (isStrongPrice(tokens[start].text) && isStrongPrice(tokens[i].text))) {
+				continue
+			}
+		}
+
+		break
+	}
+
+	if len(prices) == 0 {
+		return len(tokens), prices
+	}
+
+	// Reverse into reading order
+	for l, r := 0, len(prices)-1; l < r; l, r = l+1, r-1 {
+		prices[l], prices[r] = prices[r], prices[l]
+	}
+
+	// A single price keeps its label as part of the item name ("Pizza Large 12")
+	if len(prices) == 1 && prices[0].Label != "" {
+		prices[0].Label = ""
+		prices[0].tokenIndex++
+	}
+
+	return prices[0].tokenIndex, prices
+}
+
+// readPrice reads one amount ending at index i together with any currency around it
+func readPrice(tokens []priceToken, i int) (Price, int, bool) {
+	end := i
+	currency := ""
+	if currencyRegex.MatchString(strings.ToUpper(tokens[i].text)) {
+		currency = currencySymbols[strings.ToUpper(tokens[i].text)]
+		i--
+	}
+	if i < 0 {
+		return Price{}, 0, false
+	}
+
+	amount, detected, ok := parseAmount(tokens[i].text)
+	if !ok {
+		return Price{}, 0, false
+	}
+	if detected != "" {
+		currency = detected
+	}
+
+	start := i
+	if currency == "" && i > 0 && currencyRegex.MatchString(strings.ToUpper(tokens[i-1].text)) {
+		currency = currencySymbols[strings.ToUpper(tokens[i-1].text)]
+		start = i - 1
+	}
+
+	field := newField(tokens[start : end+1])
+	if !isStrongPrice(tokens[i].text) && currency == "" {
+		field.Confidence *= weakPricePenalty
+	}
+
+	return Price{
+		Amount:     amount,
+		Currency:   currency,
+		Confidence: field.Confidence,
+		Box:        field.Box,
+		tokenIndex: start,
+	}, start, true
+}
+
+// parseAmount parses "12.50", "12,50", "1.250,00", "1,250.00", "₺45", "45TL" or "12,-"
+func parseAmount(text string) (float64, string, bool) {
+	matches := amountRegex.FindStringSubmatch(strings.ToUpper(text))
+	if matches == nil {
+		return 0, "", false
+	}
+
+	currency := currencySymbols[matches[1]]
+	if matches[3] != "" {
+		currency = currencySymbols[matches[3]]
+	}
+
+	number := matches[2]
+	lastDot, lastComma := strings.LastIndex(number, "."), strings.LastIndex(number, ",")
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		decimal := max(lastDot, lastComma)
+		number = strings.NewReplacer(".", "", ",", "").Replace(number[:decimal]) + "." + number[decimal+1:]
+	case lastDot >= 0 || lastComma >= 0:
+		sep := string(number[max(lastDot, lastComma)])
+		if strings.Count(number, sep) > 1 || len(number)-max(lastDot, lastComma)-1 == 3 {
+			number = strings.ReplaceAll(number, sep, "")
+		} else {
+			number = strings.Replace(number, sep, ".", 1)
+		}
+	}
+
+	amount, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, "", false
+	}
+
+	return amount, currency, true
+}
+
+// isStrongPrice reports whether a token can only be a price: it carries decimals or a currency
+func isStrongPrice(text string) bool {
+	matches := amountRegex.FindStringSubmatch(strings.ToUpper(text))
+	if matches == nil {
+		return currencyRegex.MatchString(strings.ToUpper(text))
+	}
+
+	return matches[1] != "" || matches[3] != "" || strings.ContainsAny(matches[2], ".,")
+}
+
+func isSizeLabel(text string) bool {
+	text = strings.TrimSuffix(strings.ToLower(text), ":")
+	return sizeLabels[text] || unitRegex.MatchString(text)
+}
+
+func isSeparator(text string) bool {
+	return text == "/" || text == "|"
+}
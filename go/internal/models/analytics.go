@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AnalyticsEventType is what a guest did on the public menu page
+type AnalyticsEventType string
+
+const (
+	AnalyticsEventMenuView AnalyticsEventType = "menu_view"
+	AnalyticsEventItemOpen AnalyticsEventType = "item_open"
+	AnalyticsEventARLaunch AnalyticsEventType = "ar_launch"
+)
+
+// AnalyticsSource is how the guest got to the menu
+type AnalyticsSource string
+
+const (
+	AnalyticsSourceQR     AnalyticsSource = "qr"
+	AnalyticsSourceTable  AnalyticsSource = "table"
+	AnalyticsSourceLink   AnalyticsSource = "link"
+	AnalyticsSourceDirect AnalyticsSource = "direct"
+)
+
+type DeviceType string
+
+const (
+	DeviceMobile  DeviceType = "mobile"
+	DeviceTablet  DeviceType = "tablet"
+	DeviceDesktop DeviceType = "desktop"
+	DeviceUnknown DeviceType = "unknown"
+)
+
+type AnalyticsEvent struct {
+	Type    AnalyticsEventType `json:"type"`
+	ItemID  *uuid.UUID         `json:"itemId,omitempty"`
+	ModelID *uuid.UUID         `json:"modelId,omitempty"`
+}
+
+// AnalyticsBatch is the events one page of a menu sends at once, they share
+// the source and the device
+type AnalyticsBatch struct {
+	MenuID     uuid.UUID         `json:"menuId"`
+	Source     AnalyticsSource   `json:"source"`
+	TableToken string            `json:"tableToken,omitempty"`
+	UserAgent  string            `json:"-"`
+	Events     []*AnalyticsEvent `json:"events"`
+}
+
+// AnalyticsCounterKey identifies a counter, one kind of event in one hour.
+// Dimensions the event does not have are uuid.Nil. The table is given by its
+// token and resolved when the counter is stored.
+type AnalyticsCounterKey struct {
+	MenuID     uuid.UUID
+	Bucket     time.Time
+	Type       AnalyticsEventType
+	ItemID     uuid.UUID
+	ModelID    uuid.UUID
+	Source     AnalyticsSource
+	TableToken string
+	Device     DeviceType
+}
+
+type AnalyticsCounter struct {
+	AnalyticsCounterKey
+	Count int64
+}
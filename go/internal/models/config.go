@@ -1,18 +1,30 @@
 package models
 
 type Config struct {
-	DatabaseURL   string
-	DatabaseName  string
-	MqURL         string
-	CacheURL      string
-	ElasticUrl    string
-	JWTSecret     string
-	ServiceName   string
-	ServerPort    string
-	BaseUrl       string
-	TesseractPath string
-	EmailConfig   EmailConfig
-	SpacesConfig  SpacesConfig
+	DatabaseURL        string
+	DatabaseName       string
+	MqURL              string
+	CacheURL           string
+	ElasticUrl         string
+	JWTSecret          string
+	ServiceName        string
+	ServerPort         string
+	BaseUrl            string
+	ShortLinkBaseUrl   string
+	TesseractPath      string
+	PopplerPath        string
+	OcrEngine          string
+	OcrLanguages       string
+	OcrReviewThreshold float64
+	ScanWorkers        int
+	ScanUploadDir      string
+	ModelWorkers       int
+	PreprocessSteps    string
+	PreprocessMaxSize  int
+	EmailConfig        EmailConfig
+	StorageBackend     string
+	SpacesConfig       SpacesConfig
+	ThumbnailConfig    ThumbnailConfig
 }
 
 type EmailConfig struct {
@@ -23,6 +35,24 @@ type EmailConfig struct {
 	FromEmail    string
 }
 
+// ThumbnailConfig places the camera thumbnails are drawn from, in degrees, and
+// lists the sizes they are stored in
+type ThumbnailConfig struct {
+	Yaw   float64
+	Pitch float64
+	Sizes []int
+}
+
+// StorageBackend values, they select where uploaded files are kept
+const (
+	// StorageBackendLocal keeps files under the storage directory and serves
+	// them from /static
+	StorageBackendLocal = "local"
+	// StorageBackendS3 keeps files in an S3 compatible bucket such as
+	// DigitalOcean Spaces, configured by SpacesConfig
+	StorageBackendS3 = "s3"
+)
+
 type SpacesConfig struct {
 	Region          string
 	Bucket          string
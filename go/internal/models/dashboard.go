@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AnalyticsInterval is the length of one point of the dashboard series
+type AnalyticsInterval string
+
+const (
+	IntervalHour AnalyticsInterval = "hour"
+	IntervalDay  AnalyticsInterval = "day"
+	IntervalWeek AnalyticsInterval = "week"
+)
+
+// DashboardQuery selects the days the dashboard covers. From and To are dates
+// in the client's timezone, both are included and only their date is used.
+type DashboardQuery struct {
+	From     *time.Time
+	To       *time.Time
+	Interval AnalyticsInterval
+}
+
+// DashboardTotals are the event counts of a period. Conversion is the share
+// of menu views followed by an AR launch, 0 without views.
+type DashboardTotals struct {
+	Views      int64   `json:"views"`
+	ItemOpens  int64   `json:"itemOpens"`
+	ARLaunches int64   `json:"arLaunches"`
+	Conversion float64 `json:"conversion"`
+}
+
+type DashboardPeriod struct {
+	From   string          `json:"from"`
+	To     string          `json:"to"`
+	Totals DashboardTotals `json:"totals"`
+}
+
+// DashboardChange is the change against the previous period in percent, it
+// is left out when the previous period has nothing to compare with
+type DashboardChange struct {
+	Views      *float64 `json:"views,omitempty"`
+	ItemOpens  *float64 `json:"itemOpens,omitempty"`
+	ARLaunches *float64 `json:"arLaunches,omitempty"`
+	Conversion *float64 `json:"conversion,omitempty"`
+}
+
+// DashboardPoint is one interval of the series, Start is its beginning in the
+// client's timezone
+type DashboardPoint struct {
+	Start      time.Time `json:"start"`
+	Views      int64     `json:"views"`
+	ItemOpens  int64     `json:"itemOpens"`
+	ARLaunches int64     `json:"arLaunches"`
+}
+
+type DashboardItem struct {
+	ItemID     uuid.UUID `json:"itemId"`
+	MenuID     uuid.UUID `json:"menuId"`
+	Name       string    `json:"name"`
+	Opens      int64     `json:"opens"`
+	ARLaunches int64     `json:"arLaunches"`
+}
+
+type DashboardModel struct {
+	ModelID  uuid.UUID `json:"modelId"`
+	Name     string    `json:"name"`
+	Launches int64     `json:"launches"`
+}
+
+// DashboardMenu is a recently changed menu with its views in the period
+type DashboardMenu struct {
+	ID        uuid.UUID `json:"id"`
+	Label     string    `json:"label"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Views     int64     `json:"views"`
+}
+
+type Dashboard struct {
+	TotalMenus  int64             `json:"totalMenus"`
+	ActiveMenus int64             `json:"activeMenus"`
+	Timezone    string            `json:"timezone"`
+	Interval    AnalyticsInterval `json:"interval"`
+	Current     DashboardPeriod   `json:"current"`
+	Previous    DashboardPeriod   `json:"previous"`
+	Change      DashboardChange   `json:"change"`
+	Series      []*DashboardPoint `json:"series"`
+	TopItems    []*DashboardItem  `json:"topItems"`
+	TopModels   []*DashboardModel `json:"topModels"`
+	RecentMenus []*DashboardMenu  `json:"recentMenus"`
+}
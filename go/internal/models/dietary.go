@@ -0,0 +1,127 @@
+package models
+
+import "slices"
+
+// Allergen is one of the 14 allergens EU Regulation 1169/2011 requires menus
+// to declare
+type Allergen string
+
+const (
+	AllergenGluten      Allergen = "gluten"
+	AllergenCrustaceans Allergen = "crustaceans"
+	AllergenEggs        Allergen = "eggs"
+	AllergenFish        Allergen = "fish"
+	AllergenPeanuts     Allergen = "peanuts"
+	AllergenSoybeans    Allergen = "soybeans"
+	AllergenMilk        Allergen = "milk"
+	AllergenNuts        Allergen = "nuts"
+	AllergenCelery      Allergen = "celery"
+	AllergenMustard     Allergen = "mustard"
+	AllergenSesame      Allergen = "sesame"
+	AllergenSulphites   Allergen = "sulphites"
+	AllergenLupin       Allergen = "lupin"
+	AllergenMolluscs    Allergen = "molluscs"
+)
+
+// Allergens lists the allergens in the order of the regulation's annex
+var Allergens = []Allergen{
+	AllergenGluten, AllergenCrustaceans, AllergenEggs, AllergenFish, AllergenPeanuts, AllergenSoybeans, AllergenMilk,
+	AllergenNuts, AllergenCelery, AllergenMustard, AllergenSesame, AllergenSulphites, AllergenLupin, AllergenMolluscs,
+}
+
+type DietaryTag string
+
+const (
+	DietaryVegan      DietaryTag = "vegan"
+	DietaryVegetarian DietaryTag = "vegetarian"
+	DietaryHalal      DietaryTag = "halal"
+	DietaryGlutenFree DietaryTag = "gluten_free"
+)
+
+var DietaryTags = []DietaryTag{DietaryVegan, DietaryVegetarian, DietaryHalal, DietaryGlutenFree}
+
+// MaxSpiceLevel is the hottest spice level, 0 is not spicy
+const MaxSpiceLevel = 3
+
+// NutritionFacts are given per serving, amounts are in grams
+type NutritionFacts struct {
+	ServingSize   string   `json:"servingSize,omitempty"`
+	Protein       *float64 `json:"protein,omitempty"`
+	Carbohydrates *float64 `json:"carbohydrates,omitempty"`
+	Sugars        *float64 `json:"sugars,omitempty"`
+	Fat           *float64 `json:"fat,omitempty"`
+	SaturatedFat  *float64 `json:"saturatedFat,omitempty"`
+	Fiber         *float64 `json:"fiber,omitempty"`
+	Salt          *float64 `json:"salt,omitempty"`
+}
+
+// MenuItemFilter selects the items a guest can eat. Items with an excluded
+// allergen or without all the dietary tags are left out, so are items above
+// the spice level or the calories. Items without calories are kept.
+type MenuItemFilter struct {
+	ExcludeAllergens []Allergen
+	DietaryTags      []DietaryTag
+	MaxSpiceLevel    *int
+	MaxCalories      *int
+}
+
+func (f *MenuItemFilter) IsEmpty() bool {
+	return f == nil || len(f.ExcludeAllergens) == 0 && len(f.DietaryTags) == 0 && f.MaxSpiceLevel == nil && f.MaxCalories == nil
+}
+
+func (f *MenuItemFilter) Matches(item *MenuCategoryItem) bool {
+	for _, allergen := range f.ExcludeAllergens {
+		if item.HasAllergen(allergen) {
+			return false
+		}
+	}
+	for _, tag := range f.DietaryTags {
+		if !item.HasDietaryTag(tag) {
+			return false
+		}
+	}
+	if f.MaxSpiceLevel != nil && item.SpiceLevel > *f.MaxSpiceLevel {
+		return false
+	}
+	if f.MaxCalories != nil && item.Calories != nil && *item.Calories > *f.MaxCalories {
+		return false
+	}
+
+	return true
+}
+
+// FilterItems returns a copy of the menu with the items the filter matches,
+// categories left without items are dropped. The menu itself is not changed.
+func (m *Menu) FilterItems(filter *MenuItemFilter) *Menu {
+	if filter.IsEmpty() {
+		return m
+	}
+
+	filtered := *m
+	filtered.Categories = make([]*MenuCategory, 0, len(m.Categories))
+	for _, category := range m.Categories {
+		items := make([]*MenuCategoryItem, 0, len(category.MenuItems))
+		for _, item := range category.MenuItems {
+			if filter.Matches(item) {
+				items = append(items, item)
+			}
+		}
+		if len(items) == 0 {
+			continue
+		}
+
+		copied := *category
+		copied.MenuItems = items
+		filtered.Categories = append(filtered.Categories, &copied)
+	}
+
+	return &filtered
+}
+
+func (i *MenuCategoryItem) HasAllergen(allergen Allergen) bool {
+	return slices.Contains(i.Allergens, allergen)
+}
+
+func (i *MenuCategoryItem) HasDietaryTag(tag DietaryTag) bool {
+	return slices.Contains(i.DietaryTags, tag)
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DiningTable is a table or any other place in the restaurant with its own QR
+// code. The token in the QR code tells which table a scan came from.
+type DiningTable struct {
+	ID        uuid.UUID `json:"id" pg:"id"`
+	MenuID    uuid.UUID `json:"menuId" pg:"menu_id"`
+	Label     string    `json:"label" pg:"label"`
+	Token     string    `json:"token" pg:"token"`
+	QRCode    string    `json:"qrCode,omitempty" pg:"qr_code"`
+	CreatedAt time.Time `json:"createdAt" pg:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" pg:"updated_at"`
+}
+
+// TableLocation is what a table token resolves to, it is public as anyone can
+// scan the code
+type TableLocation struct {
+	MenuID  uuid.UUID `json:"menuId"`
+	TableID uuid.UUID `json:"tableId"`
+	Label   string    `json:"label"`
+}
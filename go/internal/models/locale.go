@@ -0,0 +1,93 @@
+package models
+
+// DefaultLocale is the language of menus that do not name theirs
+const DefaultLocale = "en"
+
+// SupportedLocales are the languages menus can be offered in
+var SupportedLocales = []string{"tr", "en", "de", "ar", "he"}
+
+type TextDirection string
+
+const (
+	TextDirectionLTR TextDirection = "ltr"
+	TextDirectionRTL TextDirection = "rtl"
+)
+
+// LocaleInfo is what a menu needs to know to render a locale, the script is
+// an ISO 15924 code
+type LocaleInfo struct {
+	Code      string        `json:"code"`
+	Name      string        `json:"name"`
+	Script    string        `json:"script"`
+	Direction TextDirection `json:"direction"`
+}
+
+var localeInfos = map[string]*LocaleInfo{
+	"tr": {Code: "tr", Name: "Türkçe", Script: "Latn", Direction: TextDirectionLTR},
+	"en": {Code: "en", Name: "English", Script: "Latn", Direction: TextDirectionLTR},
+	"de": {Code: "de", Name: "Deutsch", Script: "Latn", Direction: TextDirectionLTR},
+	"ar": {Code: "ar", Name: "العربية", Script: "Arab", Direction: TextDirectionRTL},
+	"he": {Code: "he", Name: "עברית", Script: "Hebr", Direction: TextDirectionRTL},
+}
+
+// scriptFonts are used for scripts the fonts of a menu were not picked for
+var scriptFonts = map[string]Font{
+	"Latn": {Heading: "Noto Serif", Body: "Noto Sans"},
+	"Arab": {Heading: "Noto Naskh Arabic", Body: "Noto Sans Arabic"},
+	"Hebr": {Heading: "Noto Serif Hebrew", Body: "Noto Sans Hebrew"},
+}
+
+// GetLocaleInfo returns the metadata of a supported locale, nil for others
+func GetLocaleInfo(locale string) *LocaleInfo {
+	return localeInfos[locale]
+}
+
+// SupportedLocaleInfos lists the metadata of the supported locales
+func SupportedLocaleInfos() []*LocaleInfo {
+	infos := make([]*LocaleInfo, 0, len(SupportedLocales))
+	for _, locale := range SupportedLocales {
+		infos = append(infos, localeInfos[locale])
+	}
+	return infos
+}
+
+// FontFor resolves the fonts of a locale. A font set for the locale wins,
+// then the menu's font when the locale is written in the same script as the
+// base locale, and the default font of the script fills the rest. The
+// spacing of the menu's font holds for every script.
+func (c *MenuCustomization) FontFor(locale string, baseLocale string) *Font {
+	info, base := GetLocaleInfo(locale), GetLocaleInfo(baseLocale)
+	if info == nil {
+		info = localeInfos[DefaultLocale]
+	}
+
+	font := scriptFonts[info.Script]
+	candidates := make([]*Font, 0, 3)
+	if c != nil {
+		candidates = append(candidates, c.Fonts[locale])
+		if base == nil || base.Script == info.Script {
+			candidates = append(candidates, c.Font)
+		} else if c.Font != nil {
+			candidates = append(candidates, &Font{Spacing: c.Font.Spacing})
+		}
+	}
+
+	// fields are resolved one by one, from the least specific font up
+	for i := len(candidates) - 1; i >= 0; i-- {
+		candidate := candidates[i]
+		if candidate == nil {
+			continue
+		}
+		if candidate.Heading != "" {
+			font.Heading = candidate.Heading
+		}
+		if candidate.Body != "" {
+			font.Body = candidate.Body
+		}
+		if candidate.Spacing != 0 {
+			font.Spacing = candidate.Spacing
+		}
+	}
+
+	return &font
+}
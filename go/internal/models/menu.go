@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -10,18 +12,38 @@ type Menu struct {
 	Description    string             `json:"description" pg:"description"`
 	ClientID       uuid.UUID          `json:"clientID" pg:"client_id"`
 	Status         string             `json:"status" pg:"status"`
-	Categories     []*MenuCategory    `json:"categories,omitempty" pg:"categories"`
+	Categories     []*MenuCategory    `json:"categories,omitempty"`
 	Customization  *MenuCustomization `json:"customization,omitempty" pg:"customization"`
 	QRCutomization *QRCutomization    `json:"qrCustomization,omitempty" pg:"qr_customization"`
 	QRCode         string             `json:"qrCode,omitempty" pg:"qr_code"`
 	CreatedAt      string             `json:"createdAt" pg:"created_at"`
 	UpdatedAt      string             `json:"updatedAt" pg:"updated_at"`
+	// PublishedVersion is the version served to guests, nil until the menu is
+	// published for the first time. The menu itself is the editable draft.
+	PublishedVersion *int       `json:"publishedVersion,omitempty" pg:"published_version"`
+	PublishedAt      *time.Time `json:"publishedAt,omitempty" pg:"published_at"`
+	// DefaultLocale is the language of the fields, Locales the languages the
+	// menu is offered in. Locale, its script and direction and the locales to
+	// switch to are set on menus served in one language.
+	DefaultLocale    string        `json:"defaultLocale,omitempty" pg:"default_locale"`
+	Locales          []string      `json:"locales,omitempty" pg:"locales"`
+	Locale           string        `json:"locale,omitempty"`
+	Script           string        `json:"script,omitempty"`
+	Direction        TextDirection `json:"direction,omitempty"`
+	AvailableLocales []*LocaleInfo `json:"availableLocales,omitempty"`
+	Translations     Translations  `json:"translations,omitempty" pg:"translations"`
 }
 
+// MenuCustomization is the look of a menu. Fonts holds fonts for single
+// locales, for scripts the menu's font does not cover.
 type MenuCustomization struct {
-	QRCode *QRCutomization `json:"qrCode,omitempty"`
+	QRCode *QRCutomization  `json:"qrCode,omitempty"`
+	Colors *Colors          `json:"colors,omitempty"`
+	Font   *Font            `json:"font,omitempty"`
+	Fonts  map[string]*Font `json:"fonts,omitempty"`
 }
 
+// Colors are hex colors such as #1a2b3c, empty ones are left to the app
 type Colors struct {
 	Primary     string `json:"primary"`
 	Secondary   string `json:"secondary"`
@@ -33,10 +55,12 @@ type Colors struct {
 	HeaderText  string `json:"headerText"`
 }
 
+// Font names the font families of a menu, spacing is the space between
+// the items in pixels
 type Font struct {
-	Heading string `json:"heading"`
-	Body    string `json:"body"`
-	Spacing int    `json:"spacing"`
+	Heading string `json:"heading,omitempty"`
+	Body    string `json:"body,omitempty"`
+	Spacing int    `json:"spacing,omitempty"`
 }
 
 type QRCutomization struct {
@@ -48,17 +72,68 @@ type QRCutomization struct {
 	BackgroundColor string `json:"backgroundColor"`
 }
 
+// QRCodeExport is a QR code rendered for print, ETag identifies the menu
+// address and the options it was rendered with
+type QRCodeExport struct {
+	Data        []byte
+	ContentType string
+	Format      string
+	ETag        string
+}
+
 type MenuCategory struct {
-	ID        uuid.UUID           `json:"id"`
-	Name      string              `json:"name"`
-	MenuItems []*MenuCategoryItem `json:"menuItems,omitempty"`
+	ID           uuid.UUID           `json:"id" pg:"id"`
+	MenuID       uuid.UUID           `json:"-" pg:"menu_id"`
+	Name         string              `json:"name" pg:"name"`
+	Status       MenuStatus          `json:"status,omitempty" pg:"status"`
+	MenuItems    []*MenuCategoryItem `json:"menuItems,omitempty"`
+	Translations Translations        `json:"translations,omitempty" pg:"translations"`
 }
 
 type MenuCategoryItem struct {
-	ID          uuid.UUID  `json:"id"`
-	Name        string     `json:"name"`
-	Description string     `json:"description"`
-	Price       float64    `json:"price"`
-	ModelID     *uuid.UUID `json:"modelId"`
-	Model       *Model     `json:"modelInfo,omitempty"`
+	ID             uuid.UUID          `json:"id" pg:"id"`
+	Name           string             `json:"name" pg:"name"`
+	Description    string             `json:"description" pg:"description"`
+	Price          float64            `json:"price" pg:"price"`
+	Variants       []*MenuItemVariant `json:"variants,omitempty" pg:"variants"`
+	ModifierGroups []*ModifierGroup   `json:"modifierGroups,omitempty" pg:"modifier_groups"`
+	Images         []string           `json:"images,omitempty" pg:"images"`
+	ModelID        *uuid.UUID         `json:"modelId" pg:"model_id"`
+	Model          *Model             `json:"modelInfo,omitempty"`
+	Status         MenuStatus         `json:"status,omitempty" pg:"status"`
+	Allergens      []Allergen         `json:"allergens" pg:"allergens"`
+	DietaryTags    []DietaryTag       `json:"dietaryTags" pg:"dietary_tags"`
+	SpiceLevel     int                `json:"spiceLevel" pg:"spice_level"`
+	Calories       *int               `json:"calories,omitempty" pg:"calories"`
+	Nutrition      *NutritionFacts    `json:"nutrition,omitempty" pg:"nutrition"`
+	Translations   Translations       `json:"translations,omitempty" pg:"translations"`
+}
+
+// MenuItemVariant is a size or kind of an item with its own price, such as
+// small and large. The default variant's price is the item's price.
+type MenuItemVariant struct {
+	ID      uuid.UUID `json:"id"`
+	Name    string    `json:"name"`
+	Price   float64   `json:"price"`
+	Default bool      `json:"default,omitempty"`
+}
+
+// ModifierGroup is a choice guests make on an item, such as a sauce or add-ons.
+// Between MinSelect and MaxSelect options are picked, a MinSelect above 0
+// makes the choice required.
+type ModifierGroup struct {
+	ID        uuid.UUID         `json:"id"`
+	Name      string            `json:"name"`
+	MinSelect int               `json:"minSelect"`
+	MaxSelect int               `json:"maxSelect"`
+	Options   []*ModifierOption `json:"options"`
+}
+
+// ModifierOption adds its price delta to the item's price, negative deltas
+// take something off
+type ModifierOption struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	PriceDelta float64   `json:"priceDelta"`
+	Default    bool      `json:"default,omitempty"`
 }
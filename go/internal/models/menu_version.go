@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MenuVersion is an immutable snapshot of a menu taken when it is published.
+// Rolling back publishes the snapshot of an earlier version as a new version.
+type MenuVersion struct {
+	ID            uuid.UUID  `json:"id" pg:"id"`
+	MenuID        uuid.UUID  `json:"menuId" pg:"menu_id"`
+	Version       int        `json:"version" pg:"version"`
+	Snapshot      *Menu      `json:"snapshot,omitempty" pg:"snapshot"`
+	SourceVersion *int       `json:"sourceVersion,omitempty" pg:"source_version"` // set when the version is a rollback
+	PublishedBy   *uuid.UUID `json:"publishedBy,omitempty" pg:"published_by"`
+	Published     bool       `json:"published" pg:"-"` // whether this is the version guests see
+	CreatedAt     time.Time  `json:"createdAt" pg:"created_at"`
+}
+
+type MenuChangeType string
+
+const (
+	MenuChangeAdded   MenuChangeType = "added"
+	MenuChangeRemoved MenuChangeType = "removed"
+	MenuChangeUpdated MenuChangeType = "updated"
+)
+
+type MenuChangeEntity string
+
+const (
+	MenuChangeEntityMenu     MenuChangeEntity = "menu"
+	MenuChangeEntityCategory MenuChangeEntity = "category"
+	MenuChangeEntityItem     MenuChangeEntity = "item"
+)
+
+// MenuChange is a single difference between two versions of a menu. Updates
+// carry the changed field with its old and new value.
+type MenuChange struct {
+	Type       MenuChangeType   `json:"type"`
+	Entity     MenuChangeEntity `json:"entity"`
+	ID         uuid.UUID        `json:"id"`
+	Name       string           `json:"name"`
+	CategoryID *uuid.UUID       `json:"categoryId,omitempty"`
+	Field      string           `json:"field,omitempty"`
+	Old        interface{}      `json:"old,omitempty"`
+	New        interface{}      `json:"new,omitempty"`
+}
+
+// MenuVersionDiff lists the changes from one version to another. A nil
+// ToVersion means the diff runs against the current draft.
+type MenuVersionDiff struct {
+	MenuID      uuid.UUID     `json:"menuId"`
+	FromVersion int           `json:"fromVersion"`
+	ToVersion   *int          `json:"toVersion"`
+	Changes     []*MenuChange `json:"changes"`
+}
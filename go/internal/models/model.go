@@ -7,12 +7,107 @@ import (
 )
 
 type Model struct {
-	ID        *uuid.UUID `json:"id" pg:"id"`
-	ClientID  uuid.UUID  `json:"clientId" pg:"client_id"`
-	Name      string     `json:"name" pg:"name"`
-	Thumbnail string     `json:"thumbnail" pg:"thumbnail"`
-	GlbFile   string     `json:"glbFile" pg:"glb_file"`
-	UsdzFile  string     `json:"usdzFile" pg:"usdz_file"`
-	CreatedAt time.Time  `json:"createdAt" pg:"created_at"`
-	UpdatedAt time.Time  `json:"updatedAt" pg:"updated_at"`
+	ID        *uuid.UUID     `json:"id" pg:"id"`
+	ClientID  uuid.UUID      `json:"clientId" pg:"client_id"`
+	Name      string         `json:"name" pg:"name"`
+	Thumbnail string         `json:"thumbnail" pg:"thumbnail"`
+	GlbFile   string         `json:"glbFile" pg:"glb_file"`
+	UsdzFile  string         `json:"usdzFile" pg:"usdz_file"`
+	Metadata  *ModelMetadata `json:"metadata,omitempty" pg:"metadata"`
+	// OptimizedGlbFile is the smaller variant of the glb file guests are
+	// served, empty until the optimization is done
+	OptimizedGlbFile string             `json:"optimizedGlbFile,omitempty" pg:"optimized_glb_file"`
+	Optimization     *ModelOptimization `json:"optimization,omitempty" pg:"optimization"`
+	// Thumbnails are drawn from the glb file when none is uploaded, Thumbnail
+	// is then the largest png of them
+	Thumbnails []*ModelThumbnail `json:"thumbnails,omitempty" pg:"thumbnails"`
+	// UsdzConversion tracks the usdz file generated from the glb file when
+	// none is uploaded, UsdzFile is empty until it is done
+	UsdzConversion *ModelConversion `json:"usdzConversion,omitempty" pg:"usdz_conversion"`
+	CreatedAt      time.Time        `json:"createdAt" pg:"created_at"`
+	UpdatedAt      time.Time        `json:"updatedAt" pg:"updated_at"`
+}
+
+// ModelThumbnail is one size and format of a drawn thumbnail, size is the
+// width and height in pixels
+type ModelThumbnail struct {
+	Size   int    `json:"size"`
+	Format string `json:"format"`
+	Path   string `json:"path"`
+}
+
+type ModelOptimizationStatus string
+
+const (
+	ModelOptimizationQueued     ModelOptimizationStatus = "queued"
+	ModelOptimizationProcessing ModelOptimizationStatus = "processing"
+	ModelOptimizationCompleted  ModelOptimizationStatus = "completed"
+	// ModelOptimizationSkipped is for models the optimizer can not handle or
+	// could not make smaller, the original is served
+	ModelOptimizationSkipped ModelOptimizationStatus = "skipped"
+	ModelOptimizationFailed  ModelOptimizationStatus = "failed"
+)
+
+// ModelOptimization tracks the optimized variant of the glb file, sizes are
+// in bytes
+type ModelOptimization struct {
+	Status        ModelOptimizationStatus `json:"status"`
+	OriginalSize  int64                   `json:"originalSize"`
+	OptimizedSize int64                   `json:"optimizedSize,omitempty"`
+	Reason        string                  `json:"reason,omitempty"`
+	CompletedAt   *time.Time              `json:"completedAt,omitempty"`
+}
+
+// ModelOptimizationMessage is published to the optimization queue for every
+// uploaded glb file
+type ModelOptimizationMessage struct {
+	ModelID uuid.UUID `json:"modelId"`
+}
+
+type ModelConversionStatus string
+
+const (
+	ModelConversionQueued     ModelConversionStatus = "queued"
+	ModelConversionProcessing ModelConversionStatus = "processing"
+	ModelConversionCompleted  ModelConversionStatus = "completed"
+	ModelConversionFailed     ModelConversionStatus = "failed"
+)
+
+// ModelConversion tracks a file generated from the glb file
+type ModelConversion struct {
+	Status      ModelConversionStatus `json:"status"`
+	Reason      string                `json:"reason,omitempty"`
+	CompletedAt *time.Time            `json:"completedAt,omitempty"`
+}
+
+// ModelConversionMessage is published to the conversion queue for every glb
+// file uploaded without a usdz file
+type ModelConversionMessage struct {
+	ModelID uuid.UUID `json:"modelId"`
+}
+
+// PublicGlbFile is the glb file guests are served, the optimized variant when
+// there is one
+func (m *Model) PublicGlbFile() string {
+	if m.OptimizedGlbFile != "" {
+		return m.OptimizedGlbFile
+	}
+	return m.GlbFile
+}
+
+// ModelMetadata is read from the glb file on upload. Dimensions are the width,
+// height and depth of the bounding box in meters.
+type ModelMetadata struct {
+	Triangles    int            `json:"triangles"`
+	Vertices     int            `json:"vertices"`
+	Materials    int            `json:"materials"`
+	Textures     int            `json:"textures"`
+	TextureSizes []*TextureSize `json:"textureSizes"`
+	Dimensions   [3]float64     `json:"dimensions"`
+	Scale        [3]float64     `json:"scale"`
+}
+
+type TextureSize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
 }
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"github.com/ahmetkoprulu/bidi-menu/common/ocr"
+	"github.com/google/uuid"
+)
+
+type ScanFieldKind string
+
+const (
+	ScanFieldCategoryName    ScanFieldKind = "category_name"
+	ScanFieldItemName        ScanFieldKind = "item_name"
+	ScanFieldItemDescription ScanFieldKind = "item_description"
+	ScanFieldItemPrice       ScanFieldKind = "item_price"
+	ScanFieldVariantPrice    ScanFieldKind = "variant_price"
+)
+
+// ScanResult is a scanned menu together with where and how confidently every
+// field was read, so editors only need to check the fields flagged for review
+type ScanResult struct {
+	Menu     *Menu           `json:"menu"`
+	Currency string          `json:"currency,omitempty"`
+	Fields   []*ScannedField `json:"fields"`
+	Review   []*ScannedField `json:"review"`
+}
+
+type ScannedField struct {
+	Path        string          `json:"path"` // e.g. categories[0].menuItems[2].price
+	Kind        ScanFieldKind   `json:"kind"`
+	CategoryID  uuid.UUID       `json:"categoryId"`
+	ItemID      *uuid.UUID      `json:"itemId,omitempty"`
+	Value       string          `json:"value"`
+	Confidence  float64         `json:"confidence"`
+	Page        int             `json:"page"`
+	Box         ocr.BoundingBox `json:"box"`
+	NeedsReview bool            `json:"needsReview"`
+}
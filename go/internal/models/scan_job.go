@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ScanJobStatus string
+
+const (
+	ScanJobStatusQueued     ScanJobStatus = "queued"
+	ScanJobStatusProcessing ScanJobStatus = "processing"
+	ScanJobStatusCompleted  ScanJobStatus = "completed"
+	ScanJobStatusFailed     ScanJobStatus = "failed"
+)
+
+type ScanJob struct {
+	ID             uuid.UUID     `json:"id" pg:"id"`
+	ClientID       uuid.UUID     `json:"clientId" pg:"client_id"`
+	Status         ScanJobStatus `json:"status" pg:"status"`
+	TotalPages     int           `json:"totalPages" pg:"total_pages"`
+	ProcessedPages int           `json:"processedPages" pg:"processed_pages"`
+	Progress       int           `json:"progress" pg:"-"` // percentage of processed pages
+	Result         *ScanResult   `json:"result,omitempty" pg:"result"`
+	Error          *string       `json:"error,omitempty" pg:"error"`
+	CreatedAt      time.Time     `json:"createdAt" pg:"created_at"`
+	UpdatedAt      time.Time     `json:"updatedAt" pg:"updated_at"`
+	CompletedAt    *time.Time    `json:"completedAt,omitempty" pg:"completed_at"`
+}
+
+// ScanJobMessage is published to the scan queue for every new scan job. The
+// files are passed by path, so workers need the upload directory mounted at the
+// same path as the api.
+type ScanJobMessage struct {
+	JobID     uuid.UUID `json:"jobId"`
+	ClientID  uuid.UUID `json:"clientId"`
+	UploadDir string    `json:"uploadDir"`
+	FilePaths []string  `json:"filePaths"`
+}
+
+// CalculateProgress fills Progress from the processed and total page counts
+func (j *ScanJob) CalculateProgress() {
+	switch {
+	case j.Status == ScanJobStatusCompleted:
+		j.Progress = 100
+	case j.TotalPages > 0:
+		j.Progress = j.ProcessedPages * 100 / j.TotalPages
+	default:
+		j.Progress = 0
+	}
+}
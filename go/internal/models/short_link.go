@@ -0,0 +1,47 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ShortLink is a short code printed in QR codes that redirects to a menu. It
+// can be pointed to another menu at any time, and schedules send guests to a
+// different menu at some times of the week.
+type ShortLink struct {
+	ID       uuid.UUID `json:"id" pg:"id"`
+	ClientID uuid.UUID `json:"clientId" pg:"client_id"`
+	Code     string    `json:"code" pg:"code"`
+	Label    string    `json:"label" pg:"label"`
+	// MenuID is the target when no schedule matches, nil once the menu is deleted
+	MenuID *uuid.UUID `json:"menuId" pg:"menu_id"`
+	// Timezone the schedules are read in, the client's timezone when empty
+	Timezone  string               `json:"timezone,omitempty" pg:"timezone"`
+	Schedules []*ShortLinkSchedule `json:"schedules"`
+	URL       string               `json:"url,omitempty"`
+	CreatedAt time.Time            `json:"createdAt" pg:"created_at"`
+	UpdatedAt time.Time            `json:"updatedAt" pg:"updated_at"`
+}
+
+// ShortLinkSchedule points the link to a menu on the given days between the
+// start and end time. Days run from 0 for Sunday to 6 for Saturday, times are
+// HH:MM and an end before the start runs past midnight.
+type ShortLinkSchedule struct {
+	ID        uuid.UUID `json:"id" pg:"id"`
+	MenuID    uuid.UUID `json:"menuId" pg:"menu_id"`
+	Days      []int     `json:"days" pg:"days"`
+	StartTime string    `json:"startTime" pg:"start_time"`
+	EndTime   string    `json:"endTime" pg:"end_time"`
+}
+
+// ShortLinkTarget is where a short link leads right now
+type ShortLinkTarget struct {
+	Code   string    `json:"code"`
+	MenuID uuid.UUID `json:"menuId"`
+	URL    string    `json:"url"`
+}
+
+// ErrShortCodeTaken is returned when a new short link's code is already used
+var ErrShortCodeTaken = errors.New("short code is already taken")
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned for missing resources and for resources that belong
+// to another client, so callers can't tell the two apart
+var ErrNotFound = errors.New("resource not found")
+
+// Tenant is the client a request acts for. Admins are not bound to a client and
+// may act on the resources of every client.
+type Tenant struct {
+	ClientID uuid.UUID
+	IsAdmin  bool
+}
+
+// Owns reports whether the tenant may act on a resource of the given client
+func (t Tenant) Owns(clientID uuid.UUID) bool {
+	return t.IsAdmin || t.ClientID == clientID
+}
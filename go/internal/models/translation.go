@@ -0,0 +1,113 @@
+package models
+
+import "github.com/google/uuid"
+
+// Translation is the text of a menu, category or item in one locale. Menus
+// translate the label and description, categories the name and items the
+// name and description.
+type Translation struct {
+	Label       string `json:"label,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Translations are keyed by locale, the menu's default locale is written in
+// the fields themselves
+type Translations map[string]*Translation
+
+// MissingTranslation is a field that has text in the default locale but
+// none in Locale
+type MissingTranslation struct {
+	Locale string           `json:"locale"`
+	Entity MenuChangeEntity `json:"entity"`
+	ID     *uuid.UUID       `json:"id,omitempty"`
+	Field  string           `json:"field"`
+	Text   string           `json:"text"`
+}
+
+type TranslationReport struct {
+	DefaultLocale string                `json:"defaultLocale"`
+	Locales       []string              `json:"locales"`
+	Missing       []*MissingTranslation `json:"missing"`
+}
+
+// BaseLocale is the locale the menu's fields are written in
+func (m *Menu) BaseLocale() string {
+	if m.DefaultLocale == "" {
+		return DefaultLocale
+	}
+	return m.DefaultLocale
+}
+
+// OfferedLocales are the locales guests can pick, the base locale first
+func (m *Menu) OfferedLocales() []string {
+	locales := []string{m.BaseLocale()}
+	for _, locale := range m.Locales {
+		if locale != locales[0] {
+			locales = append(locales, locale)
+		}
+	}
+	return locales
+}
+
+// Localize returns a copy of the menu with its texts in the locale, fields
+// without a translation keep the base text. The copy carries the script,
+// direction and fonts of the locale instead of the translations and the
+// fonts of the other locales.
+func (m *Menu) Localize(locale string) *Menu {
+	localized := *m
+	localized.Locale = locale
+	localized.Translations = nil
+	if info := GetLocaleInfo(locale); info != nil {
+		localized.Script, localized.Direction = info.Script, info.Direction
+	}
+
+	localized.AvailableLocales = make([]*LocaleInfo, 0, len(m.Locales)+1)
+	for _, offered := range m.OfferedLocales() {
+		if info := GetLocaleInfo(offered); info != nil {
+			localized.AvailableLocales = append(localized.AvailableLocales, info)
+		}
+	}
+
+	var customization MenuCustomization
+	if m.Customization != nil {
+		customization = *m.Customization
+	}
+	customization.Font = m.Customization.FontFor(locale, m.BaseLocale())
+	customization.Fonts = nil
+	localized.Customization = &customization
+	if t := m.Translations[locale]; t != nil && locale != m.BaseLocale() {
+		localized.Label = pick(t.Label, m.Label)
+		localized.Description = pick(t.Description, m.Description)
+	}
+
+	localized.Categories = make([]*MenuCategory, 0, len(m.Categories))
+	for _, category := range m.Categories {
+		c := *category
+		c.Translations = nil
+		if t := category.Translations[locale]; t != nil && locale != m.BaseLocale() {
+			c.Name = pick(t.Name, category.Name)
+		}
+
+		c.MenuItems = make([]*MenuCategoryItem, 0, len(category.MenuItems))
+		for _, item := range category.MenuItems {
+			i := *item
+			i.Translations = nil
+			if t := item.Translations[locale]; t != nil && locale != m.BaseLocale() {
+				i.Name = pick(t.Name, item.Name)
+				i.Description = pick(t.Description, item.Description)
+			}
+			c.MenuItems = append(c.MenuItems, &i)
+		}
+		localized.Categories = append(localized.Categories, &c)
+	}
+
+	return &localized
+}
+
+func pick(translated, base string) string {
+	if translated != "" {
+		return translated
+	}
+	return base
+}
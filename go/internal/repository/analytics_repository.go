@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+)
+
+type AnalyticsRepository interface {
+	// IncrementCounters adds the counts to the stored counters. Counters of
+	// menus that do not exist are dropped, unknown table tokens count without a
+	// table.
+	IncrementCounters(ctx context.Context, counters []*models.AnalyticsCounter) error
+}
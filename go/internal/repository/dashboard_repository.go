@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/google/uuid"
+)
+
+// DashboardRepository reads the figures of a client's dashboard. Periods run
+// from the start instant up to, not including, the end instant.
+type DashboardRepository interface {
+	GetMenuCounts(ctx context.Context, clientID uuid.UUID) (total int64, active int64, err error)
+	GetTotals(ctx context.Context, clientID uuid.UUID, from, to time.Time) (*models.DashboardTotals, error)
+	// GetSeries returns the intervals that have events, their starts are
+	// truncated in the given timezone
+	GetSeries(ctx context.Context, clientID uuid.UUID, from, to time.Time, interval models.AnalyticsInterval, timezone string) ([]*models.DashboardPoint, error)
+	GetTopItems(ctx context.Context, clientID uuid.UUID, from, to time.Time, limit int) ([]*models.DashboardItem, error)
+	GetTopModels(ctx context.Context, clientID uuid.UUID, from, to time.Time, limit int) ([]*models.DashboardModel, error)
+	GetRecentMenus(ctx context.Context, clientID uuid.UUID, from, to time.Time, limit int) ([]*models.DashboardMenu, error)
+}
@@ -0,0 +1,70 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/data"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/repository"
+	"github.com/google/uuid"
+)
+
+type analyticsRepository struct {
+	db *data.PgDbContext
+}
+
+func NewAnalyticsRepository(db *data.PgDbContext) repository.AnalyticsRepository {
+	return &analyticsRepository{db: db}
+}
+
+// IncrementCounters upserts all counters in one statement. The client comes
+// from the menu and the table from its token, grouping again after resolving
+// the tokens keeps two counters from hitting the same row.
+func (r *analyticsRepository) IncrementCounters(ctx context.Context, counters []*models.AnalyticsCounter) error {
+	if len(counters) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO menu_event_counts (menu_id, client_id, bucket, event_type, item_id, model_id, source, table_id, device, count)
+		SELECT e.menu_id, m.client_id, e.bucket, e.event_type, e.item_id, e.model_id, e.source,
+			COALESCE(t.id, '00000000-0000-0000-0000-000000000000'::uuid), e.device, SUM(e.count)
+		FROM unnest($1::uuid[], $2::timestamptz[], $3::text[], $4::uuid[], $5::uuid[], $6::text[], $7::text[], $8::text[], $9::bigint[])
+			AS e(menu_id, bucket, event_type, item_id, model_id, source, table_token, device, count)
+		JOIN menus m ON m.id = e.menu_id
+		LEFT JOIN dining_tables t ON t.token = e.table_token AND t.menu_id = e.menu_id
+		GROUP BY e.menu_id, m.client_id, e.bucket, e.event_type, e.item_id, e.model_id, e.source, t.id, e.device
+		ON CONFLICT (menu_id, bucket, event_type, item_id, model_id, source, table_id, device)
+		DO UPDATE SET count = menu_event_counts.count + EXCLUDED.count
+	`
+
+	menuIDs := make([]uuid.UUID, len(counters))
+	buckets := make([]time.Time, len(counters))
+	types := make([]string, len(counters))
+	itemIDs := make([]uuid.UUID, len(counters))
+	modelIDs := make([]uuid.UUID, len(counters))
+	sources := make([]string, len(counters))
+	tableTokens := make([]string, len(counters))
+	devices := make([]string, len(counters))
+	counts := make([]int64, len(counters))
+	for i, counter := range counters {
+		menuIDs[i] = counter.MenuID
+		buckets[i] = counter.Bucket
+		types[i] = string(counter.Type)
+		itemIDs[i] = counter.ItemID
+		modelIDs[i] = counter.ModelID
+		sources[i] = string(counter.Source)
+		tableTokens[i] = counter.TableToken
+		devices[i] = string(counter.Device)
+		counts[i] = counter.Count
+	}
+
+	_, err := r.db.Exec(ctx, query, menuIDs, buckets, types, itemIDs, modelIDs, sources, tableTokens, devices, counts)
+	if err != nil {
+		return fmt.Errorf("failed to increment analytics counters: %w", err)
+	}
+
+	return nil
+}
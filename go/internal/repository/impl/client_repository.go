@@ -242,7 +242,39 @@ func (r *clientRepository) GetClientsWithMenus(ctx context.Context, page, pageSi
 		SELECT 
 			c.id, c.name, c.email, c.phone, c.status, c.trial_end_date,
 			c.address, c.city, c.country, c.timezone, c.logo, c.created_at, c.updated_at,
-			m.id, m.label, m.description, m.status, m.qr_code, m.created_at, m.categories, m.customization
+			m.id, m.label, m.description, m.status, m.qr_code, m.created_at,
+			(
+				SELECT jsonb_agg(jsonb_build_object(
+					'id', mc.id,
+					'name', mc.name,
+					'status', mc.status,
+					'translations', mc.translations,
+					'menuItems', (
+						SELECT COALESCE(jsonb_agg(jsonb_build_object(
+							'id', mi.id,
+							'name', mi.name,
+							'description', COALESCE(mi.description, ''),
+							'price', mi.price,
+							'variants', mi.variants,
+							'modifierGroups', mi.modifier_groups,
+							'images', to_jsonb(mi.images),
+							'modelId', mi.model_id,
+							'status', mi.status,
+							'allergens', to_jsonb(mi.allergens),
+							'dietaryTags', to_jsonb(mi.dietary_tags),
+							'spiceLevel', mi.spice_level,
+							'calories', mi.calories,
+							'nutrition', mi.nutrition,
+							'translations', mi.translations
+						) ORDER BY mi.sort_order), '[]'::jsonb)
+						FROM menu_items mi
+						WHERE mi.category_id = mc.id
+					)
+				) ORDER BY mc.sort_order)
+				FROM menu_categories mc
+				WHERE mc.menu_id = m.id
+			) AS categories,
+			m.customization
 		FROM clients c
 		LEFT JOIN menus m ON c.id = m.client_id
 		ORDER BY c.created_at DESC
@@ -0,0 +1,182 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/data"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/repository"
+	"github.com/google/uuid"
+)
+
+type dashboardRepository struct {
+	db *data.PgDbContext
+}
+
+func NewDashboardRepository(db *data.PgDbContext) repository.DashboardRepository {
+	return &dashboardRepository{db: db}
+}
+
+func (r *dashboardRepository) GetMenuCounts(ctx context.Context, clientID uuid.UUID) (int64, int64, error) {
+	var total, active int64
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE status = 'active')
+		FROM menus
+		WHERE client_id = $1
+	`, clientID).Scan(&total, &active)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count menus: %w", err)
+	}
+
+	return total, active, nil
+}
+
+func (r *dashboardRepository) GetTotals(ctx context.Context, clientID uuid.UUID, from, to time.Time) (*models.DashboardTotals, error) {
+	var totals models.DashboardTotals
+	err := r.db.QueryRow(ctx, `
+		SELECT
+			COALESCE(SUM(count) FILTER (WHERE event_type = 'menu_view'), 0),
+			COALESCE(SUM(count) FILTER (WHERE event_type = 'item_open'), 0),
+			COALESCE(SUM(count) FILTER (WHERE event_type = 'ar_launch'), 0)
+		FROM menu_event_counts
+		WHERE client_id = $1 AND bucket >= $2 AND bucket < $3
+	`, clientID, from, to).Scan(&totals.Views, &totals.ItemOpens, &totals.ARLaunches)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum events: %w", err)
+	}
+
+	return &totals, nil
+}
+
+// GetSeries truncates the hourly counters in the client's timezone and turns
+// the local start back into an instant
+func (r *dashboardRepository) GetSeries(ctx context.Context, clientID uuid.UUID, from, to time.Time, interval models.AnalyticsInterval, timezone string) ([]*models.DashboardPoint, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT
+			date_trunc($4::text, bucket AT TIME ZONE $5::text) AT TIME ZONE $5::text AS start,
+			COALESCE(SUM(count) FILTER (WHERE event_type = 'menu_view'), 0),
+			COALESCE(SUM(count) FILTER (WHERE event_type = 'item_open'), 0),
+			COALESCE(SUM(count) FILTER (WHERE event_type = 'ar_launch'), 0)
+		FROM menu_event_counts
+		WHERE client_id = $1 AND bucket >= $2 AND bucket < $3
+		GROUP BY start
+		ORDER BY start
+	`, clientID, from, to, string(interval), timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query event series: %w", err)
+	}
+	defer rows.Close()
+
+	points := make([]*models.DashboardPoint, 0)
+	for rows.Next() {
+		var point models.DashboardPoint
+		if err := rows.Scan(&point.Start, &point.Views, &point.ItemOpens, &point.ARLaunches); err != nil {
+			return nil, fmt.Errorf("failed to scan event series: %w", err)
+		}
+		points = append(points, &point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read event series: %w", err)
+	}
+
+	return points, nil
+}
+
+// GetTopItems ranks the items by detail opens, items deleted since are left
+// out
+func (r *dashboardRepository) GetTopItems(ctx context.Context, clientID uuid.UUID, from, to time.Time, limit int) ([]*models.DashboardItem, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT e.item_id, c.menu_id, i.name,
+			COALESCE(SUM(e.count) FILTER (WHERE e.event_type = 'item_open'), 0) AS opens,
+			COALESCE(SUM(e.count) FILTER (WHERE e.event_type = 'ar_launch'), 0) AS launches
+		FROM menu_event_counts e
+		JOIN menu_items i ON i.id = e.item_id
+		JOIN menu_categories c ON c.id = i.category_id
+		WHERE e.client_id = $1 AND e.bucket >= $2 AND e.bucket < $3
+		GROUP BY e.item_id, c.menu_id, i.name
+		ORDER BY opens DESC, launches DESC, i.name
+		LIMIT $4
+	`, clientID, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top items: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]*models.DashboardItem, 0)
+	for rows.Next() {
+		var item models.DashboardItem
+		if err := rows.Scan(&item.ItemID, &item.MenuID, &item.Name, &item.Opens, &item.ARLaunches); err != nil {
+			return nil, fmt.Errorf("failed to scan top item: %w", err)
+		}
+		items = append(items, &item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read top items: %w", err)
+	}
+
+	return items, nil
+}
+
+func (r *dashboardRepository) GetTopModels(ctx context.Context, clientID uuid.UUID, from, to time.Time, limit int) ([]*models.DashboardModel, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT e.model_id, m.name, SUM(e.count) AS launches
+		FROM menu_event_counts e
+		JOIN models m ON m.id = e.model_id
+		WHERE e.client_id = $1 AND e.bucket >= $2 AND e.bucket < $3 AND e.event_type = 'ar_launch'
+		GROUP BY e.model_id, m.name
+		ORDER BY launches DESC, m.name
+		LIMIT $4
+	`, clientID, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top models: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.DashboardModel, 0)
+	for rows.Next() {
+		var model models.DashboardModel
+		if err := rows.Scan(&model.ModelID, &model.Name, &model.Launches); err != nil {
+			return nil, fmt.Errorf("failed to scan top model: %w", err)
+		}
+		result = append(result, &model)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read top models: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *dashboardRepository) GetRecentMenus(ctx context.Context, clientID uuid.UUID, from, to time.Time, limit int) ([]*models.DashboardMenu, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT m.id, m.label, m.updated_at,
+			COALESCE((
+				SELECT SUM(e.count) FROM menu_event_counts e
+				WHERE e.menu_id = m.id AND e.event_type = 'menu_view' AND e.bucket >= $2 AND e.bucket < $3
+			), 0)
+		FROM menus m
+		WHERE m.client_id = $1
+		ORDER BY m.updated_at DESC
+		LIMIT $4
+	`, clientID, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent menus: %w", err)
+	}
+	defer rows.Close()
+
+	menus := make([]*models.DashboardMenu, 0)
+	for rows.Next() {
+		var menu models.DashboardMenu
+		if err := rows.Scan(&menu.ID, &menu.Label, &menu.UpdatedAt, &menu.Views); err != nil {
+			return nil, fmt.Errorf("failed to scan recent menu: %w", err)
+		}
+		menus = append(menus, &menu)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recent menus: %w", err)
+	}
+
+	return menus, nil
+}
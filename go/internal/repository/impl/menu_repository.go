@@ -3,12 +3,15 @@ package impl
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/ahmetkoprulu/bidi-menu/common/data"
 	"github.com/ahmetkoprulu/bidi-menu/internal/models"
 	"github.com/ahmetkoprulu/bidi-menu/internal/repository"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 type menuRepository struct {
@@ -19,128 +22,104 @@ func NewMenuRepository(db *data.PgDbContext) repository.MenuRepository {
 	return &menuRepository{db: db}
 }
 
-func (r *menuRepository) DeleteMenu(ctx context.Context, id uuid.UUID) error {
+func (r *menuRepository) DeleteMenu(ctx context.Context, id uuid.UUID, tenant models.Tenant) error {
 	query := `
 		DELETE FROM menus
-		WHERE id = $1
+		WHERE id = $1 AND ($2 OR client_id = $3)
 	`
 
-	_, err := r.db.Exec(ctx, query, id)
+	result, err := r.db.Exec(ctx, query, id, tenant.IsAdmin, tenant.ClientID)
 	if err != nil {
 		return fmt.Errorf("failed to delete menu: %w", err)
 	}
 
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
 	return nil
 }
 
 func (r *menuRepository) GetMenuById(ctx context.Context, id uuid.UUID) (*models.Menu, error) {
 	query := `
-		WITH menu_data AS (
-			SELECT id, client_id, label, description, status, categories, customization
-			FROM menus
-			WHERE id = $1
-		),
-		menu_with_models AS (
-			SELECT 
-				md.*,
-				jsonb_agg(
-					DISTINCT jsonb_build_object(
-						'id', m.id,
-						'clientId', m.client_id,
-						'name', m.name,
-						'thumbnail', m.thumbnail,
-						'glbFile', m.glb_file,
-						'usdzFile', m.usdz_file,
-						'createdAt', m.created_at,
-						'updatedAt', m.updated_at
-					)
-				) FILTER (WHERE m.id IS NOT NULL) as model_list
-			FROM menu_data md
-			LEFT JOIN LATERAL jsonb_array_elements(md.categories::jsonb) as cat ON true
-			LEFT JOIN LATERAL jsonb_array_elements(cat->'menuItems') as items ON true
-			LEFT JOIN models m ON m.id::text = items->>'modelId'
-			GROUP BY md.id, md.client_id, md.label, md.description, md.status, md.categories, md.customization
-		)
-		SELECT 
-			id, client_id, label, description, status,
-			CASE 
-				WHEN categories IS NULL THEN '[]'::jsonb
-				ELSE categories::jsonb
-			END as categories,
-			COALESCE(model_list, '[]'::jsonb) as models,
-			COALESCE(customization, '[]'::jsonb) as customization
-		FROM menu_with_models
+		SELECT id, client_id, label, COALESCE(description, ''), status, customization, qr_customization,
+			COALESCE(qr_code, ''), published_version, published_at, default_locale, locales, translations
+		FROM menus
+		WHERE id = $1
 	`
 
 	var menu models.Menu
-	var categoriesJSON, modelsJSON, customizationJSON []byte
+	var customizationJSON, qrCustomizationJSON, translationsJSON []byte
 	err := r.db.QueryRow(ctx, query, id).Scan(
 		&menu.ID,
 		&menu.ClientID,
 		&menu.Label,
 		&menu.Description,
 		&menu.Status,
-		&categoriesJSON,
-		&modelsJSON,
 		&customizationJSON,
+		&qrCustomizationJSON,
+		&menu.QRCode,
+		&menu.PublishedVersion,
+		&menu.PublishedAt,
+		&menu.DefaultLocale,
+		&menu.Locales,
+		&translationsJSON,
 	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get menu: %w", err)
 	}
 
-	// Parse the models
-	var modelList []*models.Model
-	if err := json.Unmarshal(modelsJSON, &modelList); err != nil {
-		return nil, fmt.Errorf("failed to parse models: %w", err)
-	}
-
-	// Create a map for quick model lookups
-	modelMap := make(map[string]*models.Model)
-	for _, model := range modelList {
-		if model.ID != nil {
-			modelMap[model.ID.String()] = model
+	if customizationJSON != nil {
+		if err := json.Unmarshal(customizationJSON, &menu.Customization); err != nil {
+			return nil, fmt.Errorf("failed to parse customization: %w", err)
 		}
 	}
 
-	// Parse categories
-	var categories []*models.MenuCategory
-	if err := json.Unmarshal(categoriesJSON, &categories); err != nil {
-		return nil, fmt.Errorf("failed to parse categories: %w", err)
+	if qrCustomizationJSON != nil {
+		if err := json.Unmarshal(qrCustomizationJSON, &menu.QRCutomization); err != nil {
+			return nil, fmt.Errorf("failed to parse qr customization: %w", err)
+		}
 	}
 
-	// Parse customization
-	var customization *models.MenuCustomization
-	if err := json.Unmarshal(customizationJSON, &customization); err != nil {
-		return nil, fmt.Errorf("failed to parse customization: %w", err)
+	if menu.Translations, err = decodeTranslations(translationsJSON); err != nil {
+		return nil, err
 	}
 
-	// Update menu items with model information
-	for _, category := range categories {
-		for _, item := range category.MenuItems {
-			if item.ModelID != nil {
-				if model, ok := modelMap[item.ModelID.String()]; ok {
-					// Add model information to the menu item
-					item.Model = model
-				}
-			}
-		}
+	categories, err := r.getCategories(ctx, "m.id = $1", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu: %w", err)
 	}
 
 	menu.Categories = categories
-	menu.Customization = customization
 	return &menu, nil
 }
 
 func (r *menuRepository) CreateMenu(ctx context.Context, menu *models.Menu) (uuid.UUID, error) {
 	query := `
-		INSERT INTO menus (id, client_id, label, description, status, categories, customization)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id
+		INSERT INTO menus (id, client_id, label, description, status, customization, qr_customization,
+			default_locale, locales, translations)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE(NULLIF($8::text, ''), 'en'), COALESCE($9::text[], '{}'), $10::jsonb)
 	`
 
+	translations, err := encodeTranslations(menu.Translations)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
 	id := uuid.New()
 	menu.ID = &id
-	err := r.db.QueryRow(ctx, query, menu.ID, menu.ClientID, menu.Label, menu.Description, menu.Status, menu.Categories, menu.Customization).Scan(&menu.ID)
+	err = r.db.WithTransaction(ctx, func(tx data.QueryRunner) error {
+		_, err := tx.Exec(ctx, query, menu.ID, menu.ClientID, menu.Label, menu.Description, menu.Status, menu.Customization, menu.QRCutomization,
+			menu.DefaultLocale, menu.Locales, translations)
+		if err != nil {
+			return err
+		}
+
+		return r.saveCategories(ctx, tx, id, menu.Categories)
+	})
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("failed to create menu: %w", err)
 	}
@@ -148,14 +127,33 @@ func (r *menuRepository) CreateMenu(ctx context.Context, menu *models.Menu) (uui
 	return *menu.ID, nil
 }
 
-func (r *menuRepository) UpdateMenu(ctx context.Context, menu *models.Menu) error {
+func (r *menuRepository) UpdateMenu(ctx context.Context, menu *models.Menu, tenant models.Tenant) error {
 	query := `
 		UPDATE menus
-		SET label = $2, description = $3, status = $4, categories = $5, customization = $6
-		WHERE id = $1
+		SET label = $2, description = $3, status = $4, customization = $5, qr_customization = $6,
+			default_locale = COALESCE(NULLIF($9::text, ''), 'en'), locales = COALESCE($10::text[], '{}'), translations = $11::jsonb,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND ($7 OR client_id = $8)
 	`
 
-	_, err := r.db.Exec(ctx, query, menu.ID, menu.Label, menu.Description, menu.Status, menu.Categories, menu.Customization)
+	translations, err := encodeTranslations(menu.Translations)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.WithTransaction(ctx, func(tx data.QueryRunner) error {
+		result, err := tx.Exec(ctx, query, menu.ID, menu.Label, menu.Description, menu.Status, menu.Customization, menu.QRCutomization, tenant.IsAdmin, tenant.ClientID,
+			menu.DefaultLocale, menu.Locales, translations)
+		if err != nil {
+			return err
+		}
+
+		if result.RowsAffected() == 0 {
+			return models.ErrNotFound
+		}
+
+		return r.saveCategories(ctx, tx, *menu.ID, menu.Categories)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to update menu: %w", err)
 	}
@@ -163,15 +161,28 @@ func (r *menuRepository) UpdateMenu(ctx context.Context, menu *models.Menu) erro
 	return nil
 }
 
-func (r *menuRepository) GetMenuWithCategories(ctx context.Context, clientID uuid.UUID) ([]*models.MenuCategory, error) {
+// UpdateMenuQRCode stores the path of the menu's rendered QR code
+func (r *menuRepository) UpdateMenuQRCode(ctx context.Context, id uuid.UUID, qrCode string, tenant models.Tenant) error {
 	query := `
-		SELECT categories
-		FROM menus
-		WHERE client_id = $1
+		UPDATE menus
+		SET qr_code = $2
+		WHERE id = $1 AND ($3 OR client_id = $4)
 	`
 
-	var categories []*models.MenuCategory
-	err := r.db.QueryRow(ctx, query, clientID).Scan(&categories)
+	result, err := r.db.Exec(ctx, query, id, qrCode, tenant.IsAdmin, tenant.ClientID)
+	if err != nil {
+		return fmt.Errorf("failed to update menu qr code: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *menuRepository) GetMenuWithCategories(ctx context.Context, clientID uuid.UUID) ([]*models.MenuCategory, error) {
+	categories, err := r.getCategories(ctx, "m.client_id = $1", clientID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get menu categories: %w", err)
 	}
@@ -179,329 +190,959 @@ func (r *menuRepository) GetMenuWithCategories(ctx context.Context, clientID uui
 	return categories, nil
 }
 
-func (r *menuRepository) CreateCategory(ctx context.Context, category *models.MenuCategory) error {
+func (r *menuRepository) CreateCategory(ctx context.Context, category *models.MenuCategory, tenant models.Tenant) error {
 	query := `
-		UPDATE menus
-		SET categories = COALESCE(categories, '[]'::jsonb) || $1::jsonb
-		WHERE client_id = $2
+		INSERT INTO menu_categories (id, menu_id, name, sort_order, status, translations)
+		SELECT
+			$1, m.id, $3,
+			COALESCE((SELECT MAX(sort_order) + 1 FROM menu_categories WHERE menu_id = m.id), 0),
+			COALESCE(NULLIF($4::text, ''), 'active')::menu_status,
+			$7::jsonb
+		FROM menus m
+		WHERE m.id = $2 AND ($5 OR m.client_id = $6)
 	`
 
-	result, err := r.db.Exec(ctx, query, []models.MenuCategory{*category})
+	translations, err := encodeTranslations(category.Translations)
+	if err != nil {
+		return err
+	}
+
+	if category.ID == uuid.Nil {
+		category.ID = uuid.New()
+	}
+
+	result, err := r.db.Exec(ctx, query, category.ID, category.MenuID, category.Name, string(category.Status), tenant.IsAdmin, tenant.ClientID, translations)
 	if err != nil {
 		return fmt.Errorf("failed to create category: %w", err)
 	}
 
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("menu not found")
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
 	}
 
 	return nil
 }
 
-func (r *menuRepository) UpdateCategoryOrder(ctx context.Context, categoryID uuid.UUID, order int) error {
-	// Since we're using JSONB array, we need to update the entire categories array
-	// First, get the current categories
+func (r *menuRepository) UpdateCategory(ctx context.Context, category *models.MenuCategory, tenant models.Tenant) error {
 	query := `
-		SELECT categories
-		FROM menus
-		WHERE categories @> '[{"id": "' || $1::text || '"}]'
+		UPDATE menu_categories
+		SET name = $2, translations = $5::jsonb, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND menu_id IN (SELECT id FROM menus WHERE $3 OR client_id = $4)
+		RETURNING menu_id, status
 	`
 
-	var categories []*models.MenuCategory
-	err := r.db.QueryRow(ctx, query, categoryID).Scan(&categories)
+	translations, err := encodeTranslations(category.Translations)
 	if err != nil {
-		return fmt.Errorf("failed to get categories: %w", err)
+		return err
 	}
 
-	// Update the categories in the menu
-	updateQuery := `
-		UPDATE menus
-		SET categories = $1
-		WHERE categories @> '[{"id": "' || $2::text || '"}]'
+	err = r.db.QueryRow(ctx, query, category.ID, category.Name, tenant.IsAdmin, tenant.ClientID, translations).Scan(&category.MenuID, &category.Status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update category: %w", err)
+	}
+
+	return nil
+}
+
+func (r *menuRepository) UpdateCategoryOrder(ctx context.Context, categoryID uuid.UUID, order int, tenant models.Tenant) error {
+	query := `
+		UPDATE menu_categories
+		SET sort_order = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND menu_id IN (SELECT id FROM menus WHERE $3 OR client_id = $4)
 	`
 
-	result, err := r.db.Exec(ctx, updateQuery, categories, categoryID)
+	result, err := r.db.Exec(ctx, query, categoryID, order, tenant.IsAdmin, tenant.ClientID)
 	if err != nil {
 		return fmt.Errorf("failed to update category order: %w", err)
 	}
 
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("menu not found")
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
 	}
 
 	return nil
 }
 
-func (r *menuRepository) DeleteCategory(ctx context.Context, categoryID uuid.UUID) error {
+func (r *menuRepository) DeleteCategory(ctx context.Context, categoryID uuid.UUID, tenant models.Tenant) error {
 	query := `
-		UPDATE menus
-		SET categories = (
-			SELECT jsonb_agg(c)
-			FROM jsonb_array_elements(categories) c
-			WHERE c->>'id' != $1::text
-		)
-		WHERE categories @> '[{"id": "' || $1::text || '"}]'
+		DELETE FROM menu_categories
+		WHERE id = $1 AND menu_id IN (SELECT id FROM menus WHERE $2 OR client_id = $3)
 	`
 
-	result, err := r.db.Exec(ctx, query, categoryID)
+	result, err := r.db.Exec(ctx, query, categoryID, tenant.IsAdmin, tenant.ClientID)
 	if err != nil {
 		return fmt.Errorf("failed to delete category: %w", err)
 	}
 
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("category not found")
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
 	}
 
 	return nil
 }
 
-func (r *menuRepository) DeleteMenuItem(ctx context.Context, itemID uuid.UUID) error {
+// CreateMenuItem appends an item to the end of a category. Models of other
+// clients are not linked.
+func (r *menuRepository) CreateMenuItem(ctx context.Context, categoryID uuid.UUID, item *models.MenuCategoryItem, tenant models.Tenant) error {
 	query := `
-		UPDATE menus
-		SET categories = (
-			SELECT jsonb_agg(
-				CASE
-					WHEN c->'menuItems' @> '[{"id": "' || $1::text || '"}]'
-					THEN jsonb_set(
-						c,
-						'{menuItems}',
-						(
-							SELECT jsonb_agg(i)
-							FROM jsonb_array_elements(c->'menuItems') i
-							WHERE i->>'id' != $1::text
-						)
-					)
-					ELSE c
-				END
-			)
-			FROM jsonb_array_elements(categories) c
+		INSERT INTO menu_items (id, category_id, name, description, price, variants, images, model_id, sort_order, status,
+			allergens, dietary_tags, spice_level, calories, nutrition, modifier_groups, translations)
+		SELECT
+			$1, c.id, $3, $4, $5, $6::jsonb, COALESCE($7::text[], '{}'),
+			(SELECT md.id FROM models md WHERE md.id = $8 AND md.client_id = m.client_id),
+			COALESCE((SELECT MAX(sort_order) + 1 FROM menu_items WHERE category_id = c.id), 0),
+			COALESCE(NULLIF($9::text, ''), 'active')::menu_status,
+			COALESCE($12::text[], '{}'), COALESCE($13::text[], '{}'), $14, $15, $16::jsonb, $17::jsonb, $18::jsonb
+		FROM menu_categories c
+		JOIN menus m ON m.id = c.menu_id
+		WHERE c.id = $2 AND ($10 OR m.client_id = $11)
+		RETURNING model_id, status
+	`
+
+	columns, err := encodeItemColumns(item)
+	if err != nil {
+		return err
+	}
+
+	if item.ID == uuid.Nil {
+		item.ID = uuid.New()
+	}
+
+	err = r.db.QueryRow(ctx, query,
+		item.ID, categoryID, item.Name, item.Description, item.Price, columns.variants,
+		item.Images, item.ModelID, string(item.Status), tenant.IsAdmin, tenant.ClientID,
+		columns.allergens, columns.dietaryTags, item.SpiceLevel, item.Calories, columns.nutrition, columns.modifierGroups,
+		columns.translations,
+	).Scan(&item.ModelID, &item.Status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create menu item: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateMenuItem changes the name, description, prices, model and dietary
+// information of an item. Models of other clients are unlinked.
+func (r *menuRepository) UpdateMenuItem(ctx context.Context, item *models.MenuCategoryItem, tenant models.Tenant) error {
+	query := `
+		UPDATE menu_items i
+		SET name = $2,
+			description = $3,
+			price = $4,
+			model_id = (SELECT md.id FROM models md WHERE md.id = $5 AND md.client_id = m.client_id),
+			allergens = COALESCE($8::text[], '{}'),
+			dietary_tags = COALESCE($9::text[], '{}'),
+			spice_level = $10,
+			calories = $11,
+			nutrition = $12::jsonb,
+			modifier_groups = $13::jsonb,
+			variants = $14::jsonb,
+			translations = $15::jsonb,
+			updated_at = CURRENT_TIMESTAMP
+		FROM menu_categories c
+		JOIN menus m ON m.id = c.menu_id
+		WHERE i.id = $1 AND c.id = i.category_id AND ($6 OR m.client_id = $7)
+		RETURNING i.model_id, i.images, i.status
+	`
+
+	columns, err := encodeItemColumns(item)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.QueryRow(ctx, query, item.ID, item.Name, item.Description, item.Price, item.ModelID, tenant.IsAdmin, tenant.ClientID,
+		columns.allergens, columns.dietaryTags, item.SpiceLevel, item.Calories, columns.nutrition, columns.modifierGroups, columns.variants,
+		columns.translations).
+		Scan(&item.ModelID, &item.Images, &item.Status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update menu item: %w", err)
+	}
+
+	return nil
+}
+
+// MoveMenuItem puts an item at the given position of a category of the same
+// menu, the items from that position on move down by one
+func (r *menuRepository) MoveMenuItem(ctx context.Context, itemID uuid.UUID, categoryID uuid.UUID, order int, tenant models.Tenant) error {
+	menuQuery := `
+		SELECT c.menu_id
+		FROM menu_items i
+		JOIN menu_categories c ON c.id = i.category_id
+		JOIN menus m ON m.id = c.menu_id
+		WHERE i.id = $1 AND ($2 OR m.client_id = $3)
+	`
+
+	targetQuery := `
+		SELECT EXISTS (SELECT 1 FROM menu_categories WHERE id = $1 AND menu_id = $2)
+	`
+
+	shiftQuery := `
+		UPDATE menu_items
+		SET sort_order = sort_order + 1
+		WHERE category_id = $1 AND sort_order >= $2 AND id <> $3
+	`
+
+	moveQuery := `
+		UPDATE menu_items
+		SET category_id = $2, sort_order = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+
+	if order < 0 {
+		order = 0
+	}
+
+	err := r.db.WithTransaction(ctx, func(tx data.QueryRunner) error {
+		var menuID uuid.UUID
+		err := tx.QueryRow(ctx, menuQuery, itemID, tenant.IsAdmin, tenant.ClientID).Scan(&menuID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var exists bool
+		if err := tx.QueryRow(ctx, targetQuery, categoryID, menuID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("category %s: %w", categoryID, models.ErrNotFound)
+		}
+
+		if _, err := tx.Exec(ctx, shiftQuery, categoryID, order, itemID); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, moveQuery, itemID, categoryID, order)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to move menu item: %w", err)
+	}
+
+	return nil
+}
+
+func (r *menuRepository) DeleteMenuItem(ctx context.Context, itemID uuid.UUID, tenant models.Tenant) error {
+	query := `
+		DELETE FROM menu_items
+		WHERE id = $1 AND category_id IN (
+			SELECT c.id FROM menu_categories c
+			JOIN menus m ON m.id = c.menu_id
+			WHERE $2 OR m.client_id = $3
 		)
-		WHERE categories @> '[{"menuItems": [{"id": "' || $1::text || '"}]}]'
 	`
 
-	result, err := r.db.Exec(ctx, query, itemID)
+	result, err := r.db.Exec(ctx, query, itemID, tenant.IsAdmin, tenant.ClientID)
 	if err != nil {
 		return fmt.Errorf("failed to delete menu item: %w", err)
 	}
 
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("item not found")
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
 	}
 
 	return nil
 }
 
-func (r *menuRepository) UpdateItemImages(ctx context.Context, itemID uuid.UUID, images []string) error {
+func (r *menuRepository) UpdateItemImages(ctx context.Context, itemID uuid.UUID, images []string, tenant models.Tenant) error {
 	query := `
-		UPDATE menus
-		SET categories = (
-			SELECT jsonb_agg(
-				CASE
-					WHEN c->'menuItems' @> '[{"id": "' || $1::text || '"}]'
-					THEN jsonb_set(
-						c,
-						'{menuItems}',
-						(
-							SELECT jsonb_agg(
-								CASE
-									WHEN i->>'id' = $1::text
-									THEN jsonb_set(i, '{images}', $2::jsonb)
-									ELSE i
-								END
-							)
-							FROM jsonb_array_elements(c->'menuItems') i
-						)
-					)
-					ELSE c
-				END
-			)
-			FROM jsonb_array_elements(categories) c
+		UPDATE menu_items
+		SET images = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND category_id IN (
+			SELECT c.id FROM menu_categories c
+			JOIN menus m ON m.id = c.menu_id
+			WHERE $3 OR m.client_id = $4
 		)
-		WHERE categories @> '[{"menuItems": [{"id": "' || $1::text || '"}]}]'
 	`
 
-	result, err := r.db.Exec(ctx, query, itemID, images)
+	if images == nil {
+		images = []string{}
+	}
+
+	result, err := r.db.Exec(ctx, query, itemID, images, tenant.IsAdmin, tenant.ClientID)
 	if err != nil {
 		return fmt.Errorf("failed to update item images: %w", err)
 	}
 
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("item not found")
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
 	}
 
 	return nil
 }
 
-func (r *menuRepository) ReorderCategories(ctx context.Context, categoryOrders map[uuid.UUID]int) error {
-	// First, get the current categories
+func (r *menuRepository) ReorderCategories(ctx context.Context, categoryOrders map[uuid.UUID]int, tenant models.Tenant) error {
 	query := `
-		SELECT categories
-		FROM menus
-		WHERE categories @> '[{"id": "' || $1::text || '"}]'
+		UPDATE menu_categories
+		SET sort_order = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND menu_id IN (SELECT id FROM menus WHERE $3 OR client_id = $4)
 	`
 
-	var categories []*models.MenuCategory
-	err := r.db.QueryRow(ctx, query, categoryOrders).Scan(&categories)
+	err := r.db.WithTransaction(ctx, func(tx data.QueryRunner) error {
+		for categoryID, order := range categoryOrders {
+			result, err := tx.Exec(ctx, query, categoryID, order, tenant.IsAdmin, tenant.ClientID)
+			if err != nil {
+				return err
+			}
+
+			if result.RowsAffected() == 0 {
+				return fmt.Errorf("category %s: %w", categoryID, models.ErrNotFound)
+			}
+		}
+
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get categories: %w", err)
+		return fmt.Errorf("failed to reorder categories: %w", err)
 	}
 
-	// Update the categories in the menu
-	updateQuery := `
-		UPDATE menus
-		SET categories = $1
-		WHERE categories @> '[{"id": "' || $2::text || '"}]'
+	return nil
+}
+
+func (r *menuRepository) UpdateCategoryStatus(ctx context.Context, categoryID uuid.UUID, status models.MenuStatus, tenant models.Tenant) error {
+	categoryQuery := `
+		UPDATE menu_categories
+		SET status = $2::menu_status, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND menu_id IN (SELECT id FROM menus WHERE $3 OR client_id = $4)
+	`
+
+	itemsQuery := `
+		UPDATE menu_items
+		SET status = $2::menu_status, updated_at = CURRENT_TIMESTAMP
+		WHERE category_id = $1
 	`
 
-	result, err := r.db.Exec(ctx, updateQuery, categories, categoryOrders)
+	err := r.db.WithTransaction(ctx, func(tx data.QueryRunner) error {
+		result, err := tx.Exec(ctx, categoryQuery, categoryID, string(status), tenant.IsAdmin, tenant.ClientID)
+		if err != nil {
+			return err
+		}
+
+		if result.RowsAffected() == 0 {
+			return models.ErrNotFound
+		}
+
+		_, err = tx.Exec(ctx, itemsQuery, categoryID, string(status))
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("failed to reorder categories: %w", err)
+		return fmt.Errorf("failed to update category status: %w", err)
+	}
+
+	return nil
+}
+
+func (r *menuRepository) UpdateItemsStatus(ctx context.Context, itemIDs []uuid.UUID, status models.MenuStatus, tenant models.Tenant) error {
+	query := `
+		UPDATE menu_items
+		SET status = $2::menu_status, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ANY($1) AND category_id IN (
+			SELECT c.id FROM menu_categories c
+			JOIN menus m ON m.id = c.menu_id
+			WHERE $3 OR m.client_id = $4
+		)
+	`
+
+	result, err := r.db.Exec(ctx, query, itemIDs, string(status), tenant.IsAdmin, tenant.ClientID)
+	if err != nil {
+		return fmt.Errorf("failed to update items status: %w", err)
 	}
 
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("menu not found")
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
 	}
 
 	return nil
 }
 
-func (r *menuRepository) UpdateCategoryStatus(ctx context.Context, categoryID uuid.UUID, status models.MenuStatus) error {
+func (r *menuRepository) RemoveModelFromMenuItems(ctx context.Context, modelID uuid.UUID) error {
 	query := `
+		UPDATE menu_items
+		SET model_id = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE model_id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query, modelID)
+	if err != nil {
+		return fmt.Errorf("failed to remove model from menu items: %w", err)
+	}
+
+	return nil
+}
+
+// CreateMenuVersion stores the snapshot as the next version of the menu and
+// makes it the published one. The menu row is locked so concurrent publishes
+// get consecutive version numbers.
+func (r *menuRepository) CreateMenuVersion(ctx context.Context, version *models.MenuVersion, tenant models.Tenant) error {
+	lockQuery := `
+		SELECT id
+		FROM menus
+		WHERE id = $1 AND ($2 OR client_id = $3)
+		FOR UPDATE
+	`
+
+	insertQuery := `
+		INSERT INTO menu_versions (id, menu_id, version, snapshot, source_version, published_by)
+		SELECT $1, $2, COALESCE(MAX(version), 0) + 1, $3, $4, $5
+		FROM menu_versions
+		WHERE menu_id = $2
+		RETURNING version, created_at
+	`
+
+	publishQuery := `
 		UPDATE menus
-		SET categories = (
-			SELECT jsonb_agg(
-				CASE
-					WHEN c->>'id' = $1::text
-					THEN jsonb_set(
-						jsonb_set(
-							c,
-							'{status}',
-							'"' || $2::text || '"'
-						),
-						'{menuItems}',
-						(
-							SELECT jsonb_agg(
-								jsonb_set(i, '{status}', '"' || $2::text || '"')
-							)
-							FROM jsonb_array_elements(c->'menuItems') i
-						)
-					)
-					ELSE c
-				END
-			)
-			FROM jsonb_array_elements(categories) c
-		)
-		WHERE categories @> '[{"id": "' || $1::text || '"}]'
+		SET published_version = $2, published_at = $3
+		WHERE id = $1
 	`
 
-	result, err := r.db.Exec(ctx, query, categoryID, status)
+	snapshotJSON, err := json.Marshal(version.Snapshot)
 	if err != nil {
-		return fmt.Errorf("failed to update category status: %w", err)
+		return fmt.Errorf("failed to encode menu snapshot: %w", err)
+	}
+
+	if version.ID == uuid.Nil {
+		version.ID = uuid.New()
 	}
 
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("category not found")
+	err = r.db.WithTransaction(ctx, func(tx data.QueryRunner) error {
+		var menuID uuid.UUID
+		err := tx.QueryRow(ctx, lockQuery, version.MenuID, tenant.IsAdmin, tenant.ClientID).Scan(&menuID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, insertQuery, version.ID, version.MenuID, snapshotJSON, version.SourceVersion, version.PublishedBy).
+			Scan(&version.Version, &version.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, publishQuery, version.MenuID, version.Version, version.CreatedAt)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish menu: %w", err)
 	}
 
+	version.Published = true
 	return nil
 }
 
-func (r *menuRepository) UpdateItemsStatus(ctx context.Context, itemIDs []uuid.UUID, status models.MenuStatus) error {
+// GetMenuVersions lists the versions of a menu, newest first, without their
+// snapshots
+func (r *menuRepository) GetMenuVersions(ctx context.Context, menuID uuid.UUID, tenant models.Tenant) ([]*models.MenuVersion, error) {
 	query := `
-		UPDATE menus
-		SET categories = (
-			SELECT jsonb_agg(
-				CASE
-					WHEN c->'menuItems' @> ANY(ARRAY(
-						SELECT jsonb_build_object('id', id)::jsonb
-						FROM unnest($1::uuid[]) id
-					))
-					THEN jsonb_set(
-						c,
-						'{menuItems}',
-						(
-							SELECT jsonb_agg(
-								CASE
-									WHEN i->>'id' = ANY($1::text[])
-									THEN jsonb_set(i, '{status}', '"' || $2::text || '"')
-									ELSE i
-								END
-							)
-							FROM jsonb_array_elements(c->'menuItems') i
-						)
-					)
-					ELSE c
-				END
-			)
-			FROM jsonb_array_elements(categories) c
+		SELECT v.id, v.menu_id, v.version, v.source_version, v.published_by, v.created_at,
+			v.version = m.published_version
+		FROM menu_versions v
+		JOIN menus m ON m.id = v.menu_id
+		WHERE v.menu_id = $1 AND ($2 OR m.client_id = $3)
+		ORDER BY v.version DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, menuID, tenant.IsAdmin, tenant.ClientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu versions: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make([]*models.MenuVersion, 0)
+	for rows.Next() {
+		var version models.MenuVersion
+		err := rows.Scan(&version.ID, &version.MenuID, &version.Version, &version.SourceVersion, &version.PublishedBy, &version.CreatedAt, &version.Published)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan menu version: %w", err)
+		}
+		versions = append(versions, &version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating menu versions: %w", err)
+	}
+
+	return versions, nil
+}
+
+func (r *menuRepository) GetMenuVersion(ctx context.Context, menuID uuid.UUID, version int, tenant models.Tenant) (*models.MenuVersion, error) {
+	query := `
+		SELECT v.id, v.menu_id, v.version, v.snapshot, v.source_version, v.published_by, v.created_at,
+			v.version = m.published_version
+		FROM menu_versions v
+		JOIN menus m ON m.id = v.menu_id
+		WHERE v.menu_id = $1 AND v.version = $2 AND ($3 OR m.client_id = $4)
+	`
+
+	var menuVersion models.MenuVersion
+	var snapshotJSON []byte
+	err := r.db.QueryRow(ctx, query, menuID, version, tenant.IsAdmin, tenant.ClientID).Scan(
+		&menuVersion.ID, &menuVersion.MenuID, &menuVersion.Version, &snapshotJSON,
+		&menuVersion.SourceVersion, &menuVersion.PublishedBy, &menuVersion.CreatedAt, &menuVersion.Published,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu version: %w", err)
+	}
+
+	if err := json.Unmarshal(snapshotJSON, &menuVersion.Snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse menu snapshot: %w", err)
+	}
+
+	return &menuVersion, nil
+}
+
+// GetPublishedMenu returns the snapshot guests see, menus that were never
+// published are not found
+func (r *menuRepository) GetPublishedMenu(ctx context.Context, menuID uuid.UUID) (*models.Menu, error) {
+	query := `
+		SELECT v.snapshot, m.published_version, m.published_at
+		FROM menus m
+		JOIN menu_versions v ON v.menu_id = m.id AND v.version = m.published_version
+		WHERE m.id = $1
+	`
+
+	var menu models.Menu
+	var snapshotJSON []byte
+	var publishedVersion *int
+	var publishedAt *time.Time
+	err := r.db.QueryRow(ctx, query, menuID).Scan(&snapshotJSON, &publishedVersion, &publishedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get published menu: %w", err)
+	}
+
+	if err := json.Unmarshal(snapshotJSON, &menu); err != nil {
+		return nil, fmt.Errorf("failed to parse menu snapshot: %w", err)
+	}
+	if err := r.refreshModels(ctx, &menu); err != nil {
+		return nil, err
+	}
+
+	menu.PublishedVersion = publishedVersion
+	menu.PublishedAt = publishedAt
+	return &menu, nil
+}
+
+// refreshModels replaces the models of a snapshot with their current state,
+// since their files change after publishing when the glb file is optimized.
+// Models deleted since are dropped.
+func (r *menuRepository) refreshModels(ctx context.Context, menu *models.Menu) error {
+	ids := make([]uuid.UUID, 0)
+	for _, category := range menu.Categories {
+		for _, item := range category.MenuItems {
+			if item.ModelID != nil {
+				ids = append(ids, *item.ModelID)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := r.db.Query(ctx, `SELECT `+modelColumns+` FROM models WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get menu models: %w", err)
+	}
+	defer rows.Close()
+
+	current := make(map[uuid.UUID]models.Model, len(ids))
+	for rows.Next() {
+		model, err := scanModel(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan model: %w", err)
+		}
+		current[*model.ID] = model
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating menu models: %w", err)
+	}
+
+	for _, category := range menu.Categories {
+		for _, item := range category.MenuItems {
+			if item.ModelID == nil {
+				continue
+			}
+			model, ok := current[*item.ModelID]
+			if !ok {
+				item.ModelID, item.Model = nil, nil
+				continue
+			}
+			item.Model = &model
+		}
+	}
+
+	return nil
+}
+
+// getCategories loads the categories and items of the menus matching the filter,
+// which is a condition on the menus table aliased m
+func (r *menuRepository) getCategories(ctx context.Context, filter string, args ...interface{}) ([]*models.MenuCategory, error) {
+	categoriesQuery := `
+		SELECT c.id, c.menu_id, c.name, c.status, c.translations
+		FROM menu_categories c
+		JOIN menus m ON m.id = c.menu_id
+		WHERE ` + filter + `
+		ORDER BY m.created_at, c.sort_order, c.created_at
+	`
+
+	rows, err := r.db.Query(ctx, categoriesQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := make([]*models.MenuCategory, 0)
+	categoryMap := make(map[uuid.UUID]*models.MenuCategory)
+	for rows.Next() {
+		category := &models.MenuCategory{MenuItems: make([]*models.MenuCategoryItem, 0)}
+		var translationsJSON []byte
+		if err := rows.Scan(&category.ID, &category.MenuID, &category.Name, &category.Status, &translationsJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+
+		if category.Translations, err = decodeTranslations(translationsJSON); err != nil {
+			return nil, err
+		}
+
+		categories = append(categories, category)
+		categoryMap[category.ID] = category
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating categories: %w", err)
+	}
+
+	itemsQuery := `
+		SELECT
+			i.id, i.category_id, i.name, COALESCE(i.description, ''), i.price, i.variants, i.images, i.model_id, i.status,
+			i.allergens, i.dietary_tags, i.spice_level, i.calories, i.nutrition, i.modifier_groups, i.translations,
+			md.client_id, md.name, md.thumbnail, md.glb_file, md.usdz_file, md.created_at, md.updated_at
+		FROM menu_items i
+		JOIN menu_categories c ON c.id = i.category_id
+		JOIN menus m ON m.id = c.menu_id
+		LEFT JOIN models md ON md.id = i.model_id
+		WHERE ` + filter + `
+		ORDER BY i.sort_order, i.created_at
+	`
+
+	itemRows, err := r.db.Query(ctx, itemsQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu items: %w", err)
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var item models.MenuCategoryItem
+		var categoryID uuid.UUID
+		var variantsJSON, nutritionJSON, modifierGroupsJSON, translationsJSON []byte
+		var allergens, dietaryTags []string
+		var modelClientID *uuid.UUID
+		var modelName, modelThumbnail, modelGlbFile, modelUsdzFile *string
+		var modelCreatedAt, modelUpdatedAt *time.Time
+
+		err := itemRows.Scan(
+			&item.ID, &categoryID, &item.Name, &item.Description, &item.Price, &variantsJSON, &item.Images, &item.ModelID, &item.Status,
+			&allergens, &dietaryTags, &item.SpiceLevel, &item.Calories, &nutritionJSON, &modifierGroupsJSON, &translationsJSON,
+			&modelClientID, &modelName, &modelThumbnail, &modelGlbFile, &modelUsdzFile, &modelCreatedAt, &modelUpdatedAt,
 		)
-		WHERE categories @> ANY(ARRAY(
-			SELECT jsonb_build_object('menuItems', jsonb_build_array(jsonb_build_object('id', id)))::jsonb
-			FROM unnest($1::uuid[]) id
-		))
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan menu item: %w", err)
+		}
+
+		if err := decodeItemColumns(&item, variantsJSON, modifierGroupsJSON, allergens, dietaryTags, nutritionJSON, translationsJSON); err != nil {
+			return nil, err
+		}
+
+		if item.ModelID != nil && modelClientID != nil {
+			item.Model = &models.Model{
+				ID:        item.ModelID,
+				ClientID:  *modelClientID,
+				Name:      *modelName,
+				Thumbnail: *modelThumbnail,
+				GlbFile:   *modelGlbFile,
+				UsdzFile:  *modelUsdzFile,
+				CreatedAt: *modelCreatedAt,
+				UpdatedAt: *modelUpdatedAt,
+			}
+		}
+
+		if category, ok := categoryMap[categoryID]; ok {
+			category.MenuItems = append(category.MenuItems, &item)
+		}
+	}
+	if err := itemRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating menu items: %w", err)
+	}
+
+	return categories, nil
+}
+
+// saveCategories makes the categories and items of a menu match the given list.
+// Rows are upserted by id with their position as sort order, and the ones that
+// are no longer in the list are deleted. Ids that belong to another menu are
+// replaced rather than moved over.
+func (r *menuRepository) saveCategories(ctx context.Context, tx data.QueryRunner, menuID uuid.UUID, categories []*models.MenuCategory) error {
+	categoryIDs := make([]uuid.UUID, 0, len(categories))
+	itemIDs := make([]uuid.UUID, 0)
+
+	for order, category := range categories {
+		if category == nil {
+			continue
+		}
+
+		category.MenuID = menuID
+		if err := r.upsertCategory(ctx, tx, category, order); err != nil {
+			return err
+		}
+		categoryIDs = append(categoryIDs, category.ID)
+
+		for itemOrder, item := range category.MenuItems {
+			if item == nil {
+				continue
+			}
+
+			if err := r.upsertMenuItem(ctx, tx, menuID, category.ID, item, itemOrder); err != nil {
+				return err
+			}
+			itemIDs = append(itemIDs, item.ID)
+		}
+	}
+
+	_, err := tx.Exec(ctx, `
+		DELETE FROM menu_items i
+		USING menu_categories c
+		WHERE c.id = i.category_id AND c.menu_id = $1 AND NOT (i.id = ANY($2))
+	`, menuID, itemIDs)
+	if err != nil {
+		return fmt.Errorf("failed to delete removed menu items: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM menu_categories
+		WHERE menu_id = $1 AND NOT (id = ANY($2))
+	`, menuID, categoryIDs)
+	if err != nil {
+		return fmt.Errorf("failed to delete removed categories: %w", err)
+	}
+
+	return nil
+}
+
+func (r *menuRepository) upsertCategory(ctx context.Context, tx data.QueryRunner, category *models.MenuCategory, order int) error {
+	// an empty status keeps the stored one, new categories default to active
+	query := `
+		INSERT INTO menu_categories (id, menu_id, name, sort_order, status, translations)
+		VALUES ($1, $2, $3, $4, COALESCE(NULLIF($5::text, ''), 'active')::menu_status, $6::jsonb)
+		ON CONFLICT (id) DO UPDATE
+		SET name = EXCLUDED.name,
+			translations = EXCLUDED.translations,
+			sort_order = EXCLUDED.sort_order,
+			status = CASE WHEN $5::text = '' THEN menu_categories.status ELSE EXCLUDED.status END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE menu_categories.menu_id = EXCLUDED.menu_id
+		RETURNING id
 	`
 
-	result, err := r.db.Exec(ctx, query, itemIDs, status)
+	translations, err := encodeTranslations(category.Translations)
 	if err != nil {
-		return fmt.Errorf("failed to update items status: %w", err)
+		return err
 	}
 
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("no items found")
+	if category.ID == uuid.Nil {
+		category.ID = uuid.New()
+	}
+
+	err = tx.QueryRow(ctx, query, category.ID, category.MenuID, category.Name, order, string(category.Status), translations).Scan(&category.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		category.ID = uuid.New()
+		err = tx.QueryRow(ctx, query, category.ID, category.MenuID, category.Name, order, string(category.Status), translations).Scan(&category.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save category %s: %w", category.Name, err)
 	}
 
 	return nil
 }
 
-func (r *menuRepository) RemoveModelFromMenuItems(ctx context.Context, modelID uuid.UUID) error {
+func (r *menuRepository) upsertMenuItem(ctx context.Context, tx data.QueryRunner, menuID uuid.UUID, categoryID uuid.UUID, item *models.MenuCategoryItem, order int) error {
+	// nil images and an empty status keep the stored values, unknown models and
+	// models of other clients are dropped instead of failing the whole menu
 	query := `
-		UPDATE menus
-		SET categories = (
-			SELECT jsonb_agg(
-				CASE
-					WHEN c->'menuItems' @> ANY(ARRAY[jsonb_build_object('modelId', $1::text)])
-					THEN jsonb_set(
-						c,
-						'{menuItems}',
-						(
-							SELECT jsonb_agg(
-								CASE
-									WHEN i->>'modelId' = $1::text
-									THEN jsonb_set(
-										jsonb_set(
-											i,
-											'{modelId}',
-											'null'
-										),
-										'{modelInfo}',
-										'null'
-									)
-									ELSE i
-								END
-							)
-							FROM jsonb_array_elements(c->'menuItems') i
-						)
-					)
-					ELSE c
-				END
-			)
-			FROM jsonb_array_elements(categories) c
+		INSERT INTO menu_items (id, category_id, name, description, price, variants, images, model_id, sort_order, status,
+			allergens, dietary_tags, spice_level, calories, nutrition, modifier_groups, translations)
+		VALUES (
+			$1, $2, $3, $4, $5, $6::jsonb, COALESCE($7::text[], '{}'),
+			(SELECT md.id FROM models md JOIN menus m ON m.client_id = md.client_id WHERE md.id = $8 AND m.id = $11), $9,
+			COALESCE(NULLIF($10::text, ''), 'active')::menu_status,
+			COALESCE($12::text[], '{}'), COALESCE($13::text[], '{}'), $14, $15, $16::jsonb, $17::jsonb, $18::jsonb
 		)
-		WHERE categories @> ANY(ARRAY[jsonb_build_object('menuItems', jsonb_build_array(jsonb_build_object('modelId', $1::text)))])
+		ON CONFLICT (id) DO UPDATE
+		SET category_id = EXCLUDED.category_id,
+			name = EXCLUDED.name,
+			description = EXCLUDED.description,
+			price = EXCLUDED.price,
+			variants = EXCLUDED.variants,
+			images = COALESCE($7::text[], menu_items.images),
+			model_id = EXCLUDED.model_id,
+			sort_order = EXCLUDED.sort_order,
+			status = CASE WHEN $10::text = '' THEN menu_items.status ELSE EXCLUDED.status END,
+			allergens = EXCLUDED.allergens,
+			dietary_tags = EXCLUDED.dietary_tags,
+			spice_level = EXCLUDED.spice_level,
+			calories = EXCLUDED.calories,
+			nutrition = EXCLUDED.nutrition,
+			modifier_groups = EXCLUDED.modifier_groups,
+			translations = EXCLUDED.translations,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE menu_items.category_id IN (SELECT id FROM menu_categories WHERE menu_id = $11)
+		RETURNING id
 	`
 
-	_, err := r.db.Exec(ctx, query, modelID)
+	columns, err := encodeItemColumns(item)
 	if err != nil {
-		return fmt.Errorf("failed to remove model from menu items: %w", err)
+		return err
+	}
+
+	if item.ID == uuid.Nil {
+		item.ID = uuid.New()
+	}
+
+	args := func() []interface{} {
+		return []interface{}{
+			item.ID, categoryID, item.Name, item.Description, item.Price, columns.variants,
+			item.Images, item.ModelID, order, string(item.Status), menuID,
+			columns.allergens, columns.dietaryTags, item.SpiceLevel, item.Calories, columns.nutrition, columns.modifierGroups,
+			columns.translations,
+		}
+	}
+
+	err = tx.QueryRow(ctx, query, args()...).Scan(&item.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		item.ID = uuid.New()
+		err = tx.QueryRow(ctx, query, args()...).Scan(&item.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save menu item %s: %w", item.Name, err)
 	}
 
 	return nil
 }
+
+// itemColumns are the lists and documents of an item as they are stored
+type itemColumns struct {
+	variants       []byte
+	modifierGroups []byte
+	allergens      []string
+	dietaryTags    []string
+	nutrition      []byte
+	translations   []byte
+}
+
+func encodeItemColumns(item *models.MenuCategoryItem) (*itemColumns, error) {
+	columns := &itemColumns{
+		allergens:   make([]string, 0, len(item.Allergens)),
+		dietaryTags: make([]string, 0, len(item.DietaryTags)),
+	}
+	for _, allergen := range item.Allergens {
+		columns.allergens = append(columns.allergens, string(allergen))
+	}
+	for _, tag := range item.DietaryTags {
+		columns.dietaryTags = append(columns.dietaryTags, string(tag))
+	}
+
+	var err error
+	if len(item.Variants) > 0 {
+		if columns.variants, err = json.Marshal(item.Variants); err != nil {
+			return nil, fmt.Errorf("failed to encode variants: %w", err)
+		}
+	}
+	if len(item.ModifierGroups) > 0 {
+		if columns.modifierGroups, err = json.Marshal(item.ModifierGroups); err != nil {
+			return nil, fmt.Errorf("failed to encode modifier groups: %w", err)
+		}
+	}
+	if item.Nutrition != nil {
+		if columns.nutrition, err = json.Marshal(item.Nutrition); err != nil {
+			return nil, fmt.Errorf("failed to encode nutrition: %w", err)
+		}
+	}
+	if columns.translations, err = encodeTranslations(item.Translations); err != nil {
+		return nil, err
+	}
+
+	return columns, nil
+}
+
+func decodeItemColumns(item *models.MenuCategoryItem, variantsJSON, modifierGroupsJSON []byte, allergens, dietaryTags []string, nutritionJSON, translationsJSON []byte) error {
+	item.Allergens = make([]models.Allergen, 0, len(allergens))
+	for _, allergen := range allergens {
+		item.Allergens = append(item.Allergens, models.Allergen(allergen))
+	}
+	item.DietaryTags = make([]models.DietaryTag, 0, len(dietaryTags))
+	for _, tag := range dietaryTags {
+		item.DietaryTags = append(item.DietaryTags, models.DietaryTag(tag))
+	}
+
+	if variantsJSON != nil {
+		if err := json.Unmarshal(variantsJSON, &item.Variants); err != nil {
+			return fmt.Errorf("failed to parse variants: %w", err)
+		}
+	}
+	if modifierGroupsJSON != nil {
+		if err := json.Unmarshal(modifierGroupsJSON, &item.ModifierGroups); err != nil {
+			return fmt.Errorf("failed to parse modifier groups: %w", err)
+		}
+	}
+	if nutritionJSON != nil {
+		if err := json.Unmarshal(nutritionJSON, &item.Nutrition); err != nil {
+			return fmt.Errorf("failed to parse nutrition: %w", err)
+		}
+	}
+
+	var err error
+	item.Translations, err = decodeTranslations(translationsJSON)
+	return err
+}
+
+// encodeTranslations stores menus, categories and items without translations
+// as NULL
+func encodeTranslations(translations models.Translations) ([]byte, error) {
+	if len(translations) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(translations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode translations: %w", err)
+	}
+
+	return data, nil
+}
+
+func decodeTranslations(data []byte) (models.Translations, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	var translations models.Translations
+	if err := json.Unmarshal(data, &translations); err != nil {
+		return nil, fmt.Errorf("failed to parse translations: %w", err)
+	}
+
+	return translations, nil
+}
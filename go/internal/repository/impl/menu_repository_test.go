@@ -0,0 +1,119 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/data"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/golang-migrate/migrate"
+	_ "github.com/golang-migrate/migrate/database/postgres"
+	_ "github.com/golang-migrate/migrate/source/file"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// newTestDb connects to the database in TEST_DATABASE_URL and migrates it. The
+// tests touching sql are skipped without one.
+func newTestDb(t *testing.T) *data.PgDbContext {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	m, err := migrate.New("file://../../../migrations", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		t.Fatal(err)
+	}
+
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	return &data.PgDbContext{Pool: pool}
+}
+
+func createTestClient(t *testing.T, db *data.PgDbContext) uuid.UUID {
+	t.Helper()
+
+	id := uuid.New()
+	_, err := db.Exec(context.Background(), `INSERT INTO clients (id, name, email) VALUES ($1, $2, $3)`, id, "Test", id.String()+"@test.local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx := context.Background()
+		db.Exec(ctx, `DELETE FROM menus WHERE client_id = $1`, id)
+		db.Exec(ctx, `DELETE FROM clients WHERE id = $1`, id)
+	})
+
+	return id
+}
+
+func TestMenuRepositoryTenantScoping(t *testing.T) {
+	db := newTestDb(t)
+	repo := NewMenuRepository(db)
+	ctx := context.Background()
+
+	owner := models.Tenant{ClientID: createTestClient(t, db)}
+	other := models.Tenant{ClientID: createTestClient(t, db)}
+	admin := models.Tenant{ClientID: other.ClientID, IsAdmin: true}
+
+	menuID, err := repo.CreateMenu(ctx, &models.Menu{ClientID: owner.ClientID, Label: "Dinner", Status: string(models.MenuStatusActive)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("update", func(t *testing.T) {
+		if err := repo.UpdateMenuQRCode(ctx, menuID, "other.png", other); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("expected another client to get ErrNotFound, got %v", err)
+		}
+		if err := repo.UpdateMenuQRCode(ctx, menuID, "owner.png", owner); err != nil {
+			t.Errorf("expected the owner to update the menu, got %v", err)
+		}
+		if err := repo.UpdateMenuQRCode(ctx, menuID, "admin.png", admin); err != nil {
+			t.Errorf("expected an admin to update the menu, got %v", err)
+		}
+
+		menu, err := repo.GetMenuById(ctx, menuID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if menu.QRCode != "admin.png" {
+			t.Errorf("expected the admin's update, got %q", menu.QRCode)
+		}
+	})
+
+	t.Run("create category", func(t *testing.T) {
+		category := &models.MenuCategory{MenuID: menuID, Name: "Mains"}
+		if err := repo.CreateCategory(ctx, category, other); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("expected another client to get ErrNotFound, got %v", err)
+		}
+
+		category.ID = uuid.Nil
+		if err := repo.CreateCategory(ctx, category, owner); err != nil {
+			t.Errorf("expected the owner to add a category, got %v", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := repo.DeleteMenu(ctx, menuID, other); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("expected another client to get ErrNotFound, got %v", err)
+		}
+		if _, err := repo.GetMenuById(ctx, menuID); err != nil {
+			t.Errorf("expected the menu to survive another client's delete, got %v", err)
+		}
+		if err := repo.DeleteMenu(ctx, menuID, owner); err != nil {
+			t.Errorf("expected the owner to delete the menu, got %v", err)
+		}
+	})
+}
@@ -2,14 +2,20 @@ package impl
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/ahmetkoprulu/bidi-menu/common/data"
 	"github.com/ahmetkoprulu/bidi-menu/internal/models"
 	"github.com/ahmetkoprulu/bidi-menu/internal/repository"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
+// modelColumns are read by scanModel
+const modelColumns = "id, client_id, name, thumbnail, glb_file, usdz_file, metadata, optimized_glb_file, optimization, thumbnails, usdz_conversion"
+
 type modelRepository struct {
 	db *data.PgDbContext
 }
@@ -20,8 +26,8 @@ func NewModelRepository(db *data.PgDbContext) repository.ModelRepository {
 
 func (r *modelRepository) CreateModel(ctx context.Context, model *models.Model) (*uuid.UUID, error) {
 	query := `
-		INSERT INTO models (id, client_id, name, thumbnail, glb_file, usdz_file)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO models (id, client_id, name, thumbnail, glb_file, usdz_file, metadata, optimized_glb_file, optimization, thumbnails, usdz_conversion)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id
 	`
 
@@ -30,7 +36,24 @@ func (r *modelRepository) CreateModel(ctx context.Context, model *models.Model)
 		model.ID = &id
 	}
 
-	err := r.db.QueryRow(ctx, query, model.ID, model.ClientID, model.Name, model.Thumbnail, model.GlbFile, model.UsdzFile).Scan(&model.ID)
+	metadata, err := encodeModelJSON(model.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	optimization, err := encodeModelJSON(model.Optimization)
+	if err != nil {
+		return nil, err
+	}
+	thumbnails, err := encodeModelThumbnails(model.Thumbnails)
+	if err != nil {
+		return nil, err
+	}
+	usdzConversion, err := encodeModelJSON(model.UsdzConversion)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.QueryRow(ctx, query, model.ID, model.ClientID, model.Name, model.Thumbnail, model.GlbFile, model.UsdzFile, metadata, model.OptimizedGlbFile, optimization, thumbnails, usdzConversion).Scan(&model.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create model: %w", err)
 	}
@@ -38,30 +61,50 @@ func (r *modelRepository) CreateModel(ctx context.Context, model *models.Model)
 	return model.ID, nil
 }
 
-func (r *modelRepository) UpdateModel(ctx context.Context, model *models.Model) error {
+func (r *modelRepository) UpdateModel(ctx context.Context, model *models.Model, tenant models.Tenant) error {
 	query := `
 		UPDATE models
-		SET name = $2, thumbnail = $3, glb_file = $4, usdz_file = $5
-		WHERE id = $1
+		SET name = $2, thumbnail = $3, glb_file = $4, usdz_file = $5, metadata = $6, optimized_glb_file = $7, optimization = $8, thumbnails = $9, usdz_conversion = $10
+		WHERE id = $1 AND ($11 OR client_id = $12)
 	`
 
-	_, err := r.db.Exec(ctx, query, model.ID, model.Name, model.Thumbnail, model.GlbFile, model.UsdzFile)
+	metadata, err := encodeModelJSON(model.Metadata)
+	if err != nil {
+		return err
+	}
+	optimization, err := encodeModelJSON(model.Optimization)
+	if err != nil {
+		return err
+	}
+	thumbnails, err := encodeModelThumbnails(model.Thumbnails)
+	if err != nil {
+		return err
+	}
+	usdzConversion, err := encodeModelJSON(model.UsdzConversion)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Exec(ctx, query, model.ID, model.Name, model.Thumbnail, model.GlbFile, model.UsdzFile, metadata, model.OptimizedGlbFile, optimization, thumbnails, usdzConversion, tenant.IsAdmin, tenant.ClientID)
 	if err != nil {
 		return fmt.Errorf("failed to update model: %w", err)
 	}
 
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
 	return nil
 }
 
 func (r *modelRepository) GetModel(ctx context.Context, modelID uuid.UUID) (models.Model, error) {
 	query := `
-		SELECT id, client_id, name, thumbnail, glb_file, usdz_file
+		SELECT ` + modelColumns + `
 		FROM models
 		WHERE id = $1
 	`
 
-	var model models.Model
-	err := r.db.QueryRow(ctx, query, modelID).Scan(&model.ID, &model.ClientID, &model.Name, &model.Thumbnail, &model.GlbFile, &model.UsdzFile)
+	model, err := scanModel(r.db.QueryRow(ctx, query, modelID))
 	if err != nil {
 		return models.Model{}, fmt.Errorf("failed to get model: %w", err)
 	}
@@ -72,7 +115,7 @@ func (r *modelRepository) GetModel(ctx context.Context, modelID uuid.UUID) (mode
 func (r *modelRepository) GetModels(ctx context.Context, clientID uuid.UUID) ([]models.Model, error) {
 	var ms []models.Model = make([]models.Model, 0)
 	query := `
-		SELECT id, client_id, name, thumbnail, glb_file, usdz_file
+		SELECT ` + modelColumns + `
 		FROM models
 		WHERE client_id = $1
 	`
@@ -84,8 +127,7 @@ func (r *modelRepository) GetModels(ctx context.Context, clientID uuid.UUID) ([]
 	defer rows.Close()
 
 	for rows.Next() {
-		var model models.Model
-		err := rows.Scan(&model.ID, &model.ClientID, &model.Name, &model.Thumbnail, &model.GlbFile, &model.UsdzFile)
+		model, err := scanModel(rows)
 		if err != nil {
 			return []models.Model{}, fmt.Errorf("failed to scan model: %w", err)
 		}
@@ -95,32 +137,148 @@ func (r *modelRepository) GetModels(ctx context.Context, clientID uuid.UUID) ([]
 	return ms, nil
 }
 
-func (r *modelRepository) DeleteModel(ctx context.Context, modelID uuid.UUID) error {
+func (r *modelRepository) DeleteModel(ctx context.Context, modelID uuid.UUID, tenant models.Tenant) error {
 	query := `
 		DELETE FROM models
-		WHERE id = $1
+		WHERE id = $1 AND ($2 OR client_id = $3)
 	`
 
-	_, err := r.db.Exec(ctx, query, modelID)
+	result, err := r.db.Exec(ctx, query, modelID, tenant.IsAdmin, tenant.ClientID)
 	if err != nil {
 		return fmt.Errorf("failed to delete model: %w", err)
 	}
 
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
 	return nil
 }
 
-func (r *modelRepository) GetModelById(ctx context.Context, modelID uuid.UUID) (models.Model, error) {
+func (r *modelRepository) GetModelById(ctx context.Context, modelID uuid.UUID, tenant models.Tenant) (models.Model, error) {
 	query := `
-		SELECT id, client_id, name, thumbnail, glb_file, usdz_file
+		SELECT ` + modelColumns + `
 		FROM models
+		WHERE id = $1 AND ($2 OR client_id = $3)
+	`
+
+	model, err := scanModel(r.db.QueryRow(ctx, query, modelID, tenant.IsAdmin, tenant.ClientID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Model{}, models.ErrNotFound
+	}
+	if err != nil {
+		return models.Model{}, fmt.Errorf("failed to get model: %w", err)
+	}
+
+	return model, nil
+}
+
+// UpdateModelOptimization records the state of the optimization, the stored
+// glb file is kept as it is
+func (r *modelRepository) UpdateModelOptimization(ctx context.Context, modelID uuid.UUID, optimizedGlbFile string, optimization *models.ModelOptimization) error {
+	query := `
+		UPDATE models
+		SET optimized_glb_file = $2, optimization = $3
 		WHERE id = $1
 	`
 
+	data, err := encodeModelJSON(optimization)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Exec(ctx, query, modelID, optimizedGlbFile, data)
+	if err != nil {
+		return fmt.Errorf("failed to update model optimization: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+// UpdateModelUsdzConversion records the state of the usdz conversion and the
+// generated file once it is stored
+func (r *modelRepository) UpdateModelUsdzConversion(ctx context.Context, modelID uuid.UUID, usdzFile string, conversion *models.ModelConversion) error {
+	query := `
+		UPDATE models
+		SET usdz_file = $2, usdz_conversion = $3
+		WHERE id = $1
+	`
+
+	data, err := encodeModelJSON(conversion)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Exec(ctx, query, modelID, usdzFile, data)
+	if err != nil {
+		return fmt.Errorf("failed to update model usdz conversion: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanModel(row pgx.Row) (models.Model, error) {
 	var model models.Model
-	err := r.db.QueryRow(ctx, query, modelID).Scan(&model.ID, &model.ClientID, &model.Name, &model.Thumbnail, &model.GlbFile, &model.UsdzFile)
+	var metadata, optimization, thumbnails, usdzConversion []byte
+	err := row.Scan(&model.ID, &model.ClientID, &model.Name, &model.Thumbnail, &model.GlbFile, &model.UsdzFile, &metadata, &model.OptimizedGlbFile, &optimization, &thumbnails, &usdzConversion)
 	if err != nil {
-		return models.Model{}, fmt.Errorf("failed to get model: %w", err)
+		return models.Model{}, err
+	}
+
+	if metadata != nil {
+		model.Metadata = &models.ModelMetadata{}
+		if err := json.Unmarshal(metadata, model.Metadata); err != nil {
+			return models.Model{}, fmt.Errorf("failed to parse model metadata: %w", err)
+		}
+	}
+	if optimization != nil {
+		model.Optimization = &models.ModelOptimization{}
+		if err := json.Unmarshal(optimization, model.Optimization); err != nil {
+			return models.Model{}, fmt.Errorf("failed to parse model optimization: %w", err)
+		}
+	}
+	if thumbnails != nil {
+		if err := json.Unmarshal(thumbnails, &model.Thumbnails); err != nil {
+			return models.Model{}, fmt.Errorf("failed to parse model thumbnails: %w", err)
+		}
+	}
+	if usdzConversion != nil {
+		model.UsdzConversion = &models.ModelConversion{}
+		if err := json.Unmarshal(usdzConversion, model.UsdzConversion); err != nil {
+			return models.Model{}, fmt.Errorf("failed to parse model usdz conversion: %w", err)
+		}
 	}
 
 	return model, nil
 }
+
+// encodeModelJSON stores missing values as NULL, models uploaded before the
+// metadata was read have none
+func encodeModelJSON[T any](value *T) ([]byte, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode model: %w", err)
+	}
+
+	return data, nil
+}
+
+// encodeModelThumbnails stores NULL for models with an uploaded thumbnail
+func encodeModelThumbnails(thumbnails []*models.ModelThumbnail) ([]byte, error) {
+	if len(thumbnails) == 0 {
+		return nil, nil
+	}
+	return encodeModelJSON(&thumbnails)
+}
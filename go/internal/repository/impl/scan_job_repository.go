@@ -0,0 +1,123 @@
+package impl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/data"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type scanJobRepository struct {
+	db *data.PgDbContext
+}
+
+func NewScanJobRepository(db *data.PgDbContext) repository.ScanJobRepository {
+	return &scanJobRepository{db: db}
+}
+
+func (r *scanJobRepository) CreateScanJob(ctx context.Context, job *models.ScanJob) error {
+	query := `
+		INSERT INTO scan_jobs (id, client_id, status, total_pages, processed_pages)
+		VALUES ($1, $2, $3, $4, 0)
+		RETURNING created_at, updated_at
+	`
+
+	if job.ID == uuid.Nil {
+		job.ID = uuid.New()
+	}
+
+	err := r.db.QueryRow(ctx, query, job.ID, job.ClientID, job.Status, job.TotalPages).Scan(&job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create scan job: %w", err)
+	}
+
+	return nil
+}
+
+func (r *scanJobRepository) GetScanJob(ctx context.Context, id uuid.UUID, clientID uuid.UUID) (*models.ScanJob, error) {
+	query := `
+		SELECT id, client_id, status, total_pages, processed_pages, result, error,
+			created_at, updated_at, completed_at
+		FROM scan_jobs
+		WHERE id = $1 AND client_id = $2
+	`
+
+	var job models.ScanJob
+	var resultJSON []byte
+	err := r.db.QueryRow(ctx, query, id, clientID).Scan(
+		&job.ID, &job.ClientID, &job.Status, &job.TotalPages, &job.ProcessedPages,
+		&resultJSON, &job.Error, &job.CreatedAt, &job.UpdatedAt, &job.CompletedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scan job: %w", err)
+	}
+
+	if resultJSON != nil {
+		if err := json.Unmarshal(resultJSON, &job.Result); err != nil {
+			return nil, fmt.Errorf("failed to parse scan result: %w", err)
+		}
+	}
+
+	job.CalculateProgress()
+	return &job, nil
+}
+
+func (r *scanJobRepository) UpdateScanJobStatus(ctx context.Context, id uuid.UUID, status models.ScanJobStatus, errorMessage *string) error {
+	query := `
+		UPDATE scan_jobs
+		SET status = $2, error = $3, updated_at = CURRENT_TIMESTAMP,
+			completed_at = CASE WHEN $2 IN ('completed', 'failed') THEN CURRENT_TIMESTAMP ELSE completed_at END
+		WHERE id = $1
+	`
+
+	result, err := r.db.Exec(ctx, query, id, status, errorMessage)
+	if err != nil {
+		return fmt.Errorf("failed to update scan job status: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("scan job not found")
+	}
+
+	return nil
+}
+
+func (r *scanJobRepository) UpdateScanJobProgress(ctx context.Context, id uuid.UUID, processedPages int, totalPages int) error {
+	query := `
+		UPDATE scan_jobs
+		SET processed_pages = $2, total_pages = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query, id, processedPages, totalPages)
+	if err != nil {
+		return fmt.Errorf("failed to update scan job progress: %w", err)
+	}
+
+	return nil
+}
+
+func (r *scanJobRepository) CompleteScanJob(ctx context.Context, id uuid.UUID, result *models.ScanResult) error {
+	query := `
+		UPDATE scan_jobs
+		SET status = 'completed', result = $2, processed_pages = total_pages,
+			error = NULL, updated_at = CURRENT_TIMESTAMP, completed_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query, id, result)
+	if err != nil {
+		return fmt.Errorf("failed to complete scan job: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/data"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// uniqueViolation is the postgres error code of a unique constraint violation
+const uniqueViolation = "23505"
+
+const shortLinkColumns = `id, client_id, code, label, menu_id, COALESCE(timezone, ''), created_at, updated_at`
+
+type shortLinkRepository struct {
+	db *data.PgDbContext
+}
+
+func NewShortLinkRepository(db *data.PgDbContext) repository.ShortLinkRepository {
+	return &shortLinkRepository{db: db}
+}
+
+func (r *shortLinkRepository) CreateShortLink(ctx context.Context, link *models.ShortLink) error {
+	query := `
+		INSERT INTO short_links (id, client_id, code, label, menu_id, timezone)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+		RETURNING created_at, updated_at
+	`
+
+	if link.ID == uuid.Nil {
+		link.ID = uuid.New()
+	}
+
+	err := r.db.WithTransaction(ctx, func(tx data.QueryRunner) error {
+		err := tx.QueryRow(ctx, query, link.ID, link.ClientID, link.Code, link.Label, link.MenuID, link.Timezone).Scan(&link.CreatedAt, &link.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		return r.saveSchedules(ctx, tx, link)
+	})
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == "short_links_code_key" {
+		return models.ErrShortCodeTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create short link: %w", err)
+	}
+
+	return nil
+}
+
+func (r *shortLinkRepository) GetShortLinks(ctx context.Context, clientID uuid.UUID) ([]*models.ShortLink, error) {
+	query := `SELECT ` + shortLinkColumns + ` FROM short_links WHERE client_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.Query(ctx, query, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get short links: %w", err)
+	}
+	defer rows.Close()
+
+	links := make([]*models.ShortLink, 0)
+	for rows.Next() {
+		link, err := scanShortLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan short link: %w", err)
+		}
+		links = append(links, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get short links: %w", err)
+	}
+
+	if err := r.loadSchedules(ctx, links); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+func (r *shortLinkRepository) GetShortLink(ctx context.Context, id uuid.UUID, tenant models.Tenant) (*models.ShortLink, error) {
+	query := `SELECT ` + shortLinkColumns + ` FROM short_links WHERE id = $1 AND ($2 OR client_id = $3)`
+
+	return r.getShortLink(ctx, query, id, tenant.IsAdmin, tenant.ClientID)
+}
+
+// GetShortLinkByCode finds the link of a scanned code, it is not scoped to a
+// client as guests scan them
+func (r *shortLinkRepository) GetShortLinkByCode(ctx context.Context, code string) (*models.ShortLink, error) {
+	query := `SELECT ` + shortLinkColumns + ` FROM short_links WHERE code = $1`
+
+	return r.getShortLink(ctx, query, code)
+}
+
+// GetMenuShortLink returns the oldest link that falls back to the menu, the
+// one its QR code prints
+func (r *shortLinkRepository) GetMenuShortLink(ctx context.Context, menuID uuid.UUID) (*models.ShortLink, error) {
+	query := `SELECT ` + shortLinkColumns + ` FROM short_links WHERE menu_id = $1 ORDER BY created_at LIMIT 1`
+
+	return r.getShortLink(ctx, query, menuID)
+}
+
+// UpdateShortLink re-points the link and replaces its schedules, the code stays
+func (r *shortLinkRepository) UpdateShortLink(ctx context.Context, link *models.ShortLink, tenant models.Tenant) error {
+	query := `
+		UPDATE short_links
+		SET label = $2, menu_id = $3, timezone = NULLIF($4, ''), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND ($5 OR client_id = $6)
+		RETURNING client_id, code, created_at, updated_at
+	`
+
+	err := r.db.WithTransaction(ctx, func(tx data.QueryRunner) error {
+		err := tx.QueryRow(ctx, query, link.ID, link.Label, link.MenuID, link.Timezone, tenant.IsAdmin, tenant.ClientID).Scan(
+			&link.ClientID, &link.Code, &link.CreatedAt, &link.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM short_link_schedules WHERE short_link_id = $1`, link.ID); err != nil {
+			return err
+		}
+
+		return r.saveSchedules(ctx, tx, link)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update short link: %w", err)
+	}
+
+	return nil
+}
+
+func (r *shortLinkRepository) DeleteShortLink(ctx context.Context, id uuid.UUID, tenant models.Tenant) error {
+	query := `DELETE FROM short_links WHERE id = $1 AND ($2 OR client_id = $3)`
+
+	result, err := r.db.Exec(ctx, query, id, tenant.IsAdmin, tenant.ClientID)
+	if err != nil {
+		return fmt.Errorf("failed to delete short link: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *shortLinkRepository) getShortLink(ctx context.Context, query string, args ...interface{}) (*models.ShortLink, error) {
+	link, err := scanShortLink(r.db.QueryRow(ctx, query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get short link: %w", err)
+	}
+
+	if err := r.loadSchedules(ctx, []*models.ShortLink{link}); err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+func (r *shortLinkRepository) saveSchedules(ctx context.Context, tx data.QueryRunner, link *models.ShortLink) error {
+	query := `
+		INSERT INTO short_link_schedules (id, short_link_id, menu_id, days, start_time, end_time, sort_order)
+		VALUES ($1, $2, $3, $4, $5::time, $6::time, $7)
+	`
+
+	for i, schedule := range link.Schedules {
+		if schedule.ID == uuid.Nil {
+			schedule.ID = uuid.New()
+		}
+
+		_, err := tx.Exec(ctx, query, schedule.ID, link.ID, schedule.MenuID, schedule.Days, schedule.StartTime, schedule.EndTime, i)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *shortLinkRepository) loadSchedules(ctx context.Context, links []*models.ShortLink) error {
+	if len(links) == 0 {
+		return nil
+	}
+
+	query := `
+		SELECT id, short_link_id, menu_id, days, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI')
+		FROM short_link_schedules
+		WHERE short_link_id = ANY($1)
+		ORDER BY sort_order
+	`
+
+	byID := make(map[uuid.UUID]*models.ShortLink, len(links))
+	ids := make([]uuid.UUID, 0, len(links))
+	for _, link := range links {
+		link.Schedules = make([]*models.ShortLinkSchedule, 0)
+		byID[link.ID] = link
+		ids = append(ids, link.ID)
+	}
+
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get short link schedules: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var schedule models.ShortLinkSchedule
+		var linkID uuid.UUID
+		if err := rows.Scan(&schedule.ID, &linkID, &schedule.MenuID, &schedule.Days, &schedule.StartTime, &schedule.EndTime); err != nil {
+			return fmt.Errorf("failed to scan short link schedule: %w", err)
+		}
+		byID[linkID].Schedules = append(byID[linkID].Schedules, &schedule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get short link schedules: %w", err)
+	}
+
+	return nil
+}
+
+func scanShortLink(row pgx.Row) (*models.ShortLink, error) {
+	var link models.ShortLink
+	err := row.Scan(&link.ID, &link.ClientID, &link.Code, &link.Label, &link.MenuID, &link.Timezone, &link.CreatedAt, &link.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
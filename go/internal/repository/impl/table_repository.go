@@ -0,0 +1,201 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/data"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type tableRepository struct {
+	db *data.PgDbContext
+}
+
+func NewTableRepository(db *data.PgDbContext) repository.TableRepository {
+	return &tableRepository{db: db}
+}
+
+// CreateTables adds the tables after the ones the menu already has
+func (r *tableRepository) CreateTables(ctx context.Context, menuID uuid.UUID, tables []*models.DiningTable, tenant models.Tenant) error {
+	ownerQuery := `
+		SELECT EXISTS (SELECT 1 FROM menus WHERE id = $1 AND ($2 OR client_id = $3))
+	`
+	orderQuery := `
+		SELECT COALESCE(MAX(sort_order) + 1, 0) FROM dining_tables WHERE menu_id = $1
+	`
+	insertQuery := `
+		INSERT INTO dining_tables (id, menu_id, label, token, sort_order)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.WithTransaction(ctx, func(tx data.QueryRunner) error {
+		var owned bool
+		if err := tx.QueryRow(ctx, ownerQuery, menuID, tenant.IsAdmin, tenant.ClientID).Scan(&owned); err != nil {
+			return err
+		}
+		if !owned {
+			return models.ErrNotFound
+		}
+
+		var order int
+		if err := tx.QueryRow(ctx, orderQuery, menuID).Scan(&order); err != nil {
+			return err
+		}
+
+		for i, table := range tables {
+			if table.ID == uuid.Nil {
+				table.ID = uuid.New()
+			}
+			table.MenuID = menuID
+
+			err := tx.QueryRow(ctx, insertQuery, table.ID, menuID, table.Label, table.Token, order+i).Scan(&table.CreatedAt, &table.UpdatedAt)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if errors.Is(err, models.ErrNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create tables: %w", err)
+	}
+
+	return nil
+}
+
+func (r *tableRepository) GetTables(ctx context.Context, menuID uuid.UUID, tenant models.Tenant) ([]*models.DiningTable, error) {
+	query := `
+		SELECT t.id, t.menu_id, t.label, t.token, COALESCE(t.qr_code, ''), t.created_at, t.updated_at
+		FROM dining_tables t
+		JOIN menus m ON m.id = t.menu_id
+		WHERE t.menu_id = $1 AND ($2 OR m.client_id = $3)
+		ORDER BY t.sort_order, t.created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, menuID, tenant.IsAdmin, tenant.ClientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tables: %w", err)
+	}
+	defer rows.Close()
+
+	tables := make([]*models.DiningTable, 0)
+	for rows.Next() {
+		var table models.DiningTable
+		if err := rows.Scan(&table.ID, &table.MenuID, &table.Label, &table.Token, &table.QRCode, &table.CreatedAt, &table.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		tables = append(tables, &table)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get tables: %w", err)
+	}
+
+	return tables, nil
+}
+
+func (r *tableRepository) GetTable(ctx context.Context, tableID uuid.UUID, tenant models.Tenant) (*models.DiningTable, error) {
+	query := `
+		SELECT t.id, t.menu_id, t.label, t.token, COALESCE(t.qr_code, ''), t.created_at, t.updated_at
+		FROM dining_tables t
+		JOIN menus m ON m.id = t.menu_id
+		WHERE t.id = $1 AND ($2 OR m.client_id = $3)
+	`
+
+	return r.getTable(ctx, query, tableID, tenant.IsAdmin, tenant.ClientID)
+}
+
+// GetTableByToken finds the table of a scanned QR code, it is not scoped to a
+// client as guests scan them
+func (r *tableRepository) GetTableByToken(ctx context.Context, token string) (*models.DiningTable, error) {
+	query := `
+		SELECT id, menu_id, label, token, COALESCE(qr_code, ''), created_at, updated_at
+		FROM dining_tables
+		WHERE token = $1
+	`
+
+	return r.getTable(ctx, query, token)
+}
+
+func (r *tableRepository) UpdateTable(ctx context.Context, table *models.DiningTable, tenant models.Tenant) error {
+	query := `
+		UPDATE dining_tables t
+		SET label = $2, updated_at = CURRENT_TIMESTAMP
+		FROM menus m
+		WHERE t.id = $1 AND m.id = t.menu_id AND ($3 OR m.client_id = $4)
+		RETURNING t.menu_id, t.token, COALESCE(t.qr_code, ''), t.created_at, t.updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query, table.ID, table.Label, tenant.IsAdmin, tenant.ClientID).Scan(
+		&table.MenuID, &table.Token, &table.QRCode, &table.CreatedAt, &table.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update table: %w", err)
+	}
+
+	return nil
+}
+
+func (r *tableRepository) UpdateTableQRCode(ctx context.Context, tableID uuid.UUID, qrCode string, tenant models.Tenant) error {
+	query := `
+		UPDATE dining_tables t
+		SET qr_code = $2
+		FROM menus m
+		WHERE t.id = $1 AND m.id = t.menu_id AND ($3 OR m.client_id = $4)
+	`
+
+	result, err := r.db.Exec(ctx, query, tableID, qrCode, tenant.IsAdmin, tenant.ClientID)
+	if err != nil {
+		return fmt.Errorf("failed to update table qr code: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *tableRepository) DeleteTable(ctx context.Context, tableID uuid.UUID, tenant models.Tenant) error {
+	query := `
+		DELETE FROM dining_tables t
+		USING menus m
+		WHERE t.id = $1 AND m.id = t.menu_id AND ($2 OR m.client_id = $3)
+	`
+
+	result, err := r.db.Exec(ctx, query, tableID, tenant.IsAdmin, tenant.ClientID)
+	if err != nil {
+		return fmt.Errorf("failed to delete table: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *tableRepository) getTable(ctx context.Context, query string, args ...interface{}) (*models.DiningTable, error) {
+	var table models.DiningTable
+	err := r.db.QueryRow(ctx, query, args...).Scan(&table.ID, &table.MenuID, &table.Label, &table.Token, &table.QRCode, &table.CreatedAt, &table.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table: %w", err)
+	}
+
+	return &table, nil
+}
@@ -9,17 +9,26 @@ import (
 
 type MenuRepository interface {
 	CreateMenu(ctx context.Context, menu *models.Menu) (uuid.UUID, error)
-	UpdateMenu(ctx context.Context, menu *models.Menu) error
+	UpdateMenu(ctx context.Context, menu *models.Menu, tenant models.Tenant) error
 	GetMenuWithCategories(ctx context.Context, clientID uuid.UUID) ([]*models.MenuCategory, error)
 	GetMenuById(ctx context.Context, id uuid.UUID) (*models.Menu, error)
-	DeleteMenu(ctx context.Context, id uuid.UUID) error
-	CreateCategory(ctx context.Context, category *models.MenuCategory) error
-	UpdateCategoryOrder(ctx context.Context, categoryID uuid.UUID, order int) error
-	DeleteCategory(ctx context.Context, categoryID uuid.UUID) error
-	DeleteMenuItem(ctx context.Context, itemID uuid.UUID) error
-	UpdateItemImages(ctx context.Context, itemID uuid.UUID, images []string) error
-	ReorderCategories(ctx context.Context, categoryOrders map[uuid.UUID]int) error
-	UpdateCategoryStatus(ctx context.Context, categoryID uuid.UUID, status models.MenuStatus) error
-	UpdateItemsStatus(ctx context.Context, itemIDs []uuid.UUID, status models.MenuStatus) error
+	DeleteMenu(ctx context.Context, id uuid.UUID, tenant models.Tenant) error
+	UpdateMenuQRCode(ctx context.Context, id uuid.UUID, qrCode string, tenant models.Tenant) error
+	CreateCategory(ctx context.Context, category *models.MenuCategory, tenant models.Tenant) error
+	UpdateCategory(ctx context.Context, category *models.MenuCategory, tenant models.Tenant) error
+	UpdateCategoryOrder(ctx context.Context, categoryID uuid.UUID, order int, tenant models.Tenant) error
+	DeleteCategory(ctx context.Context, categoryID uuid.UUID, tenant models.Tenant) error
+	CreateMenuItem(ctx context.Context, categoryID uuid.UUID, item *models.MenuCategoryItem, tenant models.Tenant) error
+	UpdateMenuItem(ctx context.Context, item *models.MenuCategoryItem, tenant models.Tenant) error
+	MoveMenuItem(ctx context.Context, itemID uuid.UUID, categoryID uuid.UUID, order int, tenant models.Tenant) error
+	DeleteMenuItem(ctx context.Context, itemID uuid.UUID, tenant models.Tenant) error
+	UpdateItemImages(ctx context.Context, itemID uuid.UUID, images []string, tenant models.Tenant) error
+	ReorderCategories(ctx context.Context, categoryOrders map[uuid.UUID]int, tenant models.Tenant) error
+	UpdateCategoryStatus(ctx context.Context, categoryID uuid.UUID, status models.MenuStatus, tenant models.Tenant) error
+	UpdateItemsStatus(ctx context.Context, itemIDs []uuid.UUID, status models.MenuStatus, tenant models.Tenant) error
 	RemoveModelFromMenuItems(ctx context.Context, modelID uuid.UUID) error
+	CreateMenuVersion(ctx context.Context, version *models.MenuVersion, tenant models.Tenant) error
+	GetMenuVersions(ctx context.Context, menuID uuid.UUID, tenant models.Tenant) ([]*models.MenuVersion, error)
+	GetMenuVersion(ctx context.Context, menuID uuid.UUID, version int, tenant models.Tenant) (*models.MenuVersion, error)
+	GetPublishedMenu(ctx context.Context, menuID uuid.UUID) (*models.Menu, error)
 }
@@ -9,9 +9,11 @@ import (
 
 type ModelRepository interface {
 	CreateModel(ctx context.Context, model *models.Model) (*uuid.UUID, error)
-	UpdateModel(ctx context.Context, model *models.Model) error
+	UpdateModel(ctx context.Context, model *models.Model, tenant models.Tenant) error
 	GetModel(ctx context.Context, modelID uuid.UUID) (models.Model, error)
 	GetModels(ctx context.Context, clientID uuid.UUID) ([]models.Model, error)
-	DeleteModel(ctx context.Context, modelID uuid.UUID) error
-	GetModelById(ctx context.Context, modelID uuid.UUID) (models.Model, error)
+	DeleteModel(ctx context.Context, modelID uuid.UUID, tenant models.Tenant) error
+	GetModelById(ctx context.Context, modelID uuid.UUID, tenant models.Tenant) (models.Model, error)
+	UpdateModelOptimization(ctx context.Context, modelID uuid.UUID, optimizedGlbFile string, optimization *models.ModelOptimization) error
+	UpdateModelUsdzConversion(ctx context.Context, modelID uuid.UUID, usdzFile string, conversion *models.ModelConversion) error
 }
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/google/uuid"
+)
+
+type ScanJobRepository interface {
+	CreateScanJob(ctx context.Context, job *models.ScanJob) error
+	GetScanJob(ctx context.Context, id uuid.UUID, clientID uuid.UUID) (*models.ScanJob, error)
+	UpdateScanJobStatus(ctx context.Context, id uuid.UUID, status models.ScanJobStatus, errorMessage *string) error
+	UpdateScanJobProgress(ctx context.Context, id uuid.UUID, processedPages int, totalPages int) error
+	CompleteScanJob(ctx context.Context, id uuid.UUID, result *models.ScanResult) error
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/google/uuid"
+)
+
+type ShortLinkRepository interface {
+	CreateShortLink(ctx context.Context, link *models.ShortLink) error
+	GetShortLinks(ctx context.Context, clientID uuid.UUID) ([]*models.ShortLink, error)
+	GetShortLink(ctx context.Context, id uuid.UUID, tenant models.Tenant) (*models.ShortLink, error)
+	GetShortLinkByCode(ctx context.Context, code string) (*models.ShortLink, error)
+	GetMenuShortLink(ctx context.Context, menuID uuid.UUID) (*models.ShortLink, error)
+	UpdateShortLink(ctx context.Context, link *models.ShortLink, tenant models.Tenant) error
+	DeleteShortLink(ctx context.Context, id uuid.UUID, tenant models.Tenant) error
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/google/uuid"
+)
+
+type TableRepository interface {
+	CreateTables(ctx context.Context, menuID uuid.UUID, tables []*models.DiningTable, tenant models.Tenant) error
+	GetTables(ctx context.Context, menuID uuid.UUID, tenant models.Tenant) ([]*models.DiningTable, error)
+	GetTable(ctx context.Context, tableID uuid.UUID, tenant models.Tenant) (*models.DiningTable, error)
+	GetTableByToken(ctx context.Context, token string) (*models.DiningTable, error)
+	UpdateTable(ctx context.Context, table *models.DiningTable, tenant models.Tenant) error
+	UpdateTableQRCode(ctx context.Context, tableID uuid.UUID, qrCode string, tenant models.Tenant) error
+	DeleteTable(ctx context.Context, tableID uuid.UUID, tenant models.Tenant) error
+}
@@ -0,0 +1,18 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+)
+
+type AnalyticsService interface {
+	// Track counts the events of the batch in memory, they are stored with the
+	// next flush
+	Track(ctx context.Context, batch *models.AnalyticsBatch) error
+	// Flush stores the counted events
+	Flush(ctx context.Context) error
+	// Run flushes periodically until the context is done, then flushes what is
+	// left
+	Run(ctx context.Context)
+}
@@ -0,0 +1,14 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/google/uuid"
+)
+
+type DashboardService interface {
+	// GetDashboard returns the figures of the client for the queried days in
+	// the client's timezone, compared with as many days before them
+	GetDashboard(ctx context.Context, tenant models.Tenant, clientID uuid.UUID, query *models.DashboardQuery) (*models.Dashboard, error)
+}
@@ -0,0 +1,225 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/utils"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/repository"
+	"github.com/ahmetkoprulu/bidi-menu/internal/services"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// analyticsFlushInterval is how long events wait in memory at most
+	analyticsFlushInterval = 10 * time.Second
+	// analyticsFlushThreshold flushes early when this many counters are waiting
+	analyticsFlushThreshold = 5000
+	// analyticsMaxCounters bounds the buffer while the database is unreachable,
+	// new counters are dropped beyond it
+	analyticsMaxCounters = 50000
+	// analyticsFinalFlushTimeout bounds the flush when the server stops
+	analyticsFinalFlushTimeout = 5 * time.Second
+)
+
+type analyticsService struct {
+	analyticsRepo repository.AnalyticsRepository
+	counters      map[models.AnalyticsCounterKey]int64
+	dropped       int64
+	mu            sync.Mutex
+	// flushing serializes flushes, so a failed batch is merged back before the
+	// next one is taken
+	flushing sync.Mutex
+	full     chan struct{}
+	now      func() time.Time
+	logger   *utils.Loggger
+}
+
+func NewAnalyticsService(analyticsRepo repository.AnalyticsRepository) services.AnalyticsService {
+	return &analyticsService{
+		analyticsRepo: analyticsRepo,
+		counters:      make(map[models.AnalyticsCounterKey]int64),
+		full:          make(chan struct{}, 1),
+		now:           time.Now,
+		logger:        utils.Logger,
+	}
+}
+
+// Track checks the events and adds them to the hourly counters. The menu and
+// table are not looked up here, counters of unknown menus are dropped when
+// they are stored.
+func (s *analyticsService) Track(ctx context.Context, batch *models.AnalyticsBatch) error {
+	source := batch.Source
+	switch {
+	case batch.TableToken != "":
+		source = models.AnalyticsSourceTable
+	case source == "":
+		source = models.AnalyticsSourceDirect
+	case source == models.AnalyticsSourceTable:
+		return fmt.Errorf("table source requires a table token")
+	}
+
+	base := models.AnalyticsCounterKey{
+		MenuID:     batch.MenuID,
+		Bucket:     s.now().UTC().Truncate(time.Hour),
+		Source:     source,
+		TableToken: batch.TableToken,
+		Device:     deviceType(batch.UserAgent),
+	}
+
+	keys := make([]models.AnalyticsCounterKey, 0, len(batch.Events))
+	for i, event := range batch.Events {
+		if err := validateEvent(event); err != nil {
+			return fmt.Errorf("event %d: %w", i+1, err)
+		}
+
+		key := base
+		key.Type = event.Type
+		if event.ItemID != nil {
+			key.ItemID = *event.ItemID
+		}
+		if event.ModelID != nil {
+			key.ModelID = *event.ModelID
+		}
+		keys = append(keys, key)
+	}
+
+	s.mu.Lock()
+	for _, key := range keys {
+		if _, ok := s.counters[key]; !ok && len(s.counters) >= analyticsMaxCounters {
+			s.dropped++
+			continue
+		}
+		s.counters[key]++
+	}
+	full := len(s.counters) >= analyticsFlushThreshold
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.full <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Flush stores the counters in one batch. When that fails they go back into
+// the buffer and are retried with the next flush.
+func (s *analyticsService) Flush(ctx context.Context) error {
+	s.flushing.Lock()
+	defer s.flushing.Unlock()
+
+	s.mu.Lock()
+	pending, dropped := s.counters, s.dropped
+	s.counters, s.dropped = make(map[models.AnalyticsCounterKey]int64, len(pending)), 0
+	s.mu.Unlock()
+
+	if dropped > 0 {
+		s.logWarn("Dropped analytics events, the buffer was full", nil, zap.Int64("dropped", dropped))
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	counters := make([]*models.AnalyticsCounter, 0, len(pending))
+	for key, count := range pending {
+		counters = append(counters, &models.AnalyticsCounter{AnalyticsCounterKey: key, Count: count})
+	}
+
+	if err := s.analyticsRepo.IncrementCounters(ctx, counters); err != nil {
+		s.mu.Lock()
+		for key, count := range pending {
+			if _, ok := s.counters[key]; !ok && len(s.counters) >= analyticsMaxCounters {
+				s.dropped += count
+				continue
+			}
+			s.counters[key] += count
+		}
+		s.mu.Unlock()
+
+		return fmt.Errorf("failed to store analytics counters: %w", err)
+	}
+
+	return nil
+}
+
+func (s *analyticsService) Run(ctx context.Context) {
+	ticker := time.NewTicker(analyticsFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), analyticsFinalFlushTimeout)
+			if err := s.Flush(flushCtx); err != nil {
+				s.logWarn("Failed to flush analytics on shutdown", err)
+			}
+			cancel()
+			return
+		case <-ticker.C:
+		case <-s.full:
+		}
+
+		if err := s.Flush(ctx); err != nil {
+			s.logWarn("Failed to flush analytics", err)
+		}
+	}
+}
+
+func validateEvent(event *models.AnalyticsEvent) error {
+	switch event.Type {
+	case models.AnalyticsEventMenuView:
+		if event.ItemID != nil || event.ModelID != nil {
+			return fmt.Errorf("menu views have no item or model")
+		}
+	case models.AnalyticsEventItemOpen:
+		if event.ItemID == nil || *event.ItemID == uuid.Nil {
+			return fmt.Errorf("item opens require an item")
+		}
+	case models.AnalyticsEventARLaunch:
+		if event.ModelID == nil || *event.ModelID == uuid.Nil {
+			return fmt.Errorf("ar launches require a model")
+		}
+	default:
+		return fmt.Errorf("unknown event type %q", event.Type)
+	}
+
+	return nil
+}
+
+// deviceType guesses the kind of device from its user agent. iPads asking for
+// desktop sites can not be told apart from Macs and count as desktops.
+func deviceType(userAgent string) models.DeviceType {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case ua == "":
+		return models.DeviceUnknown
+	case strings.Contains(ua, "ipad"), strings.Contains(ua, "tablet"),
+		strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		return models.DeviceTablet
+	case strings.Contains(ua, "mobi"), strings.Contains(ua, "iphone"), strings.Contains(ua, "ipod"):
+		return models.DeviceMobile
+	case strings.Contains(ua, "windows"), strings.Contains(ua, "macintosh"), strings.Contains(ua, "linux"), strings.Contains(ua, "cros"):
+		return models.DeviceDesktop
+	default:
+		return models.DeviceUnknown
+	}
+}
+
+func (s *analyticsService) logWarn(message string, err error, fields ...zap.Field) {
+	if s.logger == nil {
+		return
+	}
+
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	s.logger.Warn(message, fields...)
+}
@@ -0,0 +1,123 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/google/uuid"
+)
+
+type memoryAnalyticsRepository struct {
+	counters map[models.AnalyticsCounterKey]int64
+	err      error
+}
+
+func (r *memoryAnalyticsRepository) IncrementCounters(ctx context.Context, counters []*models.AnalyticsCounter) error {
+	if r.err != nil {
+		return r.err
+	}
+	for _, counter := range counters {
+		r.counters[counter.AnalyticsCounterKey] += counter.Count
+	}
+	return nil
+}
+
+func TestAnalyticsBuffersAndFlushes(t *testing.T) {
+	repo := &memoryAnalyticsRepository{counters: map[models.AnalyticsCounterKey]int64{}}
+	service := NewAnalyticsService(repo).(*analyticsService)
+	service.now = func() time.Time { return time.Date(2024, 6, 3, 12, 34, 56, 0, time.UTC) }
+
+	ctx := context.Background()
+	menuID, itemID, modelID := uuid.New(), uuid.New(), uuid.New()
+	iphone := "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148"
+	batch := &models.AnalyticsBatch{
+		MenuID:     menuID,
+		TableToken: "terrace",
+		UserAgent:  iphone,
+		Events: []*models.AnalyticsEvent{
+			{Type: models.AnalyticsEventMenuView},
+			{Type: models.AnalyticsEventItemOpen, ItemID: &itemID},
+			{Type: models.AnalyticsEventARLaunch, ItemID: &itemID, ModelID: &modelID},
+		},
+	}
+	for i := 0; i < 3; i++ {
+		if err := service.Track(ctx, batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo.err = errors.New("database is down")
+	if err := service.Flush(ctx); err == nil {
+		t.Fatal("expected the flush to fail")
+	}
+	repo.err = nil
+	if err := service.Track(ctx, &models.AnalyticsBatch{MenuID: menuID, Events: []*models.AnalyticsEvent{{Type: models.AnalyticsEventMenuView}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	view := models.AnalyticsCounterKey{
+		MenuID:     menuID,
+		Bucket:     time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC),
+		Type:       models.AnalyticsEventMenuView,
+		Source:     models.AnalyticsSourceTable,
+		TableToken: "terrace",
+		Device:     models.DeviceMobile,
+	}
+	if count := repo.counters[view]; count != 3 {
+		t.Errorf("expected the failed batch to be kept, got %d table views", count)
+	}
+
+	launch := view
+	launch.Type, launch.ItemID, launch.ModelID = models.AnalyticsEventARLaunch, itemID, modelID
+	if count := repo.counters[launch]; count != 3 {
+		t.Errorf("expected 3 ar launches, got %d", count)
+	}
+
+	direct := models.AnalyticsCounterKey{MenuID: menuID, Bucket: view.Bucket, Type: models.AnalyticsEventMenuView, Source: models.AnalyticsSourceDirect, Device: models.DeviceUnknown}
+	if count := repo.counters[direct]; count != 1 {
+		t.Errorf("expected 1 direct view, got %d", count)
+	}
+	if len(service.counters) != 0 {
+		t.Errorf("expected an empty buffer after the flush, got %d counters", len(service.counters))
+	}
+}
+
+func TestAnalyticsRejectsInvalidEvents(t *testing.T) {
+	service := NewAnalyticsService(&memoryAnalyticsRepository{})
+	itemID := uuid.New()
+
+	for _, batch := range []*models.AnalyticsBatch{
+		{Events: []*models.AnalyticsEvent{{Type: "purchase"}}},
+		{Events: []*models.AnalyticsEvent{{Type: models.AnalyticsEventItemOpen}}},
+		{Events: []*models.AnalyticsEvent{{Type: models.AnalyticsEventARLaunch, ItemID: &itemID}}},
+		{Events: []*models.AnalyticsEvent{{Type: models.AnalyticsEventMenuView, ItemID: &itemID}}},
+		{Source: models.AnalyticsSourceTable, Events: []*models.AnalyticsEvent{{Type: models.AnalyticsEventMenuView}}},
+	} {
+		if err := service.Track(context.Background(), batch); err == nil {
+			t.Errorf("expected %+v to be rejected", batch.Events[0])
+		}
+	}
+}
+
+func TestDeviceType(t *testing.T) {
+	for userAgent, expected := range map[string]models.DeviceType{
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148":           models.DeviceMobile,
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) Chrome/120.0 Mobile Safari/537.36":     models.DeviceMobile,
+		"Mozilla/5.0 (Linux; Android 13; SM-X710) Chrome/120.0 Safari/537.36":            models.DeviceTablet,
+		"Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X) Mobile/15E148":                    models.DeviceTablet,
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0 Safari/537.36":           models.DeviceDesktop,
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 Version/17.0": models.DeviceDesktop,
+		"":           models.DeviceUnknown,
+		"curl/8.4.0": models.DeviceUnknown,
+	} {
+		if device := deviceType(userAgent); device != expected {
+			t.Errorf("%q: expected %s, got %s", userAgent, expected, device)
+		}
+	}
+}
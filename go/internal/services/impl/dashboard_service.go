@@ -0,0 +1,212 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/repository"
+	"github.com/ahmetkoprulu/bidi-menu/internal/services"
+	"github.com/google/uuid"
+)
+
+const (
+	// dashboardDefaultDays is the period shown when no dates are given
+	dashboardDefaultDays = 30
+	dashboardMaxDays     = 366
+	// dashboardMaxHourlyDays keeps hourly series to about 750 points
+	dashboardMaxHourlyDays = 31
+	dashboardTopLimit      = 10
+	dashboardRecentMenus   = 5
+	dateLayout             = "2006-01-02"
+)
+
+type dashboardService struct {
+	dashboardRepo repository.DashboardRepository
+	clientRepo    repository.ClientRepository
+	now           func() time.Time
+}
+
+func NewDashboardService(dashboardRepo repository.DashboardRepository, clientRepo repository.ClientRepository) services.DashboardService {
+	return &dashboardService{
+		dashboardRepo: dashboardRepo,
+		clientRepo:    clientRepo,
+		now:           time.Now,
+	}
+}
+
+func (s *dashboardService) GetDashboard(ctx context.Context, tenant models.Tenant, clientID uuid.UUID, query *models.DashboardQuery) (*models.Dashboard, error) {
+	if !tenant.Owns(clientID) {
+		return nil, models.ErrNotFound
+	}
+
+	location, err := s.location(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := query.Interval
+	if interval == "" {
+		interval = models.IntervalDay
+	}
+	if interval != models.IntervalHour && interval != models.IntervalDay && interval != models.IntervalWeek {
+		return nil, fmt.Errorf("unknown interval %q", interval)
+	}
+
+	to := localDate(s.now().In(location), location)
+	if query.To != nil {
+		to = localDate(*query.To, location)
+	}
+	from := to.AddDate(0, 0, -(dashboardDefaultDays - 1))
+	if query.From != nil {
+		from = localDate(*query.From, location)
+	}
+
+	days := daysBetween(from, to) + 1
+	switch {
+	case days < 1:
+		return nil, fmt.Errorf("from must not be after to")
+	case days > dashboardMaxDays:
+		return nil, fmt.Errorf("the period can be at most %d days", dashboardMaxDays)
+	case interval == models.IntervalHour && days > dashboardMaxHourlyDays:
+		return nil, fmt.Errorf("hourly series can cover at most %d days", dashboardMaxHourlyDays)
+	}
+
+	// the periods end at the midnight after their last day
+	start, end := from, to.AddDate(0, 0, 1)
+	previousFrom := from.AddDate(0, 0, -days)
+
+	dashboard := &models.Dashboard{
+		Timezone: location.String(),
+		Interval: interval,
+		Current:  models.DashboardPeriod{From: from.Format(dateLayout), To: to.Format(dateLayout)},
+		Previous: models.DashboardPeriod{From: previousFrom.Format(dateLayout), To: from.AddDate(0, 0, -1).Format(dateLayout)},
+	}
+
+	dashboard.TotalMenus, dashboard.ActiveMenus, err = s.dashboardRepo.GetMenuCounts(ctx, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu counts: %w", err)
+	}
+
+	current, err := s.dashboardRepo.GetTotals(ctx, clientID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get totals: %w", err)
+	}
+	previous, err := s.dashboardRepo.GetTotals(ctx, clientID, previousFrom, start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get previous totals: %w", err)
+	}
+	current.Conversion, previous.Conversion = conversion(current), conversion(previous)
+	dashboard.Current.Totals, dashboard.Previous.Totals = *current, *previous
+	dashboard.Change = models.DashboardChange{
+		Views:      percentChange(float64(current.Views), float64(previous.Views)),
+		ItemOpens:  percentChange(float64(current.ItemOpens), float64(previous.ItemOpens)),
+		ARLaunches: percentChange(float64(current.ARLaunches), float64(previous.ARLaunches)),
+		Conversion: percentChange(current.Conversion, previous.Conversion),
+	}
+
+	points, err := s.dashboardRepo.GetSeries(ctx, clientID, start, end, interval, location.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get series: %w", err)
+	}
+	dashboard.Series = fillSeries(points, start, end, interval, location)
+
+	if dashboard.TopItems, err = s.dashboardRepo.GetTopItems(ctx, clientID, start, end, dashboardTopLimit); err != nil {
+		return nil, fmt.Errorf("failed to get top items: %w", err)
+	}
+	if dashboard.TopModels, err = s.dashboardRepo.GetTopModels(ctx, clientID, start, end, dashboardTopLimit); err != nil {
+		return nil, fmt.Errorf("failed to get top models: %w", err)
+	}
+	if dashboard.RecentMenus, err = s.dashboardRepo.GetRecentMenus(ctx, clientID, start, end, dashboardRecentMenus); err != nil {
+		return nil, fmt.Errorf("failed to get recent menus: %w", err)
+	}
+
+	return dashboard, nil
+}
+
+// location is the client's timezone, UTC when it has none or it is not known
+func (s *dashboardService) location(ctx context.Context, clientID uuid.UUID) (*time.Location, error) {
+	client, err := s.clientRepo.GetClient(ctx, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+	if client == nil || client.Timezone == nil {
+		return time.UTC, nil
+	}
+
+	location, err := time.LoadLocation(*client.Timezone)
+	if err != nil {
+		return time.UTC, nil
+	}
+
+	return location, nil
+}
+
+// fillSeries lays the points on every interval of the period, intervals
+// without events get an empty point. Weeks start on Monday like date_trunc's.
+func fillSeries(points []*models.DashboardPoint, start, end time.Time, interval models.AnalyticsInterval, location *time.Location) []*models.DashboardPoint {
+	byStart := make(map[int64]*models.DashboardPoint, len(points))
+	for _, point := range points {
+		byStart[point.Start.Unix()] = point
+	}
+
+	if interval == models.IntervalWeek {
+		start = start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+	}
+
+	series := make([]*models.DashboardPoint, 0)
+	for at := start; at.Before(end); at = nextInterval(at, interval, location) {
+		point, ok := byStart[at.Unix()]
+		if !ok {
+			point = &models.DashboardPoint{}
+		}
+		point.Start = at
+		series = append(series, point)
+	}
+
+	return series
+}
+
+func nextInterval(at time.Time, interval models.AnalyticsInterval, location *time.Location) time.Time {
+	switch interval {
+	case models.IntervalHour:
+		return at.Add(time.Hour)
+	case models.IntervalWeek:
+		return localDate(at.AddDate(0, 0, 7), location)
+	default:
+		return localDate(at.AddDate(0, 0, 1), location)
+	}
+}
+
+// localDate is the midnight starting the date of t in the location, the date
+// is read as it is so dates parsed in UTC keep their day
+func localDate(t time.Time, location *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location)
+}
+
+func daysBetween(from, to time.Time) int {
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+func conversion(totals *models.DashboardTotals) float64 {
+	if totals.Views == 0 {
+		return 0
+	}
+
+	return math.Round(float64(totals.ARLaunches)/float64(totals.Views)*10000) / 10000
+}
+
+// percentChange is rounded to one decimal, nil when there is nothing to
+// compare with
+func percentChange(current, previous float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+
+	change := math.Round((current-previous)/previous*1000) / 10
+	return &change
+}
@@ -0,0 +1,192 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/repository"
+	"github.com/google/uuid"
+)
+
+// fakeDashboardRepository records the periods it is asked for and serves
+// fixed figures
+type fakeDashboardRepository struct {
+	totals   map[int64]*models.DashboardTotals
+	points   []*models.DashboardPoint
+	periods  [][2]time.Time
+	timezone string
+}
+
+func (r *fakeDashboardRepository) GetMenuCounts(ctx context.Context, clientID uuid.UUID) (int64, int64, error) {
+	return 3, 2, nil
+}
+
+func (r *fakeDashboardRepository) GetTotals(ctx context.Context, clientID uuid.UUID, from, to time.Time) (*models.DashboardTotals, error) {
+	r.periods = append(r.periods, [2]time.Time{from, to})
+	if totals, ok := r.totals[from.Unix()]; ok {
+		copied := *totals
+		return &copied, nil
+	}
+	return &models.DashboardTotals{}, nil
+}
+
+func (r *fakeDashboardRepository) GetSeries(ctx context.Context, clientID uuid.UUID, from, to time.Time, interval models.AnalyticsInterval, timezone string) ([]*models.DashboardPoint, error) {
+	r.timezone = timezone
+	return r.points, nil
+}
+
+func (r *fakeDashboardRepository) GetTopItems(ctx context.Context, clientID uuid.UUID, from, to time.Time, limit int) ([]*models.DashboardItem, error) {
+	return []*models.DashboardItem{}, nil
+}
+
+func (r *fakeDashboardRepository) GetTopModels(ctx context.Context, clientID uuid.UUID, from, to time.Time, limit int) ([]*models.DashboardModel, error) {
+	return []*models.DashboardModel{}, nil
+}
+
+func (r *fakeDashboardRepository) GetRecentMenus(ctx context.Context, clientID uuid.UUID, from, to time.Time, limit int) ([]*models.DashboardMenu, error) {
+	return []*models.DashboardMenu{}, nil
+}
+
+type timezoneClientRepository struct {
+	repository.ClientRepository
+	timezone string
+}
+
+func (r timezoneClientRepository) GetClient(ctx context.Context, clientID uuid.UUID) (*models.Client, error) {
+	return &models.Client{ID: clientID, Timezone: &r.timezone}, nil
+}
+
+func TestDashboardPeriods(t *testing.T) {
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	if err != nil {
+		t.Skip("timezone data is not available")
+	}
+
+	clientID := uuid.New()
+	currentStart := time.Date(2024, 5, 6, 0, 0, 0, 0, istanbul)
+	repo := &fakeDashboardRepository{
+		totals: map[int64]*models.DashboardTotals{
+			currentStart.Unix(): {Views: 150, ItemOpens: 40, ARLaunches: 30},
+		},
+		points: []*models.DashboardPoint{
+			{Start: time.Date(2024, 5, 7, 0, 0, 0, 0, istanbul), Views: 20},
+		},
+	}
+	service := NewDashboardService(repo, timezoneClientRepository{timezone: "Europe/Istanbul"}).(*dashboardService)
+	// late evening in UTC is already the next day in Istanbul
+	service.now = func() time.Time { return time.Date(2024, 5, 12, 22, 30, 0, 0, time.UTC) }
+
+	from := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	dashboard, err := service.GetDashboard(context.Background(), models.Tenant{ClientID: clientID}, clientID, &models.DashboardQuery{From: &from})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dashboard.Current.From != "2024-05-06" || dashboard.Current.To != "2024-05-13" {
+		t.Errorf("unexpected current period %s - %s", dashboard.Current.From, dashboard.Current.To)
+	}
+	if dashboard.Previous.From != "2024-04-28" || dashboard.Previous.To != "2024-05-05" {
+		t.Errorf("unexpected previous period %s - %s", dashboard.Previous.From, dashboard.Previous.To)
+	}
+	if end := repo.periods[0][1]; !end.Equal(time.Date(2024, 5, 13, 21, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the period to end at midnight in Istanbul, got %s", end.UTC())
+	}
+	if repo.timezone != "Europe/Istanbul" || dashboard.TotalMenus != 3 || dashboard.ActiveMenus != 2 {
+		t.Errorf("unexpected dashboard %+v", dashboard)
+	}
+
+	if len(dashboard.Series) != 8 {
+		t.Fatalf("expected a point for each of the 8 days, got %d", len(dashboard.Series))
+	}
+	if dashboard.Series[1].Views != 20 || dashboard.Series[0].Views != 0 || !dashboard.Series[7].Start.Equal(time.Date(2024, 5, 13, 0, 0, 0, 0, istanbul)) {
+		t.Errorf("unexpected series %+v %+v %+v", dashboard.Series[0], dashboard.Series[1], dashboard.Series[7])
+	}
+
+	if dashboard.Current.Totals.Conversion != 0.2 || dashboard.Previous.Totals.Conversion != 0 {
+		t.Errorf("unexpected conversion %v and %v", dashboard.Current.Totals.Conversion, dashboard.Previous.Totals.Conversion)
+	}
+	if dashboard.Change.Views != nil {
+		t.Errorf("expected no change without previous views, got %v", *dashboard.Change.Views)
+	}
+}
+
+func TestDashboardChange(t *testing.T) {
+	clientID := uuid.New()
+	to := time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -6)
+	repo := &fakeDashboardRepository{
+		totals: map[int64]*models.DashboardTotals{
+			from.Unix():                   {Views: 150, ItemOpens: 40, ARLaunches: 30},
+			from.AddDate(0, 0, -7).Unix(): {Views: 100, ItemOpens: 40, ARLaunches: 10},
+		},
+	}
+	service := NewDashboardService(repo, timezoneClientRepository{timezone: "Not/AZone"})
+
+	dashboard, err := service.GetDashboard(context.Background(), models.Tenant{ClientID: clientID}, clientID, &models.DashboardQuery{From: &from, To: &to, Interval: models.IntervalWeek})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dashboard.Timezone != "UTC" {
+		t.Errorf("expected an unknown timezone to fall back to UTC, got %s", dashboard.Timezone)
+	}
+	change := dashboard.Change
+	if change.Views == nil || *change.Views != 50 || *change.ItemOpens != 0 || *change.ARLaunches != 200 || *change.Conversion != 100 {
+		t.Errorf("unexpected change %+v", change)
+	}
+	// 2024-05-06 is a Monday, so the days fall into one week
+	if len(dashboard.Series) != 1 || !dashboard.Series[0].Start.Equal(from) {
+		t.Errorf("expected one week, got %d points", len(dashboard.Series))
+	}
+}
+
+func TestDashboardHourlySeriesAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("timezone data is not available")
+	}
+
+	clientID := uuid.New()
+	day := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	service := NewDashboardService(&fakeDashboardRepository{}, timezoneClientRepository{timezone: "Europe/Berlin"})
+
+	dashboard, err := service.GetDashboard(context.Background(), models.Tenant{ClientID: clientID}, clientID, &models.DashboardQuery{From: &day, To: &day, Interval: models.IntervalHour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dashboard.Series) != 23 {
+		t.Errorf("expected 23 hours on the day clocks go forward, got %d", len(dashboard.Series))
+	}
+	if start := dashboard.Series[0].Start; !start.Equal(time.Date(2024, 3, 31, 0, 0, 0, 0, berlin)) {
+		t.Errorf("expected the series to start at midnight in Berlin, got %s", start)
+	}
+}
+
+func TestDashboardRejectsInvalidQueries(t *testing.T) {
+	clientID := uuid.New()
+	service := NewDashboardService(&fakeDashboardRepository{}, timezoneClientRepository{})
+	date := func(year int, month time.Month, day int) *time.Time {
+		d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+
+	for name, query := range map[string]*models.DashboardQuery{
+		"reversed":    {From: date(2024, 5, 2), To: date(2024, 5, 1)},
+		"too long":    {From: date(2023, 1, 1), To: date(2024, 5, 1)},
+		"long hourly": {From: date(2024, 1, 1), To: date(2024, 3, 1), Interval: models.IntervalHour},
+		"interval":    {Interval: "month"},
+	} {
+		if _, err := service.GetDashboard(context.Background(), models.Tenant{ClientID: clientID}, clientID, query); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	_, err := service.GetDashboard(context.Background(), models.Tenant{ClientID: clientID}, uuid.New(), &models.DashboardQuery{})
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("expected another client's dashboard to be not found, got %v", err)
+	}
+}
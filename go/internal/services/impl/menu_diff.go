@@ -0,0 +1,211 @@
+package impl
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/google/uuid"
+)
+
+// diffMenus lists what changed from one menu snapshot to another. Categories
+// and items are matched by id, so renaming an item is an update while deleting
+// and recreating it is a removal and an addition. Changes come in the order of
+// the newer menu, removals last.
+func diffMenus(from, to *models.Menu) []*models.MenuChange {
+	changes := make([]*models.MenuChange, 0)
+
+	menuID := uuid.Nil
+	if to.ID != nil {
+		menuID = *to.ID
+	}
+
+	field := func(name string, old, new interface{}) {
+		if !equalValues(old, new) {
+			changes = append(changes, &models.MenuChange{
+				Type:   models.MenuChangeUpdated,
+				Entity: models.MenuChangeEntityMenu,
+				ID:     menuID,
+				Name:   to.Label,
+				Field:  name,
+				Old:    old,
+				New:    new,
+			})
+		}
+	}
+	field("label", from.Label, to.Label)
+	field("description", from.Description, to.Description)
+	field("status", from.Status, to.Status)
+	field("customization", from.Customization, to.Customization)
+	field("locales", from.OfferedLocales(), to.OfferedLocales())
+	field("translations", from.Translations, to.Translations)
+
+	changes = append(changes, diffCategories(from.Categories, to.Categories)...)
+	changes = append(changes, diffItems(from.Categories, to.Categories)...)
+
+	return changes
+}
+
+func diffCategories(from, to []*models.MenuCategory) []*models.MenuChange {
+	changes := make([]*models.MenuChange, 0)
+
+	oldCategories := make(map[uuid.UUID]*models.MenuCategory)
+	oldPositions := make(map[uuid.UUID]int)
+	for i, category := range from {
+		oldCategories[category.ID] = category
+		oldPositions[category.ID] = i
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	for i, category := range to {
+		seen[category.ID] = true
+
+		old, ok := oldCategories[category.ID]
+		if !ok {
+			changes = append(changes, categoryChange(models.MenuChangeAdded, category, "", nil, nil))
+			continue
+		}
+
+		if old.Name != category.Name {
+			changes = append(changes, categoryChange(models.MenuChangeUpdated, category, "name", old.Name, category.Name))
+		}
+		if !equalValues(old.Translations, category.Translations) {
+			changes = append(changes, categoryChange(models.MenuChangeUpdated, category, "translations", old.Translations, category.Translations))
+		}
+		if old.Status != category.Status {
+			changes = append(changes, categoryChange(models.MenuChangeUpdated, category, "status", old.Status, category.Status))
+		}
+		if oldPositions[category.ID] != i {
+			changes = append(changes, categoryChange(models.MenuChangeUpdated, category, "position", oldPositions[category.ID], i))
+		}
+	}
+
+	for _, category := range from {
+		if !seen[category.ID] {
+			changes = append(changes, categoryChange(models.MenuChangeRemoved, category, "", nil, nil))
+		}
+	}
+
+	return changes
+}
+
+// placedItem is an item with the category it is in and its position there
+type placedItem struct {
+	item       *models.MenuCategoryItem
+	categoryID uuid.UUID
+	position   int
+}
+
+func diffItems(from, to []*models.MenuCategory) []*models.MenuChange {
+	changes := make([]*models.MenuChange, 0)
+
+	oldItems := make(map[uuid.UUID]placedItem)
+	for _, placed := range placeItems(from) {
+		oldItems[placed.item.ID] = placed
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	for _, placed := range placeItems(to) {
+		item := placed.item
+		seen[item.ID] = true
+
+		old, ok := oldItems[item.ID]
+		if !ok {
+			changes = append(changes, itemChange(models.MenuChangeAdded, placed, "", nil, nil))
+			continue
+		}
+
+		field := func(name string, oldValue, newValue interface{}) {
+			if !equalValues(oldValue, newValue) {
+				changes = append(changes, itemChange(models.MenuChangeUpdated, placed, name, oldValue, newValue))
+			}
+		}
+		field("name", old.item.Name, item.Name)
+		field("description", old.item.Description, item.Description)
+		field("price", old.item.Price, item.Price)
+		field("variants", old.item.Variants, item.Variants)
+		field("modifierGroups", old.item.ModifierGroups, item.ModifierGroups)
+		field("images", old.item.Images, item.Images)
+		field("modelId", old.item.ModelID, item.ModelID)
+		field("status", old.item.Status, item.Status)
+		field("allergens", old.item.Allergens, item.Allergens)
+		field("dietaryTags", old.item.DietaryTags, item.DietaryTags)
+		field("spiceLevel", old.item.SpiceLevel, item.SpiceLevel)
+		field("calories", old.item.Calories, item.Calories)
+		field("nutrition", old.item.Nutrition, item.Nutrition)
+		field("translations", old.item.Translations, item.Translations)
+		field("category", old.categoryID, placed.categoryID)
+		if old.categoryID == placed.categoryID {
+			field("position", old.position, placed.position)
+		}
+	}
+
+	for _, placed := range placeItems(from) {
+		if !seen[placed.item.ID] {
+			changes = append(changes, itemChange(models.MenuChangeRemoved, placed, "", nil, nil))
+		}
+	}
+
+	return changes
+}
+
+func placeItems(categories []*models.MenuCategory) []placedItem {
+	placed := make([]placedItem, 0)
+	for _, category := range categories {
+		for i, item := range category.MenuItems {
+			placed = append(placed, placedItem{item: item, categoryID: category.ID, position: i})
+		}
+	}
+
+	return placed
+}
+
+func categoryChange(changeType models.MenuChangeType, category *models.MenuCategory, field string, old, new interface{}) *models.MenuChange {
+	return &models.MenuChange{
+		Type:   changeType,
+		Entity: models.MenuChangeEntityCategory,
+		ID:     category.ID,
+		Name:   category.Name,
+		Field:  field,
+		Old:    old,
+		New:    new,
+	}
+}
+
+func itemChange(changeType models.MenuChangeType, placed placedItem, field string, old, new interface{}) *models.MenuChange {
+	categoryID := placed.categoryID
+	return &models.MenuChange{
+		Type:       changeType,
+		Entity:     models.MenuChangeEntityItem,
+		ID:         placed.item.ID,
+		Name:       placed.item.Name,
+		CategoryID: &categoryID,
+		Field:      field,
+		Old:        old,
+		New:        new,
+	}
+}
+
+// equalValues compares two values by their json encoding, so an empty slice and
+// a missing one are the same, just like they are for the guests
+func equalValues(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+
+	aJSON, aErr := json.Marshal(a)
+	bJSON, bErr := json.Marshal(b)
+	if aErr != nil || bErr != nil {
+		return false
+	}
+
+	return normalizeJSON(aJSON) == normalizeJSON(bJSON)
+}
+
+func normalizeJSON(data []byte) string {
+	switch string(data) {
+	case "null", "[]", "{}", `""`:
+		return ""
+	}
+	return string(data)
+}
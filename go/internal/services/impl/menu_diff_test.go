@@ -0,0 +1,86 @@
+package impl
+
+import (
+	"testing"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestDiffMenus(t *testing.T) {
+	mains, drinks, desserts := uuid.New(), uuid.New(), uuid.New()
+	burger, pasta, soda, cake := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	from := &models.Menu{
+		Label: "Lunch",
+		Categories: []*models.MenuCategory{
+			{ID: mains, Name: "Mains", MenuItems: []*models.MenuCategoryItem{
+				{ID: burger, Name: "Burger", Price: 12},
+				{ID: pasta, Name: "Pasta", Price: 10},
+			}},
+			{ID: drinks, Name: "Drinks", MenuItems: []*models.MenuCategoryItem{
+				{ID: soda, Name: "Soda", Price: 3},
+			}},
+		},
+	}
+
+	to := &models.Menu{
+		Label: "Lunch",
+		Categories: []*models.MenuCategory{
+			{ID: mains, Name: "Main courses", MenuItems: []*models.MenuCategoryItem{
+				{ID: burger, Name: "Burger", Price: 13, Images: []string{}},
+				{ID: soda, Name: "Soda", Price: 3},
+			}},
+			{ID: desserts, Name: "Desserts", MenuItems: []*models.MenuCategoryItem{
+				{ID: cake, Name: "Cake", Price: 6},
+			}},
+		},
+	}
+
+	expected := []struct {
+		changeType models.MenuChangeType
+		entity     models.MenuChangeEntity
+		id         uuid.UUID
+		field      string
+	}{
+		{models.MenuChangeUpdated, models.MenuChangeEntityCategory, mains, "name"},
+		{models.MenuChangeAdded, models.MenuChangeEntityCategory, desserts, ""},
+		{models.MenuChangeRemoved, models.MenuChangeEntityCategory, drinks, ""},
+		{models.MenuChangeUpdated, models.MenuChangeEntityItem, burger, "price"},
+		{models.MenuChangeUpdated, models.MenuChangeEntityItem, soda, "category"},
+		{models.MenuChangeAdded, models.MenuChangeEntityItem, cake, ""},
+		{models.MenuChangeRemoved, models.MenuChangeEntityItem, pasta, ""},
+	}
+
+	changes := diffMenus(from, to)
+	if len(changes) != len(expected) {
+		for _, change := range changes {
+			t.Logf("%s %s %s %s", change.Type, change.Entity, change.Name, change.Field)
+		}
+		t.Fatalf("expected %d changes, got %d", len(expected), len(changes))
+	}
+
+	for i, want := range expected {
+		got := changes[i]
+		if got.Type != want.changeType || got.Entity != want.entity || got.ID != want.id || got.Field != want.field {
+			t.Errorf("change %d: expected %s %s %s, got %s %s %s %s", i, want.changeType, want.entity, want.field, got.Type, got.Entity, got.Name, got.Field)
+		}
+	}
+
+	if price := changes[3]; price.Old != 12.0 || price.New != 13.0 {
+		t.Errorf("expected the price to go from 12 to 13, got %v to %v", price.Old, price.New)
+	}
+}
+
+func TestDiffMenusWithoutChanges(t *testing.T) {
+	menu := &models.Menu{
+		Label: "Lunch",
+		Categories: []*models.MenuCategory{
+			{ID: uuid.New(), Name: "Mains", MenuItems: []*models.MenuCategoryItem{{ID: uuid.New(), Name: "Burger"}}},
+		},
+	}
+
+	if changes := diffMenus(menu, menu); len(changes) != 0 {
+		t.Errorf("expected no changes, got %d", len(changes))
+	}
+}
@@ -0,0 +1,114 @@
+package impl
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+)
+
+const (
+	maxItemCalories = 10000
+	// maxNutritionGrams bounds a single nutrient of one serving
+	maxNutritionGrams = 5000
+	maxServingSize    = 50
+)
+
+// seafoodAllergens can not be in vegetarian dishes, animalProductAllergens
+// neither in vegan ones
+var (
+	seafoodAllergens       = []models.Allergen{models.AllergenFish, models.AllergenCrustaceans, models.AllergenMolluscs}
+	animalProductAllergens = []models.Allergen{models.AllergenMilk, models.AllergenEggs}
+)
+
+// normalizeItemFacts checks the dietary information of an item and puts the
+// allergens and tags in their canonical order without duplicates
+func normalizeItemFacts(item *models.MenuCategoryItem) error {
+	allergens, err := canonical(item.Allergens, models.Allergens, "allergen")
+	if err != nil {
+		return err
+	}
+	tags, err := canonical(item.DietaryTags, models.DietaryTags, "dietary tag")
+	if err != nil {
+		return err
+	}
+
+	vegan := slices.Contains(tags, models.DietaryVegan)
+	vegetarian := vegan || slices.Contains(tags, models.DietaryVegetarian)
+	for _, allergen := range allergens {
+		switch {
+		case vegetarian && slices.Contains(seafoodAllergens, allergen):
+			return fmt.Errorf("vegetarian items can not contain %s", allergen)
+		case vegan && slices.Contains(animalProductAllergens, allergen):
+			return fmt.Errorf("vegan items can not contain %s", allergen)
+		case allergen == models.AllergenGluten && slices.Contains(tags, models.DietaryGlutenFree):
+			return fmt.Errorf("gluten free items can not contain gluten")
+		}
+	}
+
+	if item.SpiceLevel < 0 || item.SpiceLevel > models.MaxSpiceLevel {
+		return fmt.Errorf("spice level must be between 0 and %d", models.MaxSpiceLevel)
+	}
+	if item.Calories != nil && (*item.Calories < 0 || *item.Calories > maxItemCalories) {
+		return fmt.Errorf("calories must be between 0 and %d", maxItemCalories)
+	}
+	if err := validateNutrition(item.Nutrition); err != nil {
+		return err
+	}
+
+	item.Allergens, item.DietaryTags = allergens, tags
+	return nil
+}
+
+func validateNutrition(nutrition *models.NutritionFacts) error {
+	if nutrition == nil {
+		return nil
+	}
+	if len(nutrition.ServingSize) > maxServingSize {
+		return fmt.Errorf("serving size can be at most %d characters", maxServingSize)
+	}
+
+	for _, nutrient := range []struct {
+		name  string
+		grams *float64
+	}{
+		{"protein", nutrition.Protein},
+		{"carbohydrates", nutrition.Carbohydrates},
+		{"sugars", nutrition.Sugars},
+		{"fat", nutrition.Fat},
+		{"saturated fat", nutrition.SaturatedFat},
+		{"fiber", nutrition.Fiber},
+		{"salt", nutrition.Salt},
+	} {
+		if nutrient.grams != nil && (*nutrient.grams < 0 || *nutrient.grams > maxNutritionGrams) {
+			return fmt.Errorf("%s must be between 0 and %d grams", nutrient.name, maxNutritionGrams)
+		}
+	}
+
+	if nutrition.Sugars != nil && nutrition.Carbohydrates != nil && *nutrition.Sugars > *nutrition.Carbohydrates {
+		return fmt.Errorf("sugars can not exceed carbohydrates")
+	}
+	if nutrition.SaturatedFat != nil && nutrition.Fat != nil && *nutrition.SaturatedFat > *nutrition.Fat {
+		return fmt.Errorf("saturated fat can not exceed fat")
+	}
+
+	return nil
+}
+
+// canonical returns the known values in the order of known, each once
+func canonical[T ~string](values []T, known []T, kind string) ([]T, error) {
+	for _, value := range values {
+		if !slices.Contains(known, value) {
+			return nil, fmt.Errorf("unknown %s %q", kind, value)
+		}
+	}
+
+	result := make([]T, 0, len(values))
+	for _, value := range known {
+		if slices.Contains(values, value) {
+			result = append(result, value)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,40 @@
+package impl
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+)
+
+func TestNormalizeItemFacts(t *testing.T) {
+	item := &models.MenuCategoryItem{
+		Allergens:   []models.Allergen{models.AllergenSesame, models.AllergenGluten, models.AllergenSesame, models.AllergenMilk},
+		DietaryTags: []models.DietaryTag{models.DietaryHalal, models.DietaryVegetarian},
+		SpiceLevel:  3,
+	}
+	if err := normalizeItemFacts(item); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(item.Allergens, []models.Allergen{models.AllergenGluten, models.AllergenMilk, models.AllergenSesame}) {
+		t.Errorf("expected the allergens in annex order without duplicates, got %v", item.Allergens)
+	}
+	if !slices.Equal(item.DietaryTags, []models.DietaryTag{models.DietaryVegetarian, models.DietaryHalal}) {
+		t.Errorf("unexpected dietary tags %v", item.DietaryTags)
+	}
+
+	calories, negative := 12000, -1.0
+	for name, invalid := range map[string]*models.MenuCategoryItem{
+		"vegetarian fish": {DietaryTags: []models.DietaryTag{models.DietaryVegetarian}, Allergens: []models.Allergen{models.AllergenFish}},
+		"vegan eggs":      {DietaryTags: []models.DietaryTag{models.DietaryVegan}, Allergens: []models.Allergen{models.AllergenEggs}},
+		"vegan molluscs":  {DietaryTags: []models.DietaryTag{models.DietaryVegan}, Allergens: []models.Allergen{models.AllergenMolluscs}},
+		"unknown tag":     {DietaryTags: []models.DietaryTag{"keto"}},
+		"spice":           {SpiceLevel: -1},
+		"calories":        {Calories: &calories},
+		"negative fat":    {Nutrition: &models.NutritionFacts{Fat: &negative}},
+	} {
+		if err := normalizeItemFacts(invalid); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package impl
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	maxItemVariants    = 20
+	maxModifierGroups  = 20
+	maxModifierOptions = 50
+	maxOptionName      = 100
+	// maxPriceDelta bounds what a single modifier option adds or takes off
+	maxPriceDelta = 100000
+)
+
+// normalizeItemOptions checks the variants and modifier groups of an item.
+// Missing ids are generated so orders can refer to the choices, and the item
+// takes the price of its default variant, the first one unless marked.
+func normalizeItemOptions(item *models.MenuCategoryItem) error {
+	if len(item.Variants) > maxItemVariants {
+		return fmt.Errorf("an item can have at most %d variants", maxItemVariants)
+	}
+	if len(item.ModifierGroups) > maxModifierGroups {
+		return fmt.Errorf("an item can have at most %d modifier groups", maxModifierGroups)
+	}
+
+	ids := make(map[uuid.UUID]bool)
+	names := make(map[string]bool)
+	var defaultVariant *models.MenuItemVariant
+	for _, variant := range item.Variants {
+		if err := optionName(&variant.Name, names, "variant"); err != nil {
+			return err
+		}
+		if variant.Price < 0 || math.IsNaN(variant.Price) {
+			return fmt.Errorf("variant %s must not have a negative price", variant.Name)
+		}
+		if variant.Default {
+			if defaultVariant != nil {
+				return fmt.Errorf("only one variant can be the default")
+			}
+			defaultVariant = variant
+		}
+		variant.ID = uniqueID(variant.ID, ids)
+	}
+	if defaultVariant == nil && len(item.Variants) > 0 {
+		defaultVariant = item.Variants[0]
+	}
+	if defaultVariant != nil {
+		item.Price = defaultVariant.Price
+	}
+
+	groupNames := make(map[string]bool)
+	for _, group := range item.ModifierGroups {
+		if err := optionName(&group.Name, groupNames, "modifier group"); err != nil {
+			return err
+		}
+		if err := normalizeModifierGroup(group, ids); err != nil {
+			return fmt.Errorf("modifier group %s: %w", group.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func normalizeModifierGroup(group *models.ModifierGroup, ids map[uuid.UUID]bool) error {
+	if len(group.Options) == 0 || len(group.Options) > maxModifierOptions {
+		return fmt.Errorf("a group needs between 1 and %d options", maxModifierOptions)
+	}
+
+	switch {
+	case group.MinSelect < 0:
+		return fmt.Errorf("minimum selection must not be negative")
+	case group.MaxSelect < 1:
+		return fmt.Errorf("maximum selection must be at least 1")
+	case group.MinSelect > group.MaxSelect:
+		return fmt.Errorf("minimum selection must not exceed the maximum")
+	case group.MaxSelect > len(group.Options):
+		return fmt.Errorf("maximum selection must not exceed the %d options", len(group.Options))
+	}
+
+	names := make(map[string]bool)
+	defaults := 0
+	for _, option := range group.Options {
+		if err := optionName(&option.Name, names, "option"); err != nil {
+			return err
+		}
+		if math.IsNaN(option.PriceDelta) || math.Abs(option.PriceDelta) > maxPriceDelta {
+			return fmt.Errorf("option %s must change the price by at most %d", option.Name, maxPriceDelta)
+		}
+		if option.Default {
+			defaults++
+		}
+		option.ID = uniqueID(option.ID, ids)
+	}
+	if defaults > group.MaxSelect {
+		return fmt.Errorf("at most %d options can be selected by default", group.MaxSelect)
+	}
+
+	group.ID = uniqueID(group.ID, ids)
+	return nil
+}
+
+// optionName trims the name and checks it is set and not taken, names are
+// compared case insensitively
+func optionName(name *string, taken map[string]bool, kind string) error {
+	*name = strings.TrimSpace(*name)
+	if *name == "" {
+		return fmt.Errorf("%s name is required", kind)
+	}
+	if len(*name) > maxOptionName {
+		return fmt.Errorf("%s name can be at most %d characters", kind, maxOptionName)
+	}
+
+	key := strings.ToLower(*name)
+	if taken[key] {
+		return fmt.Errorf("%s %s is listed twice", kind, *name)
+	}
+	taken[key] = true
+
+	return nil
+}
+
+// uniqueID keeps the id unless it is missing or already used by another
+// choice of the item
+func uniqueID(id uuid.UUID, taken map[uuid.UUID]bool) uuid.UUID {
+	if id == uuid.Nil || taken[id] {
+		id = uuid.New()
+	}
+	taken[id] = true
+	return id
+}
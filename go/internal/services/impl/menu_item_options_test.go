@@ -0,0 +1,75 @@
+package impl
+
+import (
+	"testing"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestNormalizeItemOptions(t *testing.T) {
+	kept := uuid.New()
+	item := &models.MenuCategoryItem{
+		Price: 99,
+		Variants: []*models.MenuItemVariant{
+			{ID: kept, Name: " Small ", Price: 8},
+			{Name: "Large", Price: 12, Default: true},
+		},
+		ModifierGroups: []*models.ModifierGroup{
+			{Name: "Sauce", MinSelect: 1, MaxSelect: 1, Options: []*models.ModifierOption{
+				{ID: kept, Name: "Garlic"}, {Name: "Chili", Default: true},
+			}},
+			{Name: "Extras", MaxSelect: 2, Options: []*models.ModifierOption{
+				{Name: "Cheese", PriceDelta: 1.5}, {Name: "No onions", PriceDelta: -0.5},
+			}},
+		},
+	}
+	if err := normalizeItemOptions(item); err != nil {
+		t.Fatal(err)
+	}
+
+	if item.Price != 12 {
+		t.Errorf("expected the default variant's price, got %v", item.Price)
+	}
+	if item.Variants[0].Name != "Small" || item.Variants[0].ID != kept {
+		t.Errorf("unexpected first variant %+v", item.Variants[0])
+	}
+	ids := map[uuid.UUID]bool{}
+	for _, variant := range item.Variants {
+		ids[variant.ID] = true
+	}
+	for _, group := range item.ModifierGroups {
+		ids[group.ID] = true
+		for _, option := range group.Options {
+			ids[option.ID] = true
+		}
+	}
+	if len(ids) != 8 || ids[uuid.Nil] {
+		t.Errorf("expected 8 distinct ids, got %d", len(ids))
+	}
+
+	options := func(names ...string) []*models.ModifierOption {
+		result := make([]*models.ModifierOption, 0, len(names))
+		for _, name := range names {
+			result = append(result, &models.ModifierOption{Name: name})
+		}
+		return result
+	}
+	for name, invalid := range map[string]*models.MenuCategoryItem{
+		"variant name":     {Variants: []*models.MenuItemVariant{{Name: " ", Price: 1}}},
+		"variant twice":    {Variants: []*models.MenuItemVariant{{Name: "Small"}, {Name: "small"}}},
+		"negative price":   {Variants: []*models.MenuItemVariant{{Name: "Small", Price: -1}}},
+		"two defaults":     {Variants: []*models.MenuItemVariant{{Name: "Small", Default: true}, {Name: "Large", Default: true}}},
+		"no options":       {ModifierGroups: []*models.ModifierGroup{{Name: "Sauce", MaxSelect: 1}}},
+		"min above max":    {ModifierGroups: []*models.ModifierGroup{{Name: "Sauce", MinSelect: 2, MaxSelect: 1, Options: options("A", "B")}}},
+		"max above count":  {ModifierGroups: []*models.ModifierGroup{{Name: "Sauce", MaxSelect: 3, Options: options("A", "B")}}},
+		"zero max":         {ModifierGroups: []*models.ModifierGroup{{Name: "Sauce", Options: options("A")}}},
+		"option twice":     {ModifierGroups: []*models.ModifierGroup{{Name: "Sauce", MaxSelect: 1, Options: options("A", "a")}}},
+		"group twice":      {ModifierGroups: []*models.ModifierGroup{{Name: "Sauce", MaxSelect: 1, Options: options("A")}, {Name: "Sauce", MaxSelect: 1, Options: options("B")}}},
+		"too many default": {ModifierGroups: []*models.ModifierGroup{{Name: "Sauce", MaxSelect: 1, Options: []*models.ModifierOption{{Name: "A", Default: true}, {Name: "B", Default: true}}}}},
+	} {
+		if err := normalizeItemOptions(invalid); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -3,34 +3,49 @@ package impl
 import (
 	"context"
 	"fmt"
+	"slices"
 
+	"github.com/ahmetkoprulu/bidi-menu/common/qrcode"
 	"github.com/ahmetkoprulu/bidi-menu/common/utils"
 	"github.com/ahmetkoprulu/bidi-menu/internal/models"
 	"github.com/ahmetkoprulu/bidi-menu/internal/repository"
 	"github.com/ahmetkoprulu/bidi-menu/internal/services"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 type menuService struct {
-	ocrService OCRService
-	menuRepo   repository.MenuRepository
-	logger     *utils.Loggger
+	menuRepo         repository.MenuRepository
+	shortLinkService services.ShortLinkService
+	qrCodeService    services.QRCodeService
+	logger           *utils.Loggger
 }
 
-func NewMenuService(menuRepo repository.MenuRepository) services.MenuService {
+func NewMenuService(menuRepo repository.MenuRepository, shortLinkService services.ShortLinkService, qrCodeService services.QRCodeService) services.MenuService {
 	return &menuService{
-		menuRepo:   menuRepo,
-		logger:     utils.Logger,
-		ocrService: NewOCRService(),
+		menuRepo:         menuRepo,
+		shortLinkService: shortLinkService,
+		qrCodeService:    qrCodeService,
+		logger:           utils.Logger,
 	}
 }
 
-func (s *menuService) DeleteMenu(ctx context.Context, id uuid.UUID) error {
-	err := s.menuRepo.DeleteMenu(ctx, id)
+// DeleteMenu deletes the menu if the tenant owns it. Menus of other clients are
+// reported as not found.
+func (s *menuService) DeleteMenu(ctx context.Context, tenant models.Tenant, id uuid.UUID) error {
+	if _, err := s.GetDraftMenu(ctx, tenant, id); err != nil {
+		return fmt.Errorf("failed to delete menu: %w", err)
+	}
+
+	err := s.menuRepo.DeleteMenu(ctx, id, tenant)
 	if err != nil {
 		return fmt.Errorf("failed to delete menu: %w", err)
 	}
 
+	if err := s.qrCodeService.DeleteQRCode(id); err != nil {
+		s.logError("Failed to delete menu qr code", err, zap.String("menuId", id.String()))
+	}
+
 	return nil
 }
 
@@ -43,31 +58,161 @@ func (s *menuService) GetMenuById(ctx context.Context, id uuid.UUID) (*models.Me
 	return menu, nil
 }
 
-func (s *menuService) SaveMenu(ctx context.Context, model models.Menu) (uuid.UUID, error) {
+// GetPublishedMenu returns the version of the menu guests see
+func (s *menuService) GetPublishedMenu(ctx context.Context, id uuid.UUID) (*models.Menu, error) {
+	menu, err := s.menuRepo.GetPublishedMenu(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get published menu: %w", err)
+	}
+
+	// guests load the optimized variant of the glb files
+	for _, category := range menu.Categories {
+		for _, item := range category.MenuItems {
+			if item.Model != nil {
+				item.Model.GlbFile = item.Model.PublicGlbFile()
+			}
+		}
+	}
+
+	return menu, nil
+}
+
+// GetDraftMenu returns the editable menu if the tenant owns it. Menus of other
+// clients are reported as not found.
+func (s *menuService) GetDraftMenu(ctx context.Context, tenant models.Tenant, id uuid.UUID) (*models.Menu, error) {
+	menu, err := s.menuRepo.GetMenuById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !tenant.Owns(menu.ClientID) {
+		return nil, models.ErrNotFound
+	}
+
+	return menu, nil
+}
+
+// GetMissingTranslations lists the texts of the draft menu that are not
+// translated yet, to one locale or to all the menu is offered in
+func (s *menuService) GetMissingTranslations(ctx context.Context, tenant models.Tenant, id uuid.UUID, locale string) (*models.TranslationReport, error) {
+	menu, err := s.GetDraftMenu(ctx, tenant, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if locale != "" && !slices.Contains(menu.OfferedLocales(), locale) {
+		return nil, fmt.Errorf("menu is not offered in locale %q", locale)
+	}
+
+	return missingTranslations(menu, locale), nil
+}
+
+// ExportQRCode renders the QR code of the menu for print
+func (s *menuService) ExportQRCode(ctx context.Context, tenant models.Tenant, id uuid.UUID, opts qrcode.ExportOptions) (*models.QRCodeExport, error) {
+	menu, err := s.GetDraftMenu(ctx, tenant, id)
+	if err != nil {
+		return nil, err
+	}
+
+	export, err := s.qrCodeService.ExportMenuQRCode(ctx, menu, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export qr code: %w", err)
+	}
+
+	return export, nil
+}
+
+// SaveMenu creates or updates the menu and renders its QR code, which is set on
+// the given menu
+func (s *menuService) SaveMenu(ctx context.Context, tenant models.Tenant, model *models.Menu) (uuid.UUID, error) {
+	if !tenant.Owns(model.ClientID) {
+		return uuid.Nil, models.ErrNotFound
+	}
+
+	for _, category := range model.Categories {
+		for _, item := range category.MenuItems {
+			if err := normalizeMenuItem(item); err != nil {
+				return uuid.Nil, fmt.Errorf("item %s: %w", item.Name, err)
+			}
+		}
+	}
+
+	if err := normalizeMenuLocales(model); err != nil {
+		return uuid.Nil, err
+	}
+	if err := normalizeMenuTranslations(model); err != nil {
+		return uuid.Nil, err
+	}
+	if err := normalizeCustomization(model.Customization, model.Locales); err != nil {
+		return uuid.Nil, err
+	}
+
 	if model.ID == nil {
-		menuID, err := s.menuRepo.CreateMenu(ctx, &model)
+		menuID, err := s.menuRepo.CreateMenu(ctx, model)
 		if err != nil {
 			return uuid.Nil, fmt.Errorf("failed to create menu: %w", err)
 		}
 
+		s.refreshQRCode(ctx, tenant, model)
 		return menuID, nil
 	}
 
-	err := s.menuRepo.UpdateMenu(ctx, &model)
+	// the stored menu has to belong to the same client, menus are not moved
+	// between clients
+	existing, err := s.menuRepo.GetMenuById(ctx, *model.ID)
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("failed to update menu: %w", err)
 	}
+	if existing.ClientID != model.ClientID {
+		return uuid.Nil, models.ErrNotFound
+	}
 
+	err = s.menuRepo.UpdateMenu(ctx, model, tenant)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to update menu: %w", err)
+	}
+
+	s.refreshQRCode(ctx, tenant, model)
 	return *model.ID, nil
 }
 
-func (s *menuService) ScanMenu(ctx context.Context, clientID uuid.UUID, imagePaths []string) (*models.Menu, error) {
-	menu, err := s.ocrService.ScanMenu(ctx, imagePaths)
+// normalizeMenuItem checks an item before it is saved, the choices, the
+// dietary information and the translations are put in their canonical form.
+// Whether the menu is offered in the translated locales is up to the caller.
+func normalizeMenuItem(item *models.MenuCategoryItem) error {
+	if err := normalizeItemOptions(item); err != nil {
+		return err
+	}
+	if err := normalizeItemFacts(item); err != nil {
+		return err
+	}
+
+	var err error
+	item.Translations, err = normalizeTranslations(item.Translations, models.MenuChangeEntityItem, models.SupportedLocales)
+	return err
+}
+
+// refreshQRCode renders the QR code of a saved menu again, so it follows the
+// current customization and client logo. The code prints the menu's short
+// link, which is created for new menus. The menu is saved either way, a
+// failure only leaves the previous QR code in place.
+func (s *menuService) refreshQRCode(ctx context.Context, tenant models.Tenant, menu *models.Menu) {
+	if _, err := s.shortLinkService.EnsureMenuLink(ctx, tenant, menu); err != nil {
+		s.logError("Failed to create menu short link", err, zap.String("menuId", menu.ID.String()))
+	}
+
+	qrCode, err := s.qrCodeService.GenerateMenuQRCode(ctx, menu)
 	if err != nil {
-		return nil, fmt.Errorf("failed to scan menu: %w", err)
+		s.logError("Failed to generate menu qr code", err, zap.String("menuId", menu.ID.String()))
+		return
 	}
 
-	return menu, nil
+	if err := s.menuRepo.UpdateMenuQRCode(ctx, *menu.ID, qrCode, tenant); err != nil {
+		s.logError("Failed to save menu qr code", err, zap.String("menuId", menu.ID.String()))
+		return
+	}
+
+	menu.QRCode = qrCode
 }
 
 func (s *menuService) GetMenu(ctx context.Context, clientID uuid.UUID) ([]*models.MenuCategory, error) {
@@ -79,8 +224,36 @@ func (s *menuService) GetMenu(ctx context.Context, clientID uuid.UUID) ([]*model
 	return categories, nil
 }
 
-func (s *menuService) UpdateCategoryOrder(ctx context.Context, categoryID uuid.UUID, order int) error {
-	err := s.menuRepo.UpdateCategoryOrder(ctx, categoryID, order)
+func (s *menuService) CreateCategory(ctx context.Context, tenant models.Tenant, category *models.MenuCategory) error {
+	var err error
+	if category.Translations, err = normalizeTranslations(category.Translations, models.MenuChangeEntityCategory, models.SupportedLocales); err != nil {
+		return err
+	}
+
+	err = s.menuRepo.CreateCategory(ctx, category, tenant)
+	if err != nil {
+		return fmt.Errorf("failed to create category: %w", err)
+	}
+
+	return nil
+}
+
+func (s *menuService) UpdateCategory(ctx context.Context, tenant models.Tenant, category *models.MenuCategory) error {
+	var err error
+	if category.Translations, err = normalizeTranslations(category.Translations, models.MenuChangeEntityCategory, models.SupportedLocales); err != nil {
+		return err
+	}
+
+	err = s.menuRepo.UpdateCategory(ctx, category, tenant)
+	if err != nil {
+		return fmt.Errorf("failed to update category: %w", err)
+	}
+
+	return nil
+}
+
+func (s *menuService) UpdateCategoryOrder(ctx context.Context, tenant models.Tenant, categoryID uuid.UUID, order int) error {
+	err := s.menuRepo.UpdateCategoryOrder(ctx, categoryID, order, tenant)
 	if err != nil {
 		return fmt.Errorf("failed to update category order: %w", err)
 	}
@@ -88,8 +261,8 @@ func (s *menuService) UpdateCategoryOrder(ctx context.Context, categoryID uuid.U
 	return nil
 }
 
-func (s *menuService) DeleteCategory(ctx context.Context, categoryID uuid.UUID) error {
-	err := s.menuRepo.DeleteCategory(ctx, categoryID)
+func (s *menuService) DeleteCategory(ctx context.Context, tenant models.Tenant, categoryID uuid.UUID) error {
+	err := s.menuRepo.DeleteCategory(ctx, categoryID, tenant)
 	if err != nil {
 		return fmt.Errorf("failed to delete category: %w", err)
 	}
@@ -97,8 +270,43 @@ func (s *menuService) DeleteCategory(ctx context.Context, categoryID uuid.UUID)
 	return nil
 }
 
-func (s *menuService) DeleteMenuItem(ctx context.Context, itemID uuid.UUID) error {
-	err := s.menuRepo.DeleteMenuItem(ctx, itemID)
+func (s *menuService) CreateMenuItem(ctx context.Context, tenant models.Tenant, categoryID uuid.UUID, item *models.MenuCategoryItem) error {
+	if err := normalizeMenuItem(item); err != nil {
+		return err
+	}
+
+	err := s.menuRepo.CreateMenuItem(ctx, categoryID, item, tenant)
+	if err != nil {
+		return fmt.Errorf("failed to create menu item: %w", err)
+	}
+
+	return nil
+}
+
+func (s *menuService) UpdateMenuItem(ctx context.Context, tenant models.Tenant, item *models.MenuCategoryItem) error {
+	if err := normalizeMenuItem(item); err != nil {
+		return err
+	}
+
+	err := s.menuRepo.UpdateMenuItem(ctx, item, tenant)
+	if err != nil {
+		return fmt.Errorf("failed to update menu item: %w", err)
+	}
+
+	return nil
+}
+
+func (s *menuService) MoveMenuItem(ctx context.Context, tenant models.Tenant, itemID uuid.UUID, categoryID uuid.UUID, order int) error {
+	err := s.menuRepo.MoveMenuItem(ctx, itemID, categoryID, order, tenant)
+	if err != nil {
+		return fmt.Errorf("failed to move menu item: %w", err)
+	}
+
+	return nil
+}
+
+func (s *menuService) DeleteMenuItem(ctx context.Context, tenant models.Tenant, itemID uuid.UUID) error {
+	err := s.menuRepo.DeleteMenuItem(ctx, itemID, tenant)
 	if err != nil {
 		return fmt.Errorf("failed to delete menu item: %w", err)
 	}
@@ -106,8 +314,8 @@ func (s *menuService) DeleteMenuItem(ctx context.Context, itemID uuid.UUID) erro
 	return nil
 }
 
-func (s *menuService) UpdateItemImages(ctx context.Context, itemID uuid.UUID, images []string) error {
-	err := s.menuRepo.UpdateItemImages(ctx, itemID, images)
+func (s *menuService) UpdateItemImages(ctx context.Context, tenant models.Tenant, itemID uuid.UUID, images []string) error {
+	err := s.menuRepo.UpdateItemImages(ctx, itemID, images, tenant)
 	if err != nil {
 		return fmt.Errorf("failed to update item images: %w", err)
 	}
@@ -115,8 +323,8 @@ func (s *menuService) UpdateItemImages(ctx context.Context, itemID uuid.UUID, im
 	return nil
 }
 
-func (s *menuService) ReorderCategories(ctx context.Context, categoryOrders map[uuid.UUID]int) error {
-	err := s.menuRepo.ReorderCategories(ctx, categoryOrders)
+func (s *menuService) ReorderCategories(ctx context.Context, tenant models.Tenant, categoryOrders map[uuid.UUID]int) error {
+	err := s.menuRepo.ReorderCategories(ctx, categoryOrders, tenant)
 	if err != nil {
 		return fmt.Errorf("failed to reorder categories: %w", err)
 	}
@@ -124,8 +332,8 @@ func (s *menuService) ReorderCategories(ctx context.Context, categoryOrders map[
 	return nil
 }
 
-func (s *menuService) UpdateCategoryStatus(ctx context.Context, categoryID uuid.UUID, status models.MenuStatus) error {
-	err := s.menuRepo.UpdateCategoryStatus(ctx, categoryID, status)
+func (s *menuService) UpdateCategoryStatus(ctx context.Context, tenant models.Tenant, categoryID uuid.UUID, status models.MenuStatus) error {
+	err := s.menuRepo.UpdateCategoryStatus(ctx, categoryID, status, tenant)
 	if err != nil {
 		return fmt.Errorf("failed to update category status: %w", err)
 	}
@@ -133,8 +341,8 @@ func (s *menuService) UpdateCategoryStatus(ctx context.Context, categoryID uuid.
 	return nil
 }
 
-func (s *menuService) UpdateItemsStatus(ctx context.Context, itemIDs []uuid.UUID, status models.MenuStatus) error {
-	err := s.menuRepo.UpdateItemsStatus(ctx, itemIDs, status)
+func (s *menuService) UpdateItemsStatus(ctx context.Context, tenant models.Tenant, itemIDs []uuid.UUID, status models.MenuStatus) error {
+	err := s.menuRepo.UpdateItemsStatus(ctx, itemIDs, status, tenant)
 	if err != nil {
 		return fmt.Errorf("failed to update items status: %w", err)
 	}
@@ -150,3 +358,133 @@ func (s *menuService) RemoveModelFromMenuItems(ctx context.Context, modelID uuid
 
 	return nil
 }
+
+// PublishMenu stores the current draft as a new version and serves it to guests
+func (s *menuService) PublishMenu(ctx context.Context, tenant models.Tenant, id uuid.UUID) (*models.MenuVersion, error) {
+	return s.publish(ctx, tenant, id, nil)
+}
+
+func (s *menuService) GetMenuVersions(ctx context.Context, tenant models.Tenant, id uuid.UUID) ([]*models.MenuVersion, error) {
+	if _, err := s.GetDraftMenu(ctx, tenant, id); err != nil {
+		return nil, fmt.Errorf("failed to get menu versions: %w", err)
+	}
+
+	versions, err := s.menuRepo.GetMenuVersions(ctx, id, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu versions: %w", err)
+	}
+
+	return versions, nil
+}
+
+func (s *menuService) GetMenuVersion(ctx context.Context, tenant models.Tenant, id uuid.UUID, version int) (*models.MenuVersion, error) {
+	if _, err := s.GetDraftMenu(ctx, tenant, id); err != nil {
+		return nil, fmt.Errorf("failed to get menu version: %w", err)
+	}
+
+	menuVersion, err := s.menuRepo.GetMenuVersion(ctx, id, version, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu version: %w", err)
+	}
+
+	return menuVersion, nil
+}
+
+// DiffMenuVersions lists the changes between two versions of a menu, or between
+// a version and the current draft when toVersion is nil
+func (s *menuService) DiffMenuVersions(ctx context.Context, tenant models.Tenant, id uuid.UUID, fromVersion int, toVersion *int) (*models.MenuVersionDiff, error) {
+	from, err := s.GetMenuVersion(ctx, tenant, id, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	var to *models.Menu
+	if toVersion != nil {
+		version, err := s.GetMenuVersion(ctx, tenant, id, *toVersion)
+		if err != nil {
+			return nil, err
+		}
+		to = version.Snapshot
+	} else {
+		to, err = s.GetDraftMenu(ctx, tenant, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get menu: %w", err)
+		}
+	}
+
+	return &models.MenuVersionDiff{
+		MenuID:      id,
+		FromVersion: fromVersion,
+		ToVersion:   toVersion,
+		Changes:     diffMenus(from.Snapshot, to),
+	}, nil
+}
+
+// RollbackMenu restores the draft to an earlier version and publishes it as a
+// new version, so the history stays intact
+func (s *menuService) RollbackMenu(ctx context.Context, tenant models.Tenant, id uuid.UUID, version int) (*models.MenuVersion, error) {
+	draft, err := s.GetDraftMenu(ctx, tenant, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to roll back menu: %w", err)
+	}
+
+	source, err := s.menuRepo.GetMenuVersion(ctx, id, version, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to roll back menu: %w", err)
+	}
+
+	restored := *source.Snapshot
+	restored.ID = draft.ID
+	restored.ClientID = draft.ClientID
+	for _, category := range restored.Categories {
+		for _, item := range category.MenuItems {
+			// nil images keep the stored ones, the snapshot has to win here
+			if item.Images == nil {
+				item.Images = []string{}
+			}
+		}
+	}
+
+	if err := s.menuRepo.UpdateMenu(ctx, &restored, tenant); err != nil {
+		return nil, fmt.Errorf("failed to roll back menu: %w", err)
+	}
+	s.refreshQRCode(ctx, tenant, &restored)
+
+	return s.publish(ctx, tenant, id, &version)
+}
+
+// publish snapshots the stored draft, rather than the one passed around, so the
+// version holds exactly what was saved
+func (s *menuService) publish(ctx context.Context, tenant models.Tenant, id uuid.UUID, sourceVersion *int) (*models.MenuVersion, error) {
+	draft, err := s.GetDraftMenu(ctx, tenant, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish menu: %w", err)
+	}
+
+	draft.PublishedVersion = nil
+	draft.PublishedAt = nil
+
+	publishedBy := tenant.ClientID
+	version := &models.MenuVersion{
+		MenuID:        id,
+		Snapshot:      draft,
+		SourceVersion: sourceVersion,
+		PublishedBy:   &publishedBy,
+	}
+
+	if err := s.menuRepo.CreateMenuVersion(ctx, version, tenant); err != nil {
+		return nil, fmt.Errorf("failed to publish menu: %w", err)
+	}
+
+	// the snapshot is served through the version endpoint
+	version.Snapshot = nil
+	return version, nil
+}
+
+func (s *menuService) logError(message string, err error, fields ...zap.Field) {
+	if s.logger == nil {
+		return
+	}
+
+	s.logger.Error(message, append(fields, zap.Error(err))...)
+}
@@ -0,0 +1,106 @@
+package impl
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+)
+
+const maxFontSpacing = 64
+
+var (
+	hexColorPattern = regexp.MustCompile(`^#([0-9a-f]{3}|[0-9a-f]{6})$`)
+	// fontNamePattern keeps font names to what font services use, they end up
+	// in style sheets
+	fontNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 -]{0,63}$`)
+)
+
+// normalizeCustomization checks the theme of a menu, colors are put in
+// lower case with six digits. Fonts can only be set for the given locales.
+func normalizeCustomization(customization *models.MenuCustomization, locales []string) error {
+	if customization == nil {
+		return nil
+	}
+
+	if colors := customization.Colors; colors != nil {
+		for name, color := range map[string]*string{
+			"primary":     &colors.Primary,
+			"secondary":   &colors.Secondary,
+			"text":        &colors.Text,
+			"background":  &colors.Background,
+			"price":       &colors.Price,
+			"heading":     &colors.Heading,
+			"description": &colors.Description,
+			"header text": &colors.HeaderText,
+		} {
+			if err := normalizeHexColor(color); err != nil {
+				return fmt.Errorf("%s color: %w", name, err)
+			}
+		}
+	}
+
+	if qr := customization.QRCode; qr != nil {
+		for name, color := range map[string]*string{
+			"qr code":    &qr.QRColor,
+			"logo":       &qr.LogoColor,
+			"background": &qr.BackgroundColor,
+		} {
+			if err := normalizeHexColor(color); err != nil {
+				return fmt.Errorf("qr code %s color: %w", name, err)
+			}
+		}
+	}
+
+	if err := normalizeFont(customization.Font); err != nil {
+		return err
+	}
+	for locale, font := range customization.Fonts {
+		if !slices.Contains(locales, locale) {
+			return fmt.Errorf("font for locale %q the menu is not offered in", locale)
+		}
+		if err := normalizeFont(font); err != nil {
+			return fmt.Errorf("locale %s: %w", locale, err)
+		}
+	}
+
+	return nil
+}
+
+func normalizeHexColor(color *string) error {
+	value := strings.ToLower(strings.TrimSpace(*color))
+	if value == "" {
+		*color = ""
+		return nil
+	}
+	if !hexColorPattern.MatchString(value) {
+		return fmt.Errorf("invalid hex color %q, expected #rgb or #rrggbb", *color)
+	}
+
+	if len(value) == 4 {
+		value = string([]byte{'#', value[1], value[1], value[2], value[2], value[3], value[3]})
+	}
+
+	*color = value
+	return nil
+}
+
+func normalizeFont(font *models.Font) error {
+	if font == nil {
+		return nil
+	}
+
+	for _, name := range []*string{&font.Heading, &font.Body} {
+		*name = strings.TrimSpace(*name)
+		if *name != "" && !fontNamePattern.MatchString(*name) {
+			return fmt.Errorf("invalid font name %q", *name)
+		}
+	}
+	if font.Spacing < 0 || font.Spacing > maxFontSpacing {
+		return fmt.Errorf("font spacing must be between 0 and %d", maxFontSpacing)
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package impl
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/google/uuid"
+)
+
+// normalizeMenuLocales checks the languages of a menu and puts them in their
+// canonical order, the default locale is always offered
+func normalizeMenuLocales(menu *models.Menu) error {
+	menu.DefaultLocale = strings.ToLower(strings.TrimSpace(menu.DefaultLocale))
+	if menu.DefaultLocale == "" {
+		menu.DefaultLocale = models.DefaultLocale
+	}
+	if !slices.Contains(models.SupportedLocales, menu.DefaultLocale) {
+		return fmt.Errorf("unsupported default locale %q", menu.DefaultLocale)
+	}
+
+	locales := make([]string, 0, len(menu.Locales)+1)
+	for _, locale := range menu.Locales {
+		locales = append(locales, strings.ToLower(strings.TrimSpace(locale)))
+	}
+	locales = append(locales, menu.DefaultLocale)
+
+	locales, err := canonical(locales, models.SupportedLocales, "locale")
+	if err != nil {
+		return err
+	}
+
+	menu.Locales = locales
+	return nil
+}
+
+// normalizeMenuTranslations checks the translations of a menu and of its
+// categories and items against the locales the menu is offered in
+func normalizeMenuTranslations(menu *models.Menu) error {
+	locales := slices.DeleteFunc(slices.Clone(menu.Locales), func(locale string) bool {
+		return locale == menu.DefaultLocale
+	})
+
+	var err error
+	if menu.Translations, err = normalizeTranslations(menu.Translations, models.MenuChangeEntityMenu, locales); err != nil {
+		return err
+	}
+
+	for _, category := range menu.Categories {
+		if category.Translations, err = normalizeTranslations(category.Translations, models.MenuChangeEntityCategory, locales); err != nil {
+			return fmt.Errorf("category %s: %w", category.Name, err)
+		}
+
+		for _, item := range category.MenuItems {
+			if item.Translations, err = normalizeTranslations(item.Translations, models.MenuChangeEntityItem, locales); err != nil {
+				return fmt.Errorf("item %s: %w", item.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// normalizeTranslations trims the texts and drops the empty ones. Menus only
+// translate their label and description, categories their name and items
+// their name and description.
+func normalizeTranslations(translations models.Translations, entity models.MenuChangeEntity, locales []string) (models.Translations, error) {
+	normalized := make(models.Translations, len(translations))
+	for locale, translation := range translations {
+		if translation == nil {
+			continue
+		}
+		if !slices.Contains(locales, locale) {
+			return nil, fmt.Errorf("translation for locale %q the menu is not offered in", locale)
+		}
+
+		t := &models.Translation{
+			Label:       strings.TrimSpace(translation.Label),
+			Name:        strings.TrimSpace(translation.Name),
+			Description: strings.TrimSpace(translation.Description),
+		}
+		switch {
+		case t.Label != "" && entity != models.MenuChangeEntityMenu:
+			return nil, fmt.Errorf("only menus have a translated label")
+		case t.Name != "" && entity == models.MenuChangeEntityMenu:
+			return nil, fmt.Errorf("menus have a translated label, not a name")
+		case t.Description != "" && entity == models.MenuChangeEntityCategory:
+			return nil, fmt.Errorf("categories have no translated description")
+		}
+
+		if *t != (models.Translation{}) {
+			normalized[locale] = t
+		}
+	}
+
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+
+	return normalized, nil
+}
+
+// missingTranslations lists the fields with text in the default locale but
+// none in one of the other locales of the menu, or only in the given one
+func missingTranslations(menu *models.Menu, locale string) *models.TranslationReport {
+	report := &models.TranslationReport{
+		DefaultLocale: menu.BaseLocale(),
+		Locales:       menu.OfferedLocales(),
+		Missing:       make([]*models.MissingTranslation, 0),
+	}
+
+	for _, l := range report.Locales[1:] {
+		if locale != "" && l != locale {
+			continue
+		}
+
+		missing := func(entity models.MenuChangeEntity, id *uuid.UUID, field, text, translated string) {
+			if text != "" && translated == "" {
+				report.Missing = append(report.Missing, &models.MissingTranslation{
+					Locale: l,
+					Entity: entity,
+					ID:     id,
+					Field:  field,
+					Text:   text,
+				})
+			}
+		}
+
+		t := translationOf(menu.Translations, l)
+		missing(models.MenuChangeEntityMenu, menu.ID, "label", menu.Label, t.Label)
+		missing(models.MenuChangeEntityMenu, menu.ID, "description", menu.Description, t.Description)
+
+		for _, category := range menu.Categories {
+			t := translationOf(category.Translations, l)
+			missing(models.MenuChangeEntityCategory, &category.ID, "name", category.Name, t.Name)
+
+			for _, item := range category.MenuItems {
+				t := translationOf(item.Translations, l)
+				missing(models.MenuChangeEntityItem, &item.ID, "name", item.Name, t.Name)
+				missing(models.MenuChangeEntityItem, &item.ID, "description", item.Description, t.Description)
+			}
+		}
+	}
+
+	return report
+}
+
+func translationOf(translations models.Translations, locale string) models.Translation {
+	if t := translations[locale]; t != nil {
+		return *t
+	}
+	return models.Translation{}
+}
@@ -0,0 +1,51 @@
+package impl
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+)
+
+func TestNormalizeMenuLocales(t *testing.T) {
+	menu := &models.Menu{DefaultLocale: " TR ", Locales: []string{"ar", "en", "ar"}}
+	if err := normalizeMenuLocales(menu); err != nil {
+		t.Fatal(err)
+	}
+	if menu.DefaultLocale != "tr" || !slices.Equal(menu.Locales, []string{"tr", "en", "ar"}) {
+		t.Errorf("expected tr with tr, en and ar, got %s with %v", menu.DefaultLocale, menu.Locales)
+	}
+
+	menu = &models.Menu{}
+	if err := normalizeMenuLocales(menu); err != nil || menu.DefaultLocale != models.DefaultLocale {
+		t.Errorf("expected the default locale, got %q %v", menu.DefaultLocale, err)
+	}
+}
+
+func TestNormalizeTranslations(t *testing.T) {
+	translations, err := normalizeTranslations(models.Translations{
+		"de": {Name: " Frikadellen ", Description: "Gegrillt"},
+		"en": {Name: "  "},
+		"ar": nil,
+	}, models.MenuChangeEntityItem, models.SupportedLocales)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(translations) != 1 || *translations["de"] != (models.Translation{Name: "Frikadellen", Description: "Gegrillt"}) {
+		t.Errorf("expected only the trimmed german translation, got %v", translations)
+	}
+
+	for name, invalid := range map[string]struct {
+		entity       models.MenuChangeEntity
+		translations models.Translations
+	}{
+		"unsupported locale":   {models.MenuChangeEntityItem, models.Translations{"fr": {Name: "Boulettes"}}},
+		"item label":           {models.MenuChangeEntityItem, models.Translations{"de": {Label: "Mittag"}}},
+		"menu name":            {models.MenuChangeEntityMenu, models.Translations{"de": {Name: "Mittag"}}},
+		"category description": {models.MenuChangeEntityCategory, models.Translations{"de": {Description: "Warm"}}},
+	} {
+		if _, err := normalizeTranslations(invalid.translations, invalid.entity, models.SupportedLocales); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -1,53 +1,172 @@
 package impl
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ahmetkoprulu/bidi-menu/common/gltf"
+	"github.com/ahmetkoprulu/bidi-menu/common/mq"
+	"github.com/ahmetkoprulu/bidi-menu/common/storage"
+	"github.com/ahmetkoprulu/bidi-menu/common/thumbnail"
+	"github.com/ahmetkoprulu/bidi-menu/common/usdz"
+	"github.com/ahmetkoprulu/bidi-menu/common/utils"
+	"github.com/ahmetkoprulu/bidi-menu/common/webp"
 	"github.com/ahmetkoprulu/bidi-menu/internal/models"
 	"github.com/ahmetkoprulu/bidi-menu/internal/repository"
 	"github.com/ahmetkoprulu/bidi-menu/internal/services"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
+const (
+	// ModelOptimizationQueue is the queue uploaded glb files are optimized from
+	ModelOptimizationQueue = "model.optimize"
+	// ModelUsdzConversionQueue is the queue usdz files are generated from, for
+	// models uploaded without one
+	ModelUsdzConversionQueue = "model.usdz"
+)
+
+// modelOptimizeOptions keep textures at a size phones render without
+// scaling them down themselves
+var modelOptimizeOptions = gltf.OptimizeOptions{
+	MaxTextureSize: 2048,
+	Quantize:       true,
+}
+
+const (
+	// defaultThumbnailSize is drawn when no sizes are configured
+	defaultThumbnailSize = 512
+	// maxThumbnailSize bounds the memory a drawing takes, larger sizes are
+	// left out
+	maxThumbnailSize = 2048
+)
+
+// thumbnailEncoders are the formats drawn thumbnails are stored in, png
+// first since the thumbnail of the model is a png
+var thumbnailEncoders = []struct {
+	format string
+	encode func(io.Writer, image.Image) error
+}{
+	{"png", (&png.Encoder{CompressionLevel: png.BestCompression}).Encode},
+	{"webp", webp.Encode},
+}
+
 type modelService struct {
-	modelRepo repository.ModelRepository
+	modelRepo      repository.ModelRepository
+	storageService storage.StorageService
+	mqProvider     mq.IMqProvider
+	thumbnails     models.ThumbnailConfig
+	logger         *utils.Loggger
 }
 
-func NewModelService(modelRepo repository.ModelRepository) services.ModelService {
+func NewModelService(modelRepo repository.ModelRepository, storageService storage.StorageService, mqProvider mq.IMqProvider, thumbnails models.ThumbnailConfig) services.ModelService {
 	return &modelService{
-		modelRepo: modelRepo,
+		modelRepo:      modelRepo,
+		storageService: storageService,
+		mqProvider:     mqProvider,
+		thumbnails:     thumbnails,
+		logger:         utils.Logger,
 	}
 }
 
-func (s *modelService) SaveModel(ctx context.Context, model models.Model, isCreate bool) (*uuid.UUID, error) {
+// SaveModel stores the model and queues the optimization of its glb file.
+// Guests are served the uploaded file until the optimized one is ready. Models
+// with a usdz conversion have it queued as well, and no usdz file until it is
+// done.
+func (s *modelService) SaveModel(ctx context.Context, tenant models.Tenant, model models.Model, isCreate bool) (*uuid.UUID, error) {
+	return s.saveModel(ctx, tenant, model, isCreate, nil)
+}
+
+func (s *modelService) ReplaceModel(ctx context.Context, tenant models.Tenant, model models.Model, stagingID uuid.UUID) (*uuid.UUID, error) {
+	if model.ID == nil {
+		return nil, models.ErrNotFound
+	}
+
+	// the paths name the files where they end up
+	staged, final := stagingID.String(), model.ID.String()
+	model.GlbFile = strings.Replace(model.GlbFile, staged, final, 1)
+	model.UsdzFile = strings.Replace(model.UsdzFile, staged, final, 1)
+	model.Thumbnail = strings.Replace(model.Thumbnail, staged, final, 1)
+	for _, thumbnail := range model.Thumbnails {
+		thumbnail.Path = strings.Replace(thumbnail.Path, staged, final, 1)
+	}
+
+	return s.saveModel(ctx, tenant, model, false, &stagingID)
+}
+
+// saveModel creates or updates the model and queues the work on its files.
+// Files of an update stored under a staging id are moved in place before the
+// work is queued, so the workers read the new files.
+func (s *modelService) saveModel(ctx context.Context, tenant models.Tenant, model models.Model, isCreate bool, stagingID *uuid.UUID) (*uuid.UUID, error) {
+	if !tenant.Owns(model.ClientID) {
+		return nil, models.ErrNotFound
+	}
+
+	model.OptimizedGlbFile = ""
+	if model.Optimization == nil {
+		model.Optimization = &models.ModelOptimization{}
+	}
+	model.Optimization.Status = models.ModelOptimizationQueued
+	if model.UsdzConversion != nil {
+		model.UsdzFile = ""
+		model.UsdzConversion = &models.ModelConversion{Status: models.ModelConversionQueued}
+	}
+
 	if isCreate {
 		modelID, err := s.modelRepo.CreateModel(ctx, &model)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create menu: %w", err)
 		}
 
+		s.enqueueOptimization(ctx, *modelID)
+		if model.UsdzConversion != nil {
+			s.enqueueUsdzConversion(ctx, *modelID)
+		}
 		return modelID, nil
 	}
 
-	err := s.modelRepo.UpdateModel(ctx, &model)
+	// the stored model has to belong to the same client, models are not moved
+	// between clients
+	existing, err := s.GetModelById(ctx, tenant, *model.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.ClientID != model.ClientID {
+		return nil, models.ErrNotFound
+	}
+
+	err = s.modelRepo.UpdateModel(ctx, &model, tenant)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update menu: %w", err)
 	}
+	if stagingID != nil {
+		if err := s.storageService.ReplaceModelFiles(*stagingID, *model.ID); err != nil {
+			return nil, fmt.Errorf("failed to replace model files: %w", err)
+		}
+	}
 
+	s.enqueueOptimization(ctx, *model.ID)
+	if model.UsdzConversion != nil {
+		s.enqueueUsdzConversion(ctx, *model.ID)
+	}
 	return model.ID, nil
 }
 
-func (s *modelService) GetModel(ctx context.Context, modelID uuid.UUID) (models.Model, error) {
-	model, err := s.modelRepo.GetModel(ctx, modelID)
-	if err != nil {
-		return models.Model{}, fmt.Errorf("failed to get model: %w", err)
+func (s *modelService) GetModels(ctx context.Context, tenant models.Tenant, clientID uuid.UUID) ([]models.Model, error) {
+	if !tenant.Owns(clientID) {
+		return []models.Model{}, models.ErrNotFound
 	}
 
-	return model, nil
-}
-
-func (s *modelService) GetModels(ctx context.Context, clientID uuid.UUID) ([]models.Model, error) {
 	ms, err := s.modelRepo.GetModels(ctx, clientID)
 	if err != nil {
 		return []models.Model{}, fmt.Errorf("failed to get models: %w", err)
@@ -56,8 +175,12 @@ func (s *modelService) GetModels(ctx context.Context, clientID uuid.UUID) ([]mod
 	return ms, nil
 }
 
-func (s *modelService) DeleteModel(ctx context.Context, modelID uuid.UUID) error {
-	err := s.modelRepo.DeleteModel(ctx, modelID)
+func (s *modelService) DeleteModel(ctx context.Context, tenant models.Tenant, modelID uuid.UUID) error {
+	if _, err := s.GetModelById(ctx, tenant, modelID); err != nil {
+		return err
+	}
+
+	err := s.modelRepo.DeleteModel(ctx, modelID, tenant)
 	if err != nil {
 		return fmt.Errorf("failed to delete model: %w", err)
 	}
@@ -65,11 +188,282 @@ func (s *modelService) DeleteModel(ctx context.Context, modelID uuid.UUID) error
 	return nil
 }
 
-func (s *modelService) GetModelById(ctx context.Context, modelID uuid.UUID) (models.Model, error) {
-	model, err := s.modelRepo.GetModelById(ctx, modelID)
+// GetModelById returns the model if the tenant owns it. Models of other clients
+// are reported as not found.
+func (s *modelService) GetModelById(ctx context.Context, tenant models.Tenant, modelID uuid.UUID) (models.Model, error) {
+	model, err := s.modelRepo.GetModelById(ctx, modelID, tenant)
 	if err != nil {
 		return models.Model{}, fmt.Errorf("failed to get model: %w", err)
 	}
 
+	if !tenant.Owns(model.ClientID) {
+		return models.Model{}, models.ErrNotFound
+	}
+
 	return model, nil
 }
+
+// RenderThumbnails draws the model once at the largest configured size and
+// scales it down for the others. Every size is stored as png and webp, and the
+// largest png is stored again as the thumbnail of the model.
+func (s *modelService) RenderThumbnails(ctx context.Context, modelID uuid.UUID, doc *gltf.Document) (string, []*models.ModelThumbnail, error) {
+	sizes := slices.DeleteFunc(slices.Clone(s.thumbnails.Sizes), func(size int) bool {
+		return size < 1 || size > maxThumbnailSize
+	})
+	slices.Sort(sizes)
+	sizes = slices.Compact(sizes)
+	if len(sizes) == 0 {
+		sizes = []int{defaultThumbnailSize}
+	}
+	largest := sizes[len(sizes)-1]
+
+	drawn, err := thumbnail.Render(doc, thumbnail.Options{Size: largest, Yaw: s.thumbnails.Yaw, Pitch: s.thumbnails.Pitch})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to render thumbnail: %w", err)
+	}
+
+	thumbnails := make([]*models.ModelThumbnail, 0, len(sizes)*len(thumbnailEncoders))
+	var largestPng []byte
+	for _, size := range sizes {
+		img := drawn
+		if size != largest {
+			img = thumbnail.Resize(drawn, size)
+		}
+
+		for _, encoder := range thumbnailEncoders {
+			out := &bytes.Buffer{}
+			if err := encoder.encode(out, img); err != nil {
+				return "", nil, fmt.Errorf("failed to encode %s thumbnail: %w", encoder.format, err)
+			}
+			path, err := s.storageService.SaveRenderedThumbnail(out.Bytes(), modelID, strconv.Itoa(size), "."+encoder.format)
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to save thumbnail: %w", err)
+			}
+
+			thumbnails = append(thumbnails, &models.ModelThumbnail{Size: size, Format: encoder.format, Path: path})
+			if size == largest && encoder.format == "png" {
+				largestPng = out.Bytes()
+			}
+		}
+	}
+
+	path, err := s.storageService.SaveRenderedThumbnail(largestPng, modelID, "", ".png")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to save thumbnail: %w", err)
+	}
+
+	return path, thumbnails, nil
+}
+
+// StartWorkers subscribes the given number of consumers to the optimization
+// and usdz conversion queues. They stop when the context is cancelled.
+func (s *modelService) StartWorkers(ctx context.Context, workers int) error {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	for i := 0; i < workers; i++ {
+		err := s.mqProvider.Subscribe(ctx, ModelOptimizationQueue, func(data []byte) error {
+			return s.processOptimization(ctx, data)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to model optimization queue: %w", err)
+		}
+
+		err = s.mqProvider.Subscribe(ctx, ModelUsdzConversionQueue, func(data []byte) error {
+			return s.processUsdzConversion(ctx, data)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to model usdz conversion queue: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// enqueueOptimization publishes the model to the optimization queue. A model
+// that can not be queued keeps being served as uploaded, so the upload still
+// succeeds.
+func (s *modelService) enqueueOptimization(ctx context.Context, modelID uuid.UUID) {
+	err := s.mqProvider.Publish(ModelOptimizationQueue, models.ModelOptimizationMessage{ModelID: modelID})
+	if err == nil {
+		return
+	}
+
+	s.logError("Failed to enqueue model optimization", err, zap.String("modelId", modelID.String()))
+	optimization := &models.ModelOptimization{Status: models.ModelOptimizationFailed, Reason: "the optimization could not be queued"}
+	if err := s.modelRepo.UpdateModelOptimization(ctx, modelID, "", optimization); err != nil {
+		s.logError("Failed to mark model optimization as failed", err, zap.String("modelId", modelID.String()))
+	}
+}
+
+func (s *modelService) processOptimization(ctx context.Context, data []byte) error {
+	var message models.ModelOptimizationMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		s.logError("Failed to parse model optimization message", err)
+		return fmt.Errorf("failed to parse model optimization message: %w", err)
+	}
+
+	processing := &models.ModelOptimization{Status: models.ModelOptimizationProcessing}
+	err := s.modelRepo.UpdateModelOptimization(ctx, message.ModelID, "", processing)
+	if errors.Is(err, models.ErrNotFound) {
+		// the model was deleted while it waited
+		return nil
+	}
+	if err != nil {
+		s.logError("Failed to start model optimization", err, zap.String("modelId", message.ModelID.String()))
+		return err
+	}
+
+	path, optimization := s.optimizeGlb(message.ModelID)
+	completedAt := time.Now()
+	optimization.CompletedAt = &completedAt
+	if err := s.modelRepo.UpdateModelOptimization(ctx, message.ModelID, path, optimization); err != nil {
+		s.logError("Failed to store model optimization", err, zap.String("modelId", message.ModelID.String()))
+		return err
+	}
+
+	return nil
+}
+
+// optimizeGlb stores the optimized variant of the glb file of a model and
+// returns its path. Models the optimizer can not handle or make smaller are
+// skipped, and have no path. A model the optimizer panics on fails instead of
+// taking the worker down.
+func (s *modelService) optimizeGlb(modelID uuid.UUID) (path string, optimization *models.ModelOptimization) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logError("Model optimization panicked", fmt.Errorf("%v", r), zap.String("modelId", modelID.String()))
+			path, optimization = "", &models.ModelOptimization{Status: models.ModelOptimizationFailed, Reason: fmt.Sprintf("the model could not be processed: %v", r)}
+		}
+	}()
+
+	original, err := s.storageService.ReadGlbModel(modelID)
+	if err != nil {
+		s.logError("Failed to read glb file for optimization", err, zap.String("modelId", modelID.String()))
+		return "", &models.ModelOptimization{Status: models.ModelOptimizationFailed, Reason: "the glb file could not be read"}
+	}
+
+	optimization = &models.ModelOptimization{OriginalSize: int64(len(original))}
+	var optimized []byte
+	doc, err := gltf.Parse(original)
+	if err == nil {
+		doc, err = doc.Optimize(modelOptimizeOptions)
+	}
+	if err == nil {
+		optimized, err = doc.EncodeGLB()
+	}
+
+	switch {
+	case errors.Is(err, gltf.ErrUnsupported):
+		optimization.Status, optimization.Reason = models.ModelOptimizationSkipped, err.Error()
+		return "", optimization
+	case err != nil:
+		s.logError("Failed to optimize glb file", err, zap.String("modelId", modelID.String()))
+		optimization.Status, optimization.Reason = models.ModelOptimizationFailed, err.Error()
+		return "", optimization
+	case len(optimized) >= len(original):
+		optimization.Status, optimization.Reason = models.ModelOptimizationSkipped, "the optimized file is not smaller"
+		return "", optimization
+	}
+
+	path, err = s.storageService.SaveOptimizedGlbModel(optimized, modelID)
+	if err != nil {
+		s.logError("Failed to store optimized glb file", err, zap.String("modelId", modelID.String()))
+		optimization.Status, optimization.Reason = models.ModelOptimizationFailed, "the optimized file could not be stored"
+		return "", optimization
+	}
+
+	optimization.Status = models.ModelOptimizationCompleted
+	optimization.OptimizedSize = int64(len(optimized))
+	return path, optimization
+}
+
+// enqueueUsdzConversion publishes the model to the usdz conversion queue. A
+// model that can not be queued is marked as failed and shown without AR on
+// iOS, so the upload still succeeds.
+func (s *modelService) enqueueUsdzConversion(ctx context.Context, modelID uuid.UUID) {
+	err := s.mqProvider.Publish(ModelUsdzConversionQueue, models.ModelConversionMessage{ModelID: modelID})
+	if err == nil {
+		return
+	}
+
+	s.logError("Failed to enqueue usdz conversion", err, zap.String("modelId", modelID.String()))
+	conversion := &models.ModelConversion{Status: models.ModelConversionFailed, Reason: "the conversion could not be queued"}
+	if err := s.modelRepo.UpdateModelUsdzConversion(ctx, modelID, "", conversion); err != nil {
+		s.logError("Failed to mark usdz conversion as failed", err, zap.String("modelId", modelID.String()))
+	}
+}
+
+func (s *modelService) processUsdzConversion(ctx context.Context, data []byte) error {
+	var message models.ModelConversionMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		s.logError("Failed to parse usdz conversion message", err)
+		return fmt.Errorf("failed to parse usdz conversion message: %w", err)
+	}
+
+	processing := &models.ModelConversion{Status: models.ModelConversionProcessing}
+	err := s.modelRepo.UpdateModelUsdzConversion(ctx, message.ModelID, "", processing)
+	if errors.Is(err, models.ErrNotFound) {
+		// the model was deleted while it waited
+		return nil
+	}
+	if err != nil {
+		s.logError("Failed to start usdz conversion", err, zap.String("modelId", message.ModelID.String()))
+		return err
+	}
+
+	path, conversion := s.convertUsdz(message.ModelID)
+	completedAt := time.Now()
+	conversion.CompletedAt = &completedAt
+	if err := s.modelRepo.UpdateModelUsdzConversion(ctx, message.ModelID, path, conversion); err != nil {
+		s.logError("Failed to store usdz conversion", err, zap.String("modelId", message.ModelID.String()))
+		return err
+	}
+
+	return nil
+}
+
+// convertUsdz generates the usdz file of a model from its uploaded glb file
+// and returns its path. A model the converter panics on fails instead of
+// taking the worker down.
+func (s *modelService) convertUsdz(modelID uuid.UUID) (path string, conversion *models.ModelConversion) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logError("Usdz conversion panicked", fmt.Errorf("%v", r), zap.String("modelId", modelID.String()))
+			path, conversion = "", &models.ModelConversion{Status: models.ModelConversionFailed, Reason: fmt.Sprintf("the model could not be processed: %v", r)}
+		}
+	}()
+
+	original, err := s.storageService.ReadGlbModel(modelID)
+	if err != nil {
+		s.logError("Failed to read glb file for usdz conversion", err, zap.String("modelId", modelID.String()))
+		return "", &models.ModelConversion{Status: models.ModelConversionFailed, Reason: "the glb file could not be read"}
+	}
+
+	var converted []byte
+	doc, err := gltf.Parse(original)
+	if err == nil {
+		converted, err = usdz.Convert(doc)
+	}
+	if err != nil {
+		s.logError("Failed to convert glb file to usdz", err, zap.String("modelId", modelID.String()))
+		return "", &models.ModelConversion{Status: models.ModelConversionFailed, Reason: err.Error()}
+	}
+
+	path, err = s.storageService.SaveGeneratedUsdzModel(converted, modelID)
+	if err != nil {
+		s.logError("Failed to store generated usdz file", err, zap.String("modelId", modelID.String()))
+		return "", &models.ModelConversion{Status: models.ModelConversionFailed, Reason: "the usdz file could not be stored"}
+	}
+
+	return path, &models.ModelConversion{Status: models.ModelConversionCompleted}
+}
+
+func (s *modelService) logError(message string, err error, fields ...zap.Field) {
+	if s.logger == nil {
+		return
+	}
+
+	s.logger.Error(message, append(fields, zap.Error(err))...)
+}
@@ -0,0 +1,363 @@
+package impl
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/gltf"
+	"github.com/ahmetkoprulu/bidi-menu/common/mq"
+	"github.com/ahmetkoprulu/bidi-menu/common/storage"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/repository"
+	"github.com/google/uuid"
+)
+
+type optimizationModelRepository struct {
+	repository.ModelRepository
+	models    map[uuid.UUID]models.Model
+	updateErr error
+}
+
+func (r *optimizationModelRepository) GetModelById(ctx context.Context, modelID uuid.UUID, tenant models.Tenant) (models.Model, error) {
+	model, ok := r.models[modelID]
+	if !ok {
+		return models.Model{}, models.ErrNotFound
+	}
+	return model, nil
+}
+
+func (r *optimizationModelRepository) UpdateModel(ctx context.Context, model *models.Model, tenant models.Tenant) error {
+	if r.updateErr != nil {
+		return r.updateErr
+	}
+	r.models[*model.ID] = *model
+	return nil
+}
+
+func (r *optimizationModelRepository) CreateModel(ctx context.Context, model *models.Model) (*uuid.UUID, error) {
+	r.models[*model.ID] = *model
+	return model.ID, nil
+}
+
+func (r *optimizationModelRepository) UpdateModelUsdzConversion(ctx context.Context, modelID uuid.UUID, usdzFile string, conversion *models.ModelConversion) error {
+	model, ok := r.models[modelID]
+	if !ok {
+		return models.ErrNotFound
+	}
+	model.UsdzFile, model.UsdzConversion = usdzFile, conversion
+	r.models[modelID] = model
+	return nil
+}
+
+func (r *optimizationModelRepository) UpdateModelOptimization(ctx context.Context, modelID uuid.UUID, optimizedGlbFile string, optimization *models.ModelOptimization) error {
+	model, ok := r.models[modelID]
+	if !ok {
+		return models.ErrNotFound
+	}
+	model.OptimizedGlbFile, model.Optimization = optimizedGlbFile, optimization
+	r.models[modelID] = model
+	return nil
+}
+
+// glbStorage keeps the uploaded and optimized glb files and the generated
+// usdz files in memory
+type glbStorage struct {
+	storage.StorageService
+	uploaded, optimized, usdz map[uuid.UUID][]byte
+}
+
+func (s *glbStorage) ReadGlbModel(modelID uuid.UUID) ([]byte, error) {
+	return s.uploaded[modelID], nil
+}
+
+func (s *glbStorage) SaveOptimizedGlbModel(data []byte, modelID uuid.UUID) (string, error) {
+	s.optimized[modelID] = data
+	return "optimized/" + modelID.String(), nil
+}
+
+func (s *glbStorage) SaveGeneratedUsdzModel(data []byte, modelID uuid.UUID) (string, error) {
+	s.usdz[modelID] = data
+	return "usdz/" + modelID.String(), nil
+}
+
+// triangleGlb is a triangle followed by four kilobytes nothing refers to
+func triangleGlb(t *testing.T, extensionsUsed []string) []byte {
+	t.Helper()
+
+	return editedTriangleGlb(t, func(doc map[string]any) {
+		if extensionsUsed != nil {
+			doc["extensionsUsed"] = extensionsUsed
+		}
+	})
+}
+
+// malformedNormalsGlb is the triangle with normals whose sparse indices start
+// before their buffer view
+func malformedNormalsGlb(t *testing.T) []byte {
+	t.Helper()
+
+	return editedTriangleGlb(t, func(doc map[string]any) {
+		doc["accessors"] = append(doc["accessors"].([]any), map[string]any{
+			"componentType": gltf.ComponentFloat, "count": 3, "type": "VEC3",
+			"sparse": map[string]any{
+				"count":   1,
+				"indices": map[string]any{"bufferView": 1, "byteOffset": -4, "componentType": gltf.ComponentUnsignedShort},
+				"values":  map[string]any{"bufferView": 0},
+			},
+		})
+		doc["meshes"] = []any{map[string]any{"primitives": []any{map[string]any{"attributes": map[string]int{"POSITION": 0, "NORMAL": 1}}}}}
+	})
+}
+
+// panickingStorage panics when a worker reads the uploaded glb file
+type panickingStorage struct {
+	*glbStorage
+}
+
+func (s panickingStorage) ReadGlbModel(modelID uuid.UUID) ([]byte, error) {
+	panic("slice bounds out of range [-4:]")
+}
+
+func editedTriangleGlb(t *testing.T, edit func(doc map[string]any)) []byte {
+	t.Helper()
+
+	bin := make([]byte, 36+4096)
+	for i, v := range []float32{0, 0, 0, 1, 0, 0, 0, 1, 0} {
+		binary.LittleEndian.PutUint32(bin[i*4:], math.Float32bits(v))
+	}
+	doc := map[string]any{
+		"asset":       map[string]any{"version": "2.0"},
+		"scene":       0,
+		"scenes":      []any{map[string]any{"nodes": []int{0}}},
+		"nodes":       []any{map[string]any{"mesh": 0}},
+		"meshes":      []any{map[string]any{"primitives": []any{map[string]any{"attributes": map[string]int{"POSITION": 0}}}}},
+		"accessors":   []any{map[string]any{"bufferView": 0, "componentType": gltf.ComponentFloat, "count": 3, "type": "VEC3", "min": []float64{0, 0, 0}, "max": []float64{1, 1, 0}}},
+		"bufferViews": []any{map[string]any{"buffer": 0, "byteLength": 36}, map[string]any{"buffer": 0, "byteOffset": 36, "byteLength": 4096}},
+		"buffers":     []any{map[string]any{"byteLength": len(bin)}},
+	}
+	edit(doc)
+
+	jsonChunk, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for len(jsonChunk)%4 != 0 {
+		jsonChunk = append(jsonChunk, ' ')
+	}
+
+	out := &bytes.Buffer{}
+	length := 12 + 8 + len(jsonChunk) + 8 + len(bin)
+	binary.Write(out, binary.LittleEndian, []uint32{0x46546c67, 2, uint32(length), uint32(len(jsonChunk)), 0x4e4f534a})
+	out.Write(jsonChunk)
+	binary.Write(out, binary.LittleEndian, []uint32{uint32(len(bin)), 0x004e4942})
+	out.Write(bin)
+
+	return out.Bytes()
+}
+
+func TestModelOptimization(t *testing.T) {
+	tests := []struct {
+		name   string
+		glb    func(t *testing.T) []byte
+		panics bool
+		status models.ModelOptimizationStatus
+	}{
+		{"optimized", func(t *testing.T) []byte { return triangleGlb(t, nil) }, false, models.ModelOptimizationCompleted},
+		{"unknown extension", func(t *testing.T) []byte { return triangleGlb(t, []string{"KHR_materials_variants"}) }, false, models.ModelOptimizationSkipped},
+		{"malformed normals", malformedNormalsGlb, false, models.ModelOptimizationFailed},
+		{"panic", func(t *testing.T) []byte { return triangleGlb(t, nil) }, true, models.ModelOptimizationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := &optimizationModelRepository{models: map[uuid.UUID]models.Model{}}
+			files := &glbStorage{uploaded: map[uuid.UUID][]byte{}, optimized: map[uuid.UUID][]byte{}}
+			var store storage.StorageService = files
+			if tt.panics {
+				store = panickingStorage{files}
+			}
+			service := NewModelService(repo, store, mq.NewMemoryMqProvider(1), models.ThumbnailConfig{}).(*modelService)
+
+			clientID, modelID := uuid.New(), uuid.New()
+			files.uploaded[modelID] = tt.glb(t)
+			_, err := service.SaveModel(ctx, models.Tenant{ClientID: clientID}, models.Model{ID: &modelID, ClientID: clientID, GlbFile: "original"}, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status := repo.models[modelID].Optimization.Status; status != models.ModelOptimizationQueued {
+				t.Fatalf("expected the optimization to be queued, got %s", status)
+			}
+
+			message, _ := json.Marshal(models.ModelOptimizationMessage{ModelID: modelID})
+			if err := service.processOptimization(ctx, message); err != nil {
+				t.Fatal(err)
+			}
+
+			model := repo.models[modelID]
+			optimization := model.Optimization
+			if optimization.Status != tt.status || optimization.CompletedAt == nil {
+				t.Fatalf("unexpected optimization %+v", optimization)
+			}
+			if tt.status == models.ModelOptimizationFailed {
+				if model.PublicGlbFile() != "original" || optimization.Reason == "" {
+					t.Errorf("expected failed models to be served as uploaded with a reason, got %q %+v", model.PublicGlbFile(), optimization)
+				}
+				return
+			}
+			if optimization.OriginalSize != int64(len(files.uploaded[modelID])) {
+				t.Fatalf("unexpected optimization %+v", optimization)
+			}
+			if tt.status != models.ModelOptimizationCompleted {
+				if model.PublicGlbFile() != "original" || optimization.Reason == "" {
+					t.Errorf("expected skipped models to be served as uploaded with a reason, got %q %+v", model.PublicGlbFile(), optimization)
+				}
+				return
+			}
+
+			optimized := files.optimized[modelID]
+			if optimization.OptimizedSize != int64(len(optimized)) || optimization.OptimizedSize >= optimization.OriginalSize {
+				t.Errorf("expected a smaller file, got %d of %d bytes", optimization.OptimizedSize, optimization.OriginalSize)
+			}
+			if _, err := gltf.Parse(optimized); err != nil {
+				t.Errorf("the optimized file does not parse: %v", err)
+			}
+			if model.PublicGlbFile() != "optimized/"+modelID.String() {
+				t.Errorf("expected guests to be served the optimized file, got %q", model.PublicGlbFile())
+			}
+		})
+	}
+}
+
+func TestModelUsdzConversion(t *testing.T) {
+	tests := []struct {
+		name   string
+		glb    func(t *testing.T) []byte
+		panics bool
+		status models.ModelConversionStatus
+	}{
+		{"converted", func(t *testing.T) []byte { return triangleGlb(t, nil) }, false, models.ModelConversionCompleted},
+		{"invalid glb", func(t *testing.T) []byte { return []byte("not a model") }, false, models.ModelConversionFailed},
+		{"malformed normals", malformedNormalsGlb, false, models.ModelConversionFailed},
+		{"panic", func(t *testing.T) []byte { return triangleGlb(t, nil) }, true, models.ModelConversionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := &optimizationModelRepository{models: map[uuid.UUID]models.Model{}}
+			files := &glbStorage{uploaded: map[uuid.UUID][]byte{}, optimized: map[uuid.UUID][]byte{}, usdz: map[uuid.UUID][]byte{}}
+			var store storage.StorageService = files
+			if tt.panics {
+				store = panickingStorage{files}
+			}
+			service := NewModelService(repo, store, mq.NewMemoryMqProvider(1), models.ThumbnailConfig{}).(*modelService)
+
+			clientID, modelID := uuid.New(), uuid.New()
+			files.uploaded[modelID] = tt.glb(t)
+			model := models.Model{ID: &modelID, ClientID: clientID, GlbFile: "original", UsdzConversion: &models.ModelConversion{}}
+			if _, err := service.SaveModel(ctx, models.Tenant{ClientID: clientID}, model, true); err != nil {
+				t.Fatal(err)
+			}
+			if conversion := repo.models[modelID].UsdzConversion; conversion.Status != models.ModelConversionQueued {
+				t.Fatalf("expected the conversion to be queued, got %s", conversion.Status)
+			}
+
+			message, _ := json.Marshal(models.ModelConversionMessage{ModelID: modelID})
+			if err := service.processUsdzConversion(ctx, message); err != nil {
+				t.Fatal(err)
+			}
+
+			stored := repo.models[modelID]
+			conversion := stored.UsdzConversion
+			if conversion.Status != tt.status || conversion.CompletedAt == nil {
+				t.Fatalf("unexpected conversion %+v", conversion)
+			}
+			if tt.status != models.ModelConversionCompleted {
+				if stored.UsdzFile != "" || conversion.Reason == "" {
+					t.Errorf("expected failed conversions to have a reason and no file, got %q %+v", stored.UsdzFile, conversion)
+				}
+				return
+			}
+
+			if stored.UsdzFile != "usdz/"+modelID.String() {
+				t.Errorf("expected the generated usdz file, got %q", stored.UsdzFile)
+			}
+			data := files.usdz[modelID]
+			r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatalf("the usdz file is not a zip archive: %v", err)
+			}
+			if len(r.File) == 0 || !strings.HasSuffix(r.File[0].Name, ".usda") {
+				t.Errorf("expected the scene as the first file of the package")
+			}
+		})
+	}
+}
+
+// stagingStorage records the staged files moved over a model's files
+type stagingStorage struct {
+	storage.StorageService
+	replaced map[uuid.UUID]uuid.UUID
+}
+
+func (s *stagingStorage) ReplaceModelFiles(stagingID, modelID uuid.UUID) error {
+	s.replaced[stagingID] = modelID
+	return nil
+}
+
+func TestReplaceModel(t *testing.T) {
+	tests := []struct {
+		name      string
+		updateErr error
+	}{
+		{"saved", nil},
+		{"not saved", errors.New("connection reset")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			clientID, modelID, stagingID := uuid.New(), uuid.New(), uuid.New()
+			live := models.Model{ID: &modelID, ClientID: clientID, GlbFile: "/static/models/glb/" + modelID.String() + ".gltf"}
+			repo := &optimizationModelRepository{models: map[uuid.UUID]models.Model{modelID: live}, updateErr: tt.updateErr}
+			files := &stagingStorage{replaced: map[uuid.UUID]uuid.UUID{}}
+			service := NewModelService(repo, files, mq.NewMemoryMqProvider(1), models.ThumbnailConfig{})
+
+			update := models.Model{
+				ID:         &modelID,
+				ClientID:   clientID,
+				GlbFile:    "/static/models/glb/" + stagingID.String() + ".glb",
+				Thumbnail:  "/static/thumbnails/" + stagingID.String() + ".png",
+				Thumbnails: []*models.ModelThumbnail{{Size: 256, Format: "webp", Path: "/static/thumbnails/" + stagingID.String() + "_256.webp"}},
+			}
+			_, err := service.ReplaceModel(ctx, models.Tenant{ClientID: clientID}, update, stagingID)
+
+			if tt.updateErr != nil {
+				if err == nil || len(files.replaced) != 0 || repo.models[modelID].GlbFile != live.GlbFile {
+					t.Errorf("expected a failed update to leave the model's files alone, got %v %v", err, files.replaced)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if files.replaced[stagingID] != modelID {
+				t.Errorf("expected the staged files to replace the model's, got %v", files.replaced)
+			}
+			saved := repo.models[modelID]
+			if saved.GlbFile != "/static/models/glb/"+modelID.String()+".glb" ||
+				saved.Thumbnail != "/static/thumbnails/"+modelID.String()+".png" ||
+				saved.Thumbnails[0].Path != "/static/thumbnails/"+modelID.String()+"_256.webp" {
+				t.Errorf("expected the paths of the model's files, got %q %q %q", saved.GlbFile, saved.Thumbnail, saved.Thumbnails[0].Path)
+			}
+		})
+	}
+}
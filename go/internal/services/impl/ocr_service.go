@@ -2,147 +2,187 @@ package impl
 
 import (
 	"context"
-
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/ocr"
+	"github.com/ahmetkoprulu/bidi-menu/common/preprocess"
 	"github.com/ahmetkoprulu/bidi-menu/common/utils"
+	"github.com/ahmetkoprulu/bidi-menu/internal/menuparser"
 	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/services"
+	"go.uber.org/zap"
 )
 
-// import (
-// 	"context"
-// 	"fmt"
-// 	"regexp"
-// 	"strconv"
-// 	"strings"
-// 	"sync"
-
-// 	"github.com/ahmetkoprulu/bidi-menu/common/utils"
-// 	"github.com/ahmetkoprulu/bidi-menu/internal/models"
-// 	"github.com/otiai10/gosseract/v2"
-// )
-
-type OCRService interface {
-	ScanMenu(ctx context.Context, imagePaths []string) (*models.Menu, error)
-}
+// DefaultReviewThreshold is the confidence below which a scanned field is flagged for review
+const DefaultReviewThreshold = 0.8
 
 type ocrService struct {
-	logger *utils.Loggger
+	engine          ocr.IOcrEngine
+	pdfReader       ocr.IPdfReader
+	preprocessor    *preprocess.Preprocessor
+	reviewThreshold float64
+	logger          *utils.Loggger
+}
+
+// scanPage is a single menu page, either an uploaded image, a pdf page with a
+// text layer or a pdf page that has to be rendered before recognition
+type scanPage struct {
+	imagePath string
+	pdfPath   string
+	pdfPage   int
+	textLayer *ocr.Page
+}
+
+func NewOCRService(engine ocr.IOcrEngine, pdfReader ocr.IPdfReader, preprocessor *preprocess.Preprocessor, reviewThreshold float64) services.OCRService {
+	if reviewThreshold <= 0 {
+		reviewThreshold = DefaultReviewThreshold
+	}
+
+	return &ocrService{
+		engine:          engine,
+		pdfReader:       pdfReader,
+		preprocessor:    preprocessor,
+		reviewThreshold: reviewThreshold,
+		logger:          utils.Logger,
+	}
 }
 
-func NewOCRService() *ocrService {
-	var logger = utils.Logger
+func (s *ocrService) ScanMenu(ctx context.Context, filePaths []string, onProgress services.ScanProgressFunc) (*models.ScanResult, error) {
+	scanPages, err := s.splitPages(ctx, filePaths)
+	if err != nil {
+		return nil, err
+	}
 
-	return &ocrService{logger: logger}
+	pages, err := s.recognizePages(ctx, scanPages, onProgress)
+	if err != nil {
+		return nil, err
+	}
+
+	result := menuparser.Parse(pages)
+	return result.ToScanResult(s.reviewThreshold), nil
+}
+
+// splitPages expands every pdf into its pages, keeping the upload order
+func (s *ocrService) splitPages(ctx context.Context, filePaths []string) ([]*scanPage, error) {
+	scanPages := make([]*scanPage, 0, len(filePaths))
+	for _, path := range filePaths {
+		if !strings.EqualFold(filepath.Ext(path), ".pdf") {
+			scanPages = append(scanPages, &scanPage{imagePath: path})
+			continue
+		}
+
+		pdfPages, err := s.pdfReader.ReadPages(ctx, path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading pdf %s: %w", filepath.Base(path), err)
+		}
+
+		for _, pdfPage := range pdfPages {
+			scanPages = append(scanPages, &scanPage{
+				pdfPath:   path,
+				pdfPage:   pdfPage.Number,
+				textLayer: pdfPage.Text,
+			})
+		}
+	}
+
+	return scanPages, nil
 }
 
-func (s *ocrService) ScanMenu(ctx context.Context, imagePaths []string) (*models.Menu, error) {
-	return nil, nil
+// recognizePages runs the engine over every page concurrently and returns the
+// pages in the same order as they were given. Pdf pages with a text layer are
+// used as is.
+func (s *ocrService) recognizePages(ctx context.Context, scanPages []*scanPage, onProgress services.ScanProgressFunc) ([]*ocr.Page, error) {
+	pages := make([]*ocr.Page, len(scanPages))
+	errs := make([]error, len(scanPages))
+	semaphore := make(chan struct{}, runtime.NumCPU())
+
+	var mu sync.Mutex
+	processed := 0
+
+	var wg sync.WaitGroup
+	for i, scanPage := range scanPages {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			page, source, err := s.recognizePage(ctx, scanPage)
+			if err != nil {
+				errs[i] = fmt.Errorf("error processing page %d: %w", i+1, err)
+				return
+			}
+
+			page.Number = i + 1
+			pages[i] = page
+
+			if onProgress != nil {
+				mu.Lock()
+				processed++
+				onProgress(processed, len(scanPages))
+				mu.Unlock()
+			}
+
+			if s.logger != nil {
+				s.logger.Info("Processed menu page", zap.String("source", source), zap.Int("page", i+1), zap.Int("words", len(page.Words)))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return pages, nil
 }
 
-// func (s *ocrService) ScanMenu(ctx context.Context, imagePaths []string) (*models.Menu, error) {
-// 	var wg sync.WaitGroup
-// 	errorChan := make(chan error, len(imagePaths))
-// 	pageChan := make(chan string, len(imagePaths))
-
-// 	for _, path := range imagePaths {
-// 		wg.Add(1)
-// 		go func(path string) {
-// 			defer wg.Done()
-// 			text, err := processImage(path)
-// 			if err != nil {
-// 				errorChan <- fmt.Errorf("error processing %s: %v", path, err)
-// 				return
-// 			}
-// 			pageChan <- text
-// 			fmt.Printf("Processed: %s\n", path)
-// 		}(path)
-// 	}
-
-// 	// Wait for all goroutines to complete
-// 	wg.Wait()
-
-// 	var text string
-// 	for page := range pageChan {
-// 		text += page
-// 	}
-
-// 	// Process the text into structured data
-// 	menuData := processMenuText(text)
-// 	return &menuData, nil
-// }
-
-// func processImage(imagePath string) (string, error) {
-// 	client := gosseract.NewClient()
-// 	defer client.Close()
-
-// 	if err := client.SetImage(imagePath); err != nil {
-// 		return "", fmt.Errorf("failed to set image: %v", err)
-// 	}
-
-// 	// Configure Tesseract for line-by-line processing
-// 	client.SetPageSegMode(gosseract.PSM_SINGLE_BLOCK)
-// 	// Process the image line by line
-// 	text, err := client.Text()
-
-// 	if err != nil {
-// 		return "", fmt.Errorf("OCR processing failed: %v", err)
-// 	}
-
-// 	return text, nil
-// }
-
-// func extractPriceFromText(text string) (float64, error) {
-// 	// Look for price patterns like $10.99 or 10.99
-// 	re := regexp.MustCompile(`\$?(\d+\.?\d*)`)
-// 	matches := re.FindStringSubmatch(text)
-// 	if len(matches) > 1 {
-// 		return strconv.ParseFloat(matches[1], 64)
-// 	}
-// 	return 0, fmt.Errorf("no price found")
-// }
-
-// func processMenuText(text string) models.Menu {
-// 	lines := strings.Split(text, "\n")
-// 	menu := models.Menu{Categories: make([]*models.MenuCategory, 0)}
-// 	var currentCategory *models.MenuCategory
-
-// 	for _, line := range lines {
-// 		line = strings.TrimSpace(line)
-// 		if line == "" {
-// 			continue
-// 		}
-
-// 		// Assume lines in all caps are categories
-// 		if strings.ToUpper(line) == line && !strings.Contains(line, "$") {
-// 			if currentCategory != nil {
-// 				menu.Categories = append(menu.Categories, currentCategory)
-// 			}
-// 			currentCategory = &models.MenuCategory{
-// 				Name:      line,
-// 				MenuItems: make([]*models.MenuCategoryItem, 0),
-// 			}
-// 			continue
-// 		}
-
-// 		// Try to extract price from the line
-// 		if currentCategory != nil {
-// 			parts := strings.Split(line, "    ") // Multiple spaces as delimiter
-// 			if len(parts) >= 2 {
-// 				price, err := extractPriceFromText(parts[len(parts)-1])
-// 				if err == nil {
-// 					item := &models.MenuCategoryItem{
-// 						Name:  strings.TrimSpace(parts[0]),
-// 						Price: price,
-// 					}
-// 					currentCategory.MenuItems = append(currentCategory.MenuItems, item)
-// 				}
-// 			}
-// 		}
-// 	}
-
-// 	// Add the last category if it exists
-// 	if currentCategory != nil {
-// 		menu.Categories = append(menu.Categories, currentCategory)
-// 	}
-
-// 	return menu
-// }
+func (s *ocrService) recognizePage(ctx context.Context, page *scanPage) (*ocr.Page, string, error) {
+	if page.textLayer != nil {
+		return page.textLayer, "pdf", nil
+	}
+
+	imagePath := page.imagePath
+	if page.pdfPath != "" {
+		imagePath = fmt.Sprintf("%s-%03d.png", strings.TrimSuffix(page.pdfPath, filepath.Ext(page.pdfPath)), page.pdfPage)
+		if err := s.pdfReader.RenderPage(ctx, page.pdfPath, page.pdfPage, imagePath); err != nil {
+			return nil, "", err
+		}
+	}
+
+	recognized, err := s.engine.Recognize(ctx, s.preprocessImage(imagePath))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return recognized, s.engine.Name(), nil
+}
+
+// preprocessImage cleans up an uploaded photo or a rendered pdf page before
+// recognition. An image that fails to process is recognized as it is.
+func (s *ocrService) preprocessImage(imagePath string) string {
+	if s.preprocessor == nil || !s.preprocessor.Enabled() {
+		return imagePath
+	}
+
+	ext := strings.ToLower(filepath.Ext(imagePath))
+	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+		return imagePath
+	}
+
+	output := filepath.Join(filepath.Dir(imagePath), "preprocessed", strings.TrimSuffix(filepath.Base(imagePath), filepath.Ext(imagePath))+".png")
+	if err := s.preprocessor.ProcessFile(imagePath, output); err != nil {
+		if s.logger != nil {
+			s.logger.Error("Failed to preprocess menu image", zap.String("file", filepath.Base(imagePath)), zap.Error(err))
+		}
+		return imagePath
+	}
+
+	return output
+}
@@ -0,0 +1,115 @@
+package impl
+
+import (
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/ocr"
+	"github.com/ahmetkoprulu/bidi-menu/common/preprocess"
+)
+
+func TestScanMenuWithFakeEngine(t *testing.T) {
+	engine := ocr.NewFakeOcrEngine(map[string]string{
+		"page1.jpg": "STARTERS\nSoup of the day                 $6.50\nGarlic Bread                    $4.00",
+		"page2.jpg": "MAINS\nCheeseburger                   $12.99",
+	})
+	service := NewOCRService(engine, nil, nil, 0)
+
+	progress := 0
+	result, err := service.ScanMenu(context.Background(), []string{"/uploads/page1.jpg", "/uploads/page2.jpg"}, func(processed, total int) {
+		if total != 2 {
+			t.Errorf("expected 2 pages, got %d", total)
+		}
+		progress = max(progress, processed)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress != 2 {
+		t.Errorf("expected progress for both pages, got %d", progress)
+	}
+
+	categories := result.Menu.Categories
+	if len(categories) != 2 || categories[0].Name != "STARTERS" || categories[1].Name != "MAINS" {
+		t.Fatalf("expected the categories of both pages in order, got %+v", categories)
+	}
+	if len(categories[0].MenuItems) != 2 || categories[0].MenuItems[0].Name != "Soup of the day" || categories[0].MenuItems[0].Price != 6.5 {
+		t.Errorf("unexpected starters %+v", categories[0].MenuItems)
+	}
+	if len(categories[1].MenuItems) != 1 || categories[1].MenuItems[0].Price != 12.99 {
+		t.Errorf("unexpected mains %+v", categories[1].MenuItems)
+	}
+
+	// the fake engine is fully confident, so nothing needs review
+	if len(result.Fields) == 0 || len(result.Review) != 0 {
+		t.Errorf("expected fields without review, got %d fields and %d to review", len(result.Fields), len(result.Review))
+	}
+	for _, field := range result.Fields {
+		if field.Page == 0 || field.Box.Width == 0 {
+			t.Errorf("expected field %s to carry its page and box", field.Path)
+		}
+	}
+}
+
+func TestScanMenuEngineError(t *testing.T) {
+	service := NewOCRService(ocr.NewFakeOcrEngine(nil), nil, nil, 0)
+
+	if _, err := service.ScanMenu(context.Background(), []string{t.TempDir() + "/missing.jpg"}, nil); err == nil {
+		t.Error("expected the engine error")
+	}
+}
+
+// fakePdfReader serves fixed pages and renders blank png images
+type fakePdfReader struct {
+	pages    []*ocr.PdfPage
+	rendered []int
+}
+
+func (f *fakePdfReader) ReadPages(ctx context.Context, pdfPath string) ([]*ocr.PdfPage, error) {
+	return f.pages, nil
+}
+
+func (f *fakePdfReader) RenderPage(ctx context.Context, pdfPath string, pageNumber int, outputPath string) error {
+	f.rendered = append(f.rendered, pageNumber)
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return png.Encode(file, image.NewGray(image.Rect(0, 0, 64, 64)))
+}
+
+func TestScanMenuRendersPdfPagesWithoutTextLayer(t *testing.T) {
+	dir := t.TempDir()
+	reader := &fakePdfReader{pages: []*ocr.PdfPage{
+		{Number: 1, Text: ocr.LayoutText("STARTERS\nSoup of the day                 $6.50")},
+		{Number: 2},
+	}}
+	engine := ocr.NewFakeOcrEngine(map[string]string{"menu-002.png": "MAINS\nCheeseburger                   $12.99"})
+	preprocessor := preprocess.NewPreprocessor(preprocess.Options{Grayscale: true})
+	service := NewOCRService(engine, reader, preprocessor, 0)
+
+	result, err := service.ScanMenu(context.Background(), []string{filepath.Join(dir, "menu.pdf")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(reader.rendered, []int{2}) {
+		t.Errorf("expected only the page without a text layer to be rendered, got %v", reader.rendered)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "preprocessed", "menu-002.png")); err != nil {
+		t.Errorf("expected the rendered page to be preprocessed: %v", err)
+	}
+
+	categories := result.Menu.Categories
+	if len(categories) != 2 || categories[0].Name != "STARTERS" || categories[1].Name != "MAINS" {
+		t.Errorf("expected the text layer and the rendered page in order, got %+v", categories)
+	}
+}
@@ -0,0 +1,334 @@
+package impl
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/cache"
+	"github.com/ahmetkoprulu/bidi-menu/common/qrcode"
+	"github.com/ahmetkoprulu/bidi-menu/common/storage"
+	"github.com/ahmetkoprulu/bidi-menu/common/utils"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/repository"
+	"github.com/ahmetkoprulu/bidi-menu/internal/services"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// sheetQRSize is the pixel size of the QR codes on printed sheets
+	sheetQRSize = 600
+	// exportCacheTTL is how long a print export is reused, the key already
+	// changes with the menu's customization and the client's logo
+	exportCacheTTL = time.Hour
+	// exportCacheSize bounds how many print exports are kept in memory, callers
+	// choose the dpi, width and caption so the keys are not bounded themselves
+	exportCacheSize = 32
+)
+
+type qrCodeService struct {
+	clientRepo     repository.ClientRepository
+	shortLinkRepo  repository.ShortLinkRepository
+	storageService storage.StorageService
+	baseURL        string
+	shortBaseURL   string
+	exports        cache.Cache[[]byte]
+	logger         *utils.Loggger
+}
+
+func NewQRCodeService(clientRepo repository.ClientRepository, shortLinkRepo repository.ShortLinkRepository, storageService storage.StorageService, baseURL string, shortBaseURL string) services.QRCodeService {
+	return &qrCodeService{
+		clientRepo:     clientRepo,
+		shortLinkRepo:  shortLinkRepo,
+		storageService: storageService,
+		baseURL:        strings.TrimRight(baseURL, "/"),
+		shortBaseURL:   strings.TrimRight(shortBaseURL, "/"),
+		exports:        cache.NewLRUCache[[]byte](exportCacheSize),
+		logger:         utils.Logger,
+	}
+}
+
+// GenerateMenuQRCode renders the QR code with the menu's QR customization and
+// the client's logo in the middle, and stores it
+func (s *qrCodeService) GenerateMenuQRCode(ctx context.Context, menu *models.Menu) (string, error) {
+	if menu.ID == nil {
+		return "", fmt.Errorf("failed to generate qr code: menu has no id")
+	}
+
+	opts, _, err := s.renderOptions(ctx, menu)
+	if err != nil {
+		return "", err
+	}
+
+	return s.generate(s.menuURL(ctx, *menu.ID), opts, *menu.ID)
+}
+
+// GenerateTableQRCode renders and stores the QR code of a table, it looks like
+// the menu's but carries the table token
+func (s *qrCodeService) GenerateTableQRCode(ctx context.Context, menu *models.Menu, table *models.DiningTable) (string, error) {
+	opts, _, err := s.renderOptions(ctx, menu)
+	if err != nil {
+		return "", err
+	}
+
+	return s.generate(tableURL(s.menuURL(ctx, *menu.ID), table), opts, table.ID)
+}
+
+func (s *qrCodeService) RenderTableQRCode(ctx context.Context, menu *models.Menu, table *models.DiningTable) ([]byte, error) {
+	opts, _, err := s.renderOptions(ctx, menu)
+	if err != nil {
+		return nil, err
+	}
+
+	png, err := qrcode.Generate(tableURL(s.menuURL(ctx, *menu.ID), table), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate qr code: %w", err)
+	}
+
+	return png, nil
+}
+
+// RenderTableSheet renders a printable PDF with a card for each table, holding
+// the client's logo and name, the QR code and the table label
+func (s *qrCodeService) RenderTableSheet(ctx context.Context, menu *models.Menu, tables []*models.DiningTable) ([]byte, error) {
+	opts, client, err := s.renderOptions(ctx, menu)
+	if err != nil {
+		return nil, err
+	}
+
+	// the printed code is 70mm wide, rendering it larger keeps it sharp at about
+	// 200 dpi. The logo grows with it to cover the same share of the code.
+	size, logoSize := opts.Size, opts.LogoSize
+	if size <= 0 {
+		size = qrcode.DefaultSize
+	}
+	if logoSize <= 0 {
+		logoSize = qrcode.DefaultLogoSize
+	}
+	opts.Size = sheetQRSize
+	opts.LogoSize = logoSize * sheetQRSize / size
+
+	cards := make([]qrcode.SheetCard, 0, len(tables))
+	for _, table := range tables {
+		png, err := qrcode.Generate(tableURL(s.menuURL(ctx, *menu.ID), table), opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate qr code: %w", err)
+		}
+		cards = append(cards, qrcode.SheetCard{Label: table.Label, QRCode: png})
+	}
+
+	sheet := qrcode.SheetOptions{Logo: opts.Logo, Caption: "Scan to see the menu"}
+	if client != nil {
+		sheet.Title = client.Name
+	}
+
+	pdf, err := qrcode.Sheet(cards, sheet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render table sheet: %w", err)
+	}
+
+	return pdf, nil
+}
+
+// ExportMenuQRCode renders the menu's QR code for print. Exports are cached by
+// everything that goes into them, so repeated downloads skip loading the logo
+// and rendering.
+func (s *qrCodeService) ExportMenuQRCode(ctx context.Context, menu *models.Menu, opts qrcode.ExportOptions) (*models.QRCodeExport, error) {
+	if menu.ID == nil {
+		return nil, fmt.Errorf("failed to export qr code: menu has no id")
+	}
+
+	client, err := s.clientRepo.GetClient(ctx, menu.ClientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+
+	customization := qrOptions(menu)
+	opts.Size = customization.Size
+	opts.ErrorCorrection = customization.ErrorCorrection
+	opts.ForegroundColor = customization.ForegroundColor
+	opts.BackgroundColor = customization.BackgroundColor
+	opts.LogoSize = customization.LogoSize
+	if opts.Format == "" {
+		opts.Format = qrcode.FormatPNG
+	}
+
+	content := s.menuURL(ctx, *menu.ID)
+	key := exportKey(content, opts, client)
+	export := &models.QRCodeExport{ContentType: qrcode.ContentType(opts.Format), Format: opts.Format, ETag: key}
+	if data, err := s.exports.Get(key); err == nil {
+		export.Data = data
+		return export, nil
+	}
+
+	opts.Logo = s.clientLogo(client)
+	data, err := qrcode.Export(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.exports.Set(key, data, exportCacheTTL); err != nil {
+		s.logWarn("Failed to cache qr code export", err, zap.String("menuId", menu.ID.String()))
+	}
+
+	export.Data = data
+	return export, nil
+}
+
+// exportKey identifies an export by its content, its options and the client's
+// logo. The logo itself is not loaded for the key, its location and the time
+// the client was last updated stand in for it.
+func exportKey(content string, opts qrcode.ExportOptions, client *models.Client) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s|%s|%d|%g|%d|%q|%d|%s|%s|%s|%d", content, opts.Format, opts.DPI, opts.WidthMM, opts.QuietZone, opts.Caption,
+		opts.Size, opts.ErrorCorrection, opts.ForegroundColor, opts.BackgroundColor, opts.LogoSize)
+	if client != nil && client.Logo != nil {
+		fmt.Fprintf(hash, "|%s|%d", *client.Logo, client.UpdatedAt.UnixNano())
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
+
+// DeleteQRCode removes the stored QR code of a menu or table
+func (s *qrCodeService) DeleteQRCode(id uuid.UUID) error {
+	if err := s.storageService.DeleteQRCode(id); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete qr code: %w", err)
+	}
+
+	return nil
+}
+
+// renderOptions reads the QR customization of the menu and loads the logo of
+// its client. A logo that can not be loaded is left out rather than failing the
+// whole QR code.
+func (s *qrCodeService) renderOptions(ctx context.Context, menu *models.Menu) (qrcode.Options, *models.Client, error) {
+	opts := qrOptions(menu)
+
+	client, err := s.clientRepo.GetClient(ctx, menu.ClientID)
+	if err != nil {
+		return opts, nil, fmt.Errorf("failed to get client: %w", err)
+	}
+	opts.Logo = s.clientLogo(client)
+
+	return opts, client, nil
+}
+
+// clientLogo loads the client's logo, nil when the client has none or it can
+// not be loaded
+func (s *qrCodeService) clientLogo(client *models.Client) image.Image {
+	if client == nil || client.Logo == nil || *client.Logo == "" {
+		return nil
+	}
+
+	logo, err := s.loadLogo(*client.Logo)
+	if err != nil {
+		s.logWarn("Failed to load client logo for qr code", err, zap.String("clientId", client.ID.String()))
+		return nil
+	}
+
+	return logo
+}
+
+func (s *qrCodeService) generate(content string, opts qrcode.Options, id uuid.UUID) (string, error) {
+	png, err := qrcode.Generate(content, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate qr code: %w", err)
+	}
+
+	path, err := s.storageService.SaveQRCode(png, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to save qr code: %w", err)
+	}
+
+	return path, nil
+}
+
+// menuURL is the address the menu's QR codes point to. It is the menu's short
+// link, so printed codes keep working when the menu is replaced, or the menu's
+// public page for menus without one.
+func (s *qrCodeService) menuURL(ctx context.Context, menuID uuid.UUID) string {
+	link, err := s.shortLinkRepo.GetMenuShortLink(ctx, menuID)
+	if err == nil {
+		return shortLinkURL(s.shortBaseURL, link.Code)
+	}
+	if !errors.Is(err, models.ErrNotFound) {
+		s.logWarn("Failed to get menu short link for qr code", err, zap.String("menuId", menuID.String()))
+	}
+
+	return fmt.Sprintf("%s/menu/view/%s", s.baseURL, menuID.String())
+}
+
+// tableURL adds the token of the table to the menu's address
+func tableURL(menuURL string, table *models.DiningTable) string {
+	return fmt.Sprintf("%s?table=%s", menuURL, url.QueryEscape(table.Token))
+}
+
+// qrOptions reads the QR customization of the menu. The menu editor keeps it
+// in the menu customization, the dedicated field takes precedence when set.
+func qrOptions(menu *models.Menu) qrcode.Options {
+	customization := menu.QRCutomization
+	if customization == nil && menu.Customization != nil {
+		customization = menu.Customization.QRCode
+	}
+	if customization == nil {
+		return qrcode.Options{}
+	}
+
+	return qrcode.Options{
+		Size:            customization.Size,
+		ErrorCorrection: customization.ErrorCorrection,
+		ForegroundColor: customization.QRColor,
+		BackgroundColor: customization.BackgroundColor,
+		LogoSize:        customization.LogoSize,
+	}
+}
+
+// loadLogo decodes a logo given as a data url or as the key or public path of
+// a file in storage. Logos are never downloaded from other hosts.
+func (s *qrCodeService) loadLogo(logo string) (image.Image, error) {
+	var data []byte
+	if strings.HasPrefix(logo, "data:") {
+		_, encoded, ok := strings.Cut(logo, ",")
+		if !ok {
+			return nil, fmt.Errorf("invalid data url")
+		}
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode data url: %w", err)
+		}
+		data = decoded
+	} else {
+		content, err := s.storageService.ReadFile(logo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read logo %q: %w", logo, err)
+		}
+		data = content
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode logo: %w", err)
+	}
+
+	return img, nil
+}
+
+func (s *qrCodeService) logWarn(message string, err error, fields ...zap.Field) {
+	if s.logger == nil {
+		return
+	}
+
+	s.logger.Warn(message, append(fields, zap.Error(err))...)
+}
@@ -0,0 +1,76 @@
+package impl
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/storage"
+)
+
+// logoStorage keeps stored files by key
+type logoStorage struct {
+	storage.StorageService
+	files map[string][]byte
+}
+
+func (s *logoStorage) ReadFile(location string) ([]byte, error) {
+	data, ok := s.files[location]
+	if !ok {
+		return nil, storage.ErrNotStored
+	}
+	return data, nil
+}
+
+func TestLoadLogo(t *testing.T) {
+	logo := &bytes.Buffer{}
+	if err := png.Encode(logo, image.NewRGBA(image.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatal(err)
+	}
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write(logo.Bytes())
+	}))
+	defer server.Close()
+
+	service := &qrCodeService{storageService: &logoStorage{files: map[string][]byte{"logos/client.png": logo.Bytes()}}}
+
+	tests := []struct {
+		name   string
+		logo   string
+		loaded bool
+	}{
+		{"data url", "data:image/png;base64," + base64.StdEncoding.EncodeToString(logo.Bytes()), true},
+		{"storage key", "logos/client.png", true},
+		{"remote url", server.URL + "/logo.png", false},
+		{"metadata address", "http://169.254.169.254/latest/meta-data", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := service.loadLogo(tt.logo)
+			if !tt.loaded {
+				if err == nil {
+					t.Error("expected logos outside of the storage to be refused")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if img.Bounds().Dx() != 3 || img.Bounds().Dy() != 2 {
+				t.Errorf("expected the 3x2 logo, got %v", img.Bounds())
+			}
+		})
+	}
+
+	if requests.Load() != 0 {
+		t.Errorf("expected no logo to be downloaded, got %d requests", requests.Load())
+	}
+}
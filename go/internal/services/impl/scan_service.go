@@ -0,0 +1,168 @@
+package impl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/mq"
+	"github.com/ahmetkoprulu/bidi-menu/common/utils"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/repository"
+	"github.com/ahmetkoprulu/bidi-menu/internal/services"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ScanQueue is the queue menu scan jobs are published to
+const ScanQueue = "menu.scan"
+
+// defaultScanUploadDir keeps uploads on the local disk, which only works while
+// the api and the scan workers run on the same host
+var defaultScanUploadDir = filepath.Join(os.TempDir(), "menu-scans")
+
+type scanService struct {
+	scanJobRepo repository.ScanJobRepository
+	ocrService  services.OCRService
+	mqProvider  mq.IMqProvider
+	uploadDir   string
+	logger      *utils.Loggger
+}
+
+func NewScanService(scanJobRepo repository.ScanJobRepository, ocrService services.OCRService, mqProvider mq.IMqProvider, uploadDir string) services.ScanService {
+	if uploadDir == "" {
+		uploadDir = defaultScanUploadDir
+	}
+
+	return &scanService{
+		scanJobRepo: scanJobRepo,
+		ocrService:  ocrService,
+		mqProvider:  mqProvider,
+		uploadDir:   uploadDir,
+		logger:      utils.Logger,
+	}
+}
+
+// CreateUploadDir creates the directory the files of a new scan are saved to.
+// Scan workers read the files from it, so with a RabbitMQ queue it has to be on a
+// filesystem shared by the api and every worker.
+func (s *scanService) CreateUploadDir() (string, error) {
+	dir := filepath.Join(s.uploadDir, uuid.New().String())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	return dir, nil
+}
+
+func (s *scanService) EnqueueScan(ctx context.Context, clientID uuid.UUID, uploadDir string, filePaths []string) (*models.ScanJob, error) {
+	job := &models.ScanJob{
+		ID:         uuid.New(),
+		ClientID:   clientID,
+		Status:     models.ScanJobStatusQueued,
+		TotalPages: len(filePaths),
+	}
+
+	if err := s.scanJobRepo.CreateScanJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	message := models.ScanJobMessage{
+		JobID:     job.ID,
+		ClientID:  clientID,
+		UploadDir: uploadDir,
+		FilePaths: filePaths,
+	}
+
+	if err := s.mqProvider.Publish(ScanQueue, message); err != nil {
+		s.failJob(ctx, job.ID, err)
+		return nil, fmt.Errorf("failed to enqueue scan job: %w", err)
+	}
+
+	return job, nil
+}
+
+func (s *scanService) GetScanJob(ctx context.Context, clientID uuid.UUID, jobID uuid.UUID) (*models.ScanJob, error) {
+	job, err := s.scanJobRepo.GetScanJob(ctx, jobID, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.ClientID != clientID {
+		return nil, models.ErrNotFound
+	}
+
+	return job, nil
+}
+
+// StartWorkers subscribes the given number of consumers to the scan queue. They
+// stop when the context is cancelled.
+func (s *scanService) StartWorkers(ctx context.Context, workers int) error {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	for i := 0; i < workers; i++ {
+		err := s.mqProvider.Subscribe(ctx, ScanQueue, func(data []byte) error {
+			return s.processScanJob(ctx, data)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to scan queue: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *scanService) processScanJob(ctx context.Context, data []byte) error {
+	var message models.ScanJobMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		s.logError("Failed to parse scan job message", err)
+		return fmt.Errorf("failed to parse scan job message: %w", err)
+	}
+	defer os.RemoveAll(message.UploadDir)
+
+	if err := s.scanJobRepo.UpdateScanJobStatus(ctx, message.JobID, models.ScanJobStatusProcessing, nil); err != nil {
+		s.logError("Failed to start scan job", err, zap.String("jobId", message.JobID.String()))
+		return err
+	}
+
+	onProgress := func(processedPages int, totalPages int) {
+		if err := s.scanJobRepo.UpdateScanJobProgress(ctx, message.JobID, processedPages, totalPages); err != nil {
+			s.logError("Failed to update scan job progress", err, zap.String("jobId", message.JobID.String()))
+		}
+	}
+
+	scan, err := s.ocrService.ScanMenu(ctx, message.FilePaths, onProgress)
+	if err != nil {
+		s.failJob(ctx, message.JobID, err)
+		return err
+	}
+
+	scan.Menu.ClientID = message.ClientID
+	if err := s.scanJobRepo.CompleteScanJob(ctx, message.JobID, scan); err != nil {
+		s.failJob(ctx, message.JobID, err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *scanService) failJob(ctx context.Context, jobID uuid.UUID, cause error) {
+	s.logError("Menu scan job failed", cause, zap.String("jobId", jobID.String()))
+
+	message := cause.Error()
+	if err := s.scanJobRepo.UpdateScanJobStatus(ctx, jobID, models.ScanJobStatusFailed, &message); err != nil {
+		s.logError("Failed to mark scan job as failed", err, zap.String("jobId", jobID.String()))
+	}
+}
+
+func (s *scanService) logError(message string, err error, fields ...zap.Field) {
+	if s.logger == nil {
+		return
+	}
+
+	s.logger.Error(message, append(fields, zap.Error(err))...)
+}
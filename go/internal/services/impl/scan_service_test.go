@@ -0,0 +1,215 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/mq"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/services"
+	"github.com/google/uuid"
+)
+
+// recordingScanJobRepository keeps jobs in memory and records every status
+// they go through
+type recordingScanJobRepository struct {
+	jobs     map[uuid.UUID]*models.ScanJob
+	statuses []models.ScanJobStatus
+	done     chan struct{}
+	mu       sync.Mutex
+}
+
+func newRecordingScanJobRepository() *recordingScanJobRepository {
+	return &recordingScanJobRepository{jobs: map[uuid.UUID]*models.ScanJob{}, done: make(chan struct{}, 1)}
+}
+
+func (r *recordingScanJobRepository) CreateScanJob(ctx context.Context, job *models.ScanJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.jobs[job.ID] = job
+	r.statuses = append(r.statuses, job.Status)
+	return nil
+}
+
+func (r *recordingScanJobRepository) GetScanJob(ctx context.Context, id uuid.UUID, clientID uuid.UUID) (*models.ScanJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return job, nil
+}
+
+func (r *recordingScanJobRepository) UpdateScanJobStatus(ctx context.Context, id uuid.UUID, status models.ScanJobStatus, errorMessage *string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.jobs[id].Status = status
+	r.jobs[id].Error = errorMessage
+	r.statuses = append(r.statuses, status)
+	if status == models.ScanJobStatusFailed {
+		r.done <- struct{}{}
+	}
+	return nil
+}
+
+func (r *recordingScanJobRepository) UpdateScanJobProgress(ctx context.Context, id uuid.UUID, processedPages int, totalPages int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.jobs[id].ProcessedPages = processedPages
+	r.jobs[id].TotalPages = totalPages
+	return nil
+}
+
+func (r *recordingScanJobRepository) CompleteScanJob(ctx context.Context, id uuid.UUID, result *models.ScanResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.jobs[id].Status = models.ScanJobStatusCompleted
+	r.jobs[id].Result = result
+	r.statuses = append(r.statuses, models.ScanJobStatusCompleted)
+	r.done <- struct{}{}
+	return nil
+}
+
+func (r *recordingScanJobRepository) wait(t *testing.T) []models.ScanJobStatus {
+	t.Helper()
+
+	select {
+	case <-r.done:
+	case <-time.After(time.Second):
+		t.Fatal("scan job did not finish")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.statuses)
+}
+
+type fakeOCRService struct {
+	err error
+}
+
+func (f fakeOCRService) ScanMenu(ctx context.Context, imagePaths []string, onProgress services.ScanProgressFunc) (*models.ScanResult, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	for i := range imagePaths {
+		onProgress(i+1, len(imagePaths))
+	}
+	return &models.ScanResult{Menu: &models.Menu{Label: "Scanned"}}, nil
+}
+
+// enqueueUpload saves a page in a new upload directory and enqueues its scan
+func enqueueUpload(t *testing.T, service services.ScanService, clientID uuid.UUID) (*models.ScanJob, string) {
+	t.Helper()
+
+	dir, err := service.CreateUploadDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "000.jpg")
+	if err := os.WriteFile(path, []byte("jpg"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := service.EnqueueScan(context.Background(), clientID, dir, []string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job, dir
+}
+
+func TestScanJobCompletes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := newRecordingScanJobRepository()
+	uploadRoot := t.TempDir()
+	service := NewScanService(repo, fakeOCRService{}, mq.NewMemoryMqProvider(1), uploadRoot)
+	clientID := uuid.New()
+
+	job, dir := enqueueUpload(t, service, clientID)
+	if job.Status != models.ScanJobStatusQueued || job.TotalPages != 1 {
+		t.Fatalf("expected a queued job of 1 page, got %+v", job)
+	}
+	if filepath.Dir(dir) != uploadRoot {
+		t.Errorf("expected the upload directory under %s, got %s", uploadRoot, dir)
+	}
+
+	if err := service.StartWorkers(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := repo.wait(t)
+	if !slices.Equal(statuses, []models.ScanJobStatus{models.ScanJobStatusQueued, models.ScanJobStatusProcessing, models.ScanJobStatusCompleted}) {
+		t.Errorf("unexpected status transitions %v", statuses)
+	}
+
+	completed, err := service.GetScanJob(ctx, clientID, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completed.ProcessedPages != 1 || completed.Result.Menu.ClientID != clientID {
+		t.Errorf("expected the progress and the scanned menu of the client, got %+v", completed)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected the upload directory to be removed, got %v", err)
+	}
+
+	if _, err := service.GetScanJob(ctx, uuid.New(), job.ID); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("expected another client not to see the job, got %v", err)
+	}
+}
+
+func TestScanJobFails(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := newRecordingScanJobRepository()
+	service := NewScanService(repo, fakeOCRService{err: errors.New("engine crashed")}, mq.NewMemoryMqProvider(1), t.TempDir())
+
+	job, _ := enqueueUpload(t, service, uuid.New())
+	if err := service.StartWorkers(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := repo.wait(t)
+	if !slices.Equal(statuses, []models.ScanJobStatus{models.ScanJobStatusQueued, models.ScanJobStatusProcessing, models.ScanJobStatusFailed}) {
+		t.Errorf("unexpected status transitions %v", statuses)
+	}
+	if failed := repo.jobs[job.ID]; failed.Error == nil || *failed.Error != "engine crashed" {
+		t.Errorf("expected the error on the job, got %+v", failed.Error)
+	}
+}
+
+func TestScanJobFailsWhenNotEnqueued(t *testing.T) {
+	repo := newRecordingScanJobRepository()
+	provider := mq.NewMemoryMqProvider(1)
+	provider.Disconnect()
+	service := NewScanService(repo, fakeOCRService{}, provider, t.TempDir())
+
+	dir, err := service.CreateUploadDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.EnqueueScan(context.Background(), uuid.New(), dir, nil); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	statuses := repo.wait(t)
+	if !slices.Equal(statuses, []models.ScanJobStatus{models.ScanJobStatusQueued, models.ScanJobStatusFailed}) {
+		t.Errorf("expected the job to be marked failed, got %v", statuses)
+	}
+}
@@ -0,0 +1,330 @@
+package impl
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/repository"
+	"github.com/ahmetkoprulu/bidi-menu/internal/services"
+	"github.com/google/uuid"
+)
+
+const (
+	// shortCodeLength keeps codes short enough for a small QR code, 31^7 codes
+	// are plenty
+	shortCodeLength = 7
+	// shortCodeAlphabet leaves out characters that are easily confused
+	shortCodeAlphabet  = "23456789abcdefghjkmnpqrstuvwxyz"
+	shortCodeAttempts  = 5
+	scheduleTimeFormat = "15:04"
+)
+
+type shortLinkService struct {
+	shortLinkRepo repository.ShortLinkRepository
+	menuRepo      repository.MenuRepository
+	clientRepo    repository.ClientRepository
+	// baseURL is where the menus are served, shortBaseURL where the codes are
+	// redirected from
+	baseURL      string
+	shortBaseURL string
+	now          func() time.Time
+}
+
+func NewShortLinkService(shortLinkRepo repository.ShortLinkRepository, menuRepo repository.MenuRepository, clientRepo repository.ClientRepository, baseURL string, shortBaseURL string) services.ShortLinkService {
+	return &shortLinkService{
+		shortLinkRepo: shortLinkRepo,
+		menuRepo:      menuRepo,
+		clientRepo:    clientRepo,
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		shortBaseURL:  strings.TrimRight(shortBaseURL, "/"),
+		now:           time.Now,
+	}
+}
+
+// GetShortLinks lists the links of a client, only admins may list other clients
+func (s *shortLinkService) GetShortLinks(ctx context.Context, tenant models.Tenant, clientID uuid.UUID) ([]*models.ShortLink, error) {
+	if !tenant.Owns(clientID) {
+		return nil, models.ErrNotFound
+	}
+
+	links, err := s.shortLinkRepo.GetShortLinks(ctx, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get short links: %w", err)
+	}
+
+	for _, link := range links {
+		link.URL = shortLinkURL(s.shortBaseURL, link.Code)
+	}
+
+	return links, nil
+}
+
+func (s *shortLinkService) GetShortLink(ctx context.Context, tenant models.Tenant, id uuid.UUID) (*models.ShortLink, error) {
+	link, err := s.shortLinkRepo.GetShortLink(ctx, id, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get short link: %w", err)
+	}
+
+	if !tenant.Owns(link.ClientID) {
+		return nil, models.ErrNotFound
+	}
+
+	link.URL = shortLinkURL(s.shortBaseURL, link.Code)
+	return link, nil
+}
+
+// CreateShortLink creates the link with a new random code
+func (s *shortLinkService) CreateShortLink(ctx context.Context, tenant models.Tenant, link *models.ShortLink) error {
+	if !tenant.Owns(link.ClientID) {
+		return models.ErrNotFound
+	}
+
+	if err := s.validate(ctx, link); err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		code, err := generateShortCode()
+		if err != nil {
+			return fmt.Errorf("failed to generate short code: %w", err)
+		}
+		link.Code = code
+
+		err = s.shortLinkRepo.CreateShortLink(ctx, link)
+		if errors.Is(err, models.ErrShortCodeTaken) && attempt < shortCodeAttempts {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create short link: %w", err)
+		}
+
+		link.URL = shortLinkURL(s.shortBaseURL, link.Code)
+		return nil
+	}
+}
+
+// UpdateShortLink re-points the link, the code and so the printed QR codes
+// stay the same
+func (s *shortLinkService) UpdateShortLink(ctx context.Context, tenant models.Tenant, link *models.ShortLink) error {
+	existing, err := s.GetShortLink(ctx, tenant, link.ID)
+	if err != nil {
+		return err
+	}
+
+	// links are not moved between clients
+	link.ClientID = existing.ClientID
+	if err := s.validate(ctx, link); err != nil {
+		return err
+	}
+
+	if err := s.shortLinkRepo.UpdateShortLink(ctx, link, tenant); err != nil {
+		return fmt.Errorf("failed to update short link: %w", err)
+	}
+
+	link.URL = shortLinkURL(s.shortBaseURL, link.Code)
+	return nil
+}
+
+func (s *shortLinkService) DeleteShortLink(ctx context.Context, tenant models.Tenant, id uuid.UUID) error {
+	if _, err := s.GetShortLink(ctx, tenant, id); err != nil {
+		return err
+	}
+
+	if err := s.shortLinkRepo.DeleteShortLink(ctx, id, tenant); err != nil {
+		return fmt.Errorf("failed to delete short link: %w", err)
+	}
+
+	return nil
+}
+
+func (s *shortLinkService) EnsureMenuLink(ctx context.Context, tenant models.Tenant, menu *models.Menu) (*models.ShortLink, error) {
+	link, err := s.shortLinkRepo.GetMenuShortLink(ctx, *menu.ID)
+	if err == nil {
+		link.URL = shortLinkURL(s.shortBaseURL, link.Code)
+		return link, nil
+	}
+	if !errors.Is(err, models.ErrNotFound) {
+		return nil, fmt.Errorf("failed to get menu short link: %w", err)
+	}
+
+	link = &models.ShortLink{ClientID: menu.ClientID, Label: menu.Label, MenuID: menu.ID}
+	if err := s.CreateShortLink(ctx, tenant, link); err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// Resolve picks the menu of the first schedule that covers the current time in
+// the link's timezone, falling back to the link's menu
+func (s *shortLinkService) Resolve(ctx context.Context, code string) (*models.ShortLinkTarget, error) {
+	link, err := s.shortLinkRepo.GetShortLinkByCode(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve short link: %w", err)
+	}
+
+	menuID := link.MenuID
+	if len(link.Schedules) > 0 {
+		location, err := s.location(ctx, link)
+		if err != nil {
+			return nil, err
+		}
+
+		if scheduled := scheduledMenu(link.Schedules, s.now().In(location)); scheduled != nil {
+			menuID = scheduled
+		}
+	}
+
+	// the menu was deleted and nothing replaced it yet
+	if menuID == nil {
+		return nil, models.ErrNotFound
+	}
+
+	return &models.ShortLinkTarget{
+		Code:   link.Code,
+		MenuID: *menuID,
+		URL:    fmt.Sprintf("%s/menu/view/%s", s.baseURL, menuID.String()),
+	}, nil
+}
+
+// location is the timezone of the link, or of its client when the link has none
+func (s *shortLinkService) location(ctx context.Context, link *models.ShortLink) (*time.Location, error) {
+	timezone := link.Timezone
+	if timezone == "" {
+		client, err := s.clientRepo.GetClient(ctx, link.ClientID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get client: %w", err)
+		}
+		if client != nil && client.Timezone != nil {
+			timezone = *client.Timezone
+		}
+	}
+
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		// a broken client timezone should not take the menu down
+		return time.UTC, nil
+	}
+
+	return location, nil
+}
+
+// validate checks the schedules and that every menu the link can lead to
+// belongs to the link's client
+func (s *shortLinkService) validate(ctx context.Context, link *models.ShortLink) error {
+	if link.Timezone != "" {
+		if _, err := time.LoadLocation(link.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q", link.Timezone)
+		}
+	}
+
+	menuIDs := make([]uuid.UUID, 0, len(link.Schedules)+1)
+	if link.MenuID != nil {
+		menuIDs = append(menuIDs, *link.MenuID)
+	}
+
+	for i, schedule := range link.Schedules {
+		if err := validateSchedule(schedule); err != nil {
+			return fmt.Errorf("schedule %d: %w", i+1, err)
+		}
+		menuIDs = append(menuIDs, schedule.MenuID)
+	}
+
+	for _, menuID := range menuIDs {
+		menu, err := s.menuRepo.GetMenuById(ctx, menuID)
+		if err != nil || menu.ClientID != link.ClientID {
+			return fmt.Errorf("menu %s: %w", menuID, models.ErrNotFound)
+		}
+	}
+
+	return nil
+}
+
+func validateSchedule(schedule *models.ShortLinkSchedule) error {
+	if len(schedule.Days) == 0 {
+		return fmt.Errorf("at least one day is required")
+	}
+	for _, day := range schedule.Days {
+		if day < 0 || day > 6 {
+			return fmt.Errorf("invalid day %d, days run from 0 for Sunday to 6 for Saturday", day)
+		}
+	}
+
+	start, err := time.Parse(scheduleTimeFormat, schedule.StartTime)
+	if err != nil {
+		return fmt.Errorf("invalid start time %q, expected HH:MM", schedule.StartTime)
+	}
+	end, err := time.Parse(scheduleTimeFormat, schedule.EndTime)
+	if err != nil {
+		return fmt.Errorf("invalid end time %q, expected HH:MM", schedule.EndTime)
+	}
+	if start.Equal(end) {
+		return fmt.Errorf("start and end time are the same")
+	}
+
+	slices.Sort(schedule.Days)
+	schedule.Days = slices.Compact(schedule.Days)
+	return nil
+}
+
+// scheduledMenu returns the menu of the first schedule covering the time. A
+// schedule ending before it starts runs past midnight, the part after midnight
+// belongs to the day it started on.
+func scheduledMenu(schedules []*models.ShortLinkSchedule, now time.Time) *uuid.UUID {
+	minute := now.Hour()*60 + now.Minute()
+	today := int(now.Weekday())
+	yesterday := (today + 6) % 7
+
+	for _, schedule := range schedules {
+		start, err := time.Parse(scheduleTimeFormat, schedule.StartTime)
+		if err != nil {
+			continue
+		}
+		end, err := time.Parse(scheduleTimeFormat, schedule.EndTime)
+		if err != nil {
+			continue
+		}
+		startMinute := start.Hour()*60 + start.Minute()
+		endMinute := end.Hour()*60 + end.Minute()
+
+		var matches bool
+		if startMinute < endMinute {
+			matches = slices.Contains(schedule.Days, today) && minute >= startMinute && minute < endMinute
+		} else {
+			matches = (slices.Contains(schedule.Days, today) && minute >= startMinute) ||
+				(slices.Contains(schedule.Days, yesterday) && minute < endMinute)
+		}
+
+		if matches {
+			menuID := schedule.MenuID
+			return &menuID
+		}
+	}
+
+	return nil
+}
+
+// shortLinkURL is the address printed in QR codes for the code
+func shortLinkURL(baseURL string, code string) string {
+	return fmt.Sprintf("%s/s/%s", baseURL, code)
+}
+
+func generateShortCode() (string, error) {
+	b := make([]byte, shortCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	for i := range b {
+		b[i] = shortCodeAlphabet[int(b[i])%len(shortCodeAlphabet)]
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,66 @@
+package impl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestScheduledMenu(t *testing.T) {
+	breakfast, bar := uuid.New(), uuid.New()
+	schedules := []*models.ShortLinkSchedule{
+		{MenuID: breakfast, Days: []int{1, 2, 3, 4, 5}, StartTime: "07:00", EndTime: "11:30"},
+		// friday and saturday nights run into the next morning
+		{MenuID: bar, Days: []int{5, 6}, StartTime: "22:00", EndTime: "02:00"},
+	}
+
+	for _, tc := range []struct {
+		name string
+		now  string
+		want *uuid.UUID
+	}{
+		{"monday morning", "2024-06-03 08:15", &breakfast},
+		{"end is exclusive", "2024-06-03 11:30", nil},
+		{"sunday morning", "2024-06-09 08:15", nil},
+		{"friday night", "2024-06-07 23:00", &bar},
+		{"after midnight belongs to friday", "2024-06-08 01:59", &bar},
+		{"sunday after midnight belongs to saturday", "2024-06-09 01:00", &bar},
+		{"monday after midnight", "2024-06-10 01:00", nil},
+		{"thursday night", "2024-06-06 23:00", nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			now, err := time.Parse("2006-01-02 15:04", tc.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := scheduledMenu(schedules, now)
+			if (got == nil) != (tc.want == nil) || (got != nil && *got != *tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	schedule := &models.ShortLinkSchedule{Days: []int{3, 1, 3}, StartTime: "09:00", EndTime: "17:00"}
+	if err := validateSchedule(schedule); err != nil {
+		t.Fatal(err)
+	}
+	if len(schedule.Days) != 2 || schedule.Days[0] != 1 || schedule.Days[1] != 3 {
+		t.Errorf("expected sorted unique days, got %v", schedule.Days)
+	}
+
+	for _, invalid := range []*models.ShortLinkSchedule{
+		{Days: nil, StartTime: "09:00", EndTime: "17:00"},
+		{Days: []int{7}, StartTime: "09:00", EndTime: "17:00"},
+		{Days: []int{1}, StartTime: "9am", EndTime: "17:00"},
+		{Days: []int{1}, StartTime: "09:00", EndTime: "09:00"},
+	} {
+		if err := validateSchedule(invalid); err == nil {
+			t.Errorf("expected %+v to be rejected", invalid)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package impl
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/utils"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/ahmetkoprulu/bidi-menu/internal/repository"
+	"github.com/ahmetkoprulu/bidi-menu/internal/services"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxSheetTables is how many tables one printable sheet can hold
+	MaxSheetTables = 100
+	// DefaultTablePrefix labels numbered tables when no prefix is given
+	DefaultTablePrefix = "Table"
+)
+
+type tableService struct {
+	tableRepo     repository.TableRepository
+	menuRepo      repository.MenuRepository
+	qrCodeService services.QRCodeService
+	logger        *utils.Loggger
+}
+
+func NewTableService(tableRepo repository.TableRepository, menuRepo repository.MenuRepository, qrCodeService services.QRCodeService) services.TableService {
+	return &tableService{
+		tableRepo:     tableRepo,
+		menuRepo:      menuRepo,
+		qrCodeService: qrCodeService,
+		logger:        utils.Logger,
+	}
+}
+
+func (s *tableService) GetTables(ctx context.Context, tenant models.Tenant, menuID uuid.UUID) ([]*models.DiningTable, error) {
+	if _, err := s.getMenu(ctx, tenant, menuID); err != nil {
+		return nil, err
+	}
+
+	tables, err := s.tableRepo.GetTables(ctx, menuID, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tables: %w", err)
+	}
+
+	return tables, nil
+}
+
+// CreateTables adds a table for each label and renders their QR codes
+func (s *tableService) CreateTables(ctx context.Context, tenant models.Tenant, menuID uuid.UUID, labels []string) ([]*models.DiningTable, error) {
+	menu, err := s.getMenu(ctx, tenant, menuID)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make([]*models.DiningTable, 0, len(labels))
+	for _, label := range labels {
+		token, err := generateTableToken()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate table token: %w", err)
+		}
+		tables = append(tables, &models.DiningTable{Label: strings.TrimSpace(label), Token: token})
+	}
+
+	if err := s.tableRepo.CreateTables(ctx, menuID, tables, tenant); err != nil {
+		return nil, fmt.Errorf("failed to create tables: %w", err)
+	}
+
+	for _, table := range tables {
+		s.refreshQRCode(ctx, tenant, menu, table)
+	}
+
+	return tables, nil
+}
+
+// CreateNumberedTables adds count tables labelled with the prefix and a number,
+// counting on from the tables the menu already has
+func (s *tableService) CreateNumberedTables(ctx context.Context, tenant models.Tenant, menuID uuid.UUID, count int, prefix string) ([]*models.DiningTable, error) {
+	existing, err := s.GetTables(ctx, tenant, menuID)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		prefix = DefaultTablePrefix
+	}
+
+	labels := make([]string, count)
+	for i := range labels {
+		labels[i] = fmt.Sprintf("%s %d", prefix, len(existing)+i+1)
+	}
+
+	return s.CreateTables(ctx, tenant, menuID, labels)
+}
+
+func (s *tableService) UpdateTable(ctx context.Context, tenant models.Tenant, tableID uuid.UUID, label string) (*models.DiningTable, error) {
+	table := &models.DiningTable{ID: tableID, Label: strings.TrimSpace(label)}
+	if err := s.tableRepo.UpdateTable(ctx, table, tenant); err != nil {
+		return nil, fmt.Errorf("failed to update table: %w", err)
+	}
+
+	return table, nil
+}
+
+func (s *tableService) DeleteTable(ctx context.Context, tenant models.Tenant, tableID uuid.UUID) error {
+	if err := s.tableRepo.DeleteTable(ctx, tableID, tenant); err != nil {
+		return fmt.Errorf("failed to delete table: %w", err)
+	}
+
+	if err := s.qrCodeService.DeleteQRCode(tableID); err != nil {
+		s.logError("Failed to delete table qr code", err, zap.String("tableId", tableID.String()))
+	}
+
+	return nil
+}
+
+// GetTableQRCode renders the png of the table's QR code with the current
+// customization of its menu
+func (s *tableService) GetTableQRCode(ctx context.Context, tenant models.Tenant, tableID uuid.UUID) ([]byte, error) {
+	table, err := s.tableRepo.GetTable(ctx, tableID, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table: %w", err)
+	}
+
+	menu, err := s.getMenu(ctx, tenant, table.MenuID)
+	if err != nil {
+		return nil, err
+	}
+
+	png, err := s.qrCodeService.RenderTableQRCode(ctx, menu, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render table qr code: %w", err)
+	}
+
+	return png, nil
+}
+
+// GetTableSheet renders the printable sheet for the given tables of the menu,
+// or for a page of all its tables when none are given
+func (s *tableService) GetTableSheet(ctx context.Context, tenant models.Tenant, menuID uuid.UUID, tableIDs []uuid.UUID, offset, limit int) ([]byte, error) {
+	menu, err := s.getMenu(ctx, tenant, menuID)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := s.tableRepo.GetTables(ctx, menuID, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tables: %w", err)
+	}
+
+	if len(tableIDs) > 0 {
+		tables, err = selectTables(tables, tableIDs)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if limit <= 0 || limit > MaxSheetTables {
+			limit = MaxSheetTables
+		}
+		tables = tables[min(max(offset, 0), len(tables)):]
+		tables = tables[:min(limit, len(tables))]
+	}
+
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("no tables to print")
+	}
+	if len(tables) > MaxSheetTables {
+		return nil, fmt.Errorf("a sheet holds at most %d tables", MaxSheetTables)
+	}
+
+	pdf, err := s.qrCodeService.RenderTableSheet(ctx, menu, tables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render table sheet: %w", err)
+	}
+
+	return pdf, nil
+}
+
+// ResolveTable tells which menu and table a scanned token belongs to
+func (s *tableService) ResolveTable(ctx context.Context, token string) (*models.TableLocation, error) {
+	table, err := s.tableRepo.GetTableByToken(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve table: %w", err)
+	}
+
+	return &models.TableLocation{MenuID: table.MenuID, TableID: table.ID, Label: table.Label}, nil
+}
+
+// getMenu returns the menu if the tenant owns it. Menus of other clients are
+// reported as not found.
+func (s *tableService) getMenu(ctx context.Context, tenant models.Tenant, menuID uuid.UUID) (*models.Menu, error) {
+	menu, err := s.menuRepo.GetMenuById(ctx, menuID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu: %w", err)
+	}
+
+	if !tenant.Owns(menu.ClientID) {
+		return nil, models.ErrNotFound
+	}
+
+	return menu, nil
+}
+
+// refreshQRCode stores the table's QR code. The table is saved either way, the
+// code can still be downloaded or printed as it is rendered on demand.
+func (s *tableService) refreshQRCode(ctx context.Context, tenant models.Tenant, menu *models.Menu, table *models.DiningTable) {
+	qrCode, err := s.qrCodeService.GenerateTableQRCode(ctx, menu, table)
+	if err != nil {
+		s.logError("Failed to generate table qr code", err, zap.String("tableId", table.ID.String()))
+		return
+	}
+
+	if err := s.tableRepo.UpdateTableQRCode(ctx, table.ID, qrCode, tenant); err != nil {
+		s.logError("Failed to save table qr code", err, zap.String("tableId", table.ID.String()))
+		return
+	}
+
+	table.QRCode = qrCode
+}
+
+func (s *tableService) logError(message string, err error, fields ...zap.Field) {
+	if s.logger == nil {
+		return
+	}
+
+	s.logger.Error(message, append(fields, zap.Error(err))...)
+}
+
+// selectTables keeps the given tables in the order they were asked for, all of
+// them have to belong to the menu
+func selectTables(tables []*models.DiningTable, ids []uuid.UUID) ([]*models.DiningTable, error) {
+	byID := make(map[uuid.UUID]*models.DiningTable, len(tables))
+	for _, table := range tables {
+		byID[table.ID] = table
+	}
+
+	selected := make([]*models.DiningTable, 0, len(ids))
+	for _, id := range ids {
+		table, ok := byID[id]
+		if !ok {
+			return nil, models.ErrNotFound
+		}
+		selected = append(selected, table)
+	}
+
+	return selected, nil
+}
+
+// generateTableToken creates the short random token printed in a table's QR
+// code, it is not a secret but should not be guessable
+func generateTableToken() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
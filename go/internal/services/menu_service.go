@@ -3,15 +3,39 @@ package services
 import (
 	"context"
 
+	"github.com/ahmetkoprulu/bidi-menu/common/qrcode"
 	"github.com/ahmetkoprulu/bidi-menu/internal/models"
 	"github.com/google/uuid"
 )
 
 type MenuService interface {
-	SaveMenu(ctx context.Context, model models.Menu) (uuid.UUID, error)
-	ScanMenu(ctx context.Context, clientID uuid.UUID, imagePaths []string) (*models.Menu, error)
+	SaveMenu(ctx context.Context, tenant models.Tenant, model *models.Menu) (uuid.UUID, error)
 	GetMenu(ctx context.Context, clientID uuid.UUID) ([]*models.MenuCategory, error)
 	GetMenuById(ctx context.Context, id uuid.UUID) (*models.Menu, error)
-	DeleteMenu(ctx context.Context, id uuid.UUID) error
+	GetPublishedMenu(ctx context.Context, id uuid.UUID) (*models.Menu, error)
+	GetDraftMenu(ctx context.Context, tenant models.Tenant, id uuid.UUID) (*models.Menu, error)
+	GetMissingTranslations(ctx context.Context, tenant models.Tenant, id uuid.UUID, locale string) (*models.TranslationReport, error)
+	DeleteMenu(ctx context.Context, tenant models.Tenant, id uuid.UUID) error
+	ExportQRCode(ctx context.Context, tenant models.Tenant, id uuid.UUID, opts qrcode.ExportOptions) (*models.QRCodeExport, error)
 	RemoveModelFromMenuItems(ctx context.Context, modelID uuid.UUID) error
+
+	CreateCategory(ctx context.Context, tenant models.Tenant, category *models.MenuCategory) error
+	UpdateCategory(ctx context.Context, tenant models.Tenant, category *models.MenuCategory) error
+	UpdateCategoryOrder(ctx context.Context, tenant models.Tenant, categoryID uuid.UUID, order int) error
+	ReorderCategories(ctx context.Context, tenant models.Tenant, categoryOrders map[uuid.UUID]int) error
+	UpdateCategoryStatus(ctx context.Context, tenant models.Tenant, categoryID uuid.UUID, status models.MenuStatus) error
+	DeleteCategory(ctx context.Context, tenant models.Tenant, categoryID uuid.UUID) error
+
+	CreateMenuItem(ctx context.Context, tenant models.Tenant, categoryID uuid.UUID, item *models.MenuCategoryItem) error
+	UpdateMenuItem(ctx context.Context, tenant models.Tenant, item *models.MenuCategoryItem) error
+	MoveMenuItem(ctx context.Context, tenant models.Tenant, itemID uuid.UUID, categoryID uuid.UUID, order int) error
+	UpdateItemImages(ctx context.Context, tenant models.Tenant, itemID uuid.UUID, images []string) error
+	UpdateItemsStatus(ctx context.Context, tenant models.Tenant, itemIDs []uuid.UUID, status models.MenuStatus) error
+	DeleteMenuItem(ctx context.Context, tenant models.Tenant, itemID uuid.UUID) error
+
+	PublishMenu(ctx context.Context, tenant models.Tenant, id uuid.UUID) (*models.MenuVersion, error)
+	GetMenuVersions(ctx context.Context, tenant models.Tenant, id uuid.UUID) ([]*models.MenuVersion, error)
+	GetMenuVersion(ctx context.Context, tenant models.Tenant, id uuid.UUID, version int) (*models.MenuVersion, error)
+	DiffMenuVersions(ctx context.Context, tenant models.Tenant, id uuid.UUID, fromVersion int, toVersion *int) (*models.MenuVersionDiff, error)
+	RollbackMenu(ctx context.Context, tenant models.Tenant, id uuid.UUID, version int) (*models.MenuVersion, error)
 }
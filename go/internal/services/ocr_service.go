@@ -0,0 +1,14 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+)
+
+// ScanProgressFunc is called every time a page has been recognized
+type ScanProgressFunc func(processedPages int, totalPages int)
+
+type OCRService interface {
+	ScanMenu(ctx context.Context, imagePaths []string, onProgress ScanProgressFunc) (*models.ScanResult, error)
+}
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ahmetkoprulu/bidi-menu/common/qrcode"
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/google/uuid"
+)
+
+type QRCodeService interface {
+	// GenerateMenuQRCode renders the QR code of the menu's public page, stores
+	// it and returns its path
+	GenerateMenuQRCode(ctx context.Context, menu *models.Menu) (string, error)
+	GenerateTableQRCode(ctx context.Context, menu *models.Menu, table *models.DiningTable) (string, error)
+	RenderTableQRCode(ctx context.Context, menu *models.Menu, table *models.DiningTable) ([]byte, error)
+	RenderTableSheet(ctx context.Context, menu *models.Menu, tables []*models.DiningTable) ([]byte, error)
+	// ExportMenuQRCode renders the menu's QR code for print with its
+	// customization, the export options only set the format and physical size
+	ExportMenuQRCode(ctx context.Context, menu *models.Menu, opts qrcode.ExportOptions) (*models.QRCodeExport, error)
+	DeleteQRCode(id uuid.UUID) error
+}
@@ -0,0 +1,15 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/google/uuid"
+)
+
+type ScanService interface {
+	CreateUploadDir() (string, error)
+	EnqueueScan(ctx context.Context, clientID uuid.UUID, uploadDir string, filePaths []string) (*models.ScanJob, error)
+	GetScanJob(ctx context.Context, clientID uuid.UUID, jobID uuid.UUID) (*models.ScanJob, error)
+	StartWorkers(ctx context.Context, workers int) error
+}
@@ -0,0 +1,21 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/google/uuid"
+)
+
+type ShortLinkService interface {
+	GetShortLinks(ctx context.Context, tenant models.Tenant, clientID uuid.UUID) ([]*models.ShortLink, error)
+	GetShortLink(ctx context.Context, tenant models.Tenant, id uuid.UUID) (*models.ShortLink, error)
+	CreateShortLink(ctx context.Context, tenant models.Tenant, link *models.ShortLink) error
+	UpdateShortLink(ctx context.Context, tenant models.Tenant, link *models.ShortLink) error
+	DeleteShortLink(ctx context.Context, tenant models.Tenant, id uuid.UUID) error
+	// EnsureMenuLink returns the link the menu's QR code prints, creating it
+	// for menus that have none
+	EnsureMenuLink(ctx context.Context, tenant models.Tenant, menu *models.Menu) (*models.ShortLink, error)
+	// Resolve tells where the code leads at this moment
+	Resolve(ctx context.Context, code string) (*models.ShortLinkTarget, error)
+}
@@ -0,0 +1,19 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ahmetkoprulu/bidi-menu/internal/models"
+	"github.com/google/uuid"
+)
+
+type TableService interface {
+	GetTables(ctx context.Context, tenant models.Tenant, menuID uuid.UUID) ([]*models.DiningTable, error)
+	CreateTables(ctx context.Context, tenant models.Tenant, menuID uuid.UUID, labels []string) ([]*models.DiningTable, error)
+	CreateNumberedTables(ctx context.Context, tenant models.Tenant, menuID uuid.UUID, count int, prefix string) ([]*models.DiningTable, error)
+	UpdateTable(ctx context.Context, tenant models.Tenant, tableID uuid.UUID, label string) (*models.DiningTable, error)
+	DeleteTable(ctx context.Context, tenant models.Tenant, tableID uuid.UUID) error
+	GetTableQRCode(ctx context.Context, tenant models.Tenant, tableID uuid.UUID) ([]byte, error)
+	GetTableSheet(ctx context.Context, tenant models.Tenant, menuID uuid.UUID, tableIDs []uuid.UUID, offset, limit int) ([]byte, error)
+	ResolveTable(ctx context.Context, token string) (*models.TableLocation, error)
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS scan_jobs;
+DROP TYPE IF EXISTS scan_job_status CASCADE;
@@ -0,0 +1,16 @@
+CREATE TYPE scan_job_status AS ENUM ('queued', 'processing', 'completed', 'failed');
+
+CREATE TABLE scan_jobs (
+    id UUID PRIMARY KEY,
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    status scan_job_status NOT NULL DEFAULT 'queued',
+    total_pages INTEGER NOT NULL DEFAULT 0,
+    processed_pages INTEGER NOT NULL DEFAULT 0,
+    result JSONB,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_scan_jobs_client_id ON scan_jobs(client_id);
@@ -0,0 +1,35 @@
+ALTER TABLE menus ADD COLUMN categories JSONB;
+
+UPDATE menus m
+SET categories = (
+    SELECT COALESCE(jsonb_agg(
+        jsonb_build_object(
+            'id', c.id,
+            'name', c.name,
+            'status', c.status,
+            'menuItems', (
+                SELECT COALESCE(jsonb_agg(
+                    jsonb_strip_nulls(jsonb_build_object(
+                        'id', i.id,
+                        'name', i.name,
+                        'description', COALESCE(i.description, ''),
+                        'price', i.price,
+                        'variants', i.variants,
+                        'images', to_jsonb(i.images),
+                        'modelId', i.model_id,
+                        'status', i.status
+                    ))
+                    ORDER BY i.sort_order
+                ), '[]'::jsonb)
+                FROM menu_items i
+                WHERE i.category_id = c.id
+            )
+        )
+        ORDER BY c.sort_order
+    ), '[]'::jsonb)
+    FROM menu_categories c
+    WHERE c.menu_id = m.id
+);
+
+DROP TABLE IF EXISTS menu_items;
+DROP TABLE IF EXISTS menu_categories;
@@ -0,0 +1,95 @@
+CREATE TABLE menu_categories (
+    id UUID PRIMARY KEY,
+    menu_id UUID NOT NULL REFERENCES menus(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    status menu_status NOT NULL DEFAULT 'active',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE menu_items (
+    id UUID PRIMARY KEY,
+    category_id UUID NOT NULL REFERENCES menu_categories(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    price NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    variants JSONB,
+    images TEXT[] NOT NULL DEFAULT '{}',
+    model_id UUID REFERENCES models(id) ON DELETE SET NULL,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    status menu_status NOT NULL DEFAULT 'active',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_menu_categories_menu_id ON menu_categories(menu_id, sort_order);
+CREATE INDEX idx_menu_items_category_id ON menu_items(category_id, sort_order);
+CREATE INDEX idx_menu_items_model_id ON menu_items(model_id);
+
+-- Move the categories JSONB arrays into the new tables. Ids that are not valid
+-- uuids, or already taken by another menu, get a new one; array positions
+-- become the sort order.
+CREATE TEMPORARY TABLE migrated_categories AS
+SELECT
+    m.id AS menu_id,
+    c.value AS category,
+    c.ordinality - 1 AS sort_order,
+    CASE
+        WHEN c.value->>'id' ~* '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$'
+            AND row_number() OVER (PARTITION BY c.value->>'id' ORDER BY m.created_at) = 1
+        THEN (c.value->>'id')::uuid
+        ELSE gen_random_uuid()
+    END AS id
+FROM menus m
+CROSS JOIN LATERAL jsonb_array_elements(
+    CASE WHEN jsonb_typeof(m.categories) = 'array' THEN m.categories ELSE '[]'::jsonb END
+) WITH ORDINALITY AS c(value, ordinality);
+
+INSERT INTO menu_categories (id, menu_id, name, sort_order, status)
+SELECT
+    id,
+    menu_id,
+    COALESCE(category->>'name', ''),
+    sort_order,
+    CASE WHEN category->>'status' = 'inactive' THEN 'inactive' ELSE 'active' END::menu_status
+FROM migrated_categories;
+
+CREATE TEMPORARY TABLE migrated_items AS
+SELECT
+    mc.id AS category_id,
+    i.value AS item,
+    i.ordinality - 1 AS sort_order,
+    CASE
+        WHEN i.value->>'id' ~* '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$'
+            AND row_number() OVER (PARTITION BY i.value->>'id' ORDER BY mc.menu_id, mc.sort_order) = 1
+        THEN (i.value->>'id')::uuid
+        ELSE gen_random_uuid()
+    END AS id
+FROM migrated_categories mc
+CROSS JOIN LATERAL jsonb_array_elements(
+    CASE WHEN jsonb_typeof(mc.category->'menuItems') = 'array' THEN mc.category->'menuItems' ELSE '[]'::jsonb END
+) WITH ORDINALITY AS i(value, ordinality);
+
+INSERT INTO menu_items (id, category_id, name, description, price, variants, images, model_id, sort_order, status)
+SELECT
+    mi.id,
+    mi.category_id,
+    COALESCE(mi.item->>'name', ''),
+    mi.item->>'description',
+    CASE WHEN jsonb_typeof(mi.item->'price') = 'number' THEN (mi.item->>'price')::numeric ELSE 0 END,
+    CASE WHEN jsonb_typeof(mi.item->'variants') = 'array' THEN mi.item->'variants' END,
+    CASE
+        WHEN jsonb_typeof(mi.item->'images') = 'array'
+        THEN ARRAY(SELECT jsonb_array_elements_text(mi.item->'images'))
+        ELSE '{}'
+    END,
+    (SELECT md.id FROM models md WHERE md.id::text = mi.item->>'modelId'),
+    mi.sort_order,
+    CASE WHEN mi.item->>'status' = 'inactive' THEN 'inactive' ELSE 'active' END::menu_status
+FROM migrated_items mi;
+
+DROP TABLE migrated_items;
+DROP TABLE migrated_categories;
+
+ALTER TABLE menus DROP COLUMN categories;
@@ -0,0 +1,4 @@
+ALTER TABLE menus DROP COLUMN IF EXISTS published_at;
+ALTER TABLE menus DROP COLUMN IF EXISTS published_version;
+
+DROP TABLE IF EXISTS menu_versions;
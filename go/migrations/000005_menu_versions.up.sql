@@ -0,0 +1,74 @@
+CREATE TABLE menu_versions (
+    id UUID PRIMARY KEY,
+    menu_id UUID NOT NULL REFERENCES menus(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    snapshot JSONB NOT NULL,
+    source_version INTEGER,
+    published_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (menu_id, version)
+);
+
+CREATE INDEX idx_menu_versions_menu_id ON menu_versions(menu_id);
+
+ALTER TABLE menus ADD COLUMN published_version INTEGER;
+ALTER TABLE menus ADD COLUMN published_at TIMESTAMP WITH TIME ZONE;
+
+-- existing menus are live already, publish them as their first version so the
+-- public endpoint keeps serving them
+INSERT INTO menu_versions (id, menu_id, version, snapshot, created_at)
+SELECT
+    gen_random_uuid(),
+    m.id,
+    1,
+    jsonb_build_object(
+        'id', m.id,
+        'label', m.label,
+        'description', COALESCE(m.description, ''),
+        'clientID', m.client_id,
+        'status', m.status,
+        'customization', m.customization,
+        'createdAt', m.created_at,
+        'updatedAt', m.updated_at,
+        'categories', (
+            SELECT COALESCE(jsonb_agg(jsonb_build_object(
+                'id', mc.id,
+                'name', mc.name,
+                'status', mc.status,
+                'menuItems', (
+                    SELECT COALESCE(jsonb_agg(jsonb_build_object(
+                        'id', mi.id,
+                        'name', mi.name,
+                        'description', COALESCE(mi.description, ''),
+                        'price', mi.price,
+                        'variants', mi.variants,
+                        'images', to_jsonb(mi.images),
+                        'modelId', mi.model_id,
+                        'modelInfo', (
+                            SELECT jsonb_build_object(
+                                'id', md.id,
+                                'clientId', md.client_id,
+                                'name', md.name,
+                                'thumbnail', md.thumbnail,
+                                'glbFile', md.glb_file,
+                                'usdzFile', md.usdz_file,
+                                'createdAt', md.created_at,
+                                'updatedAt', md.updated_at
+                            )
+                            FROM models md
+                            WHERE md.id = mi.model_id
+                        ),
+                        'status', mi.status
+                    ) ORDER BY mi.sort_order, mi.created_at), '[]'::jsonb)
+                    FROM menu_items mi
+                    WHERE mi.category_id = mc.id
+                )
+            ) ORDER BY mc.sort_order, mc.created_at), '[]'::jsonb)
+            FROM menu_categories mc
+            WHERE mc.menu_id = m.id
+        )
+    ),
+    CURRENT_TIMESTAMP
+FROM menus m;
+
+UPDATE menus SET published_version = 1, published_at = CURRENT_TIMESTAMP;
@@ -0,0 +1,12 @@
+CREATE TABLE dining_tables (
+    id UUID PRIMARY KEY,
+    menu_id UUID NOT NULL REFERENCES menus(id) ON DELETE CASCADE,
+    label VARCHAR(100) NOT NULL,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    qr_code TEXT,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_dining_tables_menu_id ON dining_tables(menu_id, sort_order);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS short_link_schedules;
+DROP TABLE IF EXISTS short_links;
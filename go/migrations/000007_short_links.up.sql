@@ -0,0 +1,32 @@
+CREATE TABLE short_links (
+    id UUID PRIMARY KEY,
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    code VARCHAR(32) NOT NULL UNIQUE,
+    label VARCHAR(255) NOT NULL DEFAULT '',
+    menu_id UUID REFERENCES menus(id) ON DELETE SET NULL,
+    timezone VARCHAR(50),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- a schedule points the link to another menu on some days between two times of
+-- day, the first matching schedule wins and the link's menu is the fallback
+CREATE TABLE short_link_schedules (
+    id UUID PRIMARY KEY,
+    short_link_id UUID NOT NULL REFERENCES short_links(id) ON DELETE CASCADE,
+    menu_id UUID NOT NULL REFERENCES menus(id) ON DELETE CASCADE,
+    days SMALLINT[] NOT NULL DEFAULT '{0,1,2,3,4,5,6}',
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    sort_order INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_short_links_client_id ON short_links(client_id);
+CREATE INDEX idx_short_links_menu_id ON short_links(menu_id);
+CREATE INDEX idx_short_link_schedules_link_id ON short_link_schedules(short_link_id, sort_order);
+
+-- every existing menu gets a link, so the QR codes rendered from now on survive
+-- the menu being replaced
+INSERT INTO short_links (id, client_id, code, label, menu_id)
+SELECT gen_random_uuid(), m.client_id, substr(md5(m.id::text || random()::text), 1, 8), m.label, m.id
+FROM menus m;
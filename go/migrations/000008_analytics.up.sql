@@ -0,0 +1,19 @@
+-- hourly counters of the events sent by the public menu page. Events are summed
+-- in memory and added here in batches, the dashboard only reads these sums.
+-- Dimensions an event does not have hold the nil uuid, so they can be part of
+-- the primary key.
+CREATE TABLE menu_event_counts (
+    menu_id UUID NOT NULL REFERENCES menus(id) ON DELETE CASCADE,
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    event_type VARCHAR(20) NOT NULL,
+    item_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000',
+    model_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000',
+    source VARCHAR(10) NOT NULL DEFAULT 'direct',
+    table_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000',
+    device VARCHAR(10) NOT NULL DEFAULT 'unknown',
+    count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (menu_id, bucket, event_type, item_id, model_id, source, table_id, device)
+);
+
+CREATE INDEX idx_menu_event_counts_client_bucket ON menu_event_counts(client_id, bucket);
@@ -0,0 +1,5 @@
+ALTER TABLE menu_items DROP COLUMN IF EXISTS nutrition;
+ALTER TABLE menu_items DROP COLUMN IF EXISTS calories;
+ALTER TABLE menu_items DROP COLUMN IF EXISTS spice_level;
+ALTER TABLE menu_items DROP COLUMN IF EXISTS dietary_tags;
+ALTER TABLE menu_items DROP COLUMN IF EXISTS allergens;
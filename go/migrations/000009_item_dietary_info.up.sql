@@ -0,0 +1,5 @@
+ALTER TABLE menu_items ADD COLUMN allergens TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE menu_items ADD COLUMN dietary_tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE menu_items ADD COLUMN spice_level SMALLINT NOT NULL DEFAULT 0 CHECK (spice_level BETWEEN 0 AND 3);
+ALTER TABLE menu_items ADD COLUMN calories INTEGER CHECK (calories >= 0);
+ALTER TABLE menu_items ADD COLUMN nutrition JSONB;
@@ -0,0 +1 @@
+ALTER TABLE menu_items ADD COLUMN modifier_groups JSONB;
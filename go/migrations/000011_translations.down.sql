@@ -0,0 +1,5 @@
+ALTER TABLE menu_items DROP COLUMN IF EXISTS translations;
+ALTER TABLE menu_categories DROP COLUMN IF EXISTS translations;
+ALTER TABLE menus DROP COLUMN IF EXISTS translations;
+ALTER TABLE menus DROP COLUMN IF EXISTS locales;
+ALTER TABLE menus DROP COLUMN IF EXISTS default_locale;
@@ -0,0 +1,5 @@
+ALTER TABLE menus ADD COLUMN default_locale VARCHAR(16) NOT NULL DEFAULT 'en';
+ALTER TABLE menus ADD COLUMN locales TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE menus ADD COLUMN translations JSONB;
+ALTER TABLE menu_categories ADD COLUMN translations JSONB;
+ALTER TABLE menu_items ADD COLUMN translations JSONB;
@@ -0,0 +1,2 @@
+ALTER TABLE models DROP COLUMN IF EXISTS optimization;
+ALTER TABLE models DROP COLUMN IF EXISTS optimized_glb_file;
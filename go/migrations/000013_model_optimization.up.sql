@@ -0,0 +1,2 @@
+ALTER TABLE models ADD COLUMN optimized_glb_file TEXT NOT NULL DEFAULT '';
+ALTER TABLE models ADD COLUMN optimization JSONB;
@@ -0,0 +1 @@
+ALTER TABLE models ADD COLUMN thumbnails JSONB;